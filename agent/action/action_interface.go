@@ -1,7 +1,24 @@
 package action
 
+import (
+	"io"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
 type ProtocolVersion int
 
+// LoggingAction is implemented by actions that log, so that the dispatcher
+// can give them a logger with the correlation fields of the request
+type LoggingAction interface {
+	Action
+	WithLogger(boshlog.Logger) Action
+}
+
+// ProgressWriter receives output an action reports while it runs,
+// which get_task returns for running asynchronous actions
+type ProgressWriter io.Writer
+
 type Action interface {
 	IsAsynchronous(ProtocolVersion) bool
 	IsPersistent() bool
@@ -30,7 +30,7 @@ func (a CompilePackageAction) IsLoggable() bool {
 	return true
 }
 
-func (a CompilePackageAction) Run(blobID string, multiDigest boshcrypto.MultipleDigest, name, version string, deps boshcomp.Dependencies) (val map[string]interface{}, err error) {
+func (a CompilePackageAction) Run(progress ProgressWriter, blobID string, multiDigest boshcrypto.MultipleDigest, name, version string, deps boshcomp.Dependencies) (val map[string]interface{}, err error) {
 	pkg := boshcomp.Package{
 		BlobstoreID: blobID,
 		Name:        name,
@@ -38,6 +38,27 @@ func (a CompilePackageAction) Run(blobID string, multiDigest boshcrypto.Multiple
 		Version:     version,
 	}
 
+	compiled, err := a.compiler.Compile(pkg, modelsDependencies(deps), progress)
+	if err != nil {
+		err = bosherr.WrapErrorf(err, "Compiling package %s", pkg.Name)
+		return
+	}
+
+	val = map[string]interface{}{
+		"result": compiledPackageValue(compiled),
+	}
+	return
+}
+
+func (a CompilePackageAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a CompilePackageAction) Cancel() error {
+	return errors.New("not supported")
+}
+
+func modelsDependencies(deps boshcomp.Dependencies) []boshmodels.Package {
 	modelsDeps := []boshmodels.Package{}
 
 	for _, dep := range deps {
@@ -51,27 +72,33 @@ func (a CompilePackageAction) Run(blobID string, multiDigest boshcrypto.Multiple
 		})
 	}
 
-	uploadedBlobID, uploadedDigest, err := a.compiler.Compile(pkg, modelsDeps)
-	if err != nil {
-		err = bosherr.WrapErrorf(err, "Compiling package %s", pkg.Name)
-		return
+	return modelsDeps
+}
+
+func compiledPackageValue(compiled boshcomp.Result) map[string]interface{} {
+	result := map[string]interface{}{
+		"blobstore_id": compiled.BlobID,
+		"sha1":         compiled.Digest.String(),
 	}
 
-	result := map[string]string{
-		"blobstore_id": uploadedBlobID,
-		"sha1":         uploadedDigest.String(),
+	if compiled.Sandbox != nil {
+		result["sandbox"] = compiled.Sandbox
 	}
 
-	val = map[string]interface{}{
-		"result": result,
+	if compiled.LogsBlobID != "" {
+		result["logs"] = map[string]interface{}{
+			"blobstore_id": compiled.LogsBlobID,
+			"sha1":         compiled.LogsDigest.String(),
+		}
 	}
-	return
-}
 
-func (a CompilePackageAction) Resume() (interface{}, error) {
-	return nil, errors.New("not supported")
-}
+	if compiled.Provenance != nil {
+		result["provenance"] = map[string]interface{}{
+			"blobstore_id": compiled.ProvenanceBlobID,
+			"sha1":         compiled.ProvenanceDigest.String(),
+			"record":       compiled.Provenance,
+		}
+	}
 
-func (a CompilePackageAction) Cancel() error {
-	return errors.New("not supported")
+	return result
 }
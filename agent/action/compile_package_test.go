@@ -1,8 +1,10 @@
 package action_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -11,10 +13,12 @@ import (
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	fakecomp "github.com/cloudfoundry/bosh-agent/agent/compiler/fakes"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
 	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
 )
 
-func getCompileActionArguments() (blobID string, multiDigest boshcrypto.MultipleDigest, name, version string, deps boshcomp.Dependencies) {
+func getCompileActionArguments() (progress ProgressWriter, blobID string, multiDigest boshcrypto.MultipleDigest, name, version string, deps boshcomp.Dependencies) {
+	progress = ioutil.Discard
 	blobID = "fake-blobstore-id"
 	multiDigest = boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-sha1"))
 	name = "fake-package-name"
@@ -75,8 +79,10 @@ var _ = Describe("CompilePackageAction", func() {
 		})
 
 		It("compile package compiles the package and returns blob id", func() {
-			compiler.CompileBlobID = "my-blob-id"
-			compiler.CompileDigest = boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "some checksum")
+			compiler.CompileResult = boshcomp.Result{
+				BlobID: "my-blob-id",
+				Digest: boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "some checksum"),
+			}
 
 			expectedPkg := boshcomp.Package{
 				BlobstoreID: "fake-blobstore-id",
@@ -86,7 +92,7 @@ var _ = Describe("CompilePackageAction", func() {
 			}
 
 			expectedValue := map[string]interface{}{
-				"result": map[string]string{
+				"result": map[string]interface{}{
 					"blobstore_id": "my-blob-id",
 					"sha1":         "some checksum",
 				},
@@ -121,6 +127,78 @@ var _ = Describe("CompilePackageAction", func() {
 			Expect(compiler.CompileDeps).To(ConsistOf(expectedDeps))
 		})
 
+		It("reports the sandbox the packaging script ran in", func() {
+			compiler.CompileResult = boshcomp.Result{
+				BlobID:  "my-blob-id",
+				Digest:  boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "some checksum"),
+				Sandbox: &boshcomp.SandboxReport{TimeoutSeconds: 600, Limits: boshcgroup.Limits{MemoryMaxMB: 1024}},
+			}
+
+			value, err := action.Run(getCompileActionArguments())
+			Expect(err).ToNot(HaveOccurred())
+
+			valueJSON, err := json.Marshal(value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(valueJSON).To(MatchJSON(`{"result": {
+				"blobstore_id": "my-blob-id",
+				"sha1": "some checksum",
+				"sandbox": {"network": false, "timeout_seconds": 600, "limits": {"memory_max_mb": 1024}}
+			}}`))
+		})
+
+		It("reports where the provenance of the compiled package was uploaded", func() {
+			compiler.CompileResult = boshcomp.Result{
+				BlobID:           "my-blob-id",
+				Digest:           boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "some checksum"),
+				Provenance:       &boshcomp.Provenance{StemcellVersion: "621.5"},
+				ProvenanceBlobID: "my-provenance-blob-id",
+				ProvenanceDigest: boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "provenance checksum")),
+			}
+
+			value, err := action.Run(getCompileActionArguments())
+			Expect(err).ToNot(HaveOccurred())
+
+			provenance := value["result"].(map[string]interface{})["provenance"]
+			Expect(provenance).To(Equal(map[string]interface{}{
+				"blobstore_id": "my-provenance-blob-id",
+				"sha1":         "provenance checksum",
+				"record":       &boshcomp.Provenance{StemcellVersion: "621.5"},
+			}))
+		})
+
+		It("reports where the logs of the packaging script were uploaded", func() {
+			compiler.CompileResult = boshcomp.Result{
+				BlobID:     "my-blob-id",
+				Digest:     boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "some checksum"),
+				LogsBlobID: "my-logs-blob-id",
+				LogsDigest: boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "logs checksum"),
+			}
+
+			value, err := action.Run(getCompileActionArguments())
+			Expect(err).ToNot(HaveOccurred())
+
+			logs := value["result"].(map[string]interface{})["logs"]
+			Expect(logs).To(Equal(map[string]interface{}{
+				"blobstore_id": "my-logs-blob-id",
+				"sha1":         "logs checksum",
+			}))
+		})
+
+		It("writes the output of the compilation to progress", func() {
+			compiler.CompileResult = boshcomp.Result{
+				BlobID: "my-blob-id",
+				Digest: boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "some checksum"),
+			}
+			compiler.CompileOutput = "pkg: compiling\n"
+			progress := &bytes.Buffer{}
+
+			_, blobID, multiDigest, name, version, deps := getCompileActionArguments()
+
+			_, err := action.Run(progress, blobID, multiDigest, name, version, deps)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(progress.String()).To(Equal("pkg: compiling\n"))
+		})
+
 		It("returns error when compile fails", func() {
 			compiler.CompileErr = errors.New("fake-compile-error")
 
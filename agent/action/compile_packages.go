@@ -0,0 +1,56 @@
+package action
+
+import (
+	"errors"
+
+	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// CompilePackagesAction compiles a batch of packages whose dependencies
+// on each other form a DAG, compiling independent packages concurrently
+type CompilePackagesAction struct {
+	compiler boshcomp.Compiler
+}
+
+func NewCompilePackages(compiler boshcomp.Compiler) (compilePackages CompilePackagesAction) {
+	compilePackages.compiler = compiler
+	return
+}
+
+func (a CompilePackagesAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return true
+}
+
+func (a CompilePackagesAction) IsPersistent() bool {
+	return false
+}
+
+func (a CompilePackagesAction) IsLoggable() bool {
+	return true
+}
+
+// Run returns the compiled package of each package in the batch by name;
+// deps are the already compiled packages the batch depends on
+func (a CompilePackagesAction) Run(progress ProgressWriter, pkgs []boshcomp.BatchPackage, deps boshcomp.Dependencies) (map[string]interface{}, error) {
+	compiled, err := a.compiler.CompileBatch(pkgs, modelsDependencies(deps), progress)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Compiling packages")
+	}
+
+	results := map[string]interface{}{}
+
+	for name, result := range compiled {
+		results[name] = compiledPackageValue(result)
+	}
+
+	return map[string]interface{}{"result": results}, nil
+}
+
+func (a CompilePackagesAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a CompilePackagesAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,107 @@
+package action_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	fakecomp "github.com/cloudfoundry/bosh-agent/agent/compiler/fakes"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+)
+
+var _ = Describe("CompilePackagesAction", func() {
+	var (
+		compiler *fakecomp.FakeCompiler
+		action   CompilePackagesAction
+	)
+
+	BeforeEach(func() {
+		compiler = fakecomp.NewFakeCompiler()
+		action = NewCompilePackages(compiler)
+	})
+
+	AssertActionIsAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotCancelable(action)
+	AssertActionIsNotResumable(action)
+
+	Describe("Run", func() {
+		It("can unmarshal the packages of the batch", func() {
+			pkgsJSON := `[{
+				"name": "bar",
+				"version": "fake-bar-version",
+				"sha1": "9c7b167258b49ffa91c1689670bba9460808ad40",
+				"blobstore_id": "fake-bar-blobstore-id",
+				"dependencies": ["foo"]
+			}]`
+
+			var pkgs []boshcomp.BatchPackage
+			Expect(json.Unmarshal([]byte(pkgsJSON), &pkgs)).To(Succeed())
+
+			Expect(pkgs).To(Equal([]boshcomp.BatchPackage{{
+				Package: boshcomp.Package{
+					BlobstoreID: "fake-bar-blobstore-id",
+					Name:        "bar",
+					Sha1:        boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "9c7b167258b49ffa91c1689670bba9460808ad40")),
+					Version:     "fake-bar-version",
+				},
+				Dependencies: []string{"foo"},
+			}}))
+		})
+
+		It("compiles the batch and returns the compiled package of each package", func() {
+			pkgs := []boshcomp.BatchPackage{
+				{Package: boshcomp.Package{Name: "foo"}},
+				{Package: boshcomp.Package{Name: "bar"}, Dependencies: []string{"foo", "baz"}},
+			}
+
+			compiler.CompileBatchResults = map[string]boshcomp.Result{
+				"foo": {BlobID: "fake-foo-blob-id", Digest: boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-foo-sha1")},
+				"bar": {BlobID: "fake-bar-blob-id", Digest: boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-bar-sha1")},
+			}
+
+			value, err := action.Run(ioutil.Discard, pkgs, boshcomp.Dependencies{
+				"baz": boshcomp.Package{
+					BlobstoreID: "fake-baz-blob-id",
+					Name:        "baz",
+					Sha1:        boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-baz-sha1")),
+					Version:     "fake-baz-version",
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(value).To(Equal(map[string]interface{}{
+				"result": map[string]interface{}{
+					"foo": map[string]interface{}{"blobstore_id": "fake-foo-blob-id", "sha1": "fake-foo-sha1"},
+					"bar": map[string]interface{}{"blobstore_id": "fake-bar-blob-id", "sha1": "fake-bar-sha1"},
+				},
+			}))
+
+			Expect(compiler.CompileBatchPkgs).To(Equal(pkgs))
+			Expect(compiler.CompileBatchDeps).To(Equal([]boshmodels.Package{{
+				Name:    "baz",
+				Version: "fake-baz-version",
+				Source: boshmodels.Source{
+					Sha1:        boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-baz-sha1")),
+					BlobstoreID: "fake-baz-blob-id",
+				},
+			}}))
+		})
+
+		It("returns error when compiling the batch fails", func() {
+			compiler.CompileBatchErr = errors.New("fake-compile-batch-error")
+
+			_, err := action.Run(ioutil.Discard, []boshcomp.BatchPackage{}, boshcomp.Dependencies{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-compile-batch-error"))
+		})
+	})
+})
@@ -3,12 +3,15 @@ package action
 import (
 	boshappl "github.com/cloudfoundry/bosh-agent/agent/applier"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	boshevent "github.com/cloudfoundry/bosh-agent/agent/event"
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshnotif "github.com/cloudfoundry/bosh-agent/notification"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshntp "github.com/cloudfoundry/bosh-agent/platform/ntp"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
@@ -31,7 +34,11 @@ func NewFactory(
 	compiler boshcomp.Compiler,
 	jobSupervisor boshjobsuper.JobSupervisor,
 	specService boshas.V1Service,
+	jobsBc boshbc.BundleCollection,
+	packagesBc boshbc.BundleCollection,
 	jobScriptProvider boshscript.JobScriptProvider,
+	eventStore boshevent.Store,
+	archiveOptions bosharchive.Options,
 	logger boshlog.Logger,
 ) (factory Factory) {
 	compressor := platform.GetCompressor()
@@ -53,21 +60,29 @@ func NewFactory(
 
 			// VM admin
 			"ssh":             NewSSH(settingsService, platform, dirProvider, logger),
-			"fetch_logs":      NewFetchLogs(compressor, copier, blobstore, dirProvider),
+			"fetch_logs":      NewFetchLogs(compressor, archiveOptions.LogsFormat, copier, blobstore, dirProvider),
 			"update_settings": NewUpdateSettings(settingsService, platform, certManager, logger),
 
 			// Job management
 			"prepare":    NewPrepare(applier),
 			"apply":      NewApply(applier, specService, settingsService, dirProvider.InstanceDir(), platform.GetFs()),
+			"plan_apply": NewPlanApply(specService, settingsService, jobsBc, packagesBc),
 			"start":      NewStart(jobSupervisor, applier, specService),
 			"stop":       NewStop(jobSupervisor),
 			"drain":      NewDrain(notifier, specService, jobScriptProvider, jobSupervisor, logger),
 			"get_state":  NewGetState(settingsService, specService, jobSupervisor, vitalsService, ntpService),
+			"get_events": NewGetEvents(eventStore),
 			"run_errand": NewRunErrand(specService, dirProvider.JobsDir(), platform.GetRunner(), logger),
-			"run_script": NewRunScript(jobScriptProvider, specService, logger),
+			"run_script": NewRunScript(jobScriptProvider, specService, jobSupervisor, logger),
+
+			// Process management
+			"start_process":   NewStartProcess(jobSupervisor),
+			"stop_process":    NewStopProcess(jobSupervisor),
+			"restart_process": NewRestartProcess(jobSupervisor),
 
 			// Compilation
 			"compile_package":    NewCompilePackage(compiler),
+			"compile_packages":   NewCompilePackages(compiler),
 			"release_apply_spec": NewReleaseApplySpec(platform),
 
 			// Rendered Templates
@@ -7,13 +7,16 @@ import (
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
 
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshntp "github.com/cloudfoundry/bosh-agent/platform/ntp"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	fakebc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection/fakes"
 	fakeappl "github.com/cloudfoundry/bosh-agent/agent/applier/fakes"
 	fakecomp "github.com/cloudfoundry/bosh-agent/agent/compiler/fakes"
+	fakeevent "github.com/cloudfoundry/bosh-agent/agent/event/fakes"
 	fakescript "github.com/cloudfoundry/bosh-agent/agent/script/fakes"
 	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
@@ -37,7 +40,10 @@ var _ = Describe("concreteFactory", func() {
 		compiler          *fakecomp.FakeCompiler
 		jobSupervisor     *fakejobsuper.FakeJobSupervisor
 		specService       *fakeas.FakeV1Service
+		jobsBc            *fakebc.FakeBundleCollection
+		packagesBc        *fakebc.FakeBundleCollection
 		jobScriptProvider boshscript.JobScriptProvider
+		eventStore        *fakeevent.FakeStore
 		factory           Factory
 		logger            boshlog.Logger
 	)
@@ -53,7 +59,10 @@ var _ = Describe("concreteFactory", func() {
 		compiler = fakecomp.NewFakeCompiler()
 		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 		specService = fakeas.NewFakeV1Service()
+		jobsBc = fakebc.NewFakeBundleCollection()
+		packagesBc = fakebc.NewFakeBundleCollection()
 		jobScriptProvider = &fakescript.FakeJobScriptProvider{}
+		eventStore = &fakeevent.FakeStore{}
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 
 		factory = NewFactory(
@@ -67,7 +76,11 @@ var _ = Describe("concreteFactory", func() {
 			compiler,
 			jobSupervisor,
 			specService,
+			jobsBc,
+			packagesBc,
 			jobScriptProvider,
+			eventStore,
+			bosharchive.Options{LogsFormat: bosharchive.FormatZstd},
 			logger,
 		)
 	})
@@ -84,6 +97,12 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewApply(applier, specService, settingsService, boshdir.NewProvider("/var/vcap").InstanceDir(), platform.GetFs())))
 	})
 
+	It("plan_apply", func() {
+		action, err := factory.Create("plan_apply")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewPlanApply(specService, settingsService, jobsBc, packagesBc)))
+	})
+
 	It("drain", func() {
 		action, err := factory.Create("drain")
 		Expect(err).ToNot(HaveOccurred())
@@ -94,7 +113,7 @@ var _ = Describe("concreteFactory", func() {
 	It("fetch_logs", func() {
 		action, err := factory.Create("fetch_logs")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewFetchLogs(platform.GetCompressor(), platform.GetCopier(), blobstore, platform.GetDirProvider())))
+		Expect(action).To(Equal(NewFetchLogs(platform.GetCompressor(), bosharchive.FormatZstd, platform.GetCopier(), blobstore, platform.GetDirProvider())))
 	})
 
 	It("get_task", func() {
@@ -116,6 +135,12 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewGetState(settingsService, specService, jobSupervisor, platform.GetVitalsService(), ntpService)))
 	})
 
+	It("get_events", func() {
+		action, err := factory.Create("get_events")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewGetEvents(eventStore)))
+	})
+
 	It("list_disk", func() {
 		action, err := factory.Create("list_disk")
 		Expect(err).ToNot(HaveOccurred())
@@ -182,6 +207,24 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewStop(jobSupervisor)))
 	})
 
+	It("start_process", func() {
+		action, err := factory.Create("start_process")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewStartProcess(jobSupervisor)))
+	})
+
+	It("stop_process", func() {
+		action, err := factory.Create("stop_process")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewStopProcess(jobSupervisor)))
+	})
+
+	It("restart_process", func() {
+		action, err := factory.Create("restart_process")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewRestartProcess(jobSupervisor)))
+	})
+
 	It("unmount_disk", func() {
 		action, err := factory.Create("unmount_disk")
 		Expect(err).ToNot(HaveOccurred())
@@ -194,6 +237,12 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewCompilePackage(compiler)))
 	})
 
+	It("compile_packages", func() {
+		action, err := factory.Create("compile_packages")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewCompilePackages(compiler)))
+	})
+
 	It("run_errand", func() {
 		action, err := factory.Create("run_errand")
 		Expect(err).ToNot(HaveOccurred())
@@ -205,7 +254,7 @@ var _ = Describe("concreteFactory", func() {
 	It("run_script", func() {
 		action, err := factory.Create("run_script")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewRunScript(jobScriptProvider, specService, logger)))
+		Expect(action).To(Equal(NewRunScript(jobScriptProvider, specService, jobSupervisor, logger)))
 	})
 
 	It("prepare", func() {
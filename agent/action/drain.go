@@ -50,6 +50,11 @@ func NewDrain(
 	}
 }
 
+func (a DrainAction) WithLogger(logger boshlog.Logger) Action {
+	a.logger = logger
+	return a
+}
+
 func (a DrainAction) IsAsynchronous(_ ProtocolVersion) bool {
 	return true
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	boshaction "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
 type FakeFactory struct {
@@ -56,6 +57,8 @@ type TestAction struct {
 	CancelErr error
 
 	ProtocolVersion boshaction.ProtocolVersion
+
+	Logger boshlog.Logger
 }
 
 func (a *TestAction) IsAsynchronous(protocolVersion boshaction.ProtocolVersion) bool {
@@ -71,6 +74,11 @@ func (a *TestAction) IsLoggable() bool {
 	return a.Loggable
 }
 
+func (a *TestAction) WithLogger(logger boshlog.Logger) boshaction.Action {
+	a.Logger = logger
+	return a
+}
+
 func (a *TestAction) Run(payload []byte) (interface{}, error) {
 	return nil, nil
 }
@@ -1,6 +1,8 @@
 package fakes
 
 import (
+	"io"
+
 	boshaction "github.com/cloudfoundry/bosh-agent/agent/action"
 )
 
@@ -8,6 +10,7 @@ type FakeRunner struct {
 	RunAction          boshaction.Action
 	RunPayload         []byte
 	RunProtocolVersion boshaction.ProtocolVersion
+	RunProgress        io.Writer
 	RunValue           interface{}
 	RunErr             error
 
@@ -24,6 +27,11 @@ func (runner *FakeRunner) Run(action boshaction.Action, payload []byte, version
 	return runner.RunValue, runner.RunErr
 }
 
+func (runner *FakeRunner) RunWithProgress(action boshaction.Action, payload []byte, version boshaction.ProtocolVersion, progress io.Writer) (interface{}, error) {
+	runner.RunProgress = progress
+	return runner.Run(action, payload, version)
+}
+
 func (runner *FakeRunner) Resume(action boshaction.Action, payload []byte) (interface{}, error) {
 	runner.ResumeAction = action
 	runner.ResumePayload = payload
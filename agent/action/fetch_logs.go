@@ -3,6 +3,7 @@ package action
 import (
 	"errors"
 
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -10,19 +11,22 @@ import (
 )
 
 type FetchLogsAction struct {
-	compressor  boshcmd.Compressor
+	compressor  bosharchive.Compressor
+	format      bosharchive.Format
 	copier      boshcmd.Copier
 	blobstore   boshblob.DigestBlobstore
 	settingsDir boshdirs.Provider
 }
 
 func NewFetchLogs(
-	compressor boshcmd.Compressor,
+	compressor bosharchive.Compressor,
+	format bosharchive.Format,
 	copier boshcmd.Copier,
 	blobstore boshblob.DigestBlobstore,
 	settingsDir boshdirs.Provider,
 ) (action FetchLogsAction) {
 	action.compressor = compressor
+	action.format = format
 	action.copier = copier
 	action.blobstore = blobstore
 	action.settingsDir = settingsDir
@@ -68,7 +72,8 @@ func (a FetchLogsAction) Run(logType string, filters []string) (value map[string
 
 	defer a.copier.CleanUp(tmpDir)
 
-	tarball, err := a.compressor.CompressFilesInDir(tmpDir)
+	// The blobstore calculates the digest of the uploaded logs itself
+	tarball, _, err := a.compressor.CompressFilesInDirAs(tmpDir, a.format, nil)
 	if err != nil {
 		err = bosherr.WrapError(err, "Making logs tarball")
 		return
@@ -7,6 +7,8 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
+	fakearchive "github.com/cloudfoundry/bosh-agent/platform/archive/fakes"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
 	fakeblobstore "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
@@ -16,7 +18,7 @@ import (
 
 var _ = Describe("FetchLogsAction", func() {
 	var (
-		compressor  *fakecmd.FakeCompressor
+		compressor  *fakearchive.FakeCompressor
 		copier      *fakecmd.FakeCopier
 		blobstore   *fakeblobstore.FakeDigestBlobstore
 		dirProvider boshdirs.Provider
@@ -24,11 +26,11 @@ var _ = Describe("FetchLogsAction", func() {
 	)
 
 	BeforeEach(func() {
-		compressor = fakecmd.NewFakeCompressor()
+		compressor = fakearchive.NewFakeCompressor()
 		blobstore = &fakeblobstore.FakeDigestBlobstore{}
 		dirProvider = boshdirs.NewProvider("/fake/dir")
 		copier = fakecmd.NewFakeCopier()
-		action = NewFetchLogs(compressor, copier, blobstore, dirProvider)
+		action = NewFetchLogs(compressor, bosharchive.FormatZstd, copier, blobstore, dirProvider)
 	})
 
 	AssertActionIsAsynchronous(action)
@@ -65,6 +67,7 @@ var _ = Describe("FetchLogsAction", func() {
 			Expect(copier.FilteredCopyToTempTempDir).To(Equal(compressor.CompressFilesInDirDir))
 			Expect(copier.CleanUpTempDir).To(Equal(compressor.CompressFilesInDirDir))
 
+			Expect(compressor.CompressFilesInDirFormat).To(Equal(bosharchive.FormatZstd))
 			Expect(compressor.CompressFilesInDirTarballPath).To(Equal(blobstore.CreateArgsForCall(0)))
 
 			boshassert.MatchesJSONString(GinkgoT(), logs, `{"blobstore_id":"my-blob-id","sha1":"`+sha1+`"}`)
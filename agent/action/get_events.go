@@ -0,0 +1,52 @@
+package action
+
+import (
+	"errors"
+
+	boshevent "github.com/cloudfoundry/bosh-agent/agent/event"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type GetEventsAction struct {
+	eventStore boshevent.Store
+}
+
+func NewGetEvents(eventStore boshevent.Store) (action GetEventsAction) {
+	action.eventStore = eventStore
+	return
+}
+
+func (a GetEventsAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return false
+}
+
+func (a GetEventsAction) IsPersistent() bool {
+	return false
+}
+
+func (a GetEventsAction) IsLoggable() bool {
+	return true
+}
+
+// Run returns the kept job events, or only those after the given event id
+func (a GetEventsAction) Run(since ...int64) ([]boshevent.Event, error) {
+	var id int64
+	if len(since) > 0 {
+		id = since[0]
+	}
+
+	events, err := a.eventStore.Since(id)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting job events")
+	}
+
+	return events, nil
+}
+
+func (a GetEventsAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a GetEventsAction) Cancel() error {
+	return errors.New("not supported")
+}
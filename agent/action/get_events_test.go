@@ -0,0 +1,62 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshevent "github.com/cloudfoundry/bosh-agent/agent/event"
+	fakeevent "github.com/cloudfoundry/bosh-agent/agent/event/fakes"
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+)
+
+var _ = Describe("GetEvents", func() {
+	var (
+		eventStore *fakeevent.FakeStore
+		action     GetEventsAction
+	)
+
+	BeforeEach(func() {
+		eventStore = &fakeevent.FakeStore{}
+		action = NewGetEvents(eventStore)
+
+		for _, process := range []string{"redis", "nginx"} {
+			_, err := eventStore.Add(boshjobsuper.JobEvent{Process: process, Type: "running", Timestamp: 1456826400})
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	AssertActionIsNotAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	It("returns all kept events", func() {
+		events, err := action.Run()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].ID).To(Equal(int64(1)))
+		Expect(events[0].Process).To(Equal("redis"))
+	})
+
+	It("returns the events after the given event", func() {
+		events, err := action.Run(1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(Equal([]boshevent.Event{
+			{ID: 2, JobEvent: boshjobsuper.JobEvent{Process: "nginx", Type: "running", Timestamp: 1456826400}},
+		}))
+		Expect(eventStore.SinceID).To(Equal(int64(1)))
+	})
+
+	It("returns an error when the events cannot be read", func() {
+		eventStore.SinceErr = errors.New("fake-since-err")
+
+		_, err := action.Run()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Getting job events: fake-since-err"))
+	})
+})
@@ -2,6 +2,7 @@ package action
 
 import (
 	"errors"
+	"time"
 
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
@@ -49,12 +50,13 @@ func (a GetStateAction) IsLoggable() bool {
 type GetStateV1ApplySpec struct {
 	boshas.V1ApplySpec
 
-	AgentID   string                 `json:"agent_id"`
-	JobState  string                 `json:"job_state"`
-	Vitals    *boshvitals.Vitals     `json:"vitals,omitempty"`
-	Processes []boshjobsuper.Process `json:"processes,omitempty"`
-	VM        boshsettings.VM        `json:"vm"`
-	Ntp       boshntp.Info           `json:"ntp"`
+	AgentID            string                 `json:"agent_id"`
+	JobState           string                 `json:"job_state"`
+	JobStateStaleSince string                 `json:"job_state_stale_since,omitempty"`
+	Vitals             *boshvitals.Vitals     `json:"vitals,omitempty"`
+	Processes          []boshjobsuper.Process `json:"processes,omitempty"`
+	VM                 boshsettings.VM        `json:"vm"`
+	Ntp                boshntp.Info           `json:"ntp"`
 }
 
 func (a GetStateAction) Run(filters ...string) (GetStateV1ApplySpec, error) {
@@ -81,10 +83,16 @@ func (a GetStateAction) Run(filters ...string) (GetStateV1ApplySpec, error) {
 
 	settings := a.settingsService.GetSettings()
 
+	var staleSince string
+	if staleSinceTime := a.jobSupervisor.StatusStaleSince(); !staleSinceTime.IsZero() {
+		staleSince = staleSinceTime.UTC().Format(time.RFC3339)
+	}
+
 	value := GetStateV1ApplySpec{
 		spec,
 		settings.AgentID,
 		a.jobSupervisor.Status(),
+		staleSince,
 		vitalsReference,
 		processes,
 		settings.VM,
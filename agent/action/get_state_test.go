@@ -2,6 +2,7 @@ package action_test
 
 import (
 	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -139,6 +140,22 @@ var _ = Describe("GetState", func() {
 					boshassert.MatchesJSONMap(GinkgoT(), state.VM, expectedVM)
 				})
 
+				It("returns since when job state is stale", func() {
+					jobSupervisor.StatusStatus = "running"
+
+					state, err := action.Run()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(state.JobStateStaleSince).To(BeEmpty())
+					boshassert.LacksJSONKey(GinkgoT(), state, "job_state_stale_since")
+
+					jobSupervisor.StatusStaleSinceTime = time.Date(2016, 10, 12, 17, 37, 58, 0, time.UTC)
+
+					state, err = action.Run()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(state.JobState).To(Equal("running"))
+					Expect(state.JobStateStaleSince).To(Equal("2016-10-12T17:37:58Z"))
+				})
+
 				Describe("non-populated field formatting", func() {
 					It("returns network as empty hash if not set", func() {
 						specService.Spec = boshas.V1ApplySpec{NetworkSpecs: nil}
@@ -44,6 +44,24 @@ var _ = Describe("GetTask", func() {
 			`{"agent_task_id":"fake-task-id","state":"running"}`)
 	})
 
+	It("returns the progress of a running task", func() {
+		progress := boshtask.NewProgress(2)
+		_, err := progress.Write([]byte("line 1\nline 2\nline 3\npartial"))
+		Expect(err).ToNot(HaveOccurred())
+
+		taskService.StartedTasks["fake-task-id"] = boshtask.Task{
+			ID:       "fake-task-id",
+			State:    boshtask.StateRunning,
+			Progress: progress,
+		}
+
+		taskValue, err := action.Run("fake-task-id")
+		Expect(err).ToNot(HaveOccurred())
+
+		boshassert.MatchesJSONString(GinkgoT(), taskValue,
+			`{"agent_task_id":"fake-task-id","state":"running","progress":["line 2","line 3"]}`)
+	})
+
 	It("returns a failed task", func() {
 		taskService.StartedTasks["fake-task-id"] = boshtask.Task{
 			ID:    "fake-task-id",
@@ -26,6 +26,11 @@ func NewListDisk(
 	return
 }
 
+func (a ListDiskAction) WithLogger(logger boshlog.Logger) Action {
+	a.logger = logger
+	return a
+}
+
 func (a ListDiskAction) IsAsynchronous(version ProtocolVersion) bool {
 	if version >= 3 {
 		return true
@@ -35,6 +35,11 @@ func NewMountDisk(
 	return
 }
 
+func (a MountDiskAction) WithLogger(logger boshlog.Logger) Action {
+	a.logger = logger
+	return a
+}
+
 func (a MountDiskAction) IsAsynchronous(_ ProtocolVersion) bool {
 	return true
 }
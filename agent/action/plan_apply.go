@@ -0,0 +1,234 @@
+package action
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+
+	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// PlanApplyAction reports what apply would change without changing anything.
+// Directors do not currently send package sizes in apply specs, so in practice
+// DownloadBytes is 0 and every download is listed in UnknownSizeDownloads.
+type PlanApplyAction struct {
+	specService     boshas.V1Service
+	settingsService boshsettings.Service
+	jobsBc          boshbc.BundleCollection
+	packagesBc      boshbc.BundleCollection
+}
+
+type ApplyPlan struct {
+	Jobs     BundlesPlan `json:"jobs"`
+	Packages BundlesPlan `json:"packages"`
+
+	// DownloadBytes adds up the package sizes given in the apply spec for the
+	// packages to download; bundles to download without a size in the spec
+	// are listed in UnknownSizeDownloads
+	DownloadBytes        int64    `json:"download_bytes"`
+	UnknownSizeDownloads []string `json:"unknown_size_downloads"`
+
+	LogrotateChanged bool `json:"logrotate_changed"`
+	NetworksChanged  bool `json:"networks_changed"`
+}
+
+// BundlesPlan lists jobs or packages by name
+type BundlesPlan struct {
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+	Update []string `json:"update"`
+
+	// Download lists the desired bundles that are not installed yet
+	Download []string `json:"download"`
+}
+
+type plannedBundle struct {
+	definition boshbc.BundleDefinition
+
+	// size is 0 when unknown
+	size int64
+}
+
+func NewPlanApply(
+	specService boshas.V1Service,
+	settingsService boshsettings.Service,
+	jobsBc boshbc.BundleCollection,
+	packagesBc boshbc.BundleCollection,
+) (action PlanApplyAction) {
+	action.specService = specService
+	action.settingsService = settingsService
+	action.jobsBc = jobsBc
+	action.packagesBc = packagesBc
+	return
+}
+
+func (a PlanApplyAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return false
+}
+
+func (a PlanApplyAction) IsPersistent() bool {
+	return false
+}
+
+func (a PlanApplyAction) IsLoggable() bool {
+	return true
+}
+
+func (a PlanApplyAction) Run(desiredSpec boshas.V1ApplySpec) (ApplyPlan, error) {
+	settings := a.settingsService.GetSettings()
+
+	resolvedDesiredSpec, err := a.specService.PopulateDHCPNetworks(desiredSpec, settings)
+	if err != nil {
+		return ApplyPlan{}, bosherr.WrapError(err, "Resolving dynamic networks")
+	}
+
+	currentSpec, err := a.specService.Get()
+	if err != nil {
+		return ApplyPlan{}, bosherr.WrapError(err, "Getting current spec")
+	}
+
+	plan := ApplyPlan{
+		Jobs:                 newBundlesPlan(),
+		Packages:             newBundlesPlan(),
+		UnknownSizeDownloads: []string{},
+		LogrotateChanged:     currentSpec.MaxLogFileSize() != resolvedDesiredSpec.MaxLogFileSize(),
+		NetworksChanged:      !reflect.DeepEqual(currentSpec.NetworkSpecs, resolvedDesiredSpec.NetworkSpecs),
+	}
+
+	// Apply only sets the spec when there is no configuration to apply
+	if desiredSpec.ConfigurationHash == "" {
+		return plan, nil
+	}
+
+	plan.Jobs, err = a.planBundles(a.jobsBc, jobBundles(currentSpec), jobBundles(resolvedDesiredSpec))
+	if err != nil {
+		return ApplyPlan{}, bosherr.WrapError(err, "Planning jobs")
+	}
+
+	plan.Packages, err = a.planBundles(a.packagesBc, packageBundles(currentSpec), packageBundles(resolvedDesiredSpec))
+	if err != nil {
+		return ApplyPlan{}, bosherr.WrapError(err, "Planning packages")
+	}
+
+	plan.UnknownSizeDownloads = append(plan.UnknownSizeDownloads, plan.Jobs.Download...)
+
+	for _, pkg := range packageBundles(resolvedDesiredSpec) {
+		if !containsString(plan.Packages.Download, pkg.definition.BundleName()) {
+			continue
+		}
+
+		if pkg.size == 0 {
+			plan.UnknownSizeDownloads = append(plan.UnknownSizeDownloads, pkg.definition.BundleName())
+		}
+
+		plan.DownloadBytes += pkg.size
+	}
+
+	sort.Strings(plan.UnknownSizeDownloads)
+
+	return plan, nil
+}
+
+func (a PlanApplyAction) planBundles(bc boshbc.BundleCollection, current, desired []plannedBundle) (BundlesPlan, error) {
+	plan := newBundlesPlan()
+
+	installedBundles, err := bc.List()
+	if err != nil {
+		return plan, bosherr.WrapError(err, "Listing installed bundles")
+	}
+
+	currentVersions := map[string]string{}
+	for _, bundle := range current {
+		currentVersions[bundle.definition.BundleName()] = bundle.definition.BundleVersion()
+	}
+
+	desiredNames := map[string]bool{}
+
+	for _, bundle := range desired {
+		name := bundle.definition.BundleName()
+		desiredNames[name] = true
+
+		currentVersion, found := currentVersions[name]
+		if !found {
+			plan.Add = append(plan.Add, name)
+		} else if currentVersion != bundle.definition.BundleVersion() {
+			plan.Update = append(plan.Update, name)
+		}
+
+		desiredBundle, err := bc.Get(bundle.definition)
+		if err != nil {
+			return plan, bosherr.WrapErrorf(err, "Getting bundle %s", name)
+		}
+
+		if !containsBundle(installedBundles, desiredBundle) {
+			plan.Download = append(plan.Download, name)
+		}
+	}
+
+	for name := range currentVersions {
+		if !desiredNames[name] {
+			plan.Remove = append(plan.Remove, name)
+		}
+	}
+
+	sort.Strings(plan.Add)
+	sort.Strings(plan.Remove)
+	sort.Strings(plan.Update)
+	sort.Strings(plan.Download)
+
+	return plan, nil
+}
+
+func (a PlanApplyAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a PlanApplyAction) Cancel() error {
+	return errors.New("not supported")
+}
+
+func newBundlesPlan() BundlesPlan {
+	return BundlesPlan{
+		Add:      []string{},
+		Remove:   []string{},
+		Update:   []string{},
+		Download: []string{},
+	}
+}
+
+func jobBundles(spec boshas.V1ApplySpec) []plannedBundle {
+	bundles := []plannedBundle{}
+	for _, job := range spec.Jobs() {
+		bundles = append(bundles, plannedBundle{definition: job})
+	}
+	return bundles
+}
+
+func packageBundles(spec boshas.V1ApplySpec) []plannedBundle {
+	bundles := []plannedBundle{}
+	for _, pkgSpec := range spec.PackageSpecs {
+		bundles = append(bundles, plannedBundle{definition: pkgSpec.AsPackage(), size: pkgSpec.Size})
+	}
+	return bundles
+}
+
+func containsBundle(bundles []boshbc.Bundle, bundle boshbc.Bundle) bool {
+	for _, b := range bundles {
+		if b == bundle {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
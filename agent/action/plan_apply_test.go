@@ -0,0 +1,210 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
+	fakebc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+)
+
+var _ = Describe("PlanApplyAction", func() {
+	var (
+		specService     *fakeas.FakeV1Service
+		settingsService *fakesettings.FakeSettingsService
+		jobsBc          *fakebc.FakeBundleCollection
+		packagesBc      *fakebc.FakeBundleCollection
+		action          PlanApplyAction
+	)
+
+	packageSpec := func(name, version string, size int64) boshas.PackageSpec {
+		return boshas.PackageSpec{
+			Name:        name,
+			Version:     version,
+			Sha1:        boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, name+version)),
+			BlobstoreID: "fake-blobstore-id-" + name,
+			Size:        size,
+		}
+	}
+
+	specWithJobs := func(archiveSha1 string, jobs ...boshas.JobTemplateSpec) boshas.V1ApplySpec {
+		digest := boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, archiveSha1))
+
+		return boshas.V1ApplySpec{
+			ConfigurationHash:            "fake-config-hash",
+			JobSpec:                      boshas.JobSpec{JobTemplateSpecs: jobs},
+			RenderedTemplatesArchiveSpec: &boshas.RenderedTemplatesArchiveSpec{Sha1: &digest, BlobstoreID: "fake-archive-id"},
+		}
+	}
+
+	BeforeEach(func() {
+		specService = fakeas.NewFakeV1Service()
+		settingsService = &fakesettings.FakeSettingsService{}
+		jobsBc = fakebc.NewFakeBundleCollection()
+		packagesBc = fakebc.NewFakeBundleCollection()
+		action = NewPlanApply(specService, settingsService, jobsBc, packagesBc)
+	})
+
+	AssertActionIsNotAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotCancelable(action)
+	AssertActionIsNotResumable(action)
+
+	Describe("Run", func() {
+		It("resolves dynamic networks in the desired spec with the current settings", func() {
+			settingsService.Settings = boshsettings.Settings{AgentID: "fake-agent-id"}
+			desiredSpec := boshas.V1ApplySpec{ConfigurationHash: "fake-desired-config-hash"}
+
+			_, err := action.Run(desiredSpec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(specService.PopulateDHCPNetworksSpec).To(Equal(desiredSpec))
+			Expect(specService.PopulateDHCPNetworksSettings).To(Equal(settingsService.Settings))
+		})
+
+		It("does not change the current spec", func() {
+			_, err := action.Run(boshas.V1ApplySpec{ConfigurationHash: "fake-desired-config-hash"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(specService.ActionsCalled).To(Equal([]string{"PopulateDHCPNetworks", "Get"}))
+		})
+
+		It("plans packages to add, remove, update and download", func() {
+			installedPkg := packageSpec("fake-pkg-unchanged", "1", 10)
+
+			specService.Spec = boshas.V1ApplySpec{
+				PackageSpecs: map[string]boshas.PackageSpec{
+					"fake-pkg-unchanged": installedPkg,
+					"fake-pkg-updated":   packageSpec("fake-pkg-updated", "1", 20),
+					"fake-pkg-removed":   packageSpec("fake-pkg-removed", "1", 30),
+				},
+			}
+
+			desiredSpec := boshas.V1ApplySpec{
+				ConfigurationHash: "fake-desired-config-hash",
+				PackageSpecs: map[string]boshas.PackageSpec{
+					"fake-pkg-unchanged": installedPkg,
+					"fake-pkg-updated":   packageSpec("fake-pkg-updated", "2", 0),
+					"fake-pkg-added":     packageSpec("fake-pkg-added", "1", 40),
+				},
+			}
+			specService.PopulateDHCPNetworksResultSpec = desiredSpec
+
+			packagesBc.ListBundles = []boshbc.Bundle{packagesBc.FakeGet(installedPkg.AsPackage())}
+
+			plan, err := action.Run(desiredSpec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(plan.Packages).To(Equal(BundlesPlan{
+				Add:      []string{"fake-pkg-added"},
+				Remove:   []string{"fake-pkg-removed"},
+				Update:   []string{"fake-pkg-updated"},
+				Download: []string{"fake-pkg-added", "fake-pkg-updated"},
+			}))
+			Expect(plan.DownloadBytes).To(Equal(int64(40)))
+			Expect(plan.UnknownSizeDownloads).To(Equal([]string{"fake-pkg-updated"}))
+		})
+
+		It("plans jobs to add, remove, update and download", func() {
+			specService.Spec = specWithJobs(
+				"fake-current-archive-sha1",
+				boshas.JobTemplateSpec{Name: "fake-job-updated", Version: "1"},
+				boshas.JobTemplateSpec{Name: "fake-job-removed", Version: "1"},
+			)
+
+			desiredSpec := specWithJobs(
+				"fake-desired-archive-sha1",
+				boshas.JobTemplateSpec{Name: "fake-job-updated", Version: "1"},
+				boshas.JobTemplateSpec{Name: "fake-job-added", Version: "1"},
+			)
+			specService.PopulateDHCPNetworksResultSpec = desiredSpec
+
+			plan, err := action.Run(desiredSpec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(plan.Jobs).To(Equal(BundlesPlan{
+				Add:      []string{"fake-job-added"},
+				Remove:   []string{"fake-job-removed"},
+				Update:   []string{"fake-job-updated"},
+				Download: []string{"fake-job-added", "fake-job-updated"},
+			}))
+			Expect(plan.UnknownSizeDownloads).To(Equal([]string{"fake-job-added", "fake-job-updated"}))
+		})
+
+		It("reports whether logrotate and networks change", func() {
+			specService.Spec = boshas.V1ApplySpec{
+				NetworkSpecs: map[string]boshas.NetworkSpec{
+					"fake-net": {Fields: map[string]interface{}{"ip": "10.0.0.1"}},
+				},
+			}
+
+			desiredSpec := boshas.V1ApplySpec{
+				ConfigurationHash: "fake-desired-config-hash",
+				PropertiesSpec:    boshas.PropertiesSpec{LoggingSpec: boshas.LoggingSpec{MaxLogFileSize: "100M"}},
+				NetworkSpecs: map[string]boshas.NetworkSpec{
+					"fake-net": {Fields: map[string]interface{}{"ip": "10.0.0.2"}},
+				},
+			}
+			specService.PopulateDHCPNetworksResultSpec = desiredSpec
+
+			plan, err := action.Run(desiredSpec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plan.LogrotateChanged).To(BeTrue())
+			Expect(plan.NetworksChanged).To(BeTrue())
+
+			specService.Spec = desiredSpec
+
+			plan, err = action.Run(desiredSpec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plan.LogrotateChanged).To(BeFalse())
+			Expect(plan.NetworksChanged).To(BeFalse())
+		})
+
+		It("plans no job and package changes when desired spec does not have a configuration hash", func() {
+			specService.Spec = boshas.V1ApplySpec{
+				PackageSpecs: map[string]boshas.PackageSpec{
+					"fake-pkg": packageSpec("fake-pkg", "1", 10),
+				},
+			}
+
+			plan, err := action.Run(boshas.V1ApplySpec{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plan.Packages.Remove).To(BeEmpty())
+			Expect(plan.DownloadBytes).To(BeZero())
+		})
+
+		It("returns error when resolving dynamic networks fails", func() {
+			specService.PopulateDHCPNetworksErr = errors.New("fake-populate-dhcp-networks-err")
+
+			_, err := action.Run(boshas.V1ApplySpec{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-populate-dhcp-networks-err"))
+		})
+
+		It("returns error when current spec cannot be retrieved", func() {
+			specService.GetErr = errors.New("fake-get-spec-err")
+
+			_, err := action.Run(boshas.V1ApplySpec{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-get-spec-err"))
+		})
+
+		It("returns error when installed bundles cannot be listed", func() {
+			packagesBc.ListErr = errors.New("fake-list-err")
+
+			_, err := action.Run(boshas.V1ApplySpec{ConfigurationHash: "fake-desired-config-hash"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-list-err"))
+		})
+	})
+})
@@ -0,0 +1,55 @@
+package action
+
+import (
+	"errors"
+
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type RestartProcessAction struct {
+	jobSupervisor boshjobsuper.JobSupervisor
+}
+
+func NewRestartProcess(jobSupervisor boshjobsuper.JobSupervisor) (action RestartProcessAction) {
+	action = RestartProcessAction{
+		jobSupervisor: jobSupervisor,
+	}
+	return
+}
+
+func (a RestartProcessAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return true
+}
+
+func (a RestartProcessAction) IsPersistent() bool {
+	return false
+}
+
+func (a RestartProcessAction) IsLoggable() bool {
+	return true
+}
+
+func (a RestartProcessAction) Run(processName string) (value string, err error) {
+	if processName == "" {
+		err = bosherr.Error("Process name must be provided")
+		return
+	}
+
+	err = a.jobSupervisor.RestartProcess(processName)
+	if err != nil {
+		err = bosherr.WrapErrorf(err, "Restarting process %s", processName)
+		return
+	}
+
+	value = "restarted"
+	return
+}
+
+func (a RestartProcessAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a RestartProcessAction) Cancel() error {
+	return errors.New("not supported")
+}
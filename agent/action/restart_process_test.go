@@ -0,0 +1,57 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+)
+
+var _ = Describe("RestartProcess", func() {
+	var (
+		jobSupervisor *fakejobsuper.FakeJobSupervisor
+		action        RestartProcessAction
+	)
+
+	BeforeEach(func() {
+		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
+		action = NewRestartProcess(jobSupervisor)
+	})
+
+	AssertActionIsAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	It("returns restarted", func() {
+		restarted, err := action.Run("fake-process")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(restarted).To(Equal("restarted"))
+	})
+
+	It("restarts the process with the job supervisor", func() {
+		_, err := action.Run("fake-process")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(jobSupervisor.RestartedProcesses).To(Equal([]string{"fake-process"}))
+	})
+
+	It("returns an error when the job supervisor fails", func() {
+		jobSupervisor.RestartProcessErr = errors.New("fake-restart-err")
+
+		_, err := action.Run("fake-process")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Restarting process fake-process: fake-restart-err"))
+	})
+
+	It("requires a process name", func() {
+		_, err := action.Run("")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Process name must be provided"))
+		Expect(jobSupervisor.RestartedProcesses).To(BeEmpty())
+	})
+})
@@ -40,6 +40,11 @@ func NewRunErrand(
 	}
 }
 
+func (a RunErrandAction) WithLogger(logger boshlog.Logger) Action {
+	a.logger = logger
+	return a
+}
+
 func (a RunErrandAction) IsAsynchronous(_ ProtocolVersion) bool {
 	return true
 }
@@ -2,16 +2,25 @@ package action
 
 import (
 	"errors"
+	"time"
 
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
+// post-start scripts only run once the jobs' readiness probes pass
+const (
+	postStartScriptName       = "post-start"
+	postStartReadinessTimeout = 5 * time.Minute
+)
+
 type RunScriptAction struct {
 	scriptProvider boshscript.JobScriptProvider
 	specService    boshas.V1Service
+	jobSupervisor  boshjobsuper.JobSupervisor
 
 	logTag string
 	logger boshlog.Logger
@@ -20,17 +29,24 @@ type RunScriptAction struct {
 func NewRunScript(
 	scriptProvider boshscript.JobScriptProvider,
 	specService boshas.V1Service,
+	jobSupervisor boshjobsuper.JobSupervisor,
 	logger boshlog.Logger,
 ) RunScriptAction {
 	return RunScriptAction{
 		scriptProvider: scriptProvider,
 		specService:    specService,
+		jobSupervisor:  jobSupervisor,
 
 		logTag: "RunScript Action",
 		logger: logger,
 	}
 }
 
+func (a RunScriptAction) WithLogger(logger boshlog.Logger) Action {
+	a.logger = logger
+	return a
+}
+
 func (a RunScriptAction) IsAsynchronous(_ ProtocolVersion) bool {
 	return true
 }
@@ -52,6 +68,13 @@ func (a RunScriptAction) Run(scriptName string, options map[string]interface{})
 		return emptyResults, bosherr.WrapError(err, "Getting current spec")
 	}
 
+	if scriptName == postStartScriptName {
+		err = a.jobSupervisor.WaitUntilReady(postStartReadinessTimeout)
+		if err != nil {
+			return emptyResults, bosherr.WrapError(err, "Waiting for jobs to become ready")
+		}
+	}
+
 	var scripts []boshscript.Script
 
 	for _, job := range currentSpec.Jobs() {
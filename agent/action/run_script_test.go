@@ -2,6 +2,7 @@ package action_test
 
 import (
 	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -11,6 +12,7 @@ import (
 	fakeapplyspec "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
 	fakescript "github.com/cloudfoundry/bosh-agent/agent/script/fakes"
+	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
@@ -18,6 +20,7 @@ var _ = Describe("RunScript", func() {
 	var (
 		fakeJobScriptProvider *fakescript.FakeJobScriptProvider
 		specService           *fakeapplyspec.FakeV1Service
+		jobSupervisor         *fakejobsuper.FakeJobSupervisor
 		action                RunScriptAction
 	)
 
@@ -25,8 +28,9 @@ var _ = Describe("RunScript", func() {
 		fakeJobScriptProvider = &fakescript.FakeJobScriptProvider{}
 		specService = fakeapplyspec.NewFakeV1Service()
 		specService.Spec.RenderedTemplatesArchiveSpec = &applyspec.RenderedTemplatesArchiveSpec{}
+		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 		logger := boshlog.NewLogger(boshlog.LevelNone)
-		action = NewRunScript(fakeJobScriptProvider, specService, logger)
+		action = NewRunScript(fakeJobScriptProvider, specService, jobSupervisor, logger)
 	})
 
 	AssertActionIsAsynchronous(action)
@@ -94,6 +98,33 @@ var _ = Describe("RunScript", func() {
 				Expect(err.Error()).To(ContainSubstring("fake-error"))
 				Expect(results).To(Equal(map[string]string{}))
 			})
+
+			It("does not wait for jobs to become ready", func() {
+				_, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(jobSupervisor.WaitUntilReadyTimeout).To(BeZero())
+			})
+
+			Context("when running post-start scripts", func() {
+				act := func() (map[string]string, error) { return action.Run("post-start", map[string]interface{}{}) }
+
+				It("waits for jobs to become ready first", func() {
+					_, err := act()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(jobSupervisor.WaitUntilReadyTimeout).To(Equal(5 * time.Minute))
+					Expect(parallelScript.RunCallCount()).To(Equal(1))
+				})
+
+				It("does not run the scripts when jobs do not become ready", func() {
+					jobSupervisor.WaitUntilReadyErr = errors.New("fake-readiness-error")
+
+					results, err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Waiting for jobs to become ready: fake-readiness-error"))
+					Expect(results).To(Equal(map[string]string{}))
+					Expect(parallelScript.RunCallCount()).To(Equal(0))
+				})
+			})
 		})
 
 		Context("when current spec cannot be retrieved", func() {
@@ -3,6 +3,8 @@ package action
 import (
 	"bytes"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 	"reflect"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -10,6 +12,7 @@ import (
 
 type Runner interface {
 	Run(action Action, payload []byte, protocolVersion ProtocolVersion) (value interface{}, err error)
+	RunWithProgress(action Action, payload []byte, protocolVersion ProtocolVersion, progress io.Writer) (value interface{}, err error)
 	Resume(action Action, payload []byte) (value interface{}, err error)
 }
 
@@ -20,6 +23,12 @@ func NewRunner() Runner {
 type concreteRunner struct{}
 
 func (r concreteRunner) Run(action Action, payloadBytes []byte, protocolVersion ProtocolVersion) (value interface{}, err error) {
+	return r.RunWithProgress(action, payloadBytes, protocolVersion, ioutil.Discard)
+}
+
+// RunWithProgress passes progress to Run methods that take a ProgressWriter
+// as their first argument, after the optional ProtocolVersion
+func (r concreteRunner) RunWithProgress(action Action, payloadBytes []byte, protocolVersion ProtocolVersion, progress io.Writer) (value interface{}, err error) {
 	payloadArgs, err := r.extractJSONArguments(payloadBytes)
 	if err != nil {
 		err = bosherr.WrapError(err, "Extracting json arguments")
@@ -39,7 +48,7 @@ func (r concreteRunner) Run(action Action, payloadBytes []byte, protocolVersion
 		return
 	}
 
-	methodArgs, err := r.extractMethodArgs(runMethodType, protocolVersion, payloadArgs)
+	methodArgs, err := r.extractMethodArgs(runMethodType, protocolVersion, progress, payloadArgs)
 	if err != nil {
 		err = bosherr.WrapError(err, "Extracting method arguments from payload")
 		return
@@ -88,7 +97,9 @@ func (r concreteRunner) invalidReturnTypes(methodType reflect.Type) (valid bool)
 	return
 }
 
-func (r concreteRunner) extractMethodArgs(runMethodType reflect.Type, protocolVersion ProtocolVersion, args []interface{}) (methodArgs []reflect.Value, err error) {
+var progressWriterType = reflect.TypeOf((*ProgressWriter)(nil)).Elem()
+
+func (r concreteRunner) extractMethodArgs(runMethodType reflect.Type, protocolVersion ProtocolVersion, progress io.Writer, args []interface{}) (methodArgs []reflect.Value, err error) {
 	numberOfArgs := runMethodType.NumIn()
 	numberOfReqArgs := numberOfArgs
 
@@ -108,6 +119,19 @@ func (r concreteRunner) extractMethodArgs(runMethodType reflect.Type, protocolVe
 		}
 	}
 
+	if numberOfArgs > argsOffset {
+		argType := runMethodType.In(argsOffset)
+
+		if argType == progressWriterType {
+			if progress == nil {
+				progress = ioutil.Discard
+			}
+			methodArgs = append(methodArgs, reflect.ValueOf(progress))
+			numberOfReqArgs--
+			argsOffset++
+		}
+	}
+
 	if len(args) < numberOfReqArgs {
 		err = bosherr.Errorf("Not enough arguments, expected %d, got %d", numberOfReqArgs, len(args))
 		return
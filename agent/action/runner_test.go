@@ -1,7 +1,9 @@
 package action_test
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 
 	"github.com/stretchr/testify/assert"
 
@@ -248,6 +250,39 @@ func (a *actionWithProtocolVersion) Cancel() error {
 	return nil
 }
 
+type actionWithProgress struct {
+	ProtocolVersion ProtocolVersion
+	SubAction       string
+}
+
+func (a *actionWithProgress) IsAsynchronous(_ ProtocolVersion) bool {
+	return true
+}
+
+func (a *actionWithProgress) IsPersistent() bool {
+	return false
+}
+
+func (a *actionWithProgress) IsLoggable() bool {
+	return true
+}
+
+func (a *actionWithProgress) Run(protocolVersion ProtocolVersion, progress ProgressWriter, subAction string) (valueType, error) {
+	a.ProtocolVersion = protocolVersion
+	a.SubAction = subAction
+
+	_, err := fmt.Fprintf(progress, "running %s\n", subAction)
+	return valueType{}, err
+}
+
+func (a *actionWithProgress) Resume() (interface{}, error) {
+	return nil, nil
+}
+
+func (a *actionWithProgress) Cancel() error {
+	return nil
+}
+
 var _ = Describe("concreteRunner", func() {
 	It("runner run parses the payload", func() {
 		runner := NewRunner()
@@ -424,4 +459,30 @@ var _ = Describe("concreteRunner", func() {
 		Expect(action.ProtocolVersion).To(Equal(ProtocolVersion(1)))
 		Expect(action.SubAction).To(Equal("setup"))
 	})
+
+	It("passes progress to run method", func() {
+		runner := NewRunner()
+
+		action := &actionWithProgress{}
+		payload := `{"arguments":["setup"]}`
+		progress := &bytes.Buffer{}
+
+		_, err := runner.RunWithProgress(action, []byte(payload), 1, progress)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(action.ProtocolVersion).To(Equal(ProtocolVersion(1)))
+		Expect(action.SubAction).To(Equal("setup"))
+		Expect(progress.String()).To(Equal("running setup\n"))
+	})
+
+	It("discards progress of run method when running without progress", func() {
+		runner := NewRunner()
+
+		action := &actionWithProgress{}
+		payload := `{"arguments":["setup"]}`
+
+		_, err := runner.Run(action, []byte(payload), 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action.SubAction).To(Equal("setup"))
+	})
 })
@@ -31,6 +31,11 @@ func NewSSH(
 	return
 }
 
+func (a SSHAction) WithLogger(logger boshlog.Logger) Action {
+	a.logger = logger
+	return a
+}
+
 func (a SSHAction) IsAsynchronous(_ ProtocolVersion) bool {
 	return false
 }
@@ -0,0 +1,55 @@
+package action
+
+import (
+	"errors"
+
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type StartProcessAction struct {
+	jobSupervisor boshjobsuper.JobSupervisor
+}
+
+func NewStartProcess(jobSupervisor boshjobsuper.JobSupervisor) (action StartProcessAction) {
+	action = StartProcessAction{
+		jobSupervisor: jobSupervisor,
+	}
+	return
+}
+
+func (a StartProcessAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return true
+}
+
+func (a StartProcessAction) IsPersistent() bool {
+	return false
+}
+
+func (a StartProcessAction) IsLoggable() bool {
+	return true
+}
+
+func (a StartProcessAction) Run(processName string) (value string, err error) {
+	if processName == "" {
+		err = bosherr.Error("Process name must be provided")
+		return
+	}
+
+	err = a.jobSupervisor.StartProcess(processName)
+	if err != nil {
+		err = bosherr.WrapErrorf(err, "Starting process %s", processName)
+		return
+	}
+
+	value = "started"
+	return
+}
+
+func (a StartProcessAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a StartProcessAction) Cancel() error {
+	return errors.New("not supported")
+}
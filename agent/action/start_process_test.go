@@ -0,0 +1,57 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+)
+
+var _ = Describe("StartProcess", func() {
+	var (
+		jobSupervisor *fakejobsuper.FakeJobSupervisor
+		action        StartProcessAction
+	)
+
+	BeforeEach(func() {
+		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
+		action = NewStartProcess(jobSupervisor)
+	})
+
+	AssertActionIsAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	It("returns started", func() {
+		started, err := action.Run("fake-process")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(started).To(Equal("started"))
+	})
+
+	It("starts the process with the job supervisor", func() {
+		_, err := action.Run("fake-process")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(jobSupervisor.StartedProcesses).To(Equal([]string{"fake-process"}))
+	})
+
+	It("returns an error when the job supervisor fails", func() {
+		jobSupervisor.StartProcessErr = errors.New("fake-start-err")
+
+		_, err := action.Run("fake-process")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Starting process fake-process: fake-start-err"))
+	})
+
+	It("requires a process name", func() {
+		_, err := action.Run("")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Process name must be provided"))
+		Expect(jobSupervisor.StartedProcesses).To(BeEmpty())
+	})
+})
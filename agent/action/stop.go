@@ -30,20 +30,32 @@ func (a StopAction) IsLoggable() bool {
 	return true
 }
 
-func (a StopAction) Run(protocolVersion ProtocolVersion) (value string, err error) {
-	if protocolVersion > 2 {
-		err = a.jobSupervisor.StopAndWait()
-	} else {
-		err = a.jobSupervisor.Stop()
+// StopResult is returned to agents speaking protocol version 3 or later
+type StopResult struct {
+	Status string                       `json:"status"`
+	Jobs   []boshjobsuper.JobStopResult `json:"jobs"`
+}
+
+func (a StopAction) Run(protocolVersion ProtocolVersion) (interface{}, error) {
+	if protocolVersion <= 2 {
+		err := a.jobSupervisor.Stop()
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Stopping Monitored Services")
+		}
+
+		return "stopped", nil
 	}
 
+	jobs, err := a.jobSupervisor.StopAndWait()
 	if err != nil {
-		err = bosherr.WrapError(err, "Stopping Monitored Services")
-		return
+		return nil, bosherr.WrapError(err, "Stopping Monitored Services")
 	}
 
-	value = "stopped"
-	return
+	if jobs == nil {
+		jobs = []boshjobsuper.JobStopResult{}
+	}
+
+	return StopResult{Status: "stopped", Jobs: jobs}, nil
 }
 
 func (a StopAction) Resume() (interface{}, error) {
@@ -0,0 +1,55 @@
+package action
+
+import (
+	"errors"
+
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type StopProcessAction struct {
+	jobSupervisor boshjobsuper.JobSupervisor
+}
+
+func NewStopProcess(jobSupervisor boshjobsuper.JobSupervisor) (action StopProcessAction) {
+	action = StopProcessAction{
+		jobSupervisor: jobSupervisor,
+	}
+	return
+}
+
+func (a StopProcessAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return true
+}
+
+func (a StopProcessAction) IsPersistent() bool {
+	return false
+}
+
+func (a StopProcessAction) IsLoggable() bool {
+	return true
+}
+
+func (a StopProcessAction) Run(processName string) (value string, err error) {
+	if processName == "" {
+		err = bosherr.Error("Process name must be provided")
+		return
+	}
+
+	err = a.jobSupervisor.StopProcess(processName)
+	if err != nil {
+		err = bosherr.WrapErrorf(err, "Stopping process %s", processName)
+		return
+	}
+
+	value = "stopped"
+	return
+}
+
+func (a StopProcessAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a StopProcessAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,57 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+)
+
+var _ = Describe("StopProcess", func() {
+	var (
+		jobSupervisor *fakejobsuper.FakeJobSupervisor
+		action        StopProcessAction
+	)
+
+	BeforeEach(func() {
+		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
+		action = NewStopProcess(jobSupervisor)
+	})
+
+	AssertActionIsAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	It("returns stopped", func() {
+		stopped, err := action.Run("fake-process")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stopped).To(Equal("stopped"))
+	})
+
+	It("stops the process with the job supervisor", func() {
+		_, err := action.Run("fake-process")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(jobSupervisor.StoppedProcesses).To(Equal([]string{"fake-process"}))
+	})
+
+	It("returns an error when the job supervisor fails", func() {
+		jobSupervisor.StopProcessErr = errors.New("fake-stop-err")
+
+		_, err := action.Run("fake-process")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Stopping process fake-process: fake-stop-err"))
+	})
+
+	It("requires a process name", func() {
+		_, err := action.Run("")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Process name must be provided"))
+		Expect(jobSupervisor.StoppedProcesses).To(BeEmpty())
+	})
+})
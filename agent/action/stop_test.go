@@ -1,10 +1,13 @@
 package action_test
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
 )
 
@@ -49,4 +52,32 @@ var _ = Describe("Stop", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(jobSupervisor.StoppedAndWaited).To(BeTrue())
 	})
+
+	It("returns the outcome of stopping each job when protocol version is greater than 2", func() {
+		jobSupervisor.StopResults = []boshjobsuper.JobStopResult{
+			{Name: "db", State: boshjobsuper.JobStopStateTerminated},
+			{Name: "web", State: boshjobsuper.JobStopStateStopped},
+		}
+
+		result, err := action.Run(ProtocolVersion(3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(StopResult{
+			Status: "stopped",
+			Jobs:   jobSupervisor.StopResults,
+		}))
+	})
+
+	It("returns an empty list of jobs when the job supervisor does not report them", func() {
+		result, err := action.Run(ProtocolVersion(3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(StopResult{Status: "stopped", Jobs: []boshjobsuper.JobStopResult{}}))
+	})
+
+	It("returns an error when stopping fails", func() {
+		jobSupervisor.StopErr = errors.New("fake-stop-error")
+
+		_, err := action.Run(ProtocolVersion(3))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-stop-error"))
+	})
 })
@@ -40,6 +40,11 @@ func NewSyncDNS(blobstore boshblob.DigestBlobstore, settingsService boshsettings
 	}
 }
 
+func (a SyncDNS) WithLogger(logger boshlog.Logger) Action {
+	a.logger = logger
+	return a
+}
+
 func (a SyncDNS) IsAsynchronous(_ ProtocolVersion) bool {
 	return false
 }
@@ -28,6 +28,11 @@ func NewUpdateSettings(service boshsettings.Service, platform platform.Platform,
 	}
 }
 
+func (a UpdateSettingsAction) WithLogger(logger logger.Logger) Action {
+	a.logger = logger
+	return a
+}
+
 func (a UpdateSettingsAction) IsAsynchronous(_ ProtocolVersion) bool {
 	return true
 }
@@ -4,6 +4,7 @@ import (
 	boshaction "github.com/cloudfoundry/bosh-agent/agent/action"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	"github.com/cloudfoundry/bosh-agent/infrastructure/agentlogger"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
@@ -63,47 +64,68 @@ func (dispatcher concreteActionDispatcher) ResumePreviouslyDispatchedTasks() {
 		taskID := taskInfo.TaskID
 		payload := taskInfo.Payload
 
+		logger := agentlogger.WithFields(dispatcher.logger, agentlogger.Fields{
+			agentlogger.RequestIDField:    taskInfo.RequestID,
+			agentlogger.TaskIDField:       taskID,
+			agentlogger.ActionMethodField: taskInfo.Method,
+		})
+
+		taskAction := withLogger(action, logger)
+
 		task := dispatcher.taskService.CreateTaskWithID(
 			taskID,
-			func() (interface{}, error) { return dispatcher.actionRunner.Resume(action, payload) },
+			func() (interface{}, error) { return dispatcher.actionRunner.Resume(taskAction, payload) },
 			func(_ boshtask.Task) error { return action.Cancel() },
 			dispatcher.removeInfo,
 		)
+		task.RequestID = taskInfo.RequestID
+
+		logger.Info(actionDispatcherLogTag, "Resuming task %s", task.ID)
 
 		dispatcher.taskService.StartTask(task)
 	}
 }
 
 func (dispatcher concreteActionDispatcher) Dispatch(req boshhandler.Request) boshhandler.Response {
+	logger := agentlogger.WithFields(dispatcher.logger, agentlogger.Fields{
+		agentlogger.RequestIDField:    req.RequestID,
+		agentlogger.ActionMethodField: req.Method,
+	})
+
 	action, err := dispatcher.actionFactory.Create(req.Method)
 	if err != nil {
-		dispatcher.logger.Error(actionDispatcherLogTag, "Unknown action %s", req.Method)
+		logger.Error(actionDispatcherLogTag, "Unknown action %s", req.Method)
 		return boshhandler.NewExceptionResponse(bosherr.Errorf("unknown message %s", req.Method))
 	}
 
-	dispatcher.logger.Info(actionDispatcherLogTag, "Received request with action %s", req.Method)
+	logger.Info(actionDispatcherLogTag, "Received request with action %s", req.Method)
 	if action.IsLoggable() {
-		dispatcher.logger.DebugWithDetails(actionDispatcherLogTag, "Payload", req.Payload)
+		logger.DebugWithDetails(actionDispatcherLogTag, "Payload", req.Payload)
 	}
 
 	if action.IsAsynchronous(boshaction.ProtocolVersion(req.ProtocolVersion)) {
-		return dispatcher.dispatchAsynchronousAction(action, req)
+		return dispatcher.dispatchAsynchronousAction(action, req, logger)
 	}
 
-	return dispatcher.dispatchSynchronousAction(action, req)
+	return dispatcher.dispatchSynchronousAction(action, req, logger)
 }
 
 func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 	action boshaction.Action,
 	req boshhandler.Request,
+	logger boshlog.Logger,
 ) boshhandler.Response {
-	dispatcher.logger.Info(actionDispatcherLogTag, "Running async action %s", req.Method)
+	logger.Info(actionDispatcherLogTag, "Running async action %s", req.Method)
 
 	var task boshtask.Task
 	var err error
 
 	runTask := func() (interface{}, error) {
-		return dispatcher.actionRunner.Run(action, req.GetPayload(), boshaction.ProtocolVersion(req.ProtocolVersion))
+		taskAction := withLogger(action, agentlogger.WithFields(logger, agentlogger.Fields{
+			agentlogger.TaskIDField: task.ID,
+		}))
+
+		return dispatcher.actionRunner.RunWithProgress(taskAction, req.GetPayload(), boshaction.ProtocolVersion(req.ProtocolVersion), task.Progress)
 	}
 
 	cancelTask := func(_ boshtask.Task) error { return action.Cancel() }
@@ -112,35 +134,42 @@ func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 	// after agent restart so that API consumers do not need to know
 	// if agent is restarted midway through the task.
 	if action.IsPersistent() {
-		dispatcher.logger.Info(actionDispatcherLogTag, "Running persistent action %s", req.Method)
+		logger.Info(actionDispatcherLogTag, "Running persistent action %s", req.Method)
 		task, err = dispatcher.taskService.CreateTask(runTask, cancelTask, dispatcher.removeInfo)
 		if err != nil {
 			err = bosherr.WrapErrorf(err, "Create Task Failed %s", req.Method)
-			dispatcher.logger.Error(actionDispatcherLogTag, err.Error())
+			logger.Error(actionDispatcherLogTag, err.Error())
 			return boshhandler.NewExceptionResponse(err)
 		}
 
 		taskInfo := boshtask.Info{
-			TaskID:  task.ID,
-			Method:  req.Method,
-			Payload: req.GetPayload(),
+			TaskID:    task.ID,
+			RequestID: req.RequestID,
+			Method:    req.Method,
+			Payload:   req.GetPayload(),
 		}
 
 		err = dispatcher.taskManager.AddInfo(taskInfo)
 		if err != nil {
 			err = bosherr.WrapErrorf(err, "Action Failed %s", req.Method)
-			dispatcher.logger.Error(actionDispatcherLogTag, err.Error())
+			logger.Error(actionDispatcherLogTag, err.Error())
 			return boshhandler.NewExceptionResponse(err)
 		}
 	} else {
 		task, err = dispatcher.taskService.CreateTask(runTask, cancelTask, nil)
 		if err != nil {
 			err = bosherr.WrapErrorf(err, "Create Task Failed %s", req.Method)
-			dispatcher.logger.Error(actionDispatcherLogTag, err.Error())
+			logger.Error(actionDispatcherLogTag, err.Error())
 			return boshhandler.NewExceptionResponse(err)
 		}
 	}
 
+	task.RequestID = req.RequestID
+
+	agentlogger.WithFields(logger, agentlogger.Fields{
+		agentlogger.TaskIDField: task.ID,
+	}).Info(actionDispatcherLogTag, "Starting task %s", task.ID)
+
 	dispatcher.taskService.StartTask(task)
 
 	return boshhandler.NewValueResponse(boshtask.StateValue{
@@ -152,19 +181,29 @@ func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 func (dispatcher concreteActionDispatcher) dispatchSynchronousAction(
 	action boshaction.Action,
 	req boshhandler.Request,
+	logger boshlog.Logger,
 ) boshhandler.Response {
-	dispatcher.logger.Info(actionDispatcherLogTag, "Running sync action %s", req.Method)
+	logger.Info(actionDispatcherLogTag, "Running sync action %s", req.Method)
 
-	value, err := dispatcher.actionRunner.Run(action, req.GetPayload(), boshaction.ProtocolVersion(req.ProtocolVersion))
+	value, err := dispatcher.actionRunner.Run(withLogger(action, logger), req.GetPayload(), boshaction.ProtocolVersion(req.ProtocolVersion))
 	if err != nil {
 		err = bosherr.WrapErrorf(err, "Action Failed %s", req.Method)
-		dispatcher.logger.Error(actionDispatcherLogTag, err.Error())
+		logger.Error(actionDispatcherLogTag, err.Error())
 		return boshhandler.NewExceptionResponse(err)
 	}
 
 	return boshhandler.NewValueResponse(value)
 }
 
+// withLogger lets actions that log use logger, which carries the
+// correlation fields of the request
+func withLogger(action boshaction.Action, logger boshlog.Logger) boshaction.Action {
+	if loggingAction, ok := action.(boshaction.LoggingAction); ok {
+		return loggingAction.WithLogger(logger)
+	}
+	return action
+}
+
 func (dispatcher concreteActionDispatcher) removeInfo(task boshtask.Task) {
 	err := dispatcher.taskManager.RemoveInfo(task.ID)
 	if err != nil {
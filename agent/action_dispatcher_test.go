@@ -1,6 +1,7 @@
 package agent_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,8 +15,10 @@ import (
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	"github.com/cloudfoundry/bosh-agent/infrastructure/agentlogger"
 	"github.com/cloudfoundry/bosh-agent/logger/fakes"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
 func init() {
@@ -123,6 +126,16 @@ func init() {
 				Expect(actionRunner.RunProtocolVersion).To(Equal(action.ProtocolVersion(99)))
 			})
 
+			It("passes the progress of the task to the action", func() {
+				req = boshhandler.NewRequest("fake-reply", "fake-action", []byte("fake-payload"), boshhandler.ProtocolVersion(0))
+				dispatcher.Dispatch(req)
+
+				task := taskService.StartedTasks["fake-generated-task-id"]
+				_, err := task.Func()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(actionRunner.RunProgress).To(Equal(task.Progress))
+			})
 		})
 
 		Context("when request contains protocol version and action is Synchronous", func() {
@@ -159,9 +172,27 @@ func init() {
 				req boshhandler.Request
 			)
 
+			var action *fakeaction.TestAction
+
 			BeforeEach(func() {
 				req = boshhandler.NewRequest("fake-reply", "fake-action", []byte("fake-payload"), 0)
-				actionFactory.RegisterAction("fake-action", &fakeaction.TestAction{Asynchronous: false})
+				req.RequestID = "fake-request-id"
+				action = &fakeaction.TestAction{Asynchronous: false}
+				actionFactory.RegisterAction("fake-action", action)
+			})
+
+			It("gives the action a logger with request id and action method fields", func() {
+				outBuf := new(bytes.Buffer)
+				jsonLogger := agentlogger.NewJSONLogger(boshlog.LevelDebug, outBuf, new(bytes.Buffer))
+				dispatcher = NewActionDispatcher(jsonLogger, taskService, taskManager, actionFactory, actionRunner)
+
+				dispatcher.Dispatch(req)
+
+				outBuf.Reset()
+				action.Logger.Info("fake-tag", "fake-action-message")
+
+				Expect(outBuf.String()).To(ContainSubstring(`"request_id":"fake-request-id"`))
+				Expect(outBuf.String()).To(ContainSubstring(`"action_method":"fake-action"`))
 			})
 
 			It("handles synchronous action", func() {
@@ -189,6 +220,7 @@ func init() {
 
 			BeforeEach(func() {
 				req = boshhandler.NewRequest("fake-reply", "fake-action", []byte("fake-payload"), 0)
+				req.RequestID = "fake-request-id"
 				action = &fakeaction.TestAction{Asynchronous: true}
 				actionFactory.RegisterAction("fake-action", action)
 			})
@@ -230,6 +262,42 @@ func init() {
 					Expect(taskService.StartedTasks["fake-generated-task-id"]).ToNot(BeNil())
 				})
 
+				It("attaches the request id to the task", func() {
+					dispatcher.Dispatch(req)
+					Expect(taskService.StartedTasks["fake-generated-task-id"].RequestID).To(Equal("fake-request-id"))
+				})
+
+				It("logs with request id, action method and task id fields", func() {
+					outBuf := new(bytes.Buffer)
+					jsonLogger := agentlogger.NewJSONLogger(boshlog.LevelDebug, outBuf, new(bytes.Buffer))
+					dispatcher = NewActionDispatcher(jsonLogger, taskService, taskManager, actionFactory, actionRunner)
+
+					dispatcher.Dispatch(req)
+
+					Expect(outBuf.String()).To(ContainSubstring(`"request_id":"fake-request-id"`))
+					Expect(outBuf.String()).To(ContainSubstring(`"action_method":"fake-action"`))
+					Expect(outBuf.String()).To(ContainSubstring(`"task_id":"fake-generated-task-id"`))
+				})
+
+				It("gives the action a logger with request id, action method and task id fields", func() {
+					outBuf := new(bytes.Buffer)
+					jsonLogger := agentlogger.NewJSONLogger(boshlog.LevelDebug, outBuf, new(bytes.Buffer))
+					dispatcher = NewActionDispatcher(jsonLogger, taskService, taskManager, actionFactory, actionRunner)
+
+					dispatcher.Dispatch(req)
+
+					_, err := taskService.StartedTasks["fake-generated-task-id"].Func()
+					Expect(err).ToNot(HaveOccurred())
+
+					outBuf.Reset()
+					action.Logger.Info("fake-tag", "fake-action-message")
+
+					Expect(outBuf.String()).To(ContainSubstring(`"message":"fake-action-message"`))
+					Expect(outBuf.String()).To(ContainSubstring(`"request_id":"fake-request-id"`))
+					Expect(outBuf.String()).To(ContainSubstring(`"action_method":"fake-action"`))
+					Expect(outBuf.String()).To(ContainSubstring(`"task_id":"fake-generated-task-id"`))
+				})
+
 				It("returns create task error", func() {
 					taskService.CreateTaskErr = errors.New("fake-create-task-error")
 					resp := dispatcher.Dispatch(req)
@@ -334,9 +402,10 @@ func init() {
 					taskInfos, _ := taskManager.GetInfos()
 					Expect(taskInfos).To(Equal([]boshtask.Info{
 						boshtask.Info{
-							TaskID:  "fake-generated-task-id",
-							Method:  "fake-action",
-							Payload: []byte("fake-payload"),
+							TaskID:    "fake-generated-task-id",
+							RequestID: "fake-request-id",
+							Method:    "fake-action",
+							Payload:   []byte("fake-payload"),
 						},
 					}))
 				})
@@ -366,9 +435,10 @@ func init() {
 
 			BeforeEach(func() {
 				err := taskManager.AddInfo(boshtask.Info{
-					TaskID:  "fake-task-id-1",
-					Method:  "fake-action-1",
-					Payload: []byte("fake-task-payload-1"),
+					TaskID:    "fake-task-id-1",
+					RequestID: "fake-request-id-1",
+					Method:    "fake-action-1",
+					Payload:   []byte("fake-task-payload-1"),
 				})
 				Expect(err).ToNot(HaveOccurred())
 
@@ -389,6 +459,7 @@ func init() {
 
 				dispatcher.ResumePreviouslyDispatchedTasks()
 				Expect(len(taskService.StartedTasks)).To(Equal(2))
+				Expect(taskService.StartedTasks["fake-task-id-1"].RequestID).To(Equal("fake-request-id-1"))
 
 				{ // Check that first task executes first action
 					actionRunner.ResumeValue = "fake-resume-value-1"
@@ -7,6 +7,7 @@ import (
 
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshevent "github.com/cloudfoundry/bosh-agent/agent/event"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
@@ -29,7 +30,9 @@ type Agent struct {
 	heartbeatInterval time.Duration
 	jobSupervisor     boshjobsuper.JobSupervisor
 	specService       boshas.V1Service
+	eventStore        boshevent.Store
 	syslogServer      boshsyslog.Server
+	syslogRules       boshalert.SyslogRules
 	settingsService   boshsettings.Service
 	uuidGenerator     boshuuid.Generator
 	timeService       clock.Clock
@@ -42,7 +45,9 @@ func New(
 	actionDispatcher ActionDispatcher,
 	jobSupervisor boshjobsuper.JobSupervisor,
 	specService boshas.V1Service,
+	eventStore boshevent.Store,
 	syslogServer boshsyslog.Server,
+	syslogRules boshalert.SyslogRules,
 	heartbeatInterval time.Duration,
 	settingsService boshsettings.Service,
 	uuidGenerator boshuuid.Generator,
@@ -56,7 +61,9 @@ func New(
 		heartbeatInterval: heartbeatInterval,
 		jobSupervisor:     jobSupervisor,
 		specService:       specService,
+		eventStore:        eventStore,
 		syslogServer:      syslogServer,
+		syslogRules:       syslogRules,
 		settingsService:   settingsService,
 		uuidGenerator:     uuidGenerator,
 		timeService:       timeService,
@@ -79,6 +86,13 @@ func (a Agent) Run() error {
 		}
 	}()
 
+	go func() {
+		err := a.jobSupervisor.MonitorJobEvents(a.handleJobEvent(errCh))
+		if err != nil {
+			errCh <- err
+		}
+	}()
+
 	go func() {
 		err := a.syslogServer.Start(a.handleSyslogMsg(errCh))
 		if err != nil {
@@ -190,10 +204,28 @@ func (a Agent) handleJobFailure(errCh chan error) boshjobsuper.JobFailureHandler
 	}
 }
 
+// handleJobEvent keeps job events for get_events and publishes them; events
+// that could not be kept are published without an id
+func (a Agent) handleJobEvent(errCh chan error) boshjobsuper.JobEventHandler {
+	return func(jobEvent boshjobsuper.JobEvent) {
+		event, err := a.eventStore.Add(jobEvent)
+		if err != nil {
+			a.logger.Error(agentLogTag, "Recording job event: %s", err.Error())
+			event = boshevent.Event{JobEvent: jobEvent}
+		}
+
+		err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.JobEvent, event)
+		if err != nil {
+			errCh <- bosherr.WrapError(err, "Sending job event")
+		}
+	}
+}
+
 func (a Agent) handleSyslogMsg(errCh chan error) boshsyslog.CallbackFunc {
 	return func(msg boshsyslog.Msg) {
 		alertAdapter := boshalert.NewSSHAdapter(
 			msg,
+			a.syslogRules,
 			a.settingsService,
 			a.uuidGenerator,
 			a.timeService,
@@ -12,8 +12,11 @@ import (
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	boshevent "github.com/cloudfoundry/bosh-agent/agent/event"
+	fakeevent "github.com/cloudfoundry/bosh-agent/agent/event/fakes"
 	fakeagent "github.com/cloudfoundry/bosh-agent/agent/fakes"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
 	fakembus "github.com/cloudfoundry/bosh-agent/mbus/fakes"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
@@ -35,6 +38,7 @@ func init() {
 			actionDispatcher *fakeagent.FakeActionDispatcher
 			jobSupervisor    *fakejobsuper.FakeJobSupervisor
 			specService      *fakeas.FakeV1Service
+			eventStore       *fakeevent.FakeStore
 			syslogServer     *fakesyslog.FakeServer
 			settingsService  *fakesettings.FakeSettingsService
 			uuidGenerator    *fakeuuid.FakeGenerator
@@ -49,6 +53,7 @@ func init() {
 			actionDispatcher = &fakeagent.FakeActionDispatcher{}
 			jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 			specService = fakeas.NewFakeV1Service()
+			eventStore = &fakeevent.FakeStore{}
 			syslogServer = &fakesyslog.FakeServer{}
 			settingsService = &fakesettings.FakeSettingsService{}
 			uuidGenerator = &fakeuuid.FakeGenerator{}
@@ -60,7 +65,9 @@ func init() {
 				actionDispatcher,
 				jobSupervisor,
 				specService,
+				eventStore,
 				syslogServer,
+				boshalert.DefaultSyslogRules(),
 				5*time.Millisecond,
 				settingsService,
 				uuidGenerator,
@@ -140,7 +147,9 @@ func init() {
 						actionDispatcher,
 						jobSupervisor,
 						specService,
+						eventStore,
 						syslogServer,
+						boshalert.DefaultSyslogRules(),
 						5*time.Hour,
 						settingsService,
 						uuidGenerator,
@@ -256,6 +265,38 @@ func init() {
 				}))
 			})
 
+			It("records job events and sends them to health manager", func() {
+				handler.KeepOnRunning()
+
+				jobEvent := boshjobsuper.JobEvent{
+					Process:     "fake-process",
+					Type:        "failed",
+					Timestamp:   1306076861,
+					Description: "fake-description",
+				}
+				jobSupervisor.JobEvents = []boshjobsuper.JobEvent{jobEvent}
+
+				// Fail the first time handler.Send is called for an event (ignore heartbeats)
+				handler.SendCallback = func(input fakembus.SendInput) {
+					if input.Topic == boshhandler.JobEvent {
+						handler.SendErr = errors.New("stop")
+					}
+				}
+
+				err := agent.Run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("stop"))
+
+				expectedEvent := boshevent.Event{ID: 1, JobEvent: jobEvent}
+
+				Expect(eventStore.AddedEvents()).To(Equal([]boshevent.Event{expectedEvent}))
+				Expect(handler.SendInputs()).To(ContainElement(fakembus.SendInput{
+					Target:  boshhandler.HealthMonitor,
+					Topic:   boshhandler.JobEvent,
+					Message: expectedEvent,
+				}))
+			})
+
 			It("sends ssh alerts to health manager", func() {
 				handler.KeepOnRunning()
 
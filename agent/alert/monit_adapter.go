@@ -92,6 +92,7 @@ var eventToSeverity = map[string]SeverityLevel{
 	"content succeeded":            SeverityIgnored,
 	"content match":                SeverityIgnored,
 	"content doesn't match":        SeverityError,
+	"crash loop":                   SeverityCritical,
 	"data access error":            SeverityError,
 	"data access succeeded":        SeverityIgnored,
 	"data access changed":          SeverityWarning,
@@ -1,8 +1,6 @@
 package alert
 
 import (
-	"regexp"
-
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -11,16 +9,9 @@ import (
 	"github.com/pivotal-golang/clock"
 )
 
-var syslogMessageExpressions = map[*regexp.Regexp]string{
-	regexp.MustCompile("disconnected by user"):                  "SSH Logout",
-	regexp.MustCompile("Accepted publickey for"):                "SSH Login",
-	regexp.MustCompile("Accepted password for"):                 "SSH Login",
-	regexp.MustCompile("Failed password for"):                   "SSH Access Denied",
-	regexp.MustCompile("Connection closed by .* \\[preauth\\]"): "SSH Access Denied",
-}
-
 type sshAdapter struct {
 	message         boshsyslog.Msg
+	rules           SyslogRules
 	settingsService boshsettings.Service
 	uuidGenerator   boshuuid.Generator
 	timeService     clock.Clock
@@ -29,6 +20,7 @@ type sshAdapter struct {
 
 func NewSSHAdapter(
 	message boshsyslog.Msg,
+	rules SyslogRules,
 	settingsService boshsettings.Service,
 	uuidGenerator boshuuid.Generator,
 	timeService clock.Clock,
@@ -36,6 +28,7 @@ func NewSSHAdapter(
 ) Adapter {
 	return &sshAdapter{
 		message:         message,
+		rules:           rules,
 		settingsService: settingsService,
 		uuidGenerator:   uuidGenerator,
 		timeService:     timeService,
@@ -44,12 +37,12 @@ func NewSSHAdapter(
 }
 
 func (m *sshAdapter) IsIgnorable() bool {
-	_, found := m.title()
-	return !found
+	rule, _, found := m.rules.Match(m.message.Content)
+	return !found || rule.Severity == SeverityIgnored
 }
 
 func (m *sshAdapter) Alert() (Alert, error) {
-	title, found := m.title()
+	rule, title, found := m.rules.Match(m.message.Content)
 	if !found {
 		return Alert{}, nil
 	}
@@ -61,18 +54,9 @@ func (m *sshAdapter) Alert() (Alert, error) {
 
 	return Alert{
 		ID:        uuid,
-		Severity:  SeverityWarning,
+		Severity:  rule.Severity,
 		Title:     title,
 		Summary:   m.message.Content,
 		CreatedAt: m.timeService.Now().Unix(),
 	}, nil
 }
-
-func (m *sshAdapter) title() (title string, found bool) {
-	for expression, title := range syslogMessageExpressions {
-		if expression.MatchString(m.message.Content) {
-			return title, true
-		}
-	}
-	return "", false
-}
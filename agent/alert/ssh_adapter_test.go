@@ -37,6 +37,7 @@ var _ = Describe("sshAdapter", func() {
 			sshMsg := boshsyslog.Msg{Content: msgContent}
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
+				DefaultSyslogRules(),
 				settingsService,
 				uuidGenerator,
 				timeService,
@@ -71,6 +72,25 @@ var _ = Describe("sshAdapter", func() {
 			sshMsg := boshsyslog.Msg{Content: msgContent}
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
+				DefaultSyslogRules(),
+				settingsService,
+				uuidGenerator,
+				timeService,
+				logger,
+			)
+
+			Expect(sshAdapter.IsIgnorable()).To(BeTrue())
+		})
+
+		It("Ignores messages matching rules with ignored severity", func() {
+			rules, err := NewSyslogRules([]SyslogRuleOptions{
+				{Expression: "fake-noise", Title: "fake-title", Severity: SeverityIgnored},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			sshAdapter := NewSSHAdapter(
+				boshsyslog.Msg{Content: "some fake-noise"},
+				rules,
 				settingsService,
 				uuidGenerator,
 				timeService,
@@ -87,6 +107,7 @@ var _ = Describe("sshAdapter", func() {
 			sshMsg := boshsyslog.Msg{Content: msgContent}
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
+				DefaultSyslogRules(),
 				settingsService,
 				uuidGenerator,
 				timeService,
@@ -129,6 +150,7 @@ var _ = Describe("sshAdapter", func() {
 			sshMsg := boshsyslog.Msg{Content: msgContent}
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
+				DefaultSyslogRules(),
 				settingsService,
 				uuidGenerator,
 				timeService,
@@ -141,11 +163,36 @@ var _ = Describe("sshAdapter", func() {
 			Expect(builtAlert.Severity).To(Equal(SeverityWarning))
 		})
 
+		It("Uses the title and severity of configured rules", func() {
+			rules, err := NewSyslogRules([]SyslogRuleOptions{
+				{Expression: `sudo: +(?P<user>\S+) :`, Title: "Sudo by ${user}", Severity: SeverityError},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			msgContent := "sudo:     vcap : TTY=pts/0 ; PWD=/home/vcap ; USER=root ; COMMAND=/bin/ls"
+			sshAdapter := NewSSHAdapter(
+				boshsyslog.Msg{Content: msgContent},
+				rules,
+				settingsService,
+				uuidGenerator,
+				timeService,
+				logger,
+			)
+
+			builtAlert, err := sshAdapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(builtAlert.Title).To(Equal("Sudo by vcap"))
+			Expect(builtAlert.Severity).To(Equal(SeverityError))
+			Expect(builtAlert.Summary).To(Equal(msgContent))
+		})
+
 		It("CreatedAt is Now", func() {
 			msgContent := "disconnected by user"
 			sshMsg := boshsyslog.Msg{Content: msgContent}
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
+				DefaultSyslogRules(),
 				settingsService,
 				uuidGenerator,
 				timeService,
@@ -163,6 +210,7 @@ var _ = Describe("sshAdapter", func() {
 			sshMsg := boshsyslog.Msg{Content: msgContent}
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
+				DefaultSyslogRules(),
 				settingsService,
 				uuidGenerator,
 				timeService,
@@ -0,0 +1,90 @@
+package alert
+
+import (
+	"regexp"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// Options configures alert generation and is loaded from the agent config.
+type Options struct {
+	SyslogRules []SyslogRuleOptions
+}
+
+// SyslogRuleOptions describes an operator defined syslog alert rule.
+// Named capture groups in Expression (e.g. (?P<user>\S+)) may be
+// referenced from Title as ${user}.
+type SyslogRuleOptions struct {
+	Expression string
+	Title      string
+	Severity   SeverityLevel
+}
+
+type SyslogRule struct {
+	Expression *regexp.Regexp
+	Title      string
+	Severity   SeverityLevel
+}
+
+// SyslogRules are evaluated in order; the first matching rule wins.
+type SyslogRules []SyslogRule
+
+var defaultSyslogRules = SyslogRules{
+	{regexp.MustCompile("disconnected by user"), "SSH Logout", SeverityWarning},
+	{regexp.MustCompile("Accepted publickey for"), "SSH Login", SeverityWarning},
+	{regexp.MustCompile("Accepted password for"), "SSH Login", SeverityWarning},
+	{regexp.MustCompile("Failed password for"), "SSH Access Denied", SeverityWarning},
+	{regexp.MustCompile("Connection closed by .* \\[preauth\\]"), "SSH Access Denied", SeverityWarning},
+}
+
+func DefaultSyslogRules() SyslogRules {
+	return append(SyslogRules{}, defaultSyslogRules...)
+}
+
+// NewSyslogRules returns the default SSH rules followed by the given rules.
+func NewSyslogRules(options []SyslogRuleOptions) (SyslogRules, error) {
+	rules := DefaultSyslogRules()
+
+	for _, opts := range options {
+		if opts.Title == "" {
+			return nil, bosherr.Errorf("Missing title for syslog rule '%s'", opts.Expression)
+		}
+
+		expression, err := regexp.Compile(opts.Expression)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Compiling syslog rule '%s'", opts.Title)
+		}
+
+		severity := opts.Severity
+		switch {
+		case severity == 0:
+			severity = SeverityWarning
+		case severity != SeverityIgnored && (severity < SeverityAlert || severity > SeverityWarning):
+			return nil, bosherr.Errorf("Invalid severity %d for syslog rule '%s'", severity, opts.Title)
+		}
+
+		rules = append(rules, SyslogRule{
+			Expression: expression,
+			Title:      opts.Title,
+			Severity:   severity,
+		})
+	}
+
+	return rules, nil
+}
+
+// Match returns the first rule matching content with its title expanded
+// using the rule's named capture groups.
+func (r SyslogRules) Match(content string) (rule SyslogRule, title string, found bool) {
+	for _, rule := range r {
+		submatches := rule.Expression.FindStringSubmatchIndex(content)
+		if submatches == nil {
+			continue
+		}
+
+		title := string(rule.Expression.ExpandString(nil, rule.Title, content, submatches))
+		return rule, title, true
+	}
+
+	return SyslogRule{}, "", false
+}
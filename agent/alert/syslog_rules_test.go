@@ -0,0 +1,124 @@
+package alert_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+)
+
+var _ = Describe("SyslogRules", func() {
+	var (
+		rules SyslogRules
+	)
+
+	BeforeEach(func() {
+		var err error
+		rules, err = NewSyslogRules([]SyslogRuleOptions{
+			{
+				Expression: `sudo: +(?P<user>\S+) : .*COMMAND=(?P<command>.*)$`,
+				Title:      "Sudo by ${user}: ${command}",
+			},
+			{
+				Expression: `Out of memory: Kill(ed)? process (?P<pid>\d+) \((?P<process>[^)]+)\)`,
+				Title:      "OOM killed ${process} (pid ${pid})",
+				Severity:   SeverityCritical,
+			},
+			{
+				Expression: `type=USER_AUTH .*acct="(?P<acct>[^"]+)".*res=failed`,
+				Title:      "Audit authentication failure for ${acct}",
+				Severity:   SeverityError,
+			},
+			{
+				Expression: `type=CRED_DISP`,
+				Title:      "Audit credential disposal",
+				Severity:   SeverityIgnored,
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	DescribeTable("matching sample messages",
+		func(content, expectedTitle string, expectedSeverity SeverityLevel) {
+			rule, title, found := rules.Match(content)
+			Expect(found).To(BeTrue())
+			Expect(title).To(Equal(expectedTitle))
+			Expect(rule.Severity).To(Equal(expectedSeverity))
+		},
+		Entry("ssh logout",
+			"Received disconnect from 9.9.9.9: 11: disconnected by user",
+			"SSH Logout", SeverityWarning),
+		Entry("ssh login",
+			"Accepted publickey for vcap from 9.9.9.9 port 58850 ssh2: RSA fake-rsa-key",
+			"SSH Login", SeverityWarning),
+		Entry("sudo usage",
+			"sudo:     vcap : TTY=pts/0 ; PWD=/home/vcap ; USER=root ; COMMAND=/usr/bin/monit summary",
+			"Sudo by vcap: /usr/bin/monit summary", SeverityWarning),
+		Entry("kernel oom kill",
+			"Out of memory: Kill process 4242 (ruby) score 901 or sacrifice child",
+			"OOM killed ruby (pid 4242)", SeverityCritical),
+		Entry("kernel oom killed",
+			"Out of memory: Killed process 17 (java) total-vm:1024kB",
+			"OOM killed java (pid 17)", SeverityCritical),
+		Entry("auditd authentication failure",
+			`type=USER_AUTH msg=audit(1468533030.123:99): pid=1 uid=0 acct="vcap" exe="/usr/sbin/sshd" res=failed`,
+			"Audit authentication failure for vcap", SeverityError),
+		Entry("ignored auditd event",
+			"type=CRED_DISP msg=audit(1468533030.123:100): pid=1 uid=0",
+			"Audit credential disposal", SeverityIgnored),
+	)
+
+	It("does not match unknown messages", func() {
+		_, _, found := rules.Match("ignorable unknown message")
+		Expect(found).To(BeFalse())
+	})
+
+	It("prefers default ssh rules over configured rules", func() {
+		rules, err := NewSyslogRules([]SyslogRuleOptions{
+			{Expression: "disconnected", Title: "fake-title"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, title, found := rules.Match("disconnected by user")
+		Expect(found).To(BeTrue())
+		Expect(title).To(Equal("SSH Logout"))
+	})
+
+	It("returns an error when the expression does not compile", func() {
+		_, err := NewSyslogRules([]SyslogRuleOptions{
+			{Expression: "(", Title: "fake-title"},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Compiling syslog rule 'fake-title'"))
+	})
+
+	It("returns an error when the title is missing", func() {
+		_, err := NewSyslogRules([]SyslogRuleOptions{
+			{Expression: "fake-expression"},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Missing title for syslog rule 'fake-expression'"))
+	})
+
+	It("returns an error when the severity is not an alert severity", func() {
+		for _, severity := range []SeverityLevel{-2, 5} {
+			_, err := NewSyslogRules([]SyslogRuleOptions{
+				{Expression: "fake-expression", Title: "fake-title", Severity: severity},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid severity %d for syslog rule 'fake-title'", severity))
+		}
+	})
+
+	It("accepts alert severities and ignored rules", func() {
+		rules, err := NewSyslogRules([]SyslogRuleOptions{
+			{Expression: "fake-critical", Title: "fake-title", Severity: SeverityCritical},
+			{Expression: "fake-ignored", Title: "fake-title", Severity: SeverityIgnored},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		rule, _, _ := rules.Match("fake-ignored")
+		Expect(rule.Severity).To(Equal(SeverityIgnored))
+	})
+})
@@ -10,10 +10,25 @@ type PackageSpec struct {
 	Version     string                `json:"version"`
 	Sha1        crypto.MultipleDigest `json:"sha1"`
 	BlobstoreID string                `json:"blobstore_id"`
+
+	// Size of the package blob in bytes; directors do not send it yet, so
+	// it is usually 0
+	Size int64 `json:"size,omitempty"`
+
+	// Delta optionally allows building the package from a previous version
+	// that is already installed instead of downloading the whole blob
+	Delta *PackageDeltaSpec `json:"delta,omitempty"`
+}
+
+type PackageDeltaSpec struct {
+	BaseVersion string                `json:"base_version"`
+	BaseSha1    crypto.MultipleDigest `json:"base_sha1"`
+	Sha1        crypto.MultipleDigest `json:"sha1"`
+	BlobstoreID string                `json:"blobstore_id"`
 }
 
 func (s *PackageSpec) AsPackage() models.Package {
-	return models.Package{
+	pkg := models.Package{
 		Name:    s.Name,
 		Version: s.Version,
 		Source: models.Source{
@@ -21,4 +36,20 @@ func (s *PackageSpec) AsPackage() models.Package {
 			BlobstoreID: s.BlobstoreID,
 		},
 	}
+
+	if s.Delta != nil {
+		pkg.Delta = &models.PackageDelta{
+			Base: models.Package{
+				Name:    s.Name,
+				Version: s.Delta.BaseVersion,
+				Source:  models.Source{Sha1: s.Delta.BaseSha1},
+			},
+			Source: models.Source{
+				Sha1:        s.Delta.Sha1,
+				BlobstoreID: s.Delta.BlobstoreID,
+			},
+		}
+	}
+
+	return pkg
 }
@@ -302,6 +302,39 @@ var _ = Describe("V1ApplySpec", func() {
 			spec := V1ApplySpec{}
 			Expect(spec.Packages()).To(Equal([]models.Package{}))
 		})
+
+		It("returns packages with the delta from their base version", func() {
+			spec := V1ApplySpec{
+				PackageSpecs: map[string]PackageSpec{
+					"fake-package1-name-key": {
+						Name:        "fake-package1-name",
+						Version:     "fake-package1-version",
+						Sha1:        crypto.MustParseMultipleDigest("sha1:fakepackage1sha1"),
+						BlobstoreID: "fake-package1-blobstore-id",
+						Delta: &PackageDeltaSpec{
+							BaseVersion: "fake-package1-base-version",
+							BaseSha1:    crypto.MustParseMultipleDigest("sha1:fakepackage1basesha1"),
+							Sha1:        crypto.MustParseMultipleDigest("sha1:fakepackage1deltasha1"),
+							BlobstoreID: "fake-package1-delta-blobstore-id",
+						},
+					},
+				},
+			}
+
+			Expect(spec.Packages()[0].Delta).To(Equal(&models.PackageDelta{
+				Base: models.Package{
+					Name:    "fake-package1-name",
+					Version: "fake-package1-base-version",
+					Source: models.Source{
+						Sha1: crypto.MustParseMultipleDigest("sha1:fakepackage1basesha1"),
+					},
+				},
+				Source: models.Source{
+					Sha1:        crypto.MustParseMultipleDigest("sha1:fakepackage1deltasha1"),
+					BlobstoreID: "fake-package1-delta-blobstore-id",
+				},
+			}))
+		})
 	})
 
 	Describe("MaxLogFileSize", func() {
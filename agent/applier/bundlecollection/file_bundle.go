@@ -97,11 +97,32 @@ func (b FileBundle) Enable() (boshsys.FileSystem, string, error) {
 		return nil, "", bosherr.WrapError(err, "failed to create enable dir")
 	}
 
-	err = b.fs.Symlink(b.installPath, b.enablePath)
+	// The link is created next to the enable path and renamed over it so
+	// that the enable path points to either the previous or this bundle
+	stagedPath := b.enablePath + ".enabling"
+
+	err = b.fs.Symlink(b.installPath, stagedPath)
 	if err != nil {
 		return nil, "", bosherr.WrapError(err, "failed to enable")
 	}
 
+	err = b.fs.Rename(stagedPath, b.enablePath)
+	if err != nil {
+		// Renaming over an existing link is not supported everywhere,
+		// e.g. for directory links on Windows
+		b.logger.Debug(fileBundleLogTag, "Replacing enable path in place: %s", err.Error())
+
+		err = b.fs.RemoveAll(stagedPath)
+		if err != nil {
+			return nil, "", bosherr.WrapError(err, "failed to enable")
+		}
+
+		err = b.fs.Symlink(b.installPath, b.enablePath)
+		if err != nil {
+			return nil, "", bosherr.WrapError(err, "failed to enable")
+		}
+	}
+
 	return b.fs, b.enablePath, nil
 }
 
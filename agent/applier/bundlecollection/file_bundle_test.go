@@ -2,13 +2,16 @@ package bundlecollection_test
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
 
@@ -199,7 +202,6 @@ var _ = Describe("FileBundle", func() {
 				fileStats := fs.GetFileTestStat(enablePath)
 				Expect(fileStats).NotTo(BeNil())
 				Expect(fileStats.FileType).To(Equal(fakesys.FakeFileType(fakesys.FakeFileTypeSymlink)))
-				Expect(installPath).To(Equal(fileStats.SymlinkTarget))
 
 				fileStats = fs.GetFileTestStat("/") // dir holding symlink
 				Expect(fileStats).NotTo(BeNil())
@@ -216,6 +218,75 @@ var _ = Describe("FileBundle", func() {
 			})
 		})
 
+		Context("when the enable path already points to another bundle", func() {
+			var (
+				realFs     boshsys.FileSystem
+				tmpDir     string
+				realBundle FileBundle
+			)
+
+			BeforeEach(func() {
+				var err error
+
+				tmpDir, err = ioutil.TempDir("", "file-bundle")
+				Expect(err).NotTo(HaveOccurred())
+
+				realFs = boshsys.NewOsFileSystem(logger)
+				installPath = filepath.Join(tmpDir, "install-path")
+				enablePath = filepath.Join(tmpDir, "enable-path")
+
+				Expect(realFs.MkdirAll(installPath, os.ModePerm)).To(Succeed())
+				Expect(realFs.MkdirAll(filepath.Join(tmpDir, "previous-install-path"), os.ModePerm)).To(Succeed())
+				Expect(realFs.Symlink(filepath.Join(tmpDir, "previous-install-path"), enablePath)).To(Succeed())
+
+				realBundle = NewFileBundle(installPath, enablePath, realFs, logger)
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(tmpDir)
+			})
+
+			It("renames a link to the bundle over the enable path", func() {
+				_, _, err := realBundle.Enable()
+				Expect(err).NotTo(HaveOccurred())
+
+				target, err := realFs.Readlink(enablePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(target).To(Equal(installPath))
+
+				Expect(realFs.FileExists(enablePath + ".enabling")).To(BeFalse())
+			})
+		})
+
+		Context("when the link cannot be renamed over the enable path", func() {
+			It("replaces the enable path in place", func() {
+				_, _, err := fileBundle.Install(sourcePath)
+				Expect(err).NotTo(HaveOccurred())
+				fs.RenameError = errors.New("fake-rename-error")
+
+				_, _, err = fileBundle.Enable()
+				Expect(err).NotTo(HaveOccurred())
+
+				fileStats := fs.GetFileTestStat(enablePath)
+				Expect(fileStats).NotTo(BeNil())
+				Expect(fileStats.SymlinkTarget).To(Equal(installPath))
+				Expect(fs.FileExists(enablePath + ".enabling")).To(BeFalse())
+			})
+
+			It("returns error when the staged link cannot be removed", func() {
+				_, _, err := fileBundle.Install(sourcePath)
+				Expect(err).NotTo(HaveOccurred())
+				fs.RenameError = errors.New("fake-rename-error")
+				fs.RemoveAllStub = func(_ string) error {
+					return errors.New("fake-removeall-error")
+				}
+
+				_, _, err = fileBundle.Enable()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-removeall-error"))
+			})
+		})
+
 		Context("when bundle is not installed", func() {
 			It("returns error", func() {
 				_, _, err := fileBundle.Enable()
@@ -268,12 +339,14 @@ var _ = Describe("FileBundle", func() {
 			Expect(fs.FileExists(enablePath)).To(BeFalse())
 		})
 
+		// The fake file system does not keep link targets across renames,
+		// so enabled bundles are linked directly
 		Context("where the enabled path target is the same installed version", func() {
 			BeforeEach(func() {
 				_, _, err := fileBundle.Install(sourcePath)
 				Expect(err).NotTo(HaveOccurred())
 
-				_, _, err = fileBundle.Enable()
+				err = fs.Symlink(installPath, enablePath)
 				Expect(err).NotTo(HaveOccurred())
 			})
 
@@ -301,7 +374,7 @@ var _ = Describe("FileBundle", func() {
 				_, _, err := fileBundle.Install(sourcePath)
 				Expect(err).NotTo(HaveOccurred())
 
-				_, _, err = fileBundle.Enable()
+				err = fs.Symlink(installPath, enablePath)
 				Expect(err).NotTo(HaveOccurred())
 
 				newerFileBundle := NewFileBundle(newerInstallPath, enablePath, fs, logger)
@@ -310,7 +383,7 @@ var _ = Describe("FileBundle", func() {
 				_, _, err = newerFileBundle.Install(otherSourcePath)
 				Expect(err).NotTo(HaveOccurred())
 
-				_, _, err = newerFileBundle.Enable()
+				err = fs.Symlink(newerInstallPath, enablePath)
 				Expect(err).NotTo(HaveOccurred())
 			})
 
@@ -1,6 +1,8 @@
 package applier
 
 import (
+	"strings"
+
 	as "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	"github.com/cloudfoundry/bosh-agent/agent/applier/jobs"
 	"github.com/cloudfoundry/bosh-agent/agent/applier/packages"
@@ -16,6 +18,7 @@ type concreteApplier struct {
 	logrotateDelegate LogrotateDelegate
 	jobSupervisor     boshjobsuper.JobSupervisor
 	dirProvider       boshdirs.Provider
+	parallelism       int
 }
 
 func NewConcreteApplier(
@@ -24,41 +27,75 @@ func NewConcreteApplier(
 	logrotateDelegate LogrotateDelegate,
 	jobSupervisor boshjobsuper.JobSupervisor,
 	dirProvider boshdirs.Provider,
+	options Options,
 ) Applier {
+	parallelism := options.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+
 	return &concreteApplier{
 		jobApplier:        jobApplier,
 		packageApplier:    packageApplier,
 		logrotateDelegate: logrotateDelegate,
 		jobSupervisor:     jobSupervisor,
 		dirProvider:       dirProvider,
+		parallelism:       parallelism,
 	}
 }
 
+// Prepare downloads and installs jobs and packages that are not installed
+// yet, several at a time; the other downloads stop once one of them failed
 func (a *concreteApplier) Prepare(desiredApplySpec as.ApplySpec) error {
-	for _, job := range desiredApplySpec.Jobs() {
-		err := a.jobApplier.Prepare(job)
-		if err != nil {
-			return bosherr.WrapErrorf(err, "Preparing job %s", job.Name)
+	jobs := desiredApplySpec.Jobs()
+	pkgs := desiredApplySpec.Packages()
+
+	return forEachInParallel(a.parallelism, len(jobs)+len(pkgs), func(i int, stopCh <-chan struct{}) error {
+		if i < len(jobs) {
+			err := a.jobApplier.Prepare(jobs[i], stopCh)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Preparing job %s", jobs[i].Name)
+			}
+
+			return nil
 		}
-	}
 
-	for _, pkg := range desiredApplySpec.Packages() {
-		err := a.packageApplier.Prepare(pkg)
+		pkg := pkgs[i-len(jobs)]
+
+		err := a.packageApplier.Prepare(pkg, stopCh)
 		if err != nil {
 			return bosherr.WrapErrorf(err, "Preparing package %s", pkg.Name)
 		}
+
+		return nil
+	})
+}
+
+// Apply switches from the current to the desired jobs and packages. Jobs
+// and packages are downloaded and verified before the current jobs are
+// removed; when switching fails the current jobs and packages are enabled
+// and configured again.
+func (a *concreteApplier) Apply(currentApplySpec, desiredApplySpec as.ApplySpec) error {
+	err := a.Prepare(desiredApplySpec)
+	if err != nil {
+		return err
+	}
+
+	err = a.switchTo(desiredApplySpec, currentApplySpec)
+	if err != nil {
+		return a.rollBack(currentApplySpec, desiredApplySpec, err)
 	}
 
 	return nil
 }
 
-func (a *concreteApplier) Apply(currentApplySpec, desiredApplySpec as.ApplySpec) error {
+func (a *concreteApplier) switchTo(applySpec, otherApplySpec as.ApplySpec) error {
 	err := a.jobSupervisor.RemoveAllJobs()
 	if err != nil {
 		return bosherr.WrapError(err, "Removing all jobs")
 	}
 
-	jobs := desiredApplySpec.Jobs()
+	jobs := applySpec.Jobs()
 	for _, job := range jobs {
 		err = a.jobApplier.Apply(job)
 		if err != nil {
@@ -66,19 +103,21 @@ func (a *concreteApplier) Apply(currentApplySpec, desiredApplySpec as.ApplySpec)
 		}
 	}
 
-	err = a.jobApplier.KeepOnly(append(currentApplySpec.Jobs(), desiredApplySpec.Jobs()...))
+	// Bundles of both specs are kept so that either can be switched to
+	// without downloading them again
+	err = a.jobApplier.KeepOnly(append(otherApplySpec.Jobs(), applySpec.Jobs()...))
 	if err != nil {
 		return bosherr.WrapError(err, "Keeping only needed jobs")
 	}
 
-	for _, pkg := range desiredApplySpec.Packages() {
+	for _, pkg := range applySpec.Packages() {
 		err = a.packageApplier.Apply(pkg)
 		if err != nil {
 			return bosherr.WrapErrorf(err, "Applying package %s", pkg.Name)
 		}
 	}
 
-	err = a.packageApplier.KeepOnly(append(currentApplySpec.Packages(), desiredApplySpec.Packages()...))
+	err = a.packageApplier.KeepOnly(append(otherApplySpec.Packages(), applySpec.Packages()...))
 	if err != nil {
 		return bosherr.WrapError(err, "Keeping only needed packages")
 	}
@@ -88,7 +127,37 @@ func (a *concreteApplier) Apply(currentApplySpec, desiredApplySpec as.ApplySpec)
 		return bosherr.WrapError(err, "Reloading jobSupervisor")
 	}
 
-	return a.setUpLogrotate(desiredApplySpec)
+	return a.setUpLogrotate(applySpec)
+}
+
+// rollBack switches back to the current spec, which is still installed
+// because switchTo keeps the bundles of both specs
+func (a *concreteApplier) rollBack(currentApplySpec, desiredApplySpec as.ApplySpec, applyErr error) error {
+	err := a.switchTo(currentApplySpec, desiredApplySpec)
+	if err == nil {
+		err = a.ConfigureJobs(currentApplySpec)
+	}
+
+	if err != nil {
+		return bosherr.NewMultiError(applyErr, bosherr.WrapError(err, "Rolling back to the current spec"))
+	}
+
+	jobNames := []string{}
+	for _, job := range currentApplySpec.Jobs() {
+		jobNames = append(jobNames, job.Name)
+	}
+
+	pkgNames := []string{}
+	for _, pkg := range currentApplySpec.Packages() {
+		pkgNames = append(pkgNames, pkg.Name)
+	}
+
+	return bosherr.WrapErrorf(
+		applyErr,
+		"Rolled back to jobs [%s] and packages [%s]",
+		strings.Join(jobNames, ", "),
+		strings.Join(pkgNames, ", "),
+	)
 }
 
 func (a *concreteApplier) ConfigureJobs(desiredApplySpec as.ApplySpec) error {
@@ -2,7 +2,10 @@ package applier_test
 
 import (
 	"errors"
+	"fmt"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -36,6 +39,61 @@ func (d *FakeLogRotateDelegate) SetupLogrotate(groupName, basePath, size string)
 	return d.SetupLogrotateErr
 }
 
+type concurrencyTrackingPackageApplier struct {
+	lock    sync.Mutex
+	running int
+
+	MaxRunning       int
+	PreparedPackages []models.Package
+}
+
+func (a *concurrencyTrackingPackageApplier) Prepare(pkg models.Package, _ <-chan struct{}) error {
+	a.lock.Lock()
+	a.running++
+	if a.running > a.MaxRunning {
+		a.MaxRunning = a.running
+	}
+	a.lock.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	a.lock.Lock()
+	a.running--
+	a.PreparedPackages = append(a.PreparedPackages, pkg)
+	a.lock.Unlock()
+
+	return nil
+}
+
+func (a *concurrencyTrackingPackageApplier) Apply(pkg models.Package) error { return nil }
+
+func (a *concurrencyTrackingPackageApplier) KeepOnly(pkgs []models.Package) error { return nil }
+
+// stoppingPackageApplier fails preparing its failing package once the
+// other package started preparing, which waits until it is stopped
+type stoppingPackageApplier struct {
+	failing models.Package
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func (a *stoppingPackageApplier) Prepare(pkg models.Package, stopCh <-chan struct{}) error {
+	if pkg == a.failing {
+		<-a.started
+		return errors.New("fake-prepare-package-error")
+	}
+
+	close(a.started)
+	<-stopCh
+	close(a.stopped)
+
+	return errors.New("fake-stopped-error")
+}
+
+func (a *stoppingPackageApplier) Apply(pkg models.Package) error { return nil }
+
+func (a *stoppingPackageApplier) KeepOnly(pkgs []models.Package) error { return nil }
+
 func buildJob() models.Job {
 	uuidGen := boshuuid.NewGenerator()
 	uuid, err := uuidGen.Generate()
@@ -71,6 +129,7 @@ func init() {
 				logRotateDelegate,
 				jobSupervisor,
 				boshdirs.NewProvider("/fake-base-dir"),
+				Options{},
 			)
 		})
 
@@ -105,7 +164,7 @@ func init() {
 					&fakeas.FakeApplySpec{PackageResults: []models.Package{pkg1, pkg2}},
 				)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(packageApplier.PreparedPackages).To(Equal([]models.Package{pkg1, pkg2}))
+				Expect(packageApplier.PreparedPackages).To(ConsistOf(pkg1, pkg2))
 			})
 
 			It("returns error when preparing packages fails", func() {
@@ -119,6 +178,70 @@ func init() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-prepare-package-error"))
 			})
+
+			It("prepares at most the configured number of jobs and packages at a time", func() {
+				trackingApplier := &concurrencyTrackingPackageApplier{}
+				applier = NewConcreteApplier(
+					jobApplier,
+					trackingApplier,
+					logRotateDelegate,
+					jobSupervisor,
+					boshdirs.NewProvider("/fake-base-dir"),
+					Options{Parallelism: 2},
+				)
+
+				pkgs := []models.Package{buildPackage(), buildPackage(), buildPackage(), buildPackage(), buildPackage()}
+
+				err := applier.Prepare(&fakeas.FakeApplySpec{PackageResults: pkgs})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(trackingApplier.PreparedPackages).To(ConsistOf(pkgs))
+				Expect(trackingApplier.MaxRunning).To(Equal(2))
+			})
+
+			It("stops preparing further jobs and packages once one fails", func() {
+				applier = NewConcreteApplier(
+					jobApplier,
+					packageApplier,
+					logRotateDelegate,
+					jobSupervisor,
+					boshdirs.NewProvider("/fake-base-dir"),
+					Options{Parallelism: 1},
+				)
+
+				jobApplier.PrepareError = errors.New("fake-prepare-job-error")
+
+				err := applier.Prepare(&fakeas.FakeApplySpec{
+					JobResults:     []models.Job{buildJob(), buildJob()},
+					PackageResults: []models.Package{buildPackage()},
+				})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-prepare-job-error"))
+				Expect(jobApplier.PreparedJobs).To(HaveLen(1))
+				Expect(packageApplier.PreparedPackages).To(BeEmpty())
+			})
+
+			It("stops preparing jobs and packages still running once one fails", func() {
+				pkgs := []models.Package{buildPackage(), buildPackage()}
+				stoppingApplier := &stoppingPackageApplier{
+					failing: pkgs[1],
+					started: make(chan struct{}),
+					stopped: make(chan struct{}),
+				}
+
+				applier = NewConcreteApplier(
+					jobApplier,
+					stoppingApplier,
+					logRotateDelegate,
+					jobSupervisor,
+					boshdirs.NewProvider("/fake-base-dir"),
+					Options{Parallelism: 2},
+				)
+
+				err := applier.Prepare(&fakeas.FakeApplySpec{PackageResults: pkgs})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-prepare-package-error"))
+				Expect(stoppingApplier.stopped).To(BeClosed())
+			})
 		})
 
 		Describe("Configure jobs", func() {
@@ -147,6 +270,31 @@ func init() {
 		})
 
 		Describe("Apply", func() {
+			It("prepares jobs and packages before removing all jobs from job supervisor", func() {
+				job := buildJob()
+				pkg := buildPackage()
+
+				err := applier.Apply(
+					&fakeas.FakeApplySpec{},
+					&fakeas.FakeApplySpec{JobResults: []models.Job{job}, PackageResults: []models.Package{pkg}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(jobApplier.PreparedJobs).To(Equal([]models.Job{job}))
+				Expect(packageApplier.ActionsCalled).To(Equal([]string{"Prepare", "Apply", "KeepOnly"}))
+			})
+
+			It("returns error without removing jobs from job supervisor when preparing fails", func() {
+				packageApplier.PrepareError = errors.New("fake-prepare-package-error")
+
+				err := applier.Apply(
+					&fakeas.FakeApplySpec{},
+					&fakeas.FakeApplySpec{PackageResults: []models.Package{buildPackage()}},
+				)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-prepare-package-error"))
+				Expect(jobSupervisor.RemovedAllJobs).To(BeFalse())
+			})
+
 			It("removes all jobs from job supervisor", func() {
 				err := applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{})
 				Expect(err).ToNot(HaveOccurred())
@@ -313,6 +461,67 @@ func init() {
 				})
 			})
 
+			Context("when switching to the desired spec fails", func() {
+				var (
+					currentJob models.Job
+					currentPkg models.Package
+					desiredJob models.Job
+					desiredPkg models.Package
+				)
+
+				BeforeEach(func() {
+					currentJob = buildJob()
+					currentPkg = buildPackage()
+					desiredJob = buildJob()
+					desiredPkg = buildPackage()
+
+					jobApplier.ApplyErrors = map[string]error{
+						desiredJob.Name: errors.New("fake-apply-job-error"),
+					}
+				})
+
+				It("switches back to the current jobs and packages and reports the roll back", func() {
+					err := applier.Apply(
+						&fakeas.FakeApplySpec{JobResults: []models.Job{currentJob}, PackageResults: []models.Package{currentPkg}},
+						&fakeas.FakeApplySpec{JobResults: []models.Job{desiredJob}, PackageResults: []models.Package{desiredPkg}},
+					)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-apply-job-error"))
+					Expect(err.Error()).To(ContainSubstring(
+						fmt.Sprintf("Rolled back to jobs [%s] and packages [%s]", currentJob.Name, currentPkg.Name),
+					))
+
+					Expect(jobApplier.AppliedJobs).To(Equal([]models.Job{desiredJob, currentJob}))
+					Expect(jobApplier.KeepOnlyJobs).To(Equal([]models.Job{desiredJob, currentJob}))
+					Expect(packageApplier.AppliedPackages).To(Equal([]models.Package{currentPkg}))
+					Expect(packageApplier.KeptOnlyPackages).To(Equal([]models.Package{desiredPkg, currentPkg}))
+				})
+
+				It("configures the current jobs again", func() {
+					applier.Apply(
+						&fakeas.FakeApplySpec{JobResults: []models.Job{currentJob}},
+						&fakeas.FakeApplySpec{JobResults: []models.Job{desiredJob}},
+					)
+
+					Expect(jobApplier.ConfiguredJobs).To(Equal([]models.Job{currentJob}))
+					Expect(jobSupervisor.Reloaded).To(BeTrue())
+				})
+
+				It("returns both errors when switching back fails", func() {
+					jobApplier.ApplyErrors[currentJob.Name] = errors.New("fake-roll-back-error")
+
+					err := applier.Apply(
+						&fakeas.FakeApplySpec{JobResults: []models.Job{currentJob}},
+						&fakeas.FakeApplySpec{JobResults: []models.Job{desiredJob}},
+					)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-apply-job-error"))
+					Expect(err.Error()).To(ContainSubstring("Rolling back to the current spec"))
+					Expect(err.Error()).To(ContainSubstring("fake-roll-back-error"))
+					Expect(jobApplier.ConfiguredJobs).To(BeEmpty())
+				})
+			})
+
 			It("apply errs if setup logrotate fails", func() {
 				logRotateDelegate.SetupLogrotateErr = errors.New("fake-set-up-logrotate-error")
 
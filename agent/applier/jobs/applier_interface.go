@@ -5,7 +5,8 @@ import (
 )
 
 type Applier interface {
-	Prepare(job models.Job) error
+	// Prepare downloads and installs a job; downloads stop once stopCh is closed
+	Prepare(job models.Job, stopCh <-chan struct{}) error
 	Apply(job models.Job) error
 	Configure(job models.Job, jobIndex int) error
 	KeepOnly(jobs []models.Job) error
@@ -1,15 +1,20 @@
 package fakes
 
 import (
+	"sync"
+
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 )
 
 type FakeApplier struct {
+	lock sync.Mutex
+
 	PreparedJobs []models.Job
 	PrepareError error
 
 	AppliedJobs []models.Job
 	ApplyError  error
+	ApplyErrors map[string]error // by job name
 
 	ConfiguredJobs       []models.Job
 	ConfiguredJobIndices []int
@@ -25,13 +30,24 @@ func NewFakeApplier() *FakeApplier {
 	}
 }
 
-func (s *FakeApplier) Prepare(job models.Job) error {
+func (s *FakeApplier) Prepare(job models.Job, _ <-chan struct{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	s.PreparedJobs = append(s.PreparedJobs, job)
 	return s.PrepareError
 }
 
 func (s *FakeApplier) Apply(job models.Job) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	s.AppliedJobs = append(s.AppliedJobs, job)
+
+	if err, found := s.ApplyErrors[job.Name]; found {
+		return err
+	}
+
 	return s.ApplyError
 }
 
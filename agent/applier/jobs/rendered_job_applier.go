@@ -9,6 +9,7 @@ import (
 	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 	"github.com/cloudfoundry/bosh-agent/agent/applier/packages"
+	boshagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -49,7 +50,7 @@ func NewRenderedJobApplier(
 	}
 }
 
-func (s renderedJobApplier) Prepare(job models.Job) error {
+func (s renderedJobApplier) Prepare(job models.Job, stopCh <-chan struct{}) error {
 	s.logger.Debug(logTag, "Preparing job %v", job)
 
 	jobBundle, err := s.jobsBc.Get(job)
@@ -63,7 +64,7 @@ func (s renderedJobApplier) Prepare(job models.Job) error {
 	}
 
 	if !jobInstalled {
-		err := s.downloadAndInstall(job, jobBundle)
+		err := s.downloadAndInstall(job, jobBundle, stopCh)
 		if err != nil {
 			return err
 		}
@@ -75,7 +76,7 @@ func (s renderedJobApplier) Prepare(job models.Job) error {
 func (s *renderedJobApplier) Apply(job models.Job) error {
 	s.logger.Debug(logTag, "Applying job %v", job)
 
-	err := s.Prepare(job)
+	err := s.Prepare(job, nil)
 	if err != nil {
 		return bosherr.WrapError(err, "Preparing job")
 	}
@@ -93,7 +94,7 @@ func (s *renderedJobApplier) Apply(job models.Job) error {
 	return s.applyPackages(job)
 }
 
-func (s *renderedJobApplier) downloadAndInstall(job models.Job, jobBundle boshbc.Bundle) error {
+func (s *renderedJobApplier) downloadAndInstall(job models.Job, jobBundle boshbc.Bundle, stopCh <-chan struct{}) error {
 	tmpDir, err := s.fs.TempDir("bosh-agent-applier-jobs-RenderedJobApplier-Apply")
 	if err != nil {
 		return bosherr.WrapError(err, "Getting temp dir")
@@ -105,7 +106,7 @@ func (s *renderedJobApplier) downloadAndInstall(job models.Job, jobBundle boshbc
 		}
 	}()
 
-	file, err := s.blobstore.Get(job.Source.BlobstoreID, job.Source.Sha1)
+	file, err := boshagentblobstore.GetUnlessStopped(s.blobstore, job.Source.BlobstoreID, job.Source.Sha1, stopCh)
 	if err != nil {
 		return bosherr.WrapError(err, "Getting job source from blobstore")
 	}
@@ -349,7 +349,7 @@ func init() {
 
 			Describe("Prepare", func() {
 				act := func() error {
-					return applier.Prepare(job)
+					return applier.Prepare(job, nil)
 				}
 
 				It("return an error if getting file bundle fails", func() {
@@ -4,6 +4,15 @@ type Package struct {
 	Name    string
 	Version string
 	Source  Source
+
+	// Delta is set when the package can be built from an installed version
+	Delta *PackageDelta
+}
+
+// PackageDelta describes a blob that turns the Base package into this one
+type PackageDelta struct {
+	Base   Package
+	Source Source
 }
 
 func (s Package) BundleName() string {
@@ -0,0 +1,10 @@
+package applier
+
+// DefaultParallelism is used when Options does not set a parallelism
+const DefaultParallelism = 5
+
+type Options struct {
+	// Parallelism limits how many jobs and packages are downloaded and
+	// unpacked at the same time
+	Parallelism int
+}
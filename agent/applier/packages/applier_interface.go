@@ -5,7 +5,8 @@ import (
 )
 
 type Applier interface {
-	Prepare(pkg models.Package) error
+	// Prepare downloads and installs a package; downloads stop once stopCh is closed
+	Prepare(pkg models.Package, stopCh <-chan struct{}) error
 	Apply(pkg models.Package) error
 	KeepOnly(pkgs []models.Package) error
 }
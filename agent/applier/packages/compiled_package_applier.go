@@ -3,6 +3,7 @@ package packages
 import (
 	bc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
@@ -42,7 +43,7 @@ func NewCompiledPackageApplier(
 	}
 }
 
-func (s compiledPackageApplier) Prepare(pkg models.Package) error {
+func (s compiledPackageApplier) Prepare(pkg models.Package, stopCh <-chan struct{}) error {
 	s.logger.Debug(logTag, "Preparing package %v", pkg)
 
 	pkgBundle, err := s.packagesBc.Get(pkg)
@@ -56,7 +57,7 @@ func (s compiledPackageApplier) Prepare(pkg models.Package) error {
 	}
 
 	if !pkgInstalled {
-		err := s.downloadAndInstall(pkg, pkgBundle)
+		err := s.downloadAndInstall(pkg, pkgBundle, stopCh)
 		if err != nil {
 			return err
 		}
@@ -68,7 +69,7 @@ func (s compiledPackageApplier) Prepare(pkg models.Package) error {
 func (s compiledPackageApplier) Apply(pkg models.Package) error {
 	s.logger.Debug(logTag, "Applying package %v", pkg)
 
-	err := s.Prepare(pkg)
+	err := s.Prepare(pkg, nil)
 	if err != nil {
 		return err
 	}
@@ -86,7 +87,16 @@ func (s compiledPackageApplier) Apply(pkg models.Package) error {
 	return nil
 }
 
-func (s *compiledPackageApplier) downloadAndInstall(pkg models.Package, pkgBundle bc.Bundle) error {
+func (s *compiledPackageApplier) downloadAndInstall(pkg models.Package, pkgBundle bc.Bundle, stopCh <-chan struct{}) error {
+	if pkg.Delta != nil {
+		installed, err := s.installFromDelta(pkg, pkgBundle, stopCh)
+		if installed {
+			return err
+		}
+
+		s.logger.Warn(logTag, "Downloading whole package %s instead of building it from a delta: %s", pkg.Name, err.Error())
+	}
+
 	tmpDir, err := s.fs.TempDir("bosh-agent-applier-packages-CompiledPackageApplier-Apply")
 	if err != nil {
 		return bosherr.WrapError(err, "Getting temp dir")
@@ -98,7 +108,7 @@ func (s *compiledPackageApplier) downloadAndInstall(pkg models.Package, pkgBundl
 		}
 	}()
 
-	file, err := s.blobstore.Get(pkg.Source.BlobstoreID, pkg.Source.Sha1)
+	file, err := boshagentblobstore.GetUnlessStopped(s.blobstore, pkg.Source.BlobstoreID, pkg.Source.Sha1, stopCh)
 	if err != nil {
 		return bosherr.WrapError(err, "Fetching package blob")
 	}
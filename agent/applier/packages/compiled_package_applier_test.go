@@ -188,7 +188,7 @@ func init() {
 			}
 
 			Describe("Prepare", func() {
-				act := func() error { return applier.Prepare(pkg) }
+				act := func() error { return applier.Prepare(pkg, nil) }
 
 				It("return an error if getting file bundle fails", func() {
 					packagesBc.GetErr = errors.New("fake-get-bundle-error")
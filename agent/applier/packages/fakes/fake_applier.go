@@ -1,10 +1,14 @@
 package fakes
 
 import (
+	"sync"
+
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 )
 
 type FakeApplier struct {
+	lock sync.Mutex
+
 	ActionsCalled []string
 
 	PreparedPackages []models.Package
@@ -23,19 +27,28 @@ func NewFakeApplier() *FakeApplier {
 	}
 }
 
-func (s *FakeApplier) Prepare(pkg models.Package) error {
+func (s *FakeApplier) Prepare(pkg models.Package, _ <-chan struct{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	s.ActionsCalled = append(s.ActionsCalled, "Prepare")
 	s.PreparedPackages = append(s.PreparedPackages, pkg)
 	return s.PrepareError
 }
 
 func (s *FakeApplier) Apply(pkg models.Package) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	s.ActionsCalled = append(s.ActionsCalled, "Apply")
 	s.AppliedPackages = append(s.AppliedPackages, pkg)
 	return s.ApplyError
 }
 
 func (s *FakeApplier) KeepOnly(pkgs []models.Package) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	s.ActionsCalled = append(s.ActionsCalled, "KeepOnly")
 	s.KeptOnlyPackages = pkgs
 	return s.KeepOnlyErr
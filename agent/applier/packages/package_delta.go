@@ -0,0 +1,270 @@
+package packages
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	bc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
+	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
+)
+
+// A delta blob is a tarball with a delta.json manifest that lists every file
+// of the new package version. Each file is either taken unchanged from the
+// installed base version, shipped whole under files/, or rebuilt from the
+// base file and a patch under patches/<path>.zst. Patches are zstd frames
+// made with `zstd --patch-from=<base file> <new file>`, which decompress to
+// the new file with the whole base file as raw dictionary.
+//
+// The rebuilt package cannot be verified against the package's sha1, which
+// digests the compressed blob rather than the files in it. Instead the delta
+// blob is verified against its own digest when fetched, and the package is
+// built in an empty directory from exactly the files the manifest lists, so
+// checking the sha256 of each file verifies the whole package.
+const (
+	deltaManifestName = "delta.json"
+	deltaFilesDir     = "files"
+	deltaPatchesDir   = "patches"
+	deltaPatchSuffix  = ".zst"
+
+	deltaSourceBase    = "base"
+	deltaSourceFull    = "full"
+	deltaSourcePatch   = "patch"
+	deltaSourceDir     = "dir"
+	deltaSourceSymlink = "symlink"
+)
+
+type deltaManifest struct {
+	Files []deltaFile `json:"files"`
+}
+
+type deltaFile struct {
+	Path   string      `json:"path"`
+	Source string      `json:"source"`
+	Mode   os.FileMode `json:"mode"`
+	Sha256 string      `json:"sha256"`
+	Target string      `json:"target"`
+}
+
+// installFromDelta returns true when it got as far as installing the rebuilt
+// package; otherwise the whole package should be downloaded instead.
+func (s *compiledPackageApplier) installFromDelta(pkg models.Package, pkgBundle bc.Bundle, stopCh <-chan struct{}) (bool, error) {
+	baseBundle, err := s.packagesBc.Get(pkg.Delta.Base)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Getting base package bundle")
+	}
+
+	baseInstalled, err := baseBundle.IsInstalled()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Checking if base package is installed")
+	}
+
+	if !baseInstalled {
+		return false, bosherr.Errorf("Base version %s is not installed", pkg.Delta.Base.Version)
+	}
+
+	_, basePath, err := baseBundle.GetInstallPath()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Getting base package install path")
+	}
+
+	deltaDir, err := s.fs.TempDir("bosh-agent-applier-packages-CompiledPackageApplier-Delta")
+	if err != nil {
+		return false, bosherr.WrapError(err, "Getting temp dir for delta")
+	}
+
+	defer func() {
+		if err = s.fs.RemoveAll(deltaDir); err != nil {
+			s.logger.Warn(logTag, "Failed to clean up delta dir: %s", err.Error())
+		}
+	}()
+
+	pkgDir, err := s.fs.TempDir("bosh-agent-applier-packages-CompiledPackageApplier-Apply")
+	if err != nil {
+		return false, bosherr.WrapError(err, "Getting temp dir")
+	}
+
+	defer func() {
+		if err = s.fs.RemoveAll(pkgDir); err != nil {
+			s.logger.Warn(logTag, "Failed to clean up tmpDir: %s", err.Error())
+		}
+	}()
+
+	file, err := boshagentblobstore.GetUnlessStopped(s.blobstore, pkg.Delta.Source.BlobstoreID, pkg.Delta.Source.Sha1, stopCh)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Fetching package delta blob")
+	}
+
+	defer func() {
+		if err = s.blobstore.CleanUp(file); err != nil {
+			s.logger.Warn(logTag, "Failed to clean up blobstore blob: %s", err.Error())
+		}
+	}()
+
+	err = s.compressor.DecompressFileToDir(file, deltaDir, boshcmd.CompressorOptions{})
+	if err != nil {
+		return false, bosherr.WrapError(err, "Decompressing package delta")
+	}
+
+	err = s.applyDelta(basePath, deltaDir, pkgDir)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Applying package delta")
+	}
+
+	_, _, err = pkgBundle.Install(pkgDir)
+	if err != nil {
+		return true, bosherr.WrapError(err, "Installling package directory")
+	}
+
+	return true, nil
+}
+
+func (s *compiledPackageApplier) applyDelta(basePath, deltaDir, pkgDir string) error {
+	contents, err := s.fs.ReadFile(filepath.Join(deltaDir, deltaManifestName))
+	if err != nil {
+		return bosherr.WrapError(err, "Reading delta manifest")
+	}
+
+	var manifest deltaManifest
+
+	err = json.Unmarshal(contents, &manifest)
+	if err != nil {
+		return bosherr.WrapError(err, "Unmarshalling delta manifest")
+	}
+
+	for _, file := range manifest.Files {
+		err = s.applyDeltaFile(file, basePath, deltaDir, pkgDir)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Building '%s'", file.Path)
+		}
+	}
+
+	return nil
+}
+
+func (s *compiledPackageApplier) applyDeltaFile(file deltaFile, basePath, deltaDir, pkgDir string) error {
+	relPath := filepath.Clean(filepath.FromSlash(file.Path))
+	if filepath.IsAbs(relPath) || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return bosherr.Error("Path must be relative to the package")
+	}
+
+	err := s.checkNoSymlinks(pkgDir, relPath)
+	if err != nil {
+		return err
+	}
+
+	dstPath := filepath.Join(pkgDir, relPath)
+
+	if file.Source == deltaSourceDir {
+		return s.fs.MkdirAll(dstPath, os.FileMode(0755))
+	}
+
+	err = s.fs.MkdirAll(filepath.Dir(dstPath), os.FileMode(0755))
+	if err != nil {
+		return bosherr.WrapError(err, "Creating parent dir")
+	}
+
+	switch file.Source {
+	case deltaSourceSymlink:
+		return s.fs.Symlink(file.Target, dstPath)
+
+	case deltaSourceBase:
+		err = s.checkNoSymlinks(basePath, relPath)
+		if err != nil {
+			return err
+		}
+
+		err = s.fs.CopyFile(filepath.Join(basePath, relPath), dstPath)
+
+	case deltaSourceFull:
+		err = s.fs.CopyFile(filepath.Join(deltaDir, deltaFilesDir, relPath), dstPath)
+
+	case deltaSourcePatch:
+		err = s.checkNoSymlinks(basePath, relPath)
+		if err != nil {
+			return err
+		}
+
+		err = s.patchFile(filepath.Join(basePath, relPath), filepath.Join(deltaDir, deltaPatchesDir, relPath+deltaPatchSuffix), dstPath)
+
+	default:
+		return bosherr.Errorf("Unknown source '%s'", file.Source)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if file.Mode != 0 {
+		err = s.fs.Chmod(dstPath, file.Mode)
+		if err != nil {
+			return bosherr.WrapError(err, "Changing file mode")
+		}
+	}
+
+	return boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA256, file.Sha256).VerifyFilePath(dstPath, s.fs)
+}
+
+// checkNoSymlinks refuses to build files through symlinks, e.g. ones created
+// earlier in the delta, that could point outside of dir
+func (s *compiledPackageApplier) checkNoSymlinks(dir, relPath string) error {
+	path := dir
+
+	for _, part := range strings.Split(relPath, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+
+		info, err := s.fs.Lstat(path)
+		if err != nil {
+			return nil
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return bosherr.Errorf("Path is below or at a symlink")
+		}
+	}
+
+	return nil
+}
+
+func (s *compiledPackageApplier) patchFile(basePath, patchPath, dstPath string) error {
+	base, err := s.fs.ReadFile(basePath)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading base file")
+	}
+
+	patch, err := s.fs.OpenFile(patchPath, os.O_RDONLY, 0)
+	if err != nil {
+		return bosherr.WrapError(err, "Opening patch")
+	}
+
+	defer patch.Close()
+
+	decoder, err := zstd.NewReader(patch, zstd.WithDecoderDictRaw(0, base), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return bosherr.WrapError(err, "Reading patch")
+	}
+
+	defer decoder.Close()
+
+	dst, err := s.fs.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(0644))
+	if err != nil {
+		return bosherr.WrapError(err, "Creating patched file")
+	}
+
+	defer dst.Close()
+
+	_, err = io.Copy(dst, decoder)
+	if err != nil {
+		return bosherr.WrapError(err, "Applying patch")
+	}
+
+	return nil
+}
@@ -0,0 +1,315 @@
+package packages_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	fakebc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection/fakes"
+	"github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	. "github.com/cloudfoundry/bosh-agent/agent/applier/packages"
+	fakeblob "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	fakecmd "github.com/cloudfoundry/bosh-utils/fileutil/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/klauspost/compress/zstd"
+)
+
+type deltaTestFile struct {
+	Path   string      `json:"path"`
+	Source string      `json:"source"`
+	Mode   os.FileMode `json:"mode,omitempty"`
+	Sha256 string      `json:"sha256,omitempty"`
+	Target string      `json:"target,omitempty"`
+}
+
+func sha256Hex(contents string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(contents)))
+}
+
+// buildPatch makes a patch like `zstd --patch-from=<base> <target>` does
+func buildPatch(base, target string) []byte {
+	var patch bytes.Buffer
+
+	encoder, err := zstd.NewWriter(&patch, zstd.WithEncoderDictRaw(0, []byte(base)))
+	Expect(err).ToNot(HaveOccurred())
+
+	_, err = encoder.Write([]byte(target))
+	Expect(err).ToNot(HaveOccurred())
+	Expect(encoder.Close()).To(Succeed())
+
+	return patch.Bytes()
+}
+
+const (
+	deltaTestBaseData = "abcdefghijklmnopqrstuvwxyz-0123456789-abcdefghijklmnopqrstuvwxyz"
+	deltaTestNewData  = "abcdefghijklmnopqrstuvwxyz-01234-new-789-abcdefghijklmnopqrstuvwxyz"
+
+	// Made with `zstd --patch-from=base new` from the data above
+	deltaTestPatchHex = "28b52ffd2443850000282d6e65772d030046081d02166d4f209e8d7369"
+)
+
+var _ = Describe("compiledPackageApplier with package deltas", func() {
+	var (
+		packagesBc *fakebc.FakeBundleCollection
+		blobstore  *fakeblob.FakeDigestBlobstore
+		compressor *fakecmd.FakeCompressor
+		fs         boshsys.FileSystem
+		applier    Applier
+
+		tmpDir     string
+		pkg        models.Package
+		bundle     *fakebc.FakeBundle
+		baseBundle *fakebc.FakeBundle
+
+		deltaFiles   []deltaTestFile
+		deltaBlobs   map[string][]byte
+		installed    map[string]string
+		installModes map[string]os.FileMode
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		tmpDir, err = ioutil.TempDir("", "package-delta")
+		Expect(err).ToNot(HaveOccurred())
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs = boshsys.NewOsFileSystem(logger)
+		packagesBc = fakebc.NewFakeBundleCollection()
+		blobstore = &fakeblob.FakeDigestBlobstore{}
+		compressor = fakecmd.NewFakeCompressor()
+		applier = NewCompiledPackageApplier(packagesBc, true, blobstore, compressor, fs, logger)
+
+		basePkg := models.Package{
+			Name:    "fake-package-name",
+			Version: "fake-base-version",
+			Source: models.Source{
+				Sha1: boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-base-sha1")),
+			},
+		}
+
+		pkg = models.Package{
+			Name:    "fake-package-name",
+			Version: "fake-package-version",
+			Source: models.Source{
+				Sha1:        boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-blob-sha1")),
+				BlobstoreID: "fake-blobstore-id",
+			},
+			Delta: &models.PackageDelta{
+				Base: basePkg,
+				Source: models.Source{
+					Sha1:        boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-delta-sha1")),
+					BlobstoreID: "fake-delta-blobstore-id",
+				},
+			},
+		}
+
+		bundle = packagesBc.FakeGet(pkg)
+
+		basePath := filepath.Join(tmpDir, "base")
+		Expect(fs.WriteFileString(filepath.Join(basePath, "README"), "unchanged")).To(Succeed())
+		Expect(fs.WriteFileString(filepath.Join(basePath, "lib", "data"), deltaTestBaseData)).To(Succeed())
+		Expect(fs.WriteFileString(filepath.Join(basePath, "bin", "run"), "old run")).To(Succeed())
+
+		baseBundle = packagesBc.FakeGet(basePkg)
+		baseBundle.Installed = true
+		baseBundle.GetDirPath = basePath
+
+		deltaFiles = []deltaTestFile{
+			{Path: "README", Source: "base", Sha256: sha256Hex("unchanged")},
+			{Path: "lib/data", Source: "patch", Sha256: sha256Hex(deltaTestNewData)},
+			{Path: "bin/run", Source: "full", Mode: 0755, Sha256: sha256Hex("new run")},
+			{Path: "bin/start", Source: "symlink", Target: "run"},
+			{Path: "empty", Source: "dir"},
+		}
+
+		patch, err := hex.DecodeString(deltaTestPatchHex)
+		Expect(err).ToNot(HaveOccurred())
+
+		deltaBlobs = map[string][]byte{
+			"patches/lib/data.zst": patch,
+			"files/bin/run":        []byte("new run"),
+		}
+
+		blobstore.GetStub = func(blobID string, _ boshcrypto.Digest) (string, error) {
+			return "/fake-blob-" + blobID, nil
+		}
+
+		compressor.DecompressFileToDirCallBack = func() {
+			if compressor.DecompressFileToDirTarballPaths[len(compressor.DecompressFileToDirTarballPaths)-1] != "/fake-blob-fake-delta-blobstore-id" {
+				return
+			}
+
+			dir := compressor.DecompressFileToDirDirs[len(compressor.DecompressFileToDirDirs)-1]
+
+			manifest, err := json.Marshal(map[string]interface{}{"files": deltaFiles})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.WriteFile(filepath.Join(dir, "delta.json"), manifest)).To(Succeed())
+
+			for path, contents := range deltaBlobs {
+				Expect(fs.WriteFile(filepath.Join(dir, filepath.FromSlash(path)), contents)).To(Succeed())
+			}
+		}
+
+		installed = nil
+		installModes = map[string]os.FileMode{}
+
+		bundle.InstallCallBack = func() {
+			installed = map[string]string{}
+
+			filepath.Walk(bundle.InstallSourcePath, func(path string, info os.FileInfo, err error) error {
+				Expect(err).ToNot(HaveOccurred())
+
+				relPath, err := filepath.Rel(bundle.InstallSourcePath, path)
+				Expect(err).ToNot(HaveOccurred())
+				relPath = filepath.ToSlash(relPath)
+
+				switch {
+				case info.Mode()&os.ModeSymlink != 0:
+					target, err := os.Readlink(path)
+					Expect(err).ToNot(HaveOccurred())
+					installed[relPath] = "-> " + target
+				case info.IsDir():
+					installed[relPath+"/"] = ""
+				default:
+					installed[relPath], err = fs.ReadFileString(path)
+					Expect(err).ToNot(HaveOccurred())
+					installModes[relPath] = info.Mode()
+				}
+
+				return nil
+			})
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("builds the package from the installed base version and the delta", func() {
+		Expect(applier.Prepare(pkg, nil)).To(Succeed())
+
+		Expect(blobstore.GetCallCount()).To(Equal(1))
+		blobID, digest := blobstore.GetArgsForCall(0)
+		Expect(blobID).To(Equal("fake-delta-blobstore-id"))
+		Expect(digest).To(Equal(pkg.Delta.Source.Sha1))
+		Expect(blobstore.CleanUpArgsForCall(0)).To(Equal("/fake-blob-fake-delta-blobstore-id"))
+
+		Expect(installed).To(Equal(map[string]string{
+			"./":        "",
+			"README":    "unchanged",
+			"lib/":      "",
+			"lib/data":  deltaTestNewData,
+			"bin/":      "",
+			"bin/run":   "new run",
+			"bin/start": "-> run",
+			"empty/":    "",
+		}))
+
+		if os.PathSeparator == '/' {
+			Expect(installModes["bin/run"].Perm()).To(Equal(os.FileMode(0755)))
+		}
+
+		Expect(fs.FileExists(bundle.InstallSourcePath)).To(BeFalse())
+	})
+
+	expectWholePackageDownloaded := func() {
+		Expect(applier.Prepare(pkg, nil)).To(Succeed())
+
+		lastGet := blobstore.GetCallCount() - 1
+		blobID, _ := blobstore.GetArgsForCall(lastGet)
+		Expect(blobID).To(Equal("fake-blobstore-id"))
+
+		Expect(compressor.DecompressFileToDirTarballPaths[len(compressor.DecompressFileToDirTarballPaths)-1]).To(Equal("/fake-blob-fake-blobstore-id"))
+		Expect(bundle.Installed).To(BeTrue())
+	}
+
+	It("downloads the whole package when the base version is not installed", func() {
+		baseBundle.Installed = false
+
+		expectWholePackageDownloaded()
+		Expect(blobstore.GetCallCount()).To(Equal(1))
+	})
+
+	It("downloads the whole package when the delta blob cannot be fetched", func() {
+		blobstore.GetStub = func(blobID string, _ boshcrypto.Digest) (string, error) {
+			if blobID == "fake-delta-blobstore-id" {
+				return "", errors.New("fake-get-error")
+			}
+			return "/fake-blob-" + blobID, nil
+		}
+
+		expectWholePackageDownloaded()
+	})
+
+	It("downloads the whole package when a built file does not match its digest", func() {
+		deltaBlobs["patches/lib/data.zst"] = buildPatch(deltaTestBaseData, "fake-other-data")
+
+		expectWholePackageDownloaded()
+		Expect(blobstore.GetCallCount()).To(Equal(2))
+	})
+
+	It("downloads the whole package when a patch refers to bytes outside of the base file", func() {
+		Expect(fs.WriteFileString(filepath.Join(baseBundle.GetDirPath, "lib", "data"), "0123456789")).To(Succeed())
+
+		expectWholePackageDownloaded()
+		Expect(blobstore.GetCallCount()).To(Equal(2))
+	})
+
+	It("downloads the whole package when a patch is not in the expected format", func() {
+		deltaBlobs["patches/lib/data.zst"] = []byte("not-a-patch")
+
+		expectWholePackageDownloaded()
+		Expect(blobstore.GetCallCount()).To(Equal(2))
+	})
+
+	It("downloads the whole package when the delta refers to files outside of the package", func() {
+		deltaFiles = append(deltaFiles, deltaTestFile{Path: "../outside", Source: "base", Sha256: sha256Hex("unchanged")})
+
+		expectWholePackageDownloaded()
+		Expect(blobstore.GetCallCount()).To(Equal(2))
+	})
+
+	It("downloads the whole package when the delta writes through a symlink it created", func() {
+		outsideDir := filepath.Join(tmpDir, "outside")
+		Expect(fs.MkdirAll(outsideDir, 0755)).To(Succeed())
+
+		deltaFiles = append(deltaFiles,
+			deltaTestFile{Path: "escape", Source: "symlink", Target: outsideDir},
+			deltaTestFile{Path: "escape/run", Source: "full", Sha256: sha256Hex("new run")},
+		)
+		deltaBlobs["files/escape/run"] = []byte("new run")
+
+		expectWholePackageDownloaded()
+		Expect(blobstore.GetCallCount()).To(Equal(2))
+		Expect(fs.FileExists(filepath.Join(outsideDir, "run"))).To(BeFalse())
+	})
+
+	It("downloads the whole package when the delta overwrites a symlink it created", func() {
+		deltaFiles = append(deltaFiles, deltaTestFile{Path: "bin/start", Source: "full", Sha256: sha256Hex("new run")})
+		deltaBlobs["files/bin/start"] = []byte("new run")
+
+		expectWholePackageDownloaded()
+		Expect(blobstore.GetCallCount()).To(Equal(2))
+	})
+
+	It("returns error without downloading the whole package when installing the built package fails", func() {
+		bundle.InstallError = errors.New("fake-install-error")
+
+		err := applier.Prepare(pkg, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-install-error"))
+		Expect(blobstore.GetCallCount()).To(Equal(1))
+	})
+})
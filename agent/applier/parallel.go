@@ -0,0 +1,54 @@
+package applier
+
+import (
+	"sync"
+)
+
+// forEachInParallel calls fn for each index below count, running at most
+// parallelism calls at a time. Once a call fails no further calls are
+// started and the stopCh passed to calls already running is closed; they
+// are waited for and the first error is returned.
+func forEachInParallel(parallelism, count int, fn func(i int, stopCh <-chan struct{}) error) error {
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+
+	slots := make(chan struct{}, parallelism)
+	failed := make(chan struct{})
+
+	for i := 0; i < count; i++ {
+		select {
+		case <-failed:
+		case slots <- struct{}{}:
+		}
+
+		// A slot and the failure may become available at the same time
+		select {
+		case <-failed:
+			wg.Wait()
+			return firstErr
+		default:
+		}
+
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := fn(i, failed)
+			if err != nil {
+				failOnce.Do(func() {
+					firstErr = err
+					close(failed)
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
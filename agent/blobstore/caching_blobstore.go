@@ -0,0 +1,375 @@
+package blobstore
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	boshUtilsBlobStore "github.com/cloudfoundry/bosh-utils/blobstore"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+const cachingLogTag = "cachingBlobstore"
+
+var cacheKeyPattern = regexp.MustCompile(`^[a-z0-9]+-[a-fA-F0-9]+$`)
+
+type cacheEntry struct {
+	Size     int64 `json:"size"`
+	LastUsed int64 `json:"last_used"`
+}
+
+// cachingBlobstore keeps fetched blobs on local disk keyed by their digest
+// so that packages and jobs fetched before are not downloaded again, e.g.
+// when rolling back to a previous release. The least recently used blobs
+// are evicted to stay within quota bytes.
+type cachingBlobstore struct {
+	innerBlobstore boshUtilsBlobStore.DigestBlobstore
+	fs             boshsys.FileSystem
+	cacheDir       string
+	quota          int64
+	timeService    clock.Clock
+	logger         boshlog.Logger
+
+	// lock guards the index bookkeeping below; blobs are verified and
+	// copied without holding it
+	lock sync.Mutex
+
+	// entries is nil until the index is loaded from cacheDir
+	entries map[string]cacheEntry
+
+	// pinned counts the blobs being copied into or out of the cache per key;
+	// pinned blobs are not evicted to stay within quota
+	pinned map[string]int
+
+	// checkedOut holds the copies of cached blobs returned by Get
+	checkedOut map[string]bool
+}
+
+func NewCachingBlobstore(
+	innerBlobstore boshUtilsBlobStore.DigestBlobstore,
+	fs boshsys.FileSystem,
+	cacheDir string,
+	quota int64,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) boshUtilsBlobStore.DigestBlobstore {
+	return &cachingBlobstore{
+		innerBlobstore: innerBlobstore,
+		fs:             fs,
+		cacheDir:       cacheDir,
+		quota:          quota,
+		timeService:    timeService,
+		logger:         logger,
+		pinned:         map[string]int{},
+		checkedOut:     map[string]bool{},
+	}
+}
+
+func (b *cachingBlobstore) Get(blobID string, digest boshcrypto.Digest) (string, error) {
+	key, cacheable := cacheKey(digest)
+	if !cacheable {
+		return b.innerBlobstore.Get(blobID, digest)
+	}
+
+	fileName, found := b.checkOut(key, digest)
+	if found {
+		b.logger.Debug(cachingLogTag, "Found blob in cache. BlobID: %s, digest: %s", blobID, key)
+		return fileName, nil
+	}
+
+	fileName, err := b.innerBlobstore.Get(blobID, digest)
+	if err != nil {
+		return "", err
+	}
+
+	err = b.add(key, fileName)
+	if err != nil {
+		b.logger.Warn(cachingLogTag, "Failed to cache blob %s: %s", blobID, err.Error())
+	}
+
+	return fileName, nil
+}
+
+func (b *cachingBlobstore) CleanUp(fileName string) error {
+	b.lock.Lock()
+	checkedOut := b.checkedOut[fileName]
+	delete(b.checkedOut, fileName)
+	b.lock.Unlock()
+
+	if checkedOut {
+		return b.fs.RemoveAll(fileName)
+	}
+
+	return b.innerBlobstore.CleanUp(fileName)
+}
+
+func (b *cachingBlobstore) Create(fileName string) (string, boshcrypto.MultipleDigest, error) {
+	return b.innerBlobstore.Create(fileName)
+}
+
+func (b *cachingBlobstore) Validate() error {
+	return b.innerBlobstore.Validate()
+}
+
+func (b *cachingBlobstore) Delete(blobID string) error {
+	return b.innerBlobstore.Delete(blobID)
+}
+
+// checkOut copies a cached blob to a temporary file; cached blobs that no
+// longer match their digest are evicted
+func (b *cachingBlobstore) checkOut(key string, digest boshcrypto.Digest) (string, bool) {
+	if !b.pin(key, true) {
+		return "", false
+	}
+
+	verifyErr := digest.VerifyFilePath(b.cachePath(key), b.fs)
+
+	var fileName string
+	var copyErr error
+
+	if verifyErr == nil {
+		fileName, copyErr = b.copyOut(key)
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.unpin(key)
+
+	if verifyErr != nil {
+		// Concurrent check outs of the blob leave evicting it to the last one
+		if b.pinned[key] == 0 {
+			b.logger.Warn(cachingLogTag, "Evicting cached blob %s: %s", key, verifyErr.Error())
+			b.evict(key)
+			b.saveIndexQuietly()
+		}
+		return "", false
+	}
+
+	if copyErr != nil {
+		b.logger.Warn(cachingLogTag, "Failed to check out cached blob %s: %s", key, copyErr.Error())
+		return "", false
+	}
+
+	b.checkedOut[fileName] = true
+
+	if entry, found := b.entries[key]; found {
+		entry.LastUsed = b.timeService.Now().UnixNano()
+		b.entries[key] = entry
+		b.saveIndexQuietly()
+	}
+
+	return fileName, true
+}
+
+func (b *cachingBlobstore) copyOut(key string) (string, error) {
+	file, err := b.fs.TempFile("bosh-agent-caching-blobstore")
+	if err != nil {
+		return "", bosherr.WrapError(err, "Creating temp file")
+	}
+
+	fileName := file.Name()
+
+	err = file.Close()
+	if err != nil {
+		b.removeQuietly(fileName)
+		return "", bosherr.WrapError(err, "Closing temp file")
+	}
+
+	err = b.fs.CopyFile(b.cachePath(key), fileName)
+	if err != nil {
+		b.removeQuietly(fileName)
+		return "", bosherr.WrapError(err, "Copying cached blob")
+	}
+
+	return fileName, nil
+}
+
+func (b *cachingBlobstore) add(key, fileName string) error {
+	info, err := b.fs.Stat(fileName)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking blob size")
+	}
+
+	if info.Size() > b.quota {
+		return bosherr.Errorf("Blob of %d bytes exceeds cache quota of %d bytes", info.Size(), b.quota)
+	}
+
+	if !b.pin(key, false) {
+		return nil
+	}
+
+	err = b.fs.CopyFile(fileName, b.cachePath(key))
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.unpin(key)
+
+	if err != nil {
+		b.removeQuietly(b.cachePath(key))
+		return bosherr.WrapError(err, "Copying blob to cache")
+	}
+
+	b.entries[key] = cacheEntry{Size: info.Size(), LastUsed: b.timeService.Now().UnixNano()}
+
+	b.evictOverQuota()
+
+	return b.saveIndex()
+}
+
+// pin marks key as being copied if it is cached (or, when adding, if it is
+// neither cached nor being added already)
+func (b *cachingBlobstore) pin(key string, cached bool) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	err := b.loadIndex()
+	if err != nil {
+		b.logger.Warn(cachingLogTag, "Failed to load blob cache index: %s", err.Error())
+		return false
+	}
+
+	_, found := b.entries[key]
+	if found != cached || (!cached && b.pinned[key] > 0) {
+		return false
+	}
+
+	b.pinned[key]++
+
+	return true
+}
+
+func (b *cachingBlobstore) unpin(key string) {
+	b.pinned[key]--
+	if b.pinned[key] <= 0 {
+		delete(b.pinned, key)
+	}
+}
+
+func (b *cachingBlobstore) evictOverQuota() {
+	var size int64
+	for _, entry := range b.entries {
+		size += entry.Size
+	}
+
+	for size > b.quota {
+		var oldestKey string
+		var oldest cacheEntry
+
+		for key, entry := range b.entries {
+			if b.pinned[key] > 0 {
+				continue
+			}
+
+			if oldestKey == "" || entry.LastUsed < oldest.LastUsed {
+				oldestKey, oldest = key, entry
+			}
+		}
+
+		if oldestKey == "" {
+			return
+		}
+
+		b.logger.Debug(cachingLogTag, "Evicting least recently used blob %s", oldestKey)
+		b.evict(oldestKey)
+		size -= oldest.Size
+	}
+}
+
+func (b *cachingBlobstore) evict(key string) {
+	delete(b.entries, key)
+	b.removeQuietly(b.cachePath(key))
+}
+
+func (b *cachingBlobstore) removeQuietly(path string) {
+	err := b.fs.RemoveAll(path)
+	if err != nil {
+		b.logger.Warn(cachingLogTag, "Failed to remove %s: %s", path, err.Error())
+	}
+}
+
+func (b *cachingBlobstore) loadIndex() error {
+	if b.entries != nil {
+		return nil
+	}
+
+	err := b.fs.MkdirAll(b.cacheDir, 0700)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating blob cache directory")
+	}
+
+	entries := map[string]cacheEntry{}
+
+	if b.fs.FileExists(b.indexPath()) {
+		contents, err := b.fs.ReadFile(b.indexPath())
+		if err != nil {
+			return bosherr.WrapError(err, "Reading blob cache index")
+		}
+
+		err = json.Unmarshal(contents, &entries)
+		if err != nil {
+			return bosherr.WrapError(err, "Unmarshalling blob cache index")
+		}
+	}
+
+	b.entries = entries
+
+	return nil
+}
+
+func (b *cachingBlobstore) saveIndex() error {
+	contents, err := json.Marshal(b.entries)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling blob cache index")
+	}
+
+	err = b.fs.WriteFile(b.indexPath(), contents)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing blob cache index")
+	}
+
+	return nil
+}
+
+func (b *cachingBlobstore) saveIndexQuietly() {
+	err := b.saveIndex()
+	if err != nil {
+		b.logger.Warn(cachingLogTag, "Failed to save blob cache index: %s", err.Error())
+	}
+}
+
+func (b *cachingBlobstore) indexPath() string {
+	return filepath.Join(b.cacheDir, "index.json")
+}
+
+func (b *cachingBlobstore) cachePath(key string) string {
+	return filepath.Join(b.cacheDir, key)
+}
+
+// cacheKey names a blob after its strongest digest, e.g. sha256-<hex>
+func cacheKey(digest boshcrypto.Digest) (string, bool) {
+	if multipleDigest, ok := digest.(boshcrypto.MultipleDigest); ok {
+		strongestDigest, err := multipleDigest.DigestFor(multipleDigest.Algorithm())
+		if err != nil {
+			return "", false
+		}
+
+		digest = strongestDigest
+	}
+
+	value := digest.String()
+	if !strings.Contains(value, ":") {
+		value = digest.Algorithm().Name() + ":" + value
+	}
+
+	key := strings.Replace(value, ":", "-", 1)
+
+	return key, cacheKeyPattern.MatchString(key)
+}
@@ -0,0 +1,238 @@
+package blobstore_test
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-agent/agent/blobstore"
+	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
+	fakeblob "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("cachingBlobstore", func() {
+	var (
+		innerBlobstore   *fakeblob.FakeDigestBlobstore
+		fs               boshsys.FileSystem
+		timeService      *fakeclock.FakeClock
+		logger           boshlog.Logger
+		tmpDir           string
+		cacheDir         string
+		cachingBlobstore boshblob.DigestBlobstore
+	)
+
+	writeBlob := func(name, contents string) (string, boshcrypto.Digest) {
+		path := filepath.Join(tmpDir, name)
+		Expect(fs.WriteFileString(path, contents)).To(Succeed())
+
+		return path, boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, fmt.Sprintf("%x", sha1.Sum([]byte(contents))))
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		innerBlobstore = &fakeblob.FakeDigestBlobstore{}
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fs = boshsys.NewOsFileSystem(logger)
+		timeService = fakeclock.NewFakeClock(time.Now())
+
+		tmpDir, err = ioutil.TempDir("", "caching-blobstore")
+		Expect(err).ToNot(HaveOccurred())
+
+		cacheDir = filepath.Join(tmpDir, "cache")
+
+		cachingBlobstore = blobstore.NewCachingBlobstore(innerBlobstore, fs, cacheDir, 10, timeService, logger)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Describe("Get", func() {
+		It("fetches blobs missing from the cache from the inner blobstore", func() {
+			blobPath, digest := writeBlob("blob-a", "aaaa")
+			innerBlobstore.GetReturns(blobPath, nil)
+
+			fileName, err := cachingBlobstore.Get("blob-id-a", digest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fileName).To(Equal(blobPath))
+
+			Expect(innerBlobstore.GetCallCount()).To(Equal(1))
+			blobID, receivedDigest := innerBlobstore.GetArgsForCall(0)
+			Expect(blobID).To(Equal("blob-id-a"))
+			Expect(receivedDigest).To(Equal(digest))
+		})
+
+		It("returns a copy of a cached blob without fetching it again", func() {
+			blobPath, digest := writeBlob("blob-a", "aaaa")
+			innerBlobstore.GetReturns(blobPath, nil)
+
+			_, err := cachingBlobstore.Get("blob-id-a", digest)
+			Expect(err).ToNot(HaveOccurred())
+
+			fileName, err := cachingBlobstore.Get("other-blob-id-a", digest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fileName).ToNot(Equal(blobPath))
+			Expect(fs.ReadFileString(fileName)).To(Equal("aaaa"))
+
+			Expect(innerBlobstore.GetCallCount()).To(Equal(1))
+
+			Expect(cachingBlobstore.CleanUp(fileName)).To(Succeed())
+			Expect(fs.FileExists(fileName)).To(BeFalse())
+			Expect(innerBlobstore.CleanUpCallCount()).To(Equal(0))
+		})
+
+		It("keeps the cache across restarts", func() {
+			blobPath, digest := writeBlob("blob-a", "aaaa")
+			innerBlobstore.GetReturns(blobPath, nil)
+
+			_, err := cachingBlobstore.Get("blob-id-a", digest)
+			Expect(err).ToNot(HaveOccurred())
+
+			cachingBlobstore = blobstore.NewCachingBlobstore(innerBlobstore, fs, cacheDir, 10, timeService, logger)
+
+			fileName, err := cachingBlobstore.Get("blob-id-a", digest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.ReadFileString(fileName)).To(Equal("aaaa"))
+			Expect(innerBlobstore.GetCallCount()).To(Equal(1))
+		})
+
+		It("fetches blobs again when the cached copy no longer matches its digest", func() {
+			blobPath, digest := writeBlob("blob-a", "aaaa")
+			innerBlobstore.GetReturns(blobPath, nil)
+
+			_, err := cachingBlobstore.Get("blob-id-a", digest)
+			Expect(err).ToNot(HaveOccurred())
+
+			cachedPaths, err := filepath.Glob(filepath.Join(cacheDir, "sha1-*"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cachedPaths).To(HaveLen(1))
+			Expect(fs.WriteFileString(cachedPaths[0], "corrupted")).To(Succeed())
+
+			fileName, err := cachingBlobstore.Get("blob-id-a", digest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fileName).To(Equal(blobPath))
+			Expect(innerBlobstore.GetCallCount()).To(Equal(2))
+		})
+
+		It("evicts the least recently used blobs to stay within quota", func() {
+			blobPathA, digestA := writeBlob("blob-a", "aaaa")
+			blobPathB, digestB := writeBlob("blob-b", "bbbb")
+			blobPathC, digestC := writeBlob("blob-c", "cccc")
+
+			innerBlobstore.GetReturns(blobPathA, nil)
+			_, err := cachingBlobstore.Get("blob-id-a", digestA)
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.Increment(time.Second)
+			innerBlobstore.GetReturns(blobPathB, nil)
+			_, err = cachingBlobstore.Get("blob-id-b", digestB)
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.Increment(time.Second)
+			_, err = cachingBlobstore.Get("blob-id-a", digestA)
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.Increment(time.Second)
+			innerBlobstore.GetReturns(blobPathC, nil)
+			_, err = cachingBlobstore.Get("blob-id-c", digestC)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(innerBlobstore.GetCallCount()).To(Equal(3))
+
+			_, err = cachingBlobstore.Get("blob-id-a", digestA)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = cachingBlobstore.Get("blob-id-c", digestC)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(innerBlobstore.GetCallCount()).To(Equal(3))
+
+			innerBlobstore.GetReturns(blobPathB, nil)
+			_, err = cachingBlobstore.Get("blob-id-b", digestB)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(innerBlobstore.GetCallCount()).To(Equal(4))
+		})
+
+		It("checks out a cached blob concurrently", func() {
+			blobPath, digest := writeBlob("blob-a", "aaaa")
+			innerBlobstore.GetReturns(blobPath, nil)
+
+			_, err := cachingBlobstore.Get("blob-id-a", digest)
+			Expect(err).ToNot(HaveOccurred())
+
+			var wg sync.WaitGroup
+			fileNames := make([]string, 5)
+
+			for i := range fileNames {
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					fileName, err := cachingBlobstore.Get("blob-id-a", digest)
+					Expect(err).ToNot(HaveOccurred())
+					fileNames[i] = fileName
+				}(i)
+			}
+
+			wg.Wait()
+
+			for _, fileName := range fileNames {
+				Expect(fileName).ToNot(Equal(blobPath))
+				Expect(fs.ReadFileString(fileName)).To(Equal("aaaa"))
+				Expect(cachingBlobstore.CleanUp(fileName)).To(Succeed())
+			}
+
+			Expect(innerBlobstore.GetCallCount()).To(Equal(1))
+		})
+
+		It("does not cache blobs larger than the quota", func() {
+			blobPath, digest := writeBlob("blob-big", "larger than ten bytes")
+			innerBlobstore.GetReturns(blobPath, nil)
+
+			_, err := cachingBlobstore.Get("blob-id-big", digest)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = cachingBlobstore.Get("blob-id-big", digest)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(innerBlobstore.GetCallCount()).To(Equal(2))
+		})
+
+		It("does not cache blobs whose digest cannot name a file", func() {
+			blobPath, _ := writeBlob("blob-a", "aaaa")
+			innerBlobstore.GetReturns(blobPath, nil)
+			digest := boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "../fake-checksum")
+
+			_, err := cachingBlobstore.Get("blob-id-a", digest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists(cacheDir)).To(BeFalse())
+		})
+
+		It("returns errors from the inner blobstore", func() {
+			innerBlobstore.GetReturns("", errors.New("fake-get-error"))
+			_, digest := writeBlob("blob-a", "aaaa")
+
+			_, err := cachingBlobstore.Get("blob-id-a", digest)
+			Expect(err).To(MatchError("fake-get-error"))
+		})
+	})
+
+	Describe("CleanUp", func() {
+		It("delegates files it did not return from the cache to the inner blobstore", func() {
+			Expect(cachingBlobstore.CleanUp("/fake-blob-path")).To(Succeed())
+
+			Expect(innerBlobstore.CleanUpCallCount()).To(Equal(1))
+			Expect(innerBlobstore.CleanUpArgsForCall(0)).To(Equal("/fake-blob-path"))
+		})
+	})
+})
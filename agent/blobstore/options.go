@@ -0,0 +1,10 @@
+package blobstore
+
+// DefaultCacheQuotaMB is used when Options does not set a cache quota
+const DefaultCacheQuotaMB = 2048
+
+type Options struct {
+	// CacheQuotaMB bounds the disk space taken by cached job and package
+	// blobs; a negative quota disables the cache
+	CacheQuotaMB int
+}
@@ -0,0 +1,54 @@
+package blobstore
+
+import (
+	boshUtilsBlobStore "github.com/cloudfoundry/bosh-utils/blobstore"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// ErrStopped is returned by GetUnlessStopped when stopCh was closed
+// before the blob was fetched
+var ErrStopped = bosherr.Error("Stopped fetching blob")
+
+// GetUnlessStopped fetches a blob but returns as soon as stopCh is closed.
+// Blobstores cannot interrupt fetches, so a fetch still running then is
+// left to finish in the background and its blob is cleaned up.
+func GetUnlessStopped(
+	blobstore boshUtilsBlobStore.DigestBlobstore,
+	blobID string,
+	digest boshcrypto.Digest,
+	stopCh <-chan struct{},
+) (string, error) {
+	select {
+	case <-stopCh:
+		return "", ErrStopped
+	default:
+	}
+
+	type result struct {
+		fileName string
+		err      error
+	}
+
+	fetched := make(chan result, 1)
+
+	go func() {
+		fileName, err := blobstore.Get(blobID, digest)
+		fetched <- result{fileName: fileName, err: err}
+	}()
+
+	select {
+	case r := <-fetched:
+		return r.fileName, r.err
+
+	case <-stopCh:
+		go func() {
+			r := <-fetched
+			if r.err == nil {
+				_ = blobstore.CleanUp(r.fileName)
+			}
+		}()
+
+		return "", ErrStopped
+	}
+}
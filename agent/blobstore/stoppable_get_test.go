@@ -0,0 +1,77 @@
+package blobstore_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-agent/agent/blobstore"
+	fakeblob "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+)
+
+var _ = Describe("GetUnlessStopped", func() {
+	var (
+		innerBlobstore *fakeblob.FakeDigestBlobstore
+		digest         boshcrypto.Digest
+		stopCh         chan struct{}
+	)
+
+	BeforeEach(func() {
+		innerBlobstore = &fakeblob.FakeDigestBlobstore{}
+		digest = boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-sha1")
+		stopCh = make(chan struct{})
+	})
+
+	It("returns the fetched blob", func() {
+		innerBlobstore.GetReturns("/fake-blob", nil)
+
+		fileName, err := blobstore.GetUnlessStopped(innerBlobstore, "fake-blob-id", digest, stopCh)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fileName).To(Equal("/fake-blob"))
+
+		blobID, getDigest := innerBlobstore.GetArgsForCall(0)
+		Expect(blobID).To(Equal("fake-blob-id"))
+		Expect(getDigest).To(Equal(digest))
+	})
+
+	It("returns errors fetching the blob", func() {
+		innerBlobstore.GetReturns("", errors.New("fake-get-error"))
+
+		_, err := blobstore.GetUnlessStopped(innerBlobstore, "fake-blob-id", digest, stopCh)
+		Expect(err).To(MatchError("fake-get-error"))
+	})
+
+	It("does not fetch the blob when already stopped", func() {
+		close(stopCh)
+
+		_, err := blobstore.GetUnlessStopped(innerBlobstore, "fake-blob-id", digest, stopCh)
+		Expect(err).To(Equal(blobstore.ErrStopped))
+		Expect(innerBlobstore.GetCallCount()).To(Equal(0))
+	})
+
+	It("returns when stopped while fetching and cleans up the blob fetched later", func() {
+		fetching := make(chan struct{})
+		fetched := make(chan struct{})
+		innerBlobstore.GetStub = func(string, boshcrypto.Digest) (string, error) {
+			close(fetching)
+			<-fetched
+			return "/fake-blob", nil
+		}
+
+		go func() {
+			<-fetching
+			close(stopCh)
+		}()
+
+		_, err := blobstore.GetUnlessStopped(innerBlobstore, "fake-blob-id", digest, stopCh)
+		Expect(err).To(Equal(blobstore.ErrStopped))
+		Expect(innerBlobstore.CleanUpCallCount()).To(Equal(0))
+
+		close(fetched)
+
+		Eventually(innerBlobstore.CleanUpCallCount).Should(Equal(1))
+		Expect(innerBlobstore.CleanUpArgsForCall(0)).To(Equal("/fake-blob"))
+	})
+})
@@ -26,7 +26,9 @@ import (
 
 	devicepathresolver "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshcdrom "github.com/cloudfoundry/bosh-agent/platform/cdrom"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
@@ -815,12 +817,12 @@ func init() {
 				linuxCdrom := boshcdrom.NewLinuxCdrom("/dev/sr0", udev, runner)
 				linuxCdutil := boshcdrom.NewCdUtil(dirProvider.SettingsDir(), fs, linuxCdrom, logger)
 
-				compressor := boshcmd.NewTarballCompressor(runner, fs)
+				compressor := bosharchive.NewCompressor(fs)
 				copier := boshcmd.NewGenericCpCopier(fs, logger)
 
 				sigarCollector := boshsigar.NewSigarStatsCollector(&sigar.ConcreteSigar{})
 
-				vitalsService := boshvitals.NewService(sigarCollector, dirProvider)
+				vitalsService := boshvitals.NewService(sigarCollector, dirProvider, boshcgroup.NewManager(fs, boshcgroup.DefaultRoot, logger))
 
 				ipResolver := boship.NewResolver(boship.NetworkInterfaceToAddrsFunc)
 
@@ -16,5 +16,9 @@ type CmdResult struct {
 }
 
 type CmdRunner interface {
+	// RunCommand logs the output of cmd to files under LogsDir(jobName),
+	// also writing it to the Stdout and Stderr of cmd when they are set
 	RunCommand(jobName, taskName string, cmd boshsys.Command) (*CmdResult, error)
+
+	LogsDir(jobName string) string
 }
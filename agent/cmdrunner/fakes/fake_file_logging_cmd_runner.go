@@ -1,6 +1,8 @@
 package fakes
 
 import (
+	"path"
+
 	boshcmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
@@ -11,6 +13,9 @@ type FakeFileLoggingCmdRunner struct {
 	RunCommandTaskName string
 	RunCommandResult   *boshcmdrunner.CmdResult
 	RunCommandErr      error
+
+	// RunCommandStdout is written to the Stdout of commands that have one
+	RunCommandStdout string
 }
 
 func NewFakeFileLoggingCmdRunner() *FakeFileLoggingCmdRunner {
@@ -21,5 +26,14 @@ func (f *FakeFileLoggingCmdRunner) RunCommand(jobName, taskName string, cmd bosh
 	f.RunCommandJobName = jobName
 	f.RunCommandTaskName = taskName
 	f.RunCommands = append(f.RunCommands, cmd)
+
+	if cmd.Stdout != nil && f.RunCommandStdout != "" {
+		_, _ = cmd.Stdout.Write([]byte(f.RunCommandStdout))
+	}
+
 	return f.RunCommandResult, f.RunCommandErr
 }
+
+func (f *FakeFileLoggingCmdRunner) LogsDir(jobName string) string {
+	return path.Join("/fake-logs-dir", jobName)
+}
@@ -3,6 +3,7 @@ package cmdrunner
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"unicode/utf8"
@@ -47,6 +48,10 @@ func (f FileLoggingExecErr) Error() string {
 	)
 }
 
+func (f FileLoggingExecErr) ExitStatus() int {
+	return f.result.ExitStatus
+}
+
 func NewFileLoggingCmdRunner(
 	fs boshsys.FileSystem,
 	cmdRunner boshsys.CmdRunner,
@@ -61,8 +66,12 @@ func NewFileLoggingCmdRunner(
 	}
 }
 
+func (f FileLoggingCmdRunner) LogsDir(jobName string) string {
+	return path.Join(f.baseDir, jobName)
+}
+
 func (f FileLoggingCmdRunner) RunCommand(jobName string, taskName string, cmd boshsys.Command) (*CmdResult, error) {
-	logsDir := path.Join(f.baseDir, jobName)
+	logsDir := f.LogsDir(jobName)
 
 	err := f.fs.RemoveAll(logsDir)
 	if err != nil {
@@ -85,7 +94,7 @@ func (f FileLoggingCmdRunner) RunCommand(jobName string, taskName string, cmd bo
 		_ = stdoutFile.Close()
 	}()
 
-	cmd.Stdout = stdoutFile
+	cmd.Stdout = f.teeOutput(stdoutFile, cmd.Stdout)
 
 	stderrFile, err := f.fs.OpenFile(stderrPath, fileOpenFlag, fileOpenPerm)
 	if err != nil {
@@ -95,7 +104,7 @@ func (f FileLoggingCmdRunner) RunCommand(jobName string, taskName string, cmd bo
 		_ = stderrFile.Close()
 	}()
 
-	cmd.Stderr = stderrFile
+	cmd.Stderr = f.teeOutput(stderrFile, cmd.Stderr)
 
 	// Stdout/stderr are redirected to the files
 	_, _, exitStatus, runErr := f.cmdRunner.RunComplexCommand(cmd)
@@ -127,6 +136,13 @@ func (f FileLoggingCmdRunner) RunCommand(jobName string, taskName string, cmd bo
 	return result, nil
 }
 
+func (f FileLoggingCmdRunner) teeOutput(file boshsys.File, output io.Writer) io.Writer {
+	if output == nil {
+		return file
+	}
+	return io.MultiWriter(file, output)
+}
+
 func (f FileLoggingCmdRunner) getTruncatedOutput(file boshsys.File, truncateLength int64) ([]byte, bool, error) {
 	isTruncated := false
 
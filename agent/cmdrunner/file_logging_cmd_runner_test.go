@@ -1,6 +1,7 @@
 package cmdrunner_test
 
 import (
+	"bytes"
 	"errors"
 	"os"
 
@@ -129,6 +130,21 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(stdout).To(Equal("fake-stderr"))
 			})
+
+			It("also writes output to the writers of the command", func() {
+				stdout := &bytes.Buffer{}
+				stderr := &bytes.Buffer{}
+				cmd.Stdout = stdout
+				cmd.Stderr = stderr
+
+				_, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(stdout.String()).To(Equal("fake-stdout"))
+				Expect(stderr.String()).To(Equal("fake-stderr"))
+				Expect(fs.ReadFileString("/fake-base-dir/fake-log-dir-name/fake-log-file-name.stdout.log")).To(Equal("fake-stdout"))
+				Expect(fs.ReadFileString("/fake-base-dir/fake-log-dir-name/fake-log-file-name.stderr.log")).To(Equal("fake-stderr"))
+			})
 		})
 
 		Context("when comamnd fails", func() {
@@ -148,6 +164,12 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 				Expect(result).To(BeNil())
 			})
 
+			It("returns an error with the exit status of the command", func() {
+				_, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
+				Expect(err).To(BeAssignableToTypeOf(FileLoggingExecErr{}))
+				Expect(err.(FileLoggingExecErr).ExitStatus()).To(Equal(1))
+			})
+
 			It("saves stdout to log file", func() {
 				_, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
 				Expect(err).To(HaveOccurred())
@@ -293,4 +315,10 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 			})
 		})
 	})
+
+	Describe("LogsDir", func() {
+		It("is the directory of the job under the base directory", func() {
+			Expect(runner.LogsDir("fake-log-dir-name")).To(Equal("/fake-base-dir/fake-log-dir-name"))
+		})
+	})
 })
@@ -0,0 +1,239 @@
+package compiler
+
+import (
+	"io"
+	"path"
+	"runtime"
+	"sync"
+
+	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
+	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// BatchPackage is a package compiled together with others by CompileBatch
+type BatchPackage struct {
+	Package
+
+	// Dependencies name the packages this one depends on, either other
+	// packages of the batch or already compiled dependencies of the batch
+	Dependencies []string `json:"dependencies"`
+}
+
+type batchCompilation struct {
+	compiler concreteCompiler
+	pkgs     map[string]BatchPackage
+	deps     map[string]boshmodels.Package
+	progress io.Writer
+
+	lock    sync.Mutex
+	results map[string]Result
+	bundles []boshbc.Bundle
+	errs    []error
+}
+
+// CompileBatch installs the compiled dependencies once and then compiles
+// each package as soon as the packages it depends on are compiled, up to
+// Options.Parallelism at a time. Compiled packages stay installed until the
+// whole batch is done so that later packages of the batch can use them.
+func (c concreteCompiler) CompileBatch(pkgs []BatchPackage, deps []boshmodels.Package, progress io.Writer) (map[string]Result, error) {
+	batch := &batchCompilation{
+		compiler: c,
+		pkgs:     map[string]BatchPackage{},
+		deps:     map[string]boshmodels.Package{},
+		progress: progress,
+		results:  map[string]Result{},
+	}
+
+	for _, pkg := range pkgs {
+		if _, found := batch.pkgs[pkg.Name]; found {
+			return nil, bosherr.Errorf("Package '%s' is in the batch more than once", pkg.Name)
+		}
+		batch.pkgs[pkg.Name] = pkg
+	}
+
+	for _, dep := range deps {
+		batch.deps[dep.Name] = dep
+	}
+
+	err := batch.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.packageApplier.KeepOnly([]boshmodels.Package{})
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Removing packages")
+	}
+
+	for _, dep := range deps {
+		err := c.packageApplier.Apply(dep)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Installing dependent package: '%s'", dep.Name)
+		}
+	}
+
+	batch.run()
+
+	for _, bundle := range batch.bundles {
+		err := c.removeCompiledBundle(bundle)
+		if err != nil {
+			batch.errs = append(batch.errs, err)
+		}
+	}
+
+	err = c.packageApplier.KeepOnly([]boshmodels.Package{})
+	if err != nil {
+		batch.errs = append(batch.errs, bosherr.WrapError(err, "Removing packages"))
+	}
+
+	if len(batch.errs) > 0 {
+		return nil, bosherr.NewMultiError(batch.errs...)
+	}
+
+	return batch.results, nil
+}
+
+// validate makes sure that every dependency is known and that there are no
+// cycles, which would leave packages waiting for each other forever
+func (b *batchCompilation) validate() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	states := map[string]int{}
+
+	var visit func(name string) error
+
+	visit = func(name string) error {
+		switch states[name] {
+		case visiting:
+			return bosherr.Errorf("Package '%s' depends on itself through its dependencies", name)
+		case visited:
+			return nil
+		}
+
+		states[name] = visiting
+
+		for _, dep := range b.pkgs[name].Dependencies {
+			if _, found := b.pkgs[dep]; found {
+				err := visit(dep)
+				if err != nil {
+					return err
+				}
+			} else if _, found := b.deps[dep]; !found {
+				return bosherr.Errorf("Dependency '%s' of package '%s' is neither in the batch nor compiled", dep, name)
+			}
+		}
+
+		states[name] = visited
+
+		return nil
+	}
+
+	for name := range b.pkgs {
+		err := visit(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *batchCompilation) run() {
+	parallelism := b.compiler.options.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+
+	slots := make(chan struct{}, parallelism)
+
+	compiled := map[string]chan struct{}{}
+	for name := range b.pkgs {
+		compiled[name] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+
+	for _, pkg := range b.pkgs {
+		wg.Add(1)
+
+		go func(pkg BatchPackage) {
+			defer wg.Done()
+			defer close(compiled[pkg.Name])
+
+			for _, dep := range pkg.Dependencies {
+				if ch, found := compiled[dep]; found {
+					<-ch
+				}
+			}
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			b.compile(pkg)
+		}(pkg)
+	}
+
+	wg.Wait()
+}
+
+func (b *batchCompilation) compile(pkg BatchPackage) {
+	startedAt := b.compiler.timeService.Now()
+
+	deps, ok := b.dependencies(pkg)
+	if !ok {
+		return
+	}
+
+	result, bundle, err := b.compiler.compile(pkg.Package, deps, path.Join("compilation", pkg.Name), startedAt, b.progress)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if bundle != nil {
+		b.bundles = append(b.bundles, bundle)
+	}
+
+	if err != nil {
+		b.errs = append(b.errs, bosherr.WrapErrorf(err, "Compiling package %s", pkg.Name))
+		return
+	}
+
+	b.results[pkg.Name] = result
+}
+
+// dependencies is false once any package failed since the batch fails
+// anyway, leaving packages that have not started yet uncompiled
+func (b *batchCompilation) dependencies(pkg BatchPackage) ([]boshmodels.Package, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if len(b.errs) > 0 {
+		return nil, false
+	}
+
+	deps := []boshmodels.Package{}
+
+	for _, name := range pkg.Dependencies {
+		result, found := b.results[name]
+		if !found {
+			deps = append(deps, b.deps[name])
+			continue
+		}
+
+		deps = append(deps, boshmodels.Package{
+			Name:    name,
+			Version: b.pkgs[name].Version,
+			Source: boshmodels.Source{
+				Sha1:        boshcrypto.MustNewMultipleDigest(result.Digest),
+				BlobstoreID: result.BlobID,
+			},
+		})
+	}
+
+	return deps, true
+}
@@ -0,0 +1,251 @@
+package compiler_test
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	fakebc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection/fakes"
+	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	fakepackages "github.com/cloudfoundry/bosh-agent/agent/applier/packages/fakes"
+	fakecmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner/fakes"
+	. "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	fakecgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup/fakes"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
+	fakearchive "github.com/cloudfoundry/bosh-agent/platform/archive/fakes"
+	fakeblobstore "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("concreteCompiler", func() {
+	var (
+		compressor     *fakearchive.FakeCompressor
+		blobstore      *fakeblobstore.FakeDigestBlobstore
+		fs             *fakesys.FakeFileSystem
+		runner         *fakecmdrunner.FakeFileLoggingCmdRunner
+		packageApplier *fakepackages.FakeApplier
+		packagesBc     *fakebc.FakeBundleCollection
+		toolRunner     *fakesys.FakeCmdRunner
+		cgroups        *fakecgroup.FakeManager
+		options        Options
+		compiler       Compiler
+
+		fooBundle *fakebc.FakeBundle
+		barBundle *fakebc.FakeBundle
+		pkgs      []BatchPackage
+		deps      []boshmodels.Package
+	)
+
+	batchPackage := func(name string, deps ...string) BatchPackage {
+		return BatchPackage{
+			Package: Package{
+				BlobstoreID: name + "-blobstore-id",
+				Name:        name,
+				Sha1:        boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, name+"-sha1")),
+				Version:     name + "-version",
+			},
+			Dependencies: deps,
+		}
+	}
+
+	BeforeEach(func() {
+		compressor = fakearchive.NewFakeCompressor()
+		blobstore = &fakeblobstore.FakeDigestBlobstore{}
+		fs = fakesys.NewFakeFileSystem()
+		runner = fakecmdrunner.NewFakeFileLoggingCmdRunner()
+		packageApplier = fakepackages.NewFakeApplier()
+		packagesBc = fakebc.NewFakeBundleCollection()
+		toolRunner = fakesys.NewFakeCmdRunner()
+		cgroups = fakecgroup.NewFakeManager()
+		options = Options{}
+
+		fs.MkdirAll("/fake-compile-dir", os.ModePerm)
+
+		compressor.CompressFilesInDirTarballPath = "/tmp/compressed-compiled-package"
+		compressor.CompressFilesInDirDigest = boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-compiled-sha1")
+		blobstore.CreateReturns("fake-blob-id", boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-blob-sha1")), nil)
+
+		// Bundles are looked up concurrently, which the fake only allows for existing ones
+		fooBundle = packagesBc.FakeGet(boshmodels.LocalPackage{Name: "foo", Version: "foo-version"})
+		barBundle = packagesBc.FakeGet(boshmodels.LocalPackage{Name: "bar", Version: "bar-version"})
+		packagesBc.FakeGet(boshmodels.LocalPackage{Name: "qux", Version: "qux-version"})
+
+		pkgs = []BatchPackage{batchPackage("bar", "foo", "baz"), batchPackage("foo")}
+
+		deps = []boshmodels.Package{{
+			Name:    "baz",
+			Version: "baz-version",
+			Source: boshmodels.Source{
+				Sha1:        boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "baz-sha1")),
+				BlobstoreID: "baz-blobstore-id",
+			},
+		}}
+	})
+
+	JustBeforeEach(func() {
+		compiler = NewConcreteCompiler(
+			compressor,
+			bosharchive.FormatGzip,
+			blobstore,
+			fs,
+			runner,
+			toolRunner,
+			FakeCompileDirProvider{Dir: "/fake-compile-dir"},
+			packageApplier,
+			packagesBc,
+			cgroups,
+			fakeclock.NewFakeClock(time.Now()),
+			options,
+		)
+	})
+
+	Describe("CompileBatch", func() {
+		It("installs the compiled dependencies once and removes all packages when done", func() {
+			results, err := compiler.CompileBatch(pkgs, deps, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+
+			Expect(packageApplier.ActionsCalled).To(Equal([]string{"KeepOnly", "Apply", "KeepOnly"}))
+			Expect(packageApplier.AppliedPackages).To(Equal(deps))
+			Expect(packageApplier.KeptOnlyPackages).To(BeEmpty())
+
+			for _, bundle := range []*fakebc.FakeBundle{fooBundle, barBundle} {
+				Expect(bundle.ActionsCalled).To(Equal([]string{"InstallWithoutContents", "Enable", "Disable", "Uninstall"}))
+			}
+		})
+
+		It("compiles packages after the packages of the batch they depend on", func() {
+			results, err := compiler.CompileBatch(pkgs, deps, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(blobstore.GetCallCount()).To(Equal(2))
+			firstBlobID, _ := blobstore.GetArgsForCall(0)
+			secondBlobID, _ := blobstore.GetArgsForCall(1)
+			Expect([]string{firstBlobID, secondBlobID}).To(Equal([]string{"foo-blobstore-id", "bar-blobstore-id"}))
+
+			Expect(results["foo"].BlobID).To(Equal("fake-blob-id"))
+			Expect(results["bar"].Provenance.Dependencies).To(Equal([]ProvenancePackage{
+				{Name: "baz", Version: "baz-version", Sha1: "baz-sha1"},
+				{Name: "foo", Version: "foo-version", Sha1: "fake-compiled-sha1"},
+			}))
+		})
+
+		It("keeps the logs of each packaging script apart", func() {
+			compressor.DecompressFileToDirCallBack = func() {
+				fs.WriteFileString("/fake-compile-dir/foo/"+PackagingScriptName, "fake-packaging-script")
+			}
+
+			_, err := compiler.CompileBatch([]BatchPackage{batchPackage("foo")}, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommandJobName).To(Equal("compilation/foo"))
+		})
+
+		Context("when packaging scripts run in a sandbox with resource limits", func() {
+			BeforeEach(func() {
+				if runtime.GOOS == "windows" {
+					Skip("Namespaces are only available on Linux")
+				}
+
+				options.Sandbox = SandboxOptions{Enabled: true, Limits: boshcgroup.Limits{PidsMax: 256}}
+				toolRunner.AddCmdResult("unshare --help", fakesys.FakeCmdResult{Stdout: "     --kill-child[=<signame>]\n", Sticky: true})
+				toolRunner.AddCmdResult("setpriv --help", fakesys.FakeCmdResult{Stdout: "     --no-new-privs\n     --bounding-set <caps>\n", Sticky: true})
+
+				compressor.DecompressFileToDirCallBack = func() {
+					fs.WriteFileString("/fake-compile-dir/foo/"+PackagingScriptName, "fake-packaging-script")
+					fs.WriteFileString("/fake-compile-dir/bar/"+PackagingScriptName, "fake-packaging-script")
+				}
+			})
+
+			It("limits each package of the batch in a cgroup of its own", func() {
+				_, err := compiler.CompileBatch(pkgs, deps, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cgroups.AddedJobs).To(Equal(map[string]boshcgroup.Limits{
+					"bosh-compilation-foo": {PidsMax: 256},
+					"bosh-compilation-bar": {PidsMax: 256},
+				}))
+				Expect(cgroups.RemovedJobs).To(Equal([]string{"bosh-compilation-foo", "bosh-compilation-bar"}))
+			})
+		})
+
+		Context("when parallelism allows it", func() {
+			BeforeEach(func() {
+				options.Parallelism = 2
+			})
+
+			It("compiles independent packages at the same time", func() {
+				var lock sync.Mutex
+				started := 0
+				bothStarted := make(chan struct{})
+
+				blobstore.GetStub = func(string, boshcrypto.Digest) (string, error) {
+					lock.Lock()
+					started++
+					if started == 2 {
+						close(bothStarted)
+					}
+					lock.Unlock()
+
+					select {
+					case <-bothStarted:
+						return "", nil
+					case <-time.After(5 * time.Second):
+						return "", errors.New("fake-not-compiled-concurrently")
+					}
+				}
+
+				_, err := compiler.CompileBatch([]BatchPackage{batchPackage("foo"), batchPackage("qux")}, nil, nil)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		It("returns an error and does not compile packages depending on a package that failed", func() {
+			blobstore.GetReturns("", errors.New("fake-get-error"))
+
+			_, err := compiler.CompileBatch(pkgs, deps, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Compiling package foo"))
+			Expect(err.Error()).To(ContainSubstring("fake-get-error"))
+
+			Expect(blobstore.GetCallCount()).To(Equal(1))
+			Expect(packageApplier.ActionsCalled).To(Equal([]string{"KeepOnly", "Apply", "KeepOnly"}))
+		})
+
+		It("returns an error without installing anything when a dependency is unknown", func() {
+			_, err := compiler.CompileBatch(pkgs, nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Dependency 'baz' of package 'bar' is neither in the batch nor compiled"))
+			Expect(packageApplier.ActionsCalled).To(BeEmpty())
+		})
+
+		It("returns an error without installing anything when packages depend on each other", func() {
+			_, err := compiler.CompileBatch([]BatchPackage{batchPackage("foo", "bar"), batchPackage("bar", "foo")}, nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(MatchRegexp("Package '(foo|bar)' depends on itself"))
+			Expect(packageApplier.ActionsCalled).To(BeEmpty())
+		})
+
+		It("returns an error when a package is in the batch more than once", func() {
+			_, err := compiler.CompileBatch([]BatchPackage{batchPackage("foo"), batchPackage("foo")}, nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Package 'foo' is in the batch more than once"))
+		})
+
+		It("returns an error when installing a compiled dependency fails", func() {
+			packageApplier.ApplyError = errors.New("fake-apply-error")
+
+			_, err := compiler.CompileBatch(pkgs, deps, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-apply-error"))
+			Expect(blobstore.GetCallCount()).To(BeZero())
+		})
+	})
+})
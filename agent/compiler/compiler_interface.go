@@ -1,12 +1,21 @@
 package compiler
 
 import (
+	"io"
+
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
 	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
 )
 
+// Compiler writes the output of packaging scripts to progress as it
+// happens; progress may be nil
 type Compiler interface {
-	Compile(pkg Package, deps []boshmodels.Package) (blobID string, digest boshcrypto.Digest, err error)
+	Compile(pkg Package, deps []boshmodels.Package, progress io.Writer) (Result, error)
+
+	// CompileBatch compiles packages that may depend on each other and
+	// returns their results by package name
+	CompileBatch(pkgs []BatchPackage, deps []boshmodels.Package, progress io.Writer) (map[string]Result, error)
 }
 
 type Package struct {
@@ -17,3 +26,30 @@ type Package struct {
 }
 
 type Dependencies map[string]Package
+
+type Result struct {
+	BlobID string
+	Digest boshcrypto.Digest
+
+	// Sandbox is nil unless the packaging script ran in a sandbox
+	Sandbox *SandboxReport
+
+	Provenance       *Provenance
+	ProvenanceBlobID string
+	ProvenanceDigest boshcrypto.MultipleDigest
+
+	// LogsBlobID is the compressed output of the packaging script,
+	// empty when the package has no packaging script
+	LogsBlobID string
+	LogsDigest boshcrypto.Digest
+}
+
+// SandboxReport describes the isolation a packaging script ran with
+type SandboxReport struct {
+	Network        bool              `json:"network"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+	Limits         boshcgroup.Limits `json:"limits"`
+
+	// CPUUsageUsec is only known when the script ran with limits
+	CPUUsageUsec int64 `json:"cpu_usage_usec,omitempty"`
+}
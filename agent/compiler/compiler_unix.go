@@ -3,25 +3,70 @@
 package compiler
 
 import (
+	"io"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
-func (c concreteCompiler) runPackagingCommand(compilePath, enablePath string, pkg Package) error {
+// provenanceTools are asked for their versions with the given arguments
+var provenanceTools = [][]string{
+	{"bash", "--version"},
+	{"gcc", "--version"},
+	{"g++", "--version"},
+	{"make", "--version"},
+	{"ld", "--version"},
+}
+
+func (c concreteCompiler) runPackagingCommand(compilePath, installPath, enablePath, logsName string, pkg Package, progress io.Writer) (*SandboxReport, error) {
 	command := boshsys.Command{
-		Name: "bash",
-		Args: []string{"-x", PackagingScriptName},
-		Env: map[string]string{
-			"BOSH_COMPILE_TARGET":  compilePath,
-			"BOSH_INSTALL_TARGET":  enablePath,
-			"BOSH_PACKAGE_NAME":    pkg.Name,
-			"BOSH_PACKAGE_VERSION": pkg.Version,
-		},
+		Name:       "bash",
+		Args:       []string{"-x", PackagingScriptName},
+		Env:        packagingEnv(compilePath, enablePath, pkg),
 		WorkingDir: compilePath,
 	}
-	_, err := c.runner.RunCommand("compilation", PackagingScriptName, command)
+
+	flushProgress := streamOutput(&command, pkg.Name+": ", progress)
+	defer flushProgress()
+
+	if !c.options.Sandbox.Enabled {
+		_, err := c.runner.RunCommand(logsName, PackagingScriptName, command)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Running packaging script")
+		}
+		return nil, nil
+	}
+
+	sandbox, err := c.prepareSandbox(compilePath, installPath, enablePath, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.cleanUpSandbox(sandbox)
+
+	_, err = c.runner.RunCommand(logsName, PackagingScriptName, sandbox.wrap(command))
 	if err != nil {
-		return bosherr.WrapError(err, "Running packaging script")
+		if execErr, ok := err.(exitStatusErr); ok && execErr.ExitStatus() == sandboxTimeoutExitStatus {
+			return nil, bosherr.WrapErrorf(err, "Packaging script did not finish within %d seconds", sandbox.options.TimeoutSeconds)
+		}
+		return nil, bosherr.WrapError(err, "Running packaging script in sandbox")
 	}
-	return nil
+
+	report := sandbox.report()
+
+	if sandbox.cgroup != "" {
+		usage, err := c.cgroups.Usage(sandbox.cgroup)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Reading resource usage of packaging script")
+		}
+
+		report.CPUUsageUsec = usage.CPUUsageUsec
+	}
+
+	return report, nil
+}
+
+// exitStatusErr is implemented by errors of commands that ran but failed
+type exitStatusErr interface {
+	ExitStatus() int
 }
@@ -2,27 +2,37 @@ package compiler
 
 import (
 	"fmt"
+	"io"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
-func (c concreteCompiler) runPackagingCommand(compilePath, enablePath string, pkg Package) error {
+// provenanceTools are asked for their versions with the given arguments
+var provenanceTools = [][]string{
+	{"powershell", "-command", "$PSVersionTable.PSVersion.ToString()"},
+	{"gcc", "--version"},
+	{"make", "--version"},
+}
+
+func (c concreteCompiler) runPackagingCommand(compilePath, installPath, enablePath, logsName string, pkg Package, progress io.Writer) (*SandboxReport, error) {
+	if c.options.Sandbox.Enabled {
+		return nil, bosherr.Error("Running packaging scripts in a sandbox is not supported on Windows")
+	}
+
 	command := boshsys.Command{
-		Name: "powershell",
-		Args: []string{"-command", fmt.Sprintf(`"iex (get-content -raw %s)"`, PackagingScriptName)},
-		Env: map[string]string{
-			"BOSH_COMPILE_TARGET":  compilePath,
-			"BOSH_INSTALL_TARGET":  enablePath,
-			"BOSH_PACKAGE_NAME":    pkg.Name,
-			"BOSH_PACKAGE_VERSION": pkg.Version,
-		},
+		Name:       "powershell",
+		Args:       []string{"-command", fmt.Sprintf(`"iex (get-content -raw %s)"`, PackagingScriptName)},
+		Env:        packagingEnv(compilePath, enablePath, pkg),
 		WorkingDir: compilePath,
 	}
 
-	_, err := c.runner.RunCommand("compilation", PackagingScriptName, command)
+	flushProgress := streamOutput(&command, pkg.Name+": ", progress)
+	defer flushProgress()
+
+	_, err := c.runner.RunCommand(logsName, PackagingScriptName, command)
 	if err != nil {
-		return bosherr.WrapError(err, "Running packaging script")
+		return nil, bosherr.WrapError(err, "Running packaging script")
 	}
-	return nil
+	return nil, nil
 }
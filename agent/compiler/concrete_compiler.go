@@ -2,78 +2,119 @@ package compiler
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"time"
 
 	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 	"github.com/cloudfoundry/bosh-agent/agent/applier/packages"
 	boshcmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
-	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
 )
 
 const PackagingScriptName = "packaging"
 
 type CompileDirProvider interface {
 	CompileDir() string
+	EtcDir() string
 }
 
 type concreteCompiler struct {
-	compressor         boshcmd.Compressor
+	compressor         bosharchive.Compressor
+	format             bosharchive.Format
 	blobstore          boshblob.DigestBlobstore
 	fs                 boshsys.FileSystem
 	runner             boshcmdrunner.CmdRunner
+	toolRunner         boshsys.CmdRunner
 	compileDirProvider CompileDirProvider
 	packageApplier     packages.Applier
 	packagesBc         boshbc.BundleCollection
+	cgroups            boshcgroup.Manager
+	timeService        clock.Clock
+	options            Options
 }
 
 func NewConcreteCompiler(
-	compressor boshcmd.Compressor,
+	compressor bosharchive.Compressor,
+	format bosharchive.Format,
 	blobstore boshblob.DigestBlobstore,
 	fs boshsys.FileSystem,
 	runner boshcmdrunner.CmdRunner,
+	toolRunner boshsys.CmdRunner,
 	compileDirProvider CompileDirProvider,
 	packageApplier packages.Applier,
 	packagesBc boshbc.BundleCollection,
+	cgroups boshcgroup.Manager,
+	timeService clock.Clock,
+	options Options,
 ) Compiler {
 	return concreteCompiler{
 		compressor:         compressor,
+		format:             format,
 		blobstore:          blobstore,
 		fs:                 fs,
 		runner:             runner,
+		toolRunner:         toolRunner,
 		compileDirProvider: compileDirProvider,
 		packageApplier:     packageApplier,
 		packagesBc:         packagesBc,
+		cgroups:            cgroups,
+		timeService:        timeService,
+		options:            options,
 	}
 }
 
-func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package) (blobID string, digest boshcrypto.Digest, err error) {
-	err = c.packageApplier.KeepOnly([]boshmodels.Package{})
+func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package, progress io.Writer) (Result, error) {
+	startedAt := c.timeService.Now()
+
+	err := c.packageApplier.KeepOnly([]boshmodels.Package{})
 	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Removing packages")
+		return Result{}, bosherr.WrapError(err, "Removing packages")
 	}
 
 	for _, dep := range deps {
 		err := c.packageApplier.Apply(dep)
 		if err != nil {
-			return "", nil, bosherr.WrapErrorf(err, "Installing dependent package: '%s'", dep.Name)
+			return Result{}, bosherr.WrapErrorf(err, "Installing dependent package: '%s'", dep.Name)
 		}
 	}
 
+	result, compiledPkgBundle, err := c.compile(pkg, deps, "compilation", startedAt, progress)
+	if err != nil {
+		return Result{}, err
+	}
+
+	err = c.removeCompiledBundle(compiledPkgBundle)
+	if err != nil {
+		return Result{}, err
+	}
+
+	err = c.packageApplier.KeepOnly([]boshmodels.Package{})
+	if err != nil {
+		return Result{}, bosherr.WrapError(err, "Removing packages")
+	}
+
+	return result, nil
+}
+
+// compile builds and uploads pkg with its dependencies already installed,
+// leaving the compiled package enabled so that it can be a dependency too
+func (c concreteCompiler) compile(pkg Package, deps []boshmodels.Package, logsName string, startedAt time.Time, progress io.Writer) (result Result, compiledPkgBundle boshbc.Bundle, err error) {
 	compilePath := path.Join(c.compileDirProvider.CompileDir(), pkg.Name)
 
 	err = c.fetchAndUncompress(pkg, compilePath)
 	if err != nil {
-		return "", nil, bosherr.WrapErrorf(err, "Fetching package %s", pkg.Name)
+		return Result{}, nil, bosherr.WrapErrorf(err, "Fetching package %s", pkg.Name)
 	}
 
-	defer c.fs.RemoveAll(compilePath)
-
 	defer func() {
 		e := c.fs.RemoveAll(compilePath)
 		if e != nil && err == nil {
@@ -86,70 +127,100 @@ func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package) (blobI
 		Version: pkg.Version,
 	}
 
-	compiledPkgBundle, err := c.packagesBc.Get(compiledPkg)
+	compiledPkgBundle, err = c.packagesBc.Get(compiledPkg)
 	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Getting bundle for new package")
+		return Result{}, nil, bosherr.WrapError(err, "Getting bundle for new package")
 	}
 
 	_, installPath, err := compiledPkgBundle.InstallWithoutContents()
 	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Setting up new package bundle")
+		return Result{}, nil, bosherr.WrapError(err, "Setting up new package bundle")
 	}
 
 	_, enablePath, err := compiledPkgBundle.Enable()
 	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Enabling new package bundle")
+		return Result{}, nil, bosherr.WrapError(err, "Enabling new package bundle")
 	}
 
+	provenance := c.newProvenance(pkg, deps, startedAt)
+	provenance.Environment = provenanceEnvironment(packagingEnv(compilePath, enablePath, pkg))
+
 	scriptPath := path.Join(compilePath, PackagingScriptName)
 
 	if c.fs.FileExists(scriptPath) {
-		if err := c.runPackagingCommand(compilePath, enablePath, pkg); err != nil {
-			return "", nil, bosherr.WrapError(err, "Running packaging script")
+		provenance.PackagingScriptDigest, err = c.hashPackagingScript(scriptPath)
+		if err != nil {
+			return Result{}, nil, err
+		}
+
+		sandbox, runErr := c.runPackagingCommand(compilePath, installPath, enablePath, logsName, pkg, progress)
+
+		result.LogsBlobID, result.LogsDigest, err = c.uploadLogs(logsName)
+		if runErr != nil {
+			if err == nil {
+				runErr = bosherr.WrapErrorf(runErr, "Packaging logs uploaded to blob %s with digest %s", result.LogsBlobID, result.LogsDigest)
+			}
+			return Result{}, nil, bosherr.WrapError(runErr, "Running packaging script")
 		}
+		if err != nil {
+			return Result{}, nil, err
+		}
+
+		result.Sandbox = sandbox
 	}
 
-	tmpPackageTar, err := c.compressor.CompressFilesInDir(installPath)
+	// Use the strongest algo from the source blob for the compiled package
+	tmpPackageTar, digest, err := c.compressor.CompressFilesInDirAs(installPath, c.format, pkg.Sha1.Algorithm())
 	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Compressing compiled package")
+		return Result{}, nil, bosherr.WrapError(err, "Compressing compiled package")
 	}
 
 	defer func() {
 		_ = c.compressor.CleanUp(tmpPackageTar)
 	}()
 
-	file, err := c.fs.OpenFile(tmpPackageTar, os.O_RDONLY, os.ModePerm)
+	uploadedBlobID, _, err := c.blobstore.Create(tmpPackageTar)
 	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Opening compiled package")
+		return Result{}, nil, bosherr.WrapError(err, "Uploading compiled package")
 	}
 
-	// Use SHA256, not the strongest algo from the source blob
-	digest, err = pkg.Sha1.Algorithm().CreateDigest(file)
-	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Calculating compiled package digest")
-	}
+	provenance.CompiledSha1 = digest.String()
+	provenance.Sandbox = result.Sandbox
+	provenance.DurationSeconds = c.timeService.Since(startedAt).Seconds()
 
-	uploadedBlobID, _, err := c.blobstore.Create(tmpPackageTar)
+	result.ProvenanceBlobID, result.ProvenanceDigest, err = c.uploadProvenance(provenance)
 	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Uploading compiled package")
+		return Result{}, nil, err
 	}
 
-	err = compiledPkgBundle.Disable()
+	result.Provenance = provenance
+	result.BlobID = uploadedBlobID
+	result.Digest = digest
+
+	return result, compiledPkgBundle, nil
+}
+
+func (c concreteCompiler) removeCompiledBundle(compiledPkgBundle boshbc.Bundle) error {
+	err := compiledPkgBundle.Disable()
 	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Disabling compiled package")
+		return bosherr.WrapError(err, "Disabling compiled package")
 	}
 
 	err = compiledPkgBundle.Uninstall()
 	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Uninstalling compiled package")
+		return bosherr.WrapError(err, "Uninstalling compiled package")
 	}
 
-	err = c.packageApplier.KeepOnly([]boshmodels.Package{})
-	if err != nil {
-		return "", nil, bosherr.WrapError(err, "Removing packages")
-	}
+	return nil
+}
 
-	return uploadedBlobID, digest, nil
+func packagingEnv(compilePath, enablePath string, pkg Package) map[string]string {
+	return map[string]string{
+		"BOSH_COMPILE_TARGET":  compilePath,
+		"BOSH_INSTALL_TARGET":  enablePath,
+		"BOSH_PACKAGE_NAME":    pkg.Name,
+		"BOSH_PACKAGE_VERSION": pkg.Version,
+	}
 }
 
 func (c concreteCompiler) fetchAndUncompress(pkg Package, targetDir string) error {
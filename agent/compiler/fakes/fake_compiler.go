@@ -1,17 +1,23 @@
 package fakes
 
 import (
+	"io"
+
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
-	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
 )
 
 type FakeCompiler struct {
 	CompilePkg    boshcomp.Package
 	CompileDeps   []boshmodels.Package
-	CompileBlobID string
-	CompileDigest boshcrypto.Digest
+	CompileOutput string
+	CompileResult boshcomp.Result
 	CompileErr    error
+
+	CompileBatchPkgs    []boshcomp.BatchPackage
+	CompileBatchDeps    []boshmodels.Package
+	CompileBatchResults map[string]boshcomp.Result
+	CompileBatchErr     error
 }
 
 func NewFakeCompiler() (c *FakeCompiler) {
@@ -19,11 +25,19 @@ func NewFakeCompiler() (c *FakeCompiler) {
 	return
 }
 
-func (c *FakeCompiler) Compile(pkg boshcomp.Package, deps []boshmodels.Package) (blobID string, digest boshcrypto.Digest, err error) {
+func (c *FakeCompiler) Compile(pkg boshcomp.Package, deps []boshmodels.Package, progress io.Writer) (boshcomp.Result, error) {
 	c.CompilePkg = pkg
 	c.CompileDeps = deps
-	blobID = c.CompileBlobID
-	digest = c.CompileDigest
-	err = c.CompileErr
-	return
+
+	if progress != nil {
+		_, _ = io.WriteString(progress, c.CompileOutput)
+	}
+
+	return c.CompileResult, c.CompileErr
+}
+
+func (c *FakeCompiler) CompileBatch(pkgs []boshcomp.BatchPackage, deps []boshmodels.Package, _ io.Writer) (map[string]boshcomp.Result, error) {
+	c.CompileBatchPkgs = pkgs
+	c.CompileBatchDeps = deps
+	return c.CompileBatchResults, c.CompileBatchErr
 }
@@ -0,0 +1,103 @@
+package compiler
+
+import (
+	"bytes"
+	"io"
+
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// uploadLogs uploads the compressed logs of a packaging script, which
+// would otherwise be lost together with the compilation VM
+func (c concreteCompiler) uploadLogs(logsName string) (string, boshcrypto.Digest, error) {
+	tmpLogsTar, digest, err := c.compressor.CompressFilesInDirAs(c.runner.LogsDir(logsName), c.format, boshcrypto.DigestAlgorithmSHA1)
+	if err != nil {
+		return "", nil, bosherr.WrapError(err, "Compressing packaging logs")
+	}
+
+	defer func() {
+		_ = c.compressor.CleanUp(tmpLogsTar)
+	}()
+
+	blobID, _, err := c.blobstore.Create(tmpLogsTar)
+	if err != nil {
+		return "", nil, bosherr.WrapError(err, "Uploading packaging logs")
+	}
+
+	return blobID, digest, nil
+}
+
+// streamOutput makes command also write its output to progress, returning
+// a func that writes the last lines of output if they are not terminated
+func streamOutput(command *boshsys.Command, prefix string, progress io.Writer) func() {
+	if progress == nil {
+		return func() {}
+	}
+
+	stdout, stderr := newLineWriter(prefix, progress), newLineWriter(prefix, progress)
+	command.Stdout, command.Stderr = stdout, stderr
+
+	return func() {
+		_ = stdout.Flush()
+		_ = stderr.Flush()
+	}
+}
+
+// maxLineBytes is the length at which lineWriter splits lines so that output
+// without newlines is not buffered without bound
+const maxLineBytes = 4096
+
+// lineWriter passes only complete lines on to out, each with a prefix, so
+// that the stdout and stderr of packaging scripts running at the same time
+// can share out without mixing within lines; out must allow concurrent writes
+type lineWriter struct {
+	prefix  string
+	out     io.Writer
+	partial []byte
+}
+
+func newLineWriter(prefix string, out io.Writer) *lineWriter {
+	return &lineWriter{prefix: prefix, out: out}
+}
+
+func (w *lineWriter) Write(data []byte) (int, error) {
+	w.partial = append(w.partial, data...)
+
+	var err error
+
+	if i := bytes.LastIndexByte(w.partial, '\n'); i >= 0 {
+		err = w.writeLines(w.partial[:i+1])
+		w.partial = append([]byte{}, w.partial[i+1:]...)
+	}
+
+	for err == nil && len(w.partial) > maxLineBytes {
+		err = w.writeLines(append(append([]byte{}, w.partial[:maxLineBytes]...), '\n'))
+		w.partial = append([]byte{}, w.partial[maxLineBytes:]...)
+	}
+
+	return len(data), err
+}
+
+// Flush writes the last line even though it is not terminated
+func (w *lineWriter) Flush() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+
+	err := w.writeLines(append(w.partial, '\n'))
+	w.partial = nil
+
+	return err
+}
+
+func (w *lineWriter) writeLines(lines []byte) error {
+	if w.prefix != "" {
+		lines = bytes.Replace(lines[:len(lines)-1], []byte("\n"), []byte("\n"+w.prefix), -1)
+		lines = append(append([]byte(w.prefix), lines...), '\n')
+	}
+
+	_, err := w.out.Write(lines)
+	return err
+}
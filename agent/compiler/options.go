@@ -0,0 +1,32 @@
+package compiler
+
+import (
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+)
+
+// DefaultSandboxTimeoutSeconds is used when SandboxOptions does not set a timeout
+const DefaultSandboxTimeoutSeconds = 2 * 60 * 60
+
+type Options struct {
+	// Parallelism limits how many packages of a batch are compiled at the
+	// same time; it defaults to the number of CPUs
+	Parallelism int
+
+	Sandbox SandboxOptions
+}
+
+type SandboxOptions struct {
+	// Enabled runs packaging scripts in their own mount, pid, ipc, uts and
+	// network namespaces, seeing only system directories, the package
+	// source, its dependencies and the install target. Linux only.
+	Enabled bool
+
+	// Network keeps the host network reachable from the sandbox, e.g. for
+	// packaging scripts that download their dependencies
+	Network bool
+
+	TimeoutSeconds int
+
+	// Limits are enforced with a cgroup shared by all packaging scripts
+	Limits boshcgroup.Limits
+}
@@ -0,0 +1,184 @@
+package compiler
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// Provenance traces a compiled package back to the inputs it was built from
+type Provenance struct {
+	Package      ProvenancePackage   `json:"package"`
+	Dependencies []ProvenancePackage `json:"dependencies"`
+
+	// CompiledSha1 is the digest of the compiled package this record is about
+	CompiledSha1 string `json:"compiled_sha1"`
+
+	// PackagingScriptDigest is empty for packages without a packaging script
+	PackagingScriptDigest string `json:"packaging_script_digest,omitempty"`
+
+	// Environment is what the packaging script ran with, i.e. the agent's
+	// environment and the BOSH_* variables, with secrets redacted
+	Environment map[string]string `json:"environment"`
+
+	StemcellVersion string `json:"stemcell_version"`
+
+	// ToolVersions has the first line of the version output of each build
+	// tool found on the compile VM
+	ToolVersions map[string]string `json:"tool_versions"`
+
+	Sandbox *SandboxReport `json:"sandbox,omitempty"`
+
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+// redactedEnvValue replaces values of variables that may be secrets, i.e.
+// whose names match secretEnvNamePattern or that are URLs with credentials
+const redactedEnvValue = "[REDACTED]"
+
+var secretEnvNamePattern = regexp.MustCompile(`(?i)pass|secret|token|key|credential|auth|private`)
+
+type ProvenancePackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Sha1    string `json:"sha1"`
+}
+
+func (c concreteCompiler) newProvenance(pkg Package, deps []boshmodels.Package, startedAt time.Time) *Provenance {
+	provenance := &Provenance{
+		Package: ProvenancePackage{
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			Sha1:    pkg.Sha1.String(),
+		},
+		Dependencies:    []ProvenancePackage{},
+		StemcellVersion: c.stemcellVersion(),
+		ToolVersions:    c.toolVersions(),
+		StartedAt:       startedAt.UTC(),
+	}
+
+	for _, dep := range deps {
+		provenance.Dependencies = append(provenance.Dependencies, ProvenancePackage{
+			Name:    dep.Name,
+			Version: dep.Version,
+			Sha1:    dep.Source.Sha1.String(),
+		})
+	}
+
+	// Dependencies arrive in no particular order
+	sort.Slice(provenance.Dependencies, func(i, j int) bool {
+		return provenance.Dependencies[i].Name < provenance.Dependencies[j].Name
+	})
+
+	return provenance
+}
+
+// provenanceEnvironment adds env to the agent's environment, which commands
+// inherit, since packaging scripts may depend on either
+func provenanceEnvironment(env map[string]string) map[string]string {
+	environment := map[string]string{}
+
+	for _, entry := range os.Environ() {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 {
+			environment[parts[0]] = parts[1]
+		}
+	}
+
+	for name, value := range env {
+		environment[name] = value
+	}
+
+	for name, value := range environment {
+		if secretEnvNamePattern.MatchString(name) {
+			environment[name] = redactedEnvValue
+		} else if u, err := url.Parse(value); err == nil && u.User != nil {
+			environment[name] = redactedEnvValue
+		}
+	}
+
+	return environment
+}
+
+func (c concreteCompiler) stemcellVersion() string {
+	contents, err := c.fs.ReadFileString(path.Join(c.compileDirProvider.EtcDir(), "stemcell_version"))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(contents)
+}
+
+// toolVersions leaves out tools that are missing or do not report a version
+func (c concreteCompiler) toolVersions() map[string]string {
+	versions := map[string]string{}
+
+	for _, tool := range provenanceTools {
+		stdout, _, _, err := c.toolRunner.RunCommandQuietly(tool[0], tool[1:]...)
+		if err != nil {
+			continue
+		}
+
+		version := strings.TrimSpace(strings.SplitN(strings.TrimSpace(stdout), "\n", 2)[0])
+		if version != "" {
+			versions[tool[0]] = version
+		}
+	}
+
+	return versions
+}
+
+func (c concreteCompiler) hashPackagingScript(scriptPath string) (string, error) {
+	digest, err := boshcrypto.NewMultipleDigestFromPath(scriptPath, c.fs, []boshcrypto.Algorithm{boshcrypto.DigestAlgorithmSHA256})
+	if err != nil {
+		return "", bosherr.WrapError(err, "Hashing packaging script")
+	}
+
+	return digest.String(), nil
+}
+
+// uploadProvenance stores the record next to the compiled package
+func (c concreteCompiler) uploadProvenance(provenance *Provenance) (string, boshcrypto.MultipleDigest, error) {
+	contents, err := json.Marshal(provenance)
+	if err != nil {
+		return "", boshcrypto.MultipleDigest{}, bosherr.WrapError(err, "Marshalling provenance")
+	}
+
+	file, err := c.fs.TempFile("bosh-agent-compiler-Provenance")
+	if err != nil {
+		return "", boshcrypto.MultipleDigest{}, bosherr.WrapError(err, "Creating provenance file")
+	}
+
+	fileName := file.Name()
+
+	defer func() {
+		_ = c.fs.RemoveAll(fileName)
+	}()
+
+	err = file.Close()
+	if err != nil {
+		return "", boshcrypto.MultipleDigest{}, bosherr.WrapError(err, "Closing provenance file")
+	}
+
+	err = c.fs.WriteFile(fileName, contents)
+	if err != nil {
+		return "", boshcrypto.MultipleDigest{}, bosherr.WrapError(err, "Writing provenance file")
+	}
+
+	blobID, digest, err := c.blobstore.Create(fileName)
+	if err != nil {
+		return "", boshcrypto.MultipleDigest{}, bosherr.WrapError(err, "Uploading provenance")
+	}
+
+	return blobID, digest, nil
+}
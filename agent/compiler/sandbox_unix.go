@@ -0,0 +1,202 @@
+// +build !windows
+
+package compiler
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	// sandboxCgroupPrefix names the cgroup each limited packaging script
+	// runs in, followed by the package name
+	sandboxCgroupPrefix = "bosh-compilation-"
+
+	// sandboxTimeoutExitStatus is what timeout exits with when the
+	// packaging script did not finish in time
+	sandboxTimeoutExitStatus = 124
+
+	// sandboxKillAfterSeconds is how long a timed out packaging script
+	// may take to exit after being terminated before it is killed
+	sandboxKillAfterSeconds = 30
+)
+
+// sandboxScript runs as pid 1 of the new namespaces. It builds a root on a
+// tmpfs from read-only binds of the system directories and of each "ro:"
+// argument, writable binds of each "rw:" argument and fresh /dev, /proc and
+// /tmp, and then runs the packaging script chrooted into it without any
+// capabilities, so that it can neither undo the mounts nor regain them by
+// executing setuid binaries. Mounts made here are private to the mount
+// namespace and vanish with it.
+const sandboxScript = `set -e
+root=$1
+shift
+
+mount -t tmpfs -o mode=0755 bosh-sandbox "$root"
+
+bind() {
+  mkdir -p "$root$2"
+  mount --rbind "$2" "$root$2"
+  if [ "$1" = ro ]; then
+    mount -o remount,bind,ro "$root$2"
+  fi
+}
+
+for dir in /bin /sbin /lib /lib32 /lib64 /libx32 /usr /etc; do
+  if [ -L "$dir" ]; then
+    ln -s "$(readlink "$dir")" "$root$dir"
+  elif [ -d "$dir" ]; then
+    bind ro "$dir"
+  fi
+done
+
+mkdir -p "$root/dev" "$root/proc" "$root/tmp"
+for dev in null zero full random urandom tty; do
+  touch "$root/dev/$dev"
+  mount --bind "/dev/$dev" "$root/dev/$dev"
+done
+ln -s /proc/self/fd "$root/dev/fd"
+mount -t proc proc "$root/proc"
+mount -t tmpfs -o mode=1777 tmp "$root/tmp"
+
+for spec in "$@"; do
+  bind "${spec%%%%:*}" "${spec#*:}"
+done
+
+exec chroot "$root" ` + sandboxDropPrivileges + ` /bin/bash -c 'cd "$BOSH_COMPILE_TARGET" && exec bash -x %s'
+`
+
+// sandboxDropPrivileges empties the capability bounding set, which makes
+// root lose all capabilities on exec, and sets no_new_privs for good
+const sandboxDropPrivileges = "setpriv --no-new-privs --bounding-set=-all --inh-caps=-all --"
+
+type sandbox struct {
+	options   SandboxOptions
+	root      string
+	cgroup    string
+	procsFile string
+	readOnly  []string
+	writable  []string
+}
+
+// prepareSandbox lets the packaging script see its dependencies through the
+// packages dir and write only to its install target and compile dir
+func (c concreteCompiler) prepareSandbox(compilePath, installPath, enablePath string, pkg Package) (sandbox, error) {
+	err := c.checkSandboxTools()
+	if err != nil {
+		return sandbox{}, err
+	}
+
+	s := sandbox{
+		options:  c.options.Sandbox,
+		root:     compilePath + "-bosh-agent-sandbox",
+		readOnly: []string{path.Dir(enablePath), path.Dir(path.Dir(installPath))},
+		writable: []string{installPath, compilePath},
+	}
+
+	if s.options.TimeoutSeconds <= 0 {
+		s.options.TimeoutSeconds = DefaultSandboxTimeoutSeconds
+	}
+
+	// Each compilation has its own cgroup so that its cpu usage is neither
+	// cumulative nor mixed with concurrent compilations
+	if !s.options.Limits.Empty() {
+		s.cgroup = sandboxCgroupPrefix + pkg.Name
+
+		err = c.cgroups.AddJob(s.cgroup, s.options.Limits)
+		if err != nil {
+			return sandbox{}, bosherr.WrapError(err, "Limiting resources of packaging script")
+		}
+
+		s.procsFile = c.cgroups.ProcsFile(s.cgroup)
+	}
+
+	err = c.fs.MkdirAll(s.root, 0700)
+	if err != nil {
+		return sandbox{}, bosherr.WrapError(err, "Creating sandbox root")
+	}
+
+	return s, nil
+}
+
+// checkSandboxTools fails unless unshare supports --kill-child, which
+// util-linux added in 2.32, since the sandbox relies on it to not leak
+// processes, and unless setpriv can drop the capabilities of packaging scripts
+func (c concreteCompiler) checkSandboxTools() error {
+	stdout, _, _, err := c.toolRunner.RunCommandQuietly("unshare", "--help")
+	if err != nil {
+		return bosherr.WrapError(err, "Sandboxing packaging scripts requires unshare from util-linux 2.32 or greater")
+	}
+
+	if !strings.Contains(stdout, "--kill-child") {
+		return bosherr.Error("Sandboxing packaging scripts requires unshare from util-linux 2.32 or greater, which supports --kill-child")
+	}
+
+	stdout, _, _, err = c.toolRunner.RunCommandQuietly("setpriv", "--help")
+	if err != nil {
+		return bosherr.WrapError(err, "Sandboxing packaging scripts requires setpriv from util-linux")
+	}
+
+	if !strings.Contains(stdout, "--bounding-set") || !strings.Contains(stdout, "--no-new-privs") {
+		return bosherr.Error("Sandboxing packaging scripts requires setpriv from util-linux, which supports --bounding-set and --no-new-privs")
+	}
+
+	return nil
+}
+
+// cleanUpSandbox removes the sandbox root and cgroup, which may fail while
+// processes the packaging script left behind are still exiting
+func (c concreteCompiler) cleanUpSandbox(s sandbox) {
+	_ = c.fs.RemoveAll(s.root)
+
+	if s.cgroup != "" {
+		_ = c.cgroups.RemoveJob(s.cgroup)
+	}
+}
+
+// wrap runs the command's packaging script in the sandbox, keeping its env
+func (s sandbox) wrap(command boshsys.Command) boshsys.Command {
+	unshare := []string{"unshare", "--mount", "--pid", "--ipc", "--uts"}
+	if !s.options.Network {
+		unshare = append(unshare, "--net")
+	}
+
+	unshare = append(unshare, "--fork", "--kill-child", "--", "bash", "-c", fmt.Sprintf(sandboxScript, PackagingScriptName), "bosh-sandbox", s.root)
+
+	for _, dir := range s.readOnly {
+		unshare = append(unshare, "ro:"+dir)
+	}
+
+	for _, dir := range s.writable {
+		unshare = append(unshare, "rw:"+dir)
+	}
+
+	args := append([]string{
+		"--kill-after=" + strconv.Itoa(sandboxKillAfterSeconds),
+		strconv.Itoa(s.options.TimeoutSeconds),
+	}, unshare...)
+
+	command.Name = "timeout"
+	command.Args = args
+
+	// Joining the cgroup before exec'ing limits everything forked later
+	if s.procsFile != "" {
+		command.Name = "sh"
+		command.Args = append([]string{"-c", `echo $$ > "$1" && shift && exec "$@"`, "bosh-sandbox", s.procsFile, "timeout"}, args...)
+	}
+
+	return command
+}
+
+func (s sandbox) report() *SandboxReport {
+	return &SandboxReport{
+		Network:        s.options.Network,
+		TimeoutSeconds: s.options.TimeoutSeconds,
+		Limits:         s.options.Limits,
+	}
+}
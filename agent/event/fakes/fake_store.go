@@ -0,0 +1,55 @@
+package fakes
+
+import (
+	"sync"
+
+	boshevent "github.com/cloudfoundry/bosh-agent/agent/event"
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+)
+
+type FakeStore struct {
+	lock sync.Mutex
+
+	Events []boshevent.Event
+	AddErr error
+
+	SinceID  int64
+	SinceErr error
+}
+
+func (s *FakeStore) Add(jobEvent boshjobsuper.JobEvent) (boshevent.Event, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.AddErr != nil {
+		return boshevent.Event{}, s.AddErr
+	}
+
+	event := boshevent.Event{ID: int64(len(s.Events) + 1), JobEvent: jobEvent}
+	s.Events = append(s.Events, event)
+
+	return event, nil
+}
+
+func (s *FakeStore) Since(id int64) ([]boshevent.Event, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.SinceID = id
+
+	since := []boshevent.Event{}
+	for _, event := range s.Events {
+		if event.ID > id {
+			since = append(since, event)
+		}
+	}
+
+	return since, s.SinceErr
+}
+
+func (s *FakeStore) AddedEvents() []boshevent.Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]boshevent.Event{}, s.Events...)
+}
@@ -0,0 +1,218 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sync"
+
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// DefaultCapacity is the number of events kept for get_events
+const DefaultCapacity = 1000
+
+// Event is a job event numbered in the order it was added; the numbers
+// let the director ask for the events it has not seen yet
+type Event struct {
+	ID int64 `json:"id"`
+
+	boshjobsuper.JobEvent
+}
+
+type Store interface {
+	// Add numbers and keeps an event, dropping the oldest events over capacity
+	Add(jobEvent boshjobsuper.JobEvent) (Event, error)
+
+	// Since returns the kept events numbered after id, oldest first
+	Since(id int64) ([]Event, error)
+}
+
+type fileStore struct {
+	fs       boshsys.FileSystem
+	path     string
+	capacity int
+
+	lock   sync.Mutex
+	loaded bool
+
+	// events are the kept events, oldest first; lines counts the events in
+	// the file, which also holds dropped events until it is compacted
+	events []Event
+	lines  int
+}
+
+// NewFileStore keeps events in a file so that they survive agent restarts.
+// Events are appended to the file one json line at a time; the file is
+// only rewritten with the kept events once it holds twice as many.
+func NewFileStore(fs boshsys.FileSystem, path string, capacity int) Store {
+	return &fileStore{fs: fs, path: path, capacity: capacity}
+}
+
+func (s *fileStore) Add(jobEvent boshjobsuper.JobEvent) (Event, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.load()
+	if err != nil {
+		return Event{}, err
+	}
+
+	event := Event{ID: 1, JobEvent: jobEvent}
+	if len(s.events) > 0 {
+		event.ID = s.events[len(s.events)-1].ID + 1
+	}
+
+	events := append(append([]Event{}, s.events...), event)
+	if len(events) > s.capacity {
+		events = events[len(events)-s.capacity:]
+	}
+
+	if s.lines+1 > 2*s.capacity {
+		err = s.write(events)
+		if err != nil {
+			return Event{}, err
+		}
+
+		s.lines = len(events)
+	} else {
+		err = s.append(event)
+		if err != nil {
+			return Event{}, err
+		}
+
+		s.lines++
+	}
+
+	s.events = events
+
+	return event, nil
+}
+
+func (s *fileStore) Since(id int64) ([]Event, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	since := []Event{}
+	for _, event := range s.events {
+		if event.ID > id {
+			since = append(since, event)
+		}
+	}
+
+	return since, nil
+}
+
+// load reads the events file once; later changes are kept in memory
+func (s *fileStore) load() error {
+	if s.loaded {
+		return nil
+	}
+
+	events := []Event{}
+	lines := 0
+	incomplete := false
+
+	if s.fs.FileExists(s.path) {
+		contents, err := s.fs.ReadFile(s.path)
+		if err != nil {
+			return bosherr.WrapError(err, "Reading events")
+		}
+
+		for _, line := range bytes.SplitAfter(contents, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+
+			var event Event
+
+			err = json.Unmarshal(line, &event)
+			if err != nil {
+				// The last line is incomplete when the agent stopped while appending it
+				if !bytes.HasSuffix(line, []byte("\n")) {
+					incomplete = true
+					break
+				}
+
+				return bosherr.WrapError(err, "Unmarshalling events")
+			}
+
+			events = append(events, event)
+			lines++
+		}
+	}
+
+	if len(events) > s.capacity {
+		events = events[len(events)-s.capacity:]
+	}
+
+	// Events appended after an incomplete line would end up on the same line
+	if incomplete {
+		err := s.write(events)
+		if err != nil {
+			return err
+		}
+
+		lines = len(events)
+	}
+
+	s.events = events
+	s.lines = lines
+	s.loaded = true
+
+	return nil
+}
+
+func (s *fileStore) append(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling event")
+	}
+
+	file, err := s.fs.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing events")
+	}
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		_ = file.Close()
+		return bosherr.WrapError(err, "Writing events")
+	}
+
+	err = file.Close()
+	if err != nil {
+		return bosherr.WrapError(err, "Writing events")
+	}
+
+	return nil
+}
+
+// write replaces the file with the given events, dropping the others
+func (s *fileStore) write(events []Event) error {
+	var contents bytes.Buffer
+
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return bosherr.WrapError(err, "Marshalling event")
+		}
+
+		contents.Write(line)
+		contents.WriteByte('\n')
+	}
+
+	err := s.fs.WriteFile(s.path, contents.Bytes())
+	if err != nil {
+		return bosherr.WrapError(err, "Writing events")
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package event_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/event"
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+var _ = Describe("FileStore", func() {
+	var (
+		fs    boshsys.FileSystem
+		dir   string
+		path  string
+		store Store
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "event-store")
+		Expect(err).ToNot(HaveOccurred())
+
+		fs = boshsys.NewOsFileSystem(boshlog.NewLogger(boshlog.LevelNone))
+		path = filepath.Join(dir, "job_events.jsonl")
+		store = NewFileStore(fs, path, 3)
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	jobEvent := func(process string) boshjobsuper.JobEvent {
+		return boshjobsuper.JobEvent{Process: process, Type: "running", Timestamp: 1456826400}
+	}
+
+	addEvents := func(processes ...string) {
+		for _, process := range processes {
+			_, err := store.Add(jobEvent(process))
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	fileLines := func() []string {
+		contents, err := ioutil.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		return strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	}
+
+	It("numbers events in the order they were added", func() {
+		Expect(store.Add(jobEvent("redis"))).To(Equal(Event{ID: 1, JobEvent: jobEvent("redis")}))
+		Expect(store.Add(jobEvent("nginx"))).To(Equal(Event{ID: 2, JobEvent: jobEvent("nginx")}))
+
+		Expect(store.Since(0)).To(Equal([]Event{
+			{ID: 1, JobEvent: jobEvent("redis")},
+			{ID: 2, JobEvent: jobEvent("nginx")},
+		}))
+		Expect(store.Since(1)).To(Equal([]Event{{ID: 2, JobEvent: jobEvent("nginx")}}))
+		Expect(store.Since(2)).To(BeEmpty())
+	})
+
+	It("keeps events across instances", func() {
+		addEvents("redis")
+
+		store = NewFileStore(fs, path, 3)
+
+		Expect(store.Add(jobEvent("nginx"))).To(Equal(Event{ID: 2, JobEvent: jobEvent("nginx")}))
+		Expect(store.Since(0)).To(HaveLen(2))
+	})
+
+	It("appends events to the file one line at a time", func() {
+		addEvents("redis", "nginx")
+
+		Expect(fileLines()).To(Equal([]string{
+			`{"id":1,"process":"redis","type":"running","timestamp":1456826400}`,
+			`{"id":2,"process":"nginx","type":"running","timestamp":1456826400}`,
+		}))
+	})
+
+	It("drops the oldest events over capacity", func() {
+		addEvents("a", "b", "c", "d")
+
+		events, err := store.Since(0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(3))
+		Expect(events[0]).To(Equal(Event{ID: 2, JobEvent: jobEvent("b")}))
+		Expect(events[2]).To(Equal(Event{ID: 4, JobEvent: jobEvent("d")}))
+
+		store = NewFileStore(fs, path, 3)
+		Expect(store.Since(0)).To(Equal(events))
+	})
+
+	It("compacts the file once it holds twice as many events as kept", func() {
+		addEvents("a", "b", "c", "d", "e", "f")
+		Expect(fileLines()).To(HaveLen(6))
+
+		addEvents("g")
+		Expect(fileLines()).To(HaveLen(3))
+
+		events, err := NewFileStore(fs, path, 3).Since(0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events[0]).To(Equal(Event{ID: 5, JobEvent: jobEvent("e")}))
+		Expect(events[2]).To(Equal(Event{ID: 7, JobEvent: jobEvent("g")}))
+	})
+
+	It("ignores an incomplete last line", func() {
+		Expect(ioutil.WriteFile(path, []byte(`{"id":1,"process":"redis"}`+"\n"+`{"id":2,"pro`), 0644)).To(Succeed())
+
+		Expect(store.Since(0)).To(Equal([]Event{{ID: 1, JobEvent: boshjobsuper.JobEvent{Process: "redis"}}}))
+	})
+
+	It("drops an incomplete last line so that events added later can be loaded again", func() {
+		Expect(ioutil.WriteFile(path, []byte(`{"id":1,"process":"redis"}`+"\n"+`{"id":2,"pro`), 0644)).To(Succeed())
+
+		addEvents("a")
+
+		store = NewFileStore(fs, path, 3)
+
+		Expect(store.Since(0)).To(Equal([]Event{
+			{ID: 1, JobEvent: boshjobsuper.JobEvent{Process: "redis"}},
+			{ID: 2, JobEvent: jobEvent("a")},
+		}))
+		Expect(fileLines()).To(HaveLen(2))
+	})
+
+	It("returns an error when events cannot be written", func() {
+		Expect(os.Mkdir(path, 0755)).To(Succeed())
+
+		_, err := store.Add(jobEvent("redis"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Reading events"))
+
+		store = NewFileStore(fs, filepath.Join(dir, "missing", "job_events.jsonl"), 3)
+
+		_, err = store.Add(jobEvent("redis"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Writing events"))
+	})
+
+	It("returns an error when the events file is invalid", func() {
+		Expect(ioutil.WriteFile(path, []byte("{\n"), 0644)).To(Succeed())
+
+		_, err := store.Since(0)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unmarshalling events"))
+	})
+})
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"github.com/cloudfoundry/bosh-agent/infrastructure/agentlogger"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
 )
@@ -57,6 +58,7 @@ func (service asyncTaskService) CreateTaskWithID(
 		Func:       taskFunc,
 		CancelFunc: cancelFunc,
 		EndFunc:    endFunc,
+		Progress:   NewProgress(DefaultProgressLines),
 	}
 }
 
@@ -104,7 +106,11 @@ func (service asyncTaskService) processTasks() {
 		if err != nil {
 			task.Error = err
 			task.State = StateFailed
-			service.logger.Error("Task Service", "Failed processing task #%s got: %s", task.ID, err.Error())
+			logger := agentlogger.WithFields(service.logger, agentlogger.Fields{
+				agentlogger.RequestIDField: task.RequestID,
+				agentlogger.TaskIDField:    task.ID,
+			})
+			logger.Error("Task Service", "Failed processing task #%s got: %s", task.ID, err.Error())
 		} else {
 			task.Value = value
 			task.State = StateDone
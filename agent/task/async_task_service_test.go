@@ -209,6 +209,7 @@ func init() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(task.ID).To(Equal("fake-uuid"))
 				Expect(task.State).To(Equal(StateRunning))
+				Expect(task.Progress.Lines()).To(BeEmpty())
 
 				task.Func()
 				Expect(runFuncCalled).To(BeTrue())
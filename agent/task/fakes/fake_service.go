@@ -39,6 +39,7 @@ func (s *FakeService) CreateTaskWithID(
 		Func:       taskFunc,
 		CancelFunc: cancelFunc,
 		EndFunc:    endFunc,
+		Progress:   boshtask.NewProgress(boshtask.DefaultProgressLines),
 	}
 }
 
@@ -6,9 +6,10 @@ import (
 )
 
 type Info struct {
-	TaskID  string
-	Method  string
-	Payload []byte
+	TaskID    string
+	RequestID string
+	Method    string
+	Payload   []byte
 }
 
 type ManagerProvider interface {
@@ -0,0 +1,71 @@
+package task
+
+import (
+	"bytes"
+	"sync"
+)
+
+// DefaultProgressLines is the number of output lines kept for a running task
+const DefaultProgressLines = 100
+
+// MaxProgressLineBytes is the length at which lines are split so that output
+// without newlines does not grow without bound
+const MaxProgressLineBytes = 4096
+
+// Progress keeps the last lines of output written by a running task
+// so that they can be reported by get_task before the task finishes
+type Progress struct {
+	maxLines int
+
+	lock    sync.Mutex
+	lines   []string
+	partial []byte
+}
+
+func NewProgress(maxLines int) *Progress {
+	if maxLines <= 0 {
+		maxLines = DefaultProgressLines
+	}
+
+	return &Progress{maxLines: maxLines}
+}
+
+func (p *Progress) Write(data []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.partial = append(p.partial, data...)
+
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i >= 0 && i <= MaxProgressLineBytes {
+			p.lines = append(p.lines, string(bytes.TrimRight(p.partial[:i], "\r")))
+			p.partial = p.partial[i+1:]
+		} else if len(p.partial) > MaxProgressLineBytes {
+			p.lines = append(p.lines, string(p.partial[:MaxProgressLineBytes]))
+			p.partial = p.partial[MaxProgressLineBytes:]
+		} else {
+			break
+		}
+	}
+
+	p.partial = append([]byte{}, p.partial...)
+
+	if len(p.lines) > p.maxLines {
+		p.lines = append([]string{}, p.lines[len(p.lines)-p.maxLines:]...)
+	}
+
+	return len(data), nil
+}
+
+// Lines returns the complete lines written so far, oldest first
+func (p *Progress) Lines() []string {
+	if p == nil {
+		return nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]string{}, p.lines...)
+}
@@ -0,0 +1,88 @@
+package task_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/task"
+)
+
+var _ = Describe("Progress", func() {
+	var (
+		progress *Progress
+	)
+
+	BeforeEach(func() {
+		progress = NewProgress(3)
+	})
+
+	It("keeps complete lines written in several chunks", func() {
+		for _, chunk := range []string{"first li", "ne\r\nsecond line\n", "third"} {
+			n, err := progress.Write([]byte(chunk))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(len(chunk)))
+		}
+
+		Expect(progress.Lines()).To(Equal([]string{"first line", "second line"}))
+	})
+
+	It("keeps only the last lines", func() {
+		_, err := progress.Write([]byte("1\n2\n3\n4\n5\n"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(progress.Lines()).To(Equal([]string{"3", "4", "5"}))
+	})
+
+	It("keeps the default number of lines when no number is given", func() {
+		progress = NewProgress(0)
+
+		for i := 0; i < DefaultProgressLines+1; i++ {
+			_, err := fmt.Fprintf(progress, "%d\n", i)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		lines := progress.Lines()
+		Expect(lines).To(HaveLen(DefaultProgressLines))
+		Expect(lines[0]).To(Equal("1"))
+	})
+
+	It("splits lines longer than the maximum so that output without newlines does not grow without bound", func() {
+		_, err := progress.Write([]byte(strings.Repeat("x", 2*MaxProgressLineBytes+1)))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = progress.Write([]byte("y\n"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(progress.Lines()).To(Equal([]string{
+			strings.Repeat("x", MaxProgressLineBytes),
+			strings.Repeat("x", MaxProgressLineBytes),
+			"xy",
+		}))
+	})
+
+	It("can be written concurrently", func() {
+		progress = NewProgress(100)
+
+		wg := &sync.WaitGroup{}
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, _ = fmt.Fprintf(progress, "%d\n", i)
+			}(i)
+		}
+
+		wg.Wait()
+		Expect(progress.Lines()).To(HaveLen(10))
+	})
+
+	It("has no lines when there is no progress", func() {
+		var noProgress *Progress
+		Expect(noProgress.Lines()).To(BeNil())
+	})
+})
@@ -15,10 +15,14 @@ const (
 )
 
 type Task struct {
-	ID    string
-	State State
-	Value interface{}
-	Error error
+	ID        string
+	RequestID string
+	State     State
+	Value     interface{}
+	Error     error
+
+	// Progress receives the output of the task while it runs
+	Progress *Progress
 
 	Func       Func
 	CancelFunc CancelFunc
@@ -33,6 +37,7 @@ func (t Task) Cancel() error {
 }
 
 type StateValue struct {
-	AgentTaskID string `json:"agent_task_id"`
-	State       State  `json:"state"`
+	AgentTaskID string   `json:"agent_task_id"`
+	State       State    `json:"state"`
+	Progress    []string `json:"progress,omitempty"`
 }
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"net"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/pivotal-golang/clock"
 
 	boshagent "github.com/cloudfoundry/bosh-agent/agent"
 	boshaction "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	boshapplier "github.com/cloudfoundry/bosh-agent/agent/applier"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
@@ -18,18 +20,22 @@ import (
 	boshagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore"
 	boshrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	boshevent "github.com/cloudfoundry/bosh-agent/agent/event"
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
 	boshmonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
 	boshmbus "github.com/cloudfoundry/bosh-agent/mbus"
 	boshnotif "github.com/cloudfoundry/bosh-agent/notification"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshsigar "github.com/cloudfoundry/bosh-agent/sigar"
 	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
+	boshforwarder "github.com/cloudfoundry/bosh-agent/syslog/forwarder"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -41,16 +47,21 @@ import (
 type App interface {
 	Setup(opts Options) error
 	Run() error
+	Stop() error
 	GetPlatform() boshplatform.Platform
 }
 
 type app struct {
-	logger      boshlog.Logger
-	agent       boshagent.Agent
-	platform    boshplatform.Platform
-	fs          boshsys.FileSystem
-	logTag      string
-	dirProvider boshdirs.Provider
+	logger       boshlog.Logger
+	agent        boshagent.Agent
+	logForwarder boshforwarder.Forwarder
+	platform     boshplatform.Platform
+	forwarding   sync.WaitGroup
+	forwarderMu  sync.Mutex
+	stopped      bool
+	fs           boshsys.FileSystem
+	logTag       string
+	dirProvider  boshdirs.Provider
 }
 
 func New(logger boshlog.Logger, fs boshsys.FileSystem) App {
@@ -121,7 +132,7 @@ func (app *app) Setup(opts Options) error {
 	}
 
 	blobManager := boshblob.NewBlobManager(app.platform.GetFs(), app.dirProvider.BlobsDir())
-	blobstore, err := app.setupBlobstore(settingsService.GetSettings().Blobstore, blobManager)
+	blobstore, err := app.setupBlobstore(settingsService.GetSettings().Blobstore, blobManager, config.Blobstore, timeService)
 
 	if err != nil {
 		return bosherr.WrapError(err, "Getting blobstore")
@@ -149,7 +160,7 @@ func (app *app) Setup(opts Options) error {
 
 	notifier := boshnotif.NewNotifier(mbusHandler)
 
-	applier, compiler := app.buildApplierAndCompiler(app.dirProvider, blobstore, jobSupervisor)
+	applier, compiler, jobsBc, packagesBc := app.buildApplierAndCompiler(app.dirProvider, blobstore, jobSupervisor, config.Applier, config.Archive, config.Compiler, timeService)
 
 	uuidGen := boshuuid.NewGenerator()
 
@@ -167,6 +178,12 @@ func (app *app) Setup(opts Options) error {
 		specFilePath,
 	)
 
+	eventStore := boshevent.NewFileStore(
+		app.platform.GetFs(),
+		filepath.Join(app.dirProvider.BoshDir(), "job_events.jsonl"),
+		boshevent.DefaultCapacity,
+	)
+
 	jobScriptProvider := boshscript.NewConcreteJobScriptProvider(
 		app.platform.GetRunner(),
 		app.platform.GetFs(),
@@ -186,7 +203,11 @@ func (app *app) Setup(opts Options) error {
 		compiler,
 		jobSupervisor,
 		specService,
+		jobsBc,
+		packagesBc,
 		jobScriptProvider,
+		eventStore,
+		config.Archive,
 		app.logger,
 	)
 
@@ -200,7 +221,25 @@ func (app *app) Setup(opts Options) error {
 		actionRunner,
 	)
 
-	syslogServer := boshsyslog.NewServer(33331, net.Listen, app.logger)
+	syslogServer := boshsyslog.NewServer(33331, net.Listen, net.ListenPacket, app.logger)
+
+	syslogRules, err := boshalert.NewSyslogRules(config.Alert.SyslogRules)
+	if err != nil {
+		return bosherr.WrapError(err, "Building syslog alert rules")
+	}
+
+	agentSettings := settingsService.GetSettings()
+	if agentSettings.Env.Bosh.Syslog.Enabled() {
+		app.forwarderMu.Lock()
+		app.logForwarder = boshforwarder.NewForwarder(
+			agentSettings.Env.Bosh.Syslog,
+			agentSettings.AgentID,
+			app.dirProvider,
+			timeService,
+			app.logger,
+		)
+		app.forwarderMu.Unlock()
+	}
 
 	app.agent = boshagent.New(
 		app.logger,
@@ -209,7 +248,9 @@ func (app *app) Setup(opts Options) error {
 		actionDispatcher,
 		jobSupervisor,
 		specService,
+		eventStore,
 		syslogServer,
+		syslogRules,
 		time.Second*30,
 		settingsService,
 		uuidGen,
@@ -220,6 +261,9 @@ func (app *app) Setup(opts Options) error {
 }
 
 func (app *app) Run() error {
+	app.startLogForwarder()
+	defer func() { _ = app.Stop() }()
+
 	err := app.agent.Run()
 	if err != nil {
 		return bosherr.WrapError(err, "Running agent")
@@ -227,6 +271,46 @@ func (app *app) Run() error {
 	return nil
 }
 
+func (app *app) startLogForwarder() {
+	app.forwarderMu.Lock()
+	defer app.forwarderMu.Unlock()
+
+	if app.logForwarder == nil || app.stopped {
+		return
+	}
+
+	app.forwarding.Add(1)
+	go func() {
+		defer app.forwarding.Done()
+		err := app.logForwarder.Start()
+		if err != nil {
+			app.logger.Error(app.logTag, "Forwarding logs: %s", err.Error())
+		}
+	}()
+}
+
+// Stop stops forwarding logs and waits until the forwarder
+// has saved its position in the disk buffer
+func (app *app) Stop() error {
+	app.forwarderMu.Lock()
+	app.stopped = true
+	logForwarder := app.logForwarder
+	app.forwarderMu.Unlock()
+
+	if logForwarder == nil {
+		return nil
+	}
+
+	err := logForwarder.Stop()
+	if err != nil {
+		return bosherr.WrapError(err, "Stopping log forwarder")
+	}
+
+	app.forwarding.Wait()
+
+	return nil
+}
+
 func (app *app) GetPlatform() boshplatform.Platform {
 	return app.platform
 }
@@ -235,7 +319,11 @@ func (app *app) buildApplierAndCompiler(
 	dirProvider boshdirs.Provider,
 	blobstore boshblob.DigestBlobstore,
 	jobSupervisor boshjobsuper.JobSupervisor,
-) (boshapplier.Applier, boshcomp.Compiler) {
+	applierOptions boshapplier.Options,
+	archiveOptions bosharchive.Options,
+	compilerOptions boshcomp.Options,
+	timeService clock.Clock,
+) (boshapplier.Applier, boshcomp.Compiler, boshbc.BundleCollection, boshbc.BundleCollection) {
 	fileSystem := app.platform.GetFs()
 
 	jobsBc := boshbc.NewFileBundleCollection(
@@ -273,6 +361,7 @@ func (app *app) buildApplierAndCompiler(
 		app.platform,
 		jobSupervisor,
 		dirProvider,
+		applierOptions,
 	)
 
 	cmdRunner := boshrunner.NewFileLoggingCmdRunner(
@@ -284,15 +373,20 @@ func (app *app) buildApplierAndCompiler(
 
 	compiler := boshcomp.NewConcreteCompiler(
 		app.platform.GetCompressor(),
+		archiveOptions.CompiledPackagesFormat,
 		blobstore,
 		fileSystem,
 		cmdRunner,
+		app.platform.GetRunner(),
 		dirProvider,
 		packageApplierProvider.Root(),
 		packageApplierProvider.RootBundleCollection(),
+		boshcgroup.NewManager(fileSystem, boshcgroup.DefaultRoot, app.logger),
+		timeService,
+		compilerOptions,
 	)
 
-	return applier, compiler
+	return applier, compiler, jobsBc, packageApplierProvider.RootBundleCollection()
 }
 
 func (app *app) loadConfig(path string) (Config, error) {
@@ -317,7 +411,12 @@ func (app *app) fileContents(path string) string {
 	return contents
 }
 
-func (app *app) setupBlobstore(blobstoreSettings boshsettings.Blobstore, blobManager boshblob.BlobManagerInterface) (boshblob.DigestBlobstore, error) {
+func (app *app) setupBlobstore(
+	blobstoreSettings boshsettings.Blobstore,
+	blobManager boshblob.BlobManagerInterface,
+	options boshagentblobstore.Options,
+	timeService clock.Clock,
+) (boshblob.DigestBlobstore, error) {
 	blobstoreProvider := boshblob.NewProvider(
 		app.platform.GetFs(),
 		app.platform.GetRunner(),
@@ -330,5 +429,21 @@ func (app *app) setupBlobstore(blobstoreSettings boshsettings.Blobstore, blobMan
 		return nil, bosherr.WrapError(err, "Getting blobstore")
 	}
 
+	cacheQuotaMB := options.CacheQuotaMB
+	if cacheQuotaMB == 0 {
+		cacheQuotaMB = boshagentblobstore.DefaultCacheQuotaMB
+	}
+
+	if cacheQuotaMB > 0 {
+		blobstore = boshagentblobstore.NewCachingBlobstore(
+			blobstore,
+			app.platform.GetFs(),
+			app.dirProvider.BlobsCacheDir(),
+			int64(cacheQuotaMB)*1024*1024,
+			timeService,
+			app.logger,
+		)
+	}
+
 	return boshagentblobstore.NewCascadingBlobstore(blobstore, blobManager, app.logger), nil
 }
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -232,4 +233,60 @@ func init() {
 			})
 		})
 	})
+
+	Describe("Stop", func() {
+		var (
+			logForwarder *stoppableForwarder
+			logger       *loggerfakes.FakeLogger
+		)
+
+		BeforeEach(func() {
+			logForwarder = &stoppableForwarder{
+				stopCh:  make(chan struct{}),
+				stopped: make(chan struct{}),
+			}
+			logger = &loggerfakes.FakeLogger{}
+		})
+
+		It("stops the log forwarder and waits until it has stopped", func() {
+			a := &app{logger: logger, logForwarder: logForwarder, logTag: "App"}
+			a.startLogForwarder()
+
+			err := a.Stop()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logForwarder.stopped).To(BeClosed())
+		})
+
+		It("does not start the log forwarder once stopped", func() {
+			a := &app{logger: logger, logForwarder: logForwarder, logTag: "App"}
+
+			err := a.Stop()
+			Expect(err).ToNot(HaveOccurred())
+
+			a.startLogForwarder()
+			Consistently(logForwarder.stopped).ShouldNot(BeClosed())
+		})
+
+		It("does nothing without a log forwarder", func() {
+			a := &app{logger: logger, logTag: "App"}
+			Expect(a.Stop()).To(Succeed())
+		})
+	})
+}
+
+type stoppableForwarder struct {
+	stopCh  chan struct{}
+	stopped chan struct{}
+}
+
+func (f *stoppableForwarder) Start() error {
+	<-f.stopCh
+	time.Sleep(10 * time.Millisecond)
+	close(f.stopped)
+	return nil
+}
+
+func (f *stoppableForwarder) Stop() error {
+	close(f.stopCh)
+	return nil
 }
@@ -3,8 +3,13 @@ package app
 import (
 	"encoding/json"
 
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshapplier "github.com/cloudfoundry/bosh-agent/agent/applier"
+	boshagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore"
+	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
@@ -12,6 +17,11 @@ import (
 type Config struct {
 	Platform       boshplatform.Options
 	Infrastructure boshinf.Options
+	Alert          boshalert.Options
+	Applier        boshapplier.Options
+	Blobstore      boshagentblobstore.Options
+	Archive        bosharchive.Options
+	Compiler       boshcomp.Options
 }
 
 func LoadConfigFromPath(fs boshsys.FileSystem, path string) (Config, error) {
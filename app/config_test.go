@@ -4,8 +4,14 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshapplier "github.com/cloudfoundry/bosh-agent/agent/applier"
+	boshagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore"
+	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
 
@@ -63,6 +69,33 @@ var _ = Describe("LoadConfigFromPath", func() {
 				  "UseServerName": true,
 				  "UseRegistry": true
 				}
+			},
+			"Alert": {
+				"SyslogRules": [
+					{
+						"Expression": "sudo: +(?P<user>\\S+) :",
+						"Title": "Sudo by ${user}",
+						"Severity": 3
+					}
+				]
+			},
+			"Applier": {
+				"Parallelism": 8
+			},
+			"Blobstore": {
+				"CacheQuotaMB": 512
+			},
+			"Archive": {
+				"CompiledPackagesFormat": "zstd",
+				"LogsFormat": "gzip"
+			},
+			"Compiler": {
+				"Parallelism": 4,
+				"Sandbox": {
+					"Enabled": true,
+					"TimeoutSeconds": 600,
+					"Limits": {"memory_max_mb": 2048, "pids_max": 512}
+				}
 			}
 		}`)
 
@@ -108,6 +141,33 @@ var _ = Describe("LoadConfigFromPath", func() {
 					UseRegistry:   true,
 				},
 			},
+			Alert: boshalert.Options{
+				SyslogRules: []boshalert.SyslogRuleOptions{
+					{
+						Expression: `sudo: +(?P<user>\S+) :`,
+						Title:      "Sudo by ${user}",
+						Severity:   boshalert.SeverityError,
+					},
+				},
+			},
+			Applier: boshapplier.Options{
+				Parallelism: 8,
+			},
+			Blobstore: boshagentblobstore.Options{
+				CacheQuotaMB: 512,
+			},
+			Archive: bosharchive.Options{
+				CompiledPackagesFormat: bosharchive.FormatZstd,
+				LogsFormat:             bosharchive.FormatGzip,
+			},
+			Compiler: boshcomp.Options{
+				Parallelism: 4,
+				Sandbox: boshcomp.SandboxOptions{
+					Enabled:        true,
+					TimeoutSeconds: 600,
+					Limits:         boshcgroup.Limits{MemoryMaxMB: 2048, PidsMax: 512},
+				},
+			},
 		}))
 	})
 
@@ -3,6 +3,13 @@ package app
 import (
 	"flag"
 	"io/ioutil"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
 )
 
 type Options struct {
@@ -11,6 +18,7 @@ type Options struct {
 	BaseDirectory      string
 	JobSupervisor      string
 	ConfigPath         string
+	LogFormat          string
 	VersionCheck       bool
 }
 
@@ -25,6 +33,7 @@ func ParseOptions(args []string) (Options, error) {
 	flagSet.StringVar(&opts.ConfigPath, "C", "", "Config path")
 	flagSet.StringVar(&opts.JobSupervisor, "M", "monit", "Set jobsupervisor")
 	flagSet.StringVar(&opts.BaseDirectory, "b", "/var/vcap", "Set Base Directory")
+	flagSet.StringVar(&opts.LogFormat, "log-format", LogFormatText, "Log format (text or json)")
 	flagSet.BoolVar(&opts.VersionCheck, "v", false, "version")
 
 	// The following two options are accepted but ignored for compatibility with the old agent
@@ -37,6 +46,13 @@ func ParseOptions(args []string) (Options, error) {
 	// cannot call flagSet.Parse in the return statement due to gccgo
 	// execution order issues: https://code.google.com/p/go/issues/detail?id=8698&thanks=8698&ts=1410376474
 	err := flagSet.Parse(args[1:])
+	if err != nil {
+		return opts, err
+	}
+
+	if opts.LogFormat != LogFormatText && opts.LogFormat != LogFormatJSON {
+		return opts, bosherr.Errorf("Unknown log format '%s'", opts.LogFormat)
+	}
 
-	return opts, err
+	return opts, nil
 }
@@ -25,4 +25,18 @@ var _ = Describe("ParseOptions", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(opts.ConfigPath).To(Equal(""))
 	})
+
+	It("parses log format", func() {
+		opts, err := ParseOptions([]string{"bosh-agent", "-log-format", "json"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.LogFormat).To(Equal(LogFormatJSON))
+
+		opts, err = ParseOptions([]string{"bosh-agent"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.LogFormat).To(Equal(LogFormatText))
+
+		_, err = ParseOptions([]string{"bosh-agent", "-log-format", "fake-format"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unknown log format 'fake-format'"))
+	})
 })
@@ -5,6 +5,7 @@ trap {
 }
 
 $env:GOPATH = Join-Path -Path $PWD "gopath"
+$env:GO111MODULE = "off"
 $env:PATH = $env:GOPATH + "/bin;C:/go/bin;C:/var/vcap/bosh/bin;" + $env:PATH
 
 cd $env:GOPATH/src/github.com/cloudfoundry/bosh-agent
@@ -16,7 +17,8 @@ function NeedsToInstallGo() {
         return $true
     }
     $version = "$(go.exe version)"
-    if ($version -match 'go version go1\.[1-7]\.\d windows\/amd64') {
+    # The vendored zstd decoder needs go1.22 or greater
+    if ($version -match 'go version go1\.([0-9]|1[0-9]|2[01])(\.\d+)? windows\/amd64') {
         Write-Host "Installed version of Go is not supported, Go will be updated"
         return $true
     }
@@ -25,9 +27,9 @@ function NeedsToInstallGo() {
 }
 
 if (NeedsToInstallGo) {
-    Write-Host "Installing Go 1.8.3"
+    Write-Host "Installing Go 1.22.12"
 
-    Invoke-WebRequest 'https://storage.googleapis.com/golang/go1.8.3.windows-amd64.msi' `
+    Invoke-WebRequest 'https://dl.google.com/go/go1.22.12.windows-amd64.msi' `
         -UseBasicParsing -OutFile go.msi
 
     $p = Start-Process -FilePath "msiexec" `
@@ -17,8 +17,8 @@ const (
 )
 
 type CommonEventFormat interface {
-	ProduceHTTPRequestEventLog(*http.Request, int, string) (string, error)
-	ProduceNATSRequestEventLog(string, string, string, string, int, string, string) (string, error)
+	ProduceHTTPRequestEventLog(*http.Request, int, string, string) (string, error)
+	ProduceNATSRequestEventLog(string, string, string, string, int, string, string, string) (string, error)
 }
 
 func NewCommonEventFormat() CommonEventFormat {
@@ -27,7 +27,7 @@ func NewCommonEventFormat() CommonEventFormat {
 
 type concreteCommonEventFormat struct{}
 
-func (cef concreteCommonEventFormat) ProduceHTTPRequestEventLog(request *http.Request, respStatusCode int, respBody string) (string, error) {
+func (cef concreteCommonEventFormat) ProduceHTTPRequestEventLog(request *http.Request, respStatusCode int, respBody string, requestID string) (string, error) {
 	name := request.URL.Path
 	severity := 1
 	if respStatusCode >= 400 {
@@ -48,6 +48,10 @@ func (cef concreteCommonEventFormat) ProduceHTTPRequestEventLog(request *http.Re
 		`duser=%s requestMethod=%s src=%s spt=%s shost=%s cs1=%s cs1Label=httpHeaders cs2=basic cs2Label=authType cs3=%v cs3Label=responseStatus `,
 		username, request.Method, strings.Split(request.RemoteAddr, ":")[0], strings.Split(request.RemoteAddr, ":")[1], hostname, headerString, respStatusCode)
 
+	if requestID != "" {
+		extension += fmt.Sprintf("cs5=%s cs5Label=requestID ", requestID)
+	}
+
 	if respStatusCode >= 400 {
 		var buffer bytes.Buffer
 
@@ -59,7 +63,7 @@ func (cef concreteCommonEventFormat) ProduceHTTPRequestEventLog(request *http.Re
 	return fmt.Sprintf("CEF:%v|%s|%s|%s|%s|%s|%v|%s", cefVersion, deviceVendor, deviceProduct, deviceVersion, signatureID, name, severity, extension), nil
 }
 
-func (cef concreteCommonEventFormat) ProduceNATSRequestEventLog(addr string, port string, username string, msgMethod string, severity int, subject string, respBody string) (string, error) {
+func (cef concreteCommonEventFormat) ProduceNATSRequestEventLog(addr string, port string, username string, msgMethod string, severity int, subject string, respBody string, requestID string) (string, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
 		return "", err
@@ -69,6 +73,10 @@ func (cef concreteCommonEventFormat) ProduceNATSRequestEventLog(addr string, por
 		`duser=%s src=%s spt=%s shost=%s `,
 		username, addr, port, hostname)
 
+	if requestID != "" {
+		extension += fmt.Sprintf("cs5=%s cs5Label=requestID ", requestID)
+	}
+
 	if severity >= 7 {
 		var buffer bytes.Buffer
 
@@ -25,7 +25,7 @@ var _ = Describe("CommonEventFormat", func() {
 		})
 
 		It("should produce CEF string", func() {
-			cefLog, err := cef.ProduceHTTPRequestEventLog(request, 201, "{}")
+			cefLog, err := cef.ProduceHTTPRequestEventLog(request, 201, "{}", "")
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(cefLog).To(ContainSubstring("CEF:0|CloudFoundry|BOSH|1|agent_api|/blobs|1|duser=username requestMethod=GET"))
@@ -35,11 +35,19 @@ var _ = Describe("CommonEventFormat", func() {
 			Expect(cefLog).To(ContainSubstring("cs1=HOST=host.example.com&X_REAL_IP=12.12.34.56&X_FORWARDED_FOR=forward&X_FORWARDED_PROTO=proto&USER_AGENT=my.agent cs1Label=httpHeaders"))
 			Expect(cefLog).To(ContainSubstring("cs2=basic cs2Label=authType cs3=201 cs3Label=responseStatus"))
 			Expect(cefLog).NotTo(ContainSubstring("cs4Label=statusReason"))
+			Expect(cefLog).NotTo(ContainSubstring("cs5Label=requestID"))
+		})
+
+		It("includes the request ID when known", func() {
+			cefLog, err := cef.ProduceHTTPRequestEventLog(request, 500, "", "fake-request-id")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cefLog).To(ContainSubstring("cs3=500 cs3Label=responseStatus cs5=fake-request-id cs5Label=requestID cs4= cs4Label=statusReason"))
 		})
 
 		Context("when responding with an error", func() {
 			It("should produce CEF string with severity=7 and statusReason", func() {
-				cefLog, err := cef.ProduceHTTPRequestEventLog(request, 400, `{"reason": "no reason"}`)
+				cefLog, err := cef.ProduceHTTPRequestEventLog(request, 400, `{"reason": "no reason"}`, "")
 
 				Expect(err).NotTo(HaveOccurred())
 				Expect(cefLog).To(ContainSubstring("CEF:0|CloudFoundry|BOSH|1|agent_api|/blobs|7|duser=username requestMethod=GET"))
@@ -52,7 +60,7 @@ var _ = Describe("CommonEventFormat", func() {
 
 	Context("when incoming request is a NATs request", func() {
 		It("should produce CEF string", func() {
-			cefLog, err := cef.ProduceNATSRequestEventLog("12.12.56.78", "56734", "nats_user", "get_task", 1, "agent.agent-id", "")
+			cefLog, err := cef.ProduceNATSRequestEventLog("12.12.56.78", "56734", "nats_user", "get_task", 1, "agent.agent-id", "", "")
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(cefLog).To(ContainSubstring("CEF:0|CloudFoundry|BOSH|1|agent_api|get_task|1|duser=nats_user"))
@@ -60,11 +68,19 @@ var _ = Describe("CommonEventFormat", func() {
 			Expect(cefLog).To(ContainSubstring("spt="))
 			Expect(cefLog).To(ContainSubstring("shost"))
 			Expect(cefLog).NotTo(ContainSubstring("cs1Label=statusReason"))
+			Expect(cefLog).NotTo(ContainSubstring("cs5Label=requestID"))
+		})
+
+		It("includes the request ID when known", func() {
+			cefLog, err := cef.ProduceNATSRequestEventLog("12.12.56.78", "56734", "nats_user", "get_task", 1, "agent.agent-id", "", "fake-request-id")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cefLog).To(ContainSubstring("cs5=fake-request-id cs5Label=requestID"))
 		})
 
 		Context("when responding with an error", func() {
 			It("should produce CEF string with severity=7 and statusReason", func() {
-				cefLog, err := cef.ProduceNATSRequestEventLog("12.12.56.78", "56734", "director.director-id", "get_task", 7, "agent.agent-id", `{"reason": "no reason"}`)
+				cefLog, err := cef.ProduceNATSRequestEventLog("12.12.56.78", "56734", "director.director-id", "get_task", 7, "agent.agent-id", `{"reason": "no reason"}`, "")
 
 				Expect(err).NotTo(HaveOccurred())
 				Expect(cefLog).To(ContainSubstring("CEF:0|CloudFoundry|BOSH|1|agent_api|get_task|7|duser=director.director-id"))
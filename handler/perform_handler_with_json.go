@@ -5,6 +5,7 @@ import (
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
 )
 
 const (
@@ -23,18 +24,25 @@ func PerformHandlerWithJSON(rawJSON []byte, handler Func, maxResponseLength int,
 
 	request.Payload = rawJSON
 
+	if request.RequestID == "" {
+		request.RequestID, err = boshuuid.NewGenerator().Generate()
+		if err != nil {
+			return []byte{}, request, bosherr.WrapError(err, "Generating request ID")
+		}
+	}
+
 	response := handler(request)
 	if response == nil {
-		logger.Info(mbusHandlerLogTag, "Nil response returned from handler")
+		logger.Info(mbusHandlerLogTag, "Nil response returned from handler for request %s", request.RequestID)
 		return []byte{}, request, nil
 	}
 
-	respJSON, err := marshalResponse(response, maxResponseLength, logger)
+	respJSON, err := marshalResponse(WithRequestID(response, request.RequestID), maxResponseLength, logger)
 	if err != nil {
 		return respJSON, request, err
 	}
 
-	logger.Info(mbusHandlerLogTag, "Responding")
+	logger.Info(mbusHandlerLogTag, "Responding to request %s", request.RequestID)
 	logger.DebugWithDetails(mbusHandlerLogTag, "Payload", respJSON)
 
 	return respJSON, request, nil
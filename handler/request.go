@@ -16,6 +16,10 @@ type Request struct {
 	Method          string
 	Payload         []byte
 	ProtocolVersion ProtocolVersion `json:"protocol"`
+
+	// RequestID correlates log lines, tasks and audit records of a single
+	// request; it is taken from the message or generated when missing
+	RequestID string `json:"request_id"`
 }
 
 func (r Request) GetPayload() []byte {
@@ -11,7 +11,8 @@ type Response interface {
 }
 
 type valueResponse struct {
-	Value interface{} `json:"value"`
+	Value     interface{} `json:"value"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
 func NewValueResponse(value interface{}) Response {
@@ -27,6 +28,8 @@ type exceptionResponse struct {
 		Message string `json:"message,omitempty"`
 	} `json:"exception"`
 
+	RequestID string `json:"request_id,omitempty"`
+
 	err error
 }
 
@@ -41,9 +44,25 @@ func (r exceptionResponse) Shorten() Response {
 	if typedErr, ok := r.err.(bosherr.ShortenableError); ok {
 		sr := exceptionResponse{}
 		sr.Exception.Message = typedErr.ShortError()
+		sr.RequestID = r.RequestID
 		sr.err = typedErr
 		return sr
 	}
 
 	return r
 }
+
+// WithRequestID returns a copy of the response that includes the request ID
+// so that callers can correlate it with agent logs
+func WithRequestID(response Response, requestID string) Response {
+	switch r := response.(type) {
+	case valueResponse:
+		r.RequestID = requestID
+		return r
+	case exceptionResponse:
+		r.RequestID = requestID
+		return r
+	}
+
+	return response
+}
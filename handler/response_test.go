@@ -67,6 +67,15 @@ var _ = Describe("NewExceptionResponse", func() {
 				`{"exception":{"message":"fake-short-msg2"}}`,
 			)
 		})
+
+		It("keeps the request id when shortened", func() {
+			resp := WithRequestID(NewExceptionResponse(err), "fake-request-id")
+			boshassert.MatchesJSONString(
+				GinkgoT(),
+				resp.Shorten(),
+				`{"exception":{"message":"fake-short-msg1"},"request_id":"fake-request-id"}`,
+			)
+		})
 	})
 
 	Context("with error that cannot be shortened", func() {
@@ -92,3 +101,15 @@ var _ = Describe("NewExceptionResponse", func() {
 		})
 	})
 })
+
+var _ = Describe("WithRequestID", func() {
+	It("includes the request id in value responses", func() {
+		resp := WithRequestID(NewValueResponse("fake-value"), "fake-request-id")
+		boshassert.MatchesJSONString(GinkgoT(), resp, `{"value":"fake-value","request_id":"fake-request-id"}`)
+	})
+
+	It("includes the request id in exception responses", func() {
+		resp := WithRequestID(NewExceptionResponse(errors.New("fake-msg")), "fake-request-id")
+		boshassert.MatchesJSONString(GinkgoT(), resp, `{"exception":{"message":"fake-msg"},"request_id":"fake-request-id"}`)
+	})
+})
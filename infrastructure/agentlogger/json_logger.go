@@ -0,0 +1,155 @@
+package agentlogger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// Fields are correlation values attached to every line logged
+// by a logger returned from WithFields
+type Fields map[string]string
+
+const (
+	RequestIDField    = "request_id"
+	TaskIDField       = "task_id"
+	ActionMethodField = "action_method"
+)
+
+type FieldLogger interface {
+	logger.Logger
+	WithFields(Fields) logger.Logger
+}
+
+// WithFields returns a logger that attaches fields to each line when the
+// underlying logger supports them; other loggers are returned unchanged.
+func WithFields(l logger.Logger, fields Fields) logger.Logger {
+	if fieldLogger, ok := l.(FieldLogger); ok {
+		return fieldLogger.WithFields(fields)
+	}
+	return l
+}
+
+type jsonOutput struct {
+	level       logger.LogLevel
+	out         io.Writer
+	err         io.Writer
+	forcedDebug bool
+	lock        sync.Mutex
+}
+
+// jsonLogger writes one JSON object per line, e.g.
+// {"level":"INFO","message":"Running sync action ping","tag":"Action Dispatcher","timestamp":"..."}
+type jsonLogger struct {
+	output *jsonOutput
+	fields Fields
+}
+
+func NewJSONLogger(level logger.LogLevel, out, err io.Writer) logger.Logger {
+	return &jsonLogger{
+		output: &jsonOutput{level: level, out: out, err: err},
+	}
+}
+
+func (l *jsonLogger) WithFields(fields Fields) logger.Logger {
+	merged := Fields{}
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return &jsonLogger{output: l.output, fields: merged}
+}
+
+func (l *jsonLogger) Debug(tag, msg string, args ...interface{}) {
+	l.write(logger.LevelDebug, "DEBUG", tag, msg, args...)
+}
+
+func (l *jsonLogger) DebugWithDetails(tag, msg string, args ...interface{}) {
+	msg = msg + "\n********************\n%s\n********************"
+	l.Debug(tag, msg, args...)
+}
+
+func (l *jsonLogger) Info(tag, msg string, args ...interface{}) {
+	l.write(logger.LevelInfo, "INFO", tag, msg, args...)
+}
+
+func (l *jsonLogger) Warn(tag, msg string, args ...interface{}) {
+	l.write(logger.LevelWarn, "WARN", tag, msg, args...)
+}
+
+func (l *jsonLogger) Error(tag, msg string, args ...interface{}) {
+	l.write(logger.LevelError, "ERROR", tag, msg, args...)
+}
+
+func (l *jsonLogger) ErrorWithDetails(tag, msg string, args ...interface{}) {
+	msg = msg + "\n********************\n%s\n********************"
+	l.Error(tag, msg, args...)
+}
+
+func (l *jsonLogger) HandlePanic(tag string) {
+	if e := recover(); e != nil {
+		var msg string
+		switch obj := e.(type) {
+		case string:
+			msg = obj
+		case fmt.Stringer:
+			msg = obj.String()
+		case error:
+			msg = obj.Error()
+		default:
+			msg = fmt.Sprintf("%#v", obj)
+		}
+		l.ErrorWithDetails(tag, "Panic: %s", msg, debug.Stack())
+		os.Exit(2)
+	}
+}
+
+func (l *jsonLogger) ToggleForcedDebug() {
+	l.output.lock.Lock()
+	defer l.output.lock.Unlock()
+
+	l.output.forcedDebug = !l.output.forcedDebug
+}
+
+func (l *jsonLogger) Flush() error                       { return nil }
+func (l *jsonLogger) FlushTimeout(_ time.Duration) error { return nil }
+
+func (l *jsonLogger) write(level logger.LogLevel, levelName, tag, msg string, args ...interface{}) {
+	l.output.lock.Lock()
+	defer l.output.lock.Unlock()
+
+	if l.output.level > level && !l.output.forcedDebug {
+		return
+	}
+
+	entry := map[string]string{}
+	for key, value := range l.fields {
+		entry[key] = value
+	}
+
+	entry["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = levelName
+	entry["tag"] = tag
+	entry["message"] = fmt.Sprintf(msg, args...)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	w := l.output.out
+	if level >= logger.LevelWarn {
+		w = l.output.err
+	}
+
+	_, _ = w.Write(append(line, '\n'))
+}
@@ -0,0 +1,104 @@
+package agentlogger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/bosh-agent/infrastructure/agentlogger"
+	"github.com/cloudfoundry/bosh-utils/logger"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSON logger", func() {
+	var (
+		outBuf *bytes.Buffer
+		errBuf *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		outBuf = new(bytes.Buffer)
+		errBuf = new(bytes.Buffer)
+	})
+
+	decodeLines := func(buf *bytes.Buffer) []map[string]string {
+		entries := []map[string]string{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			entry := map[string]string{}
+			Expect(json.Unmarshal([]byte(line), &entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	It("writes one JSON object per line", func() {
+		jsonLogger := agentlogger.NewJSONLogger(logger.LevelDebug, outBuf, errBuf)
+
+		jsonLogger.Debug("fake-tag", "fake-debug %s", "arg")
+		jsonLogger.Info("fake-tag", "multi\nline")
+		jsonLogger.Warn("fake-tag", "fake-warn")
+		jsonLogger.Error("fake-tag", "fake-error %d", 1)
+
+		out := decodeLines(outBuf)
+		Expect(out).To(HaveLen(2))
+		Expect(out[0]["level"]).To(Equal("DEBUG"))
+		Expect(out[0]["tag"]).To(Equal("fake-tag"))
+		Expect(out[0]["message"]).To(Equal("fake-debug arg"))
+		Expect(out[1]["level"]).To(Equal("INFO"))
+		Expect(out[1]["message"]).To(Equal("multi\nline"))
+
+		timestamp, err := time.Parse(time.RFC3339Nano, out[0]["timestamp"])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timestamp).To(BeTemporally("~", time.Now(), time.Minute))
+
+		errs := decodeLines(errBuf)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0]["level"]).To(Equal("WARN"))
+		Expect(errs[1]["level"]).To(Equal("ERROR"))
+		Expect(errs[1]["message"]).To(Equal("fake-error 1"))
+	})
+
+	It("skips lines below the log level unless debug is forced", func() {
+		jsonLogger := agentlogger.NewJSONLogger(logger.LevelInfo, outBuf, errBuf)
+
+		jsonLogger.Debug("fake-tag", "skipped")
+		Expect(outBuf.Len()).To(Equal(0))
+
+		jsonLogger.ToggleForcedDebug()
+		jsonLogger.Debug("fake-tag", "forced")
+		Expect(decodeLines(outBuf)[0]["message"]).To(Equal("forced"))
+	})
+
+	It("attaches correlation fields", func() {
+		jsonLogger := agentlogger.NewJSONLogger(logger.LevelDebug, outBuf, errBuf)
+
+		methodLogger := agentlogger.WithFields(jsonLogger, agentlogger.Fields{
+			agentlogger.ActionMethodField: "apply",
+		})
+		taskLogger := agentlogger.WithFields(methodLogger, agentlogger.Fields{
+			agentlogger.TaskIDField: "fake-task-id",
+		})
+
+		methodLogger.Info("fake-tag", "method")
+		taskLogger.Info("fake-tag", "task")
+		jsonLogger.Info("fake-tag", "plain")
+
+		out := decodeLines(outBuf)
+		Expect(out[0]).To(HaveKeyWithValue("action_method", "apply"))
+		Expect(out[0]).ToNot(HaveKey("task_id"))
+		Expect(out[1]).To(HaveKeyWithValue("action_method", "apply"))
+		Expect(out[1]).To(HaveKeyWithValue("task_id", "fake-task-id"))
+		Expect(out[2]).ToNot(HaveKey("action_method"))
+	})
+
+	It("returns other loggers unchanged from WithFields", func() {
+		textLogger := logger.NewWriterLogger(logger.LevelDebug, outBuf, errBuf)
+
+		Expect(agentlogger.WithFields(textLogger, agentlogger.Fields{"fake": "field"})).To(Equal(textLogger))
+	})
+})
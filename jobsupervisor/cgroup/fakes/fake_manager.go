@@ -0,0 +1,117 @@
+package fakes
+
+import (
+	"sort"
+	"sync"
+
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+)
+
+type FakeManager struct {
+	lock sync.Mutex
+
+	IsSupported bool
+
+	AddedJobs map[string]boshcgroup.Limits
+	AddJobErr error
+
+	AddedProcesses map[string][]int
+	AddProcessErr  error
+
+	JobsErr error
+
+	Usages   map[string]boshcgroup.Usage
+	UsageErr error
+
+	RemovedJobs  []string
+	RemoveJobErr error
+
+	RemovedAllJobs bool
+}
+
+func NewFakeManager() *FakeManager {
+	return &FakeManager{
+		IsSupported:    true,
+		AddedJobs:      map[string]boshcgroup.Limits{},
+		AddedProcesses: map[string][]int{},
+		Usages:         map[string]boshcgroup.Usage{},
+	}
+}
+
+func (m *FakeManager) Supported() bool {
+	return m.IsSupported
+}
+
+func (m *FakeManager) AddJob(job string, limits boshcgroup.Limits) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.AddJobErr != nil {
+		return m.AddJobErr
+	}
+
+	m.AddedJobs[job] = limits
+
+	return nil
+}
+
+func (m *FakeManager) AddProcess(job string, pid int) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.AddProcessErr != nil {
+		return m.AddProcessErr
+	}
+
+	m.AddedProcesses[job] = append(m.AddedProcesses[job], pid)
+
+	return nil
+}
+
+func (m *FakeManager) AddedProcessesOf(job string) []int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]int{}, m.AddedProcesses[job]...)
+}
+
+func (m *FakeManager) ProcsFile(job string) string {
+	return "/fake-cgroup/bosh-jobs/" + job + "/cgroup.procs"
+}
+
+func (m *FakeManager) Jobs() ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	jobs := []string{}
+	for job := range m.Usages {
+		jobs = append(jobs, job)
+	}
+
+	sort.Strings(jobs)
+
+	return jobs, m.JobsErr
+}
+
+func (m *FakeManager) Usage(job string) (boshcgroup.Usage, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.Usages[job], m.UsageErr
+}
+
+func (m *FakeManager) RemoveJob(job string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.RemovedJobs = append(m.RemovedJobs, job)
+
+	return m.RemoveJobErr
+}
+
+func (m *FakeManager) RemoveAllJobs() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.RemovedAllJobs = true
+}
@@ -0,0 +1,77 @@
+package cgroup
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// LimitsFileName is the sidecar file in a job directory declaring the
+// resources the job's processes may use together
+const LimitsFileName = "resources.json"
+
+// Range of cgroup v1 cpu shares, which jobs declare for familiarity
+const (
+	minCPUShares     = 2
+	maxCPUShares     = 262144
+	defaultCPUShares = 1024
+)
+
+// Limits of a job; zero values mean unlimited
+type Limits struct {
+	// CPUShares is the relative share of CPU time under contention,
+	// 1024 being the share of a job without limits
+	CPUShares int `json:"cpu_shares,omitempty"`
+
+	MemoryMaxMB int `json:"memory_max_mb,omitempty"`
+	PidsMax     int `json:"pids_max,omitempty"`
+}
+
+func (l Limits) Empty() bool {
+	return l == Limits{}
+}
+
+// LoadLimits reads the limits declared in jobDir; a job without a limits
+// file is not limited
+func LoadLimits(fs boshsys.FileSystem, jobDir string) (Limits, error) {
+	path := filepath.Join(jobDir, LimitsFileName)
+	if !fs.FileExists(path) {
+		return Limits{}, nil
+	}
+
+	contents, err := fs.ReadFile(path)
+	if err != nil {
+		return Limits{}, bosherr.WrapErrorf(err, "Reading limits file %s", path)
+	}
+
+	var limits Limits
+
+	err = json.Unmarshal(contents, &limits)
+	if err != nil {
+		return Limits{}, bosherr.WrapErrorf(err, "Parsing limits file %s", path)
+	}
+
+	if limits.CPUShares != 0 && (limits.CPUShares < minCPUShares || limits.CPUShares > maxCPUShares) {
+		return Limits{}, bosherr.Errorf("CPU shares must be between %d and %d in %s", minCPUShares, maxCPUShares, path)
+	}
+
+	if limits.MemoryMaxMB < 0 || limits.PidsMax < 0 {
+		return Limits{}, bosherr.Errorf("Negative memory or pids limit in %s", path)
+	}
+
+	return limits, nil
+}
+
+// cpuWeight converts cpu shares to the cgroup v2 cpu weight the same way
+// runc does; jobs compete only with each other so jobs without limits get
+// the weight of the default shares
+func (l Limits) cpuWeight() int {
+	shares := l.CPUShares
+	if shares == 0 {
+		shares = defaultCPUShares
+	}
+
+	return 1 + ((shares-minCPUShares)*9999)/(maxCPUShares-minCPUShares)
+}
@@ -0,0 +1,55 @@
+package cgroup_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("LoadLimits", func() {
+	var fs *fakesys.FakeFileSystem
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+	})
+
+	It("does not limit jobs without a limits file", func() {
+		limits, err := LoadLimits(fs, "/var/vcap/jobs/redis")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limits.Empty()).To(BeTrue())
+	})
+
+	It("reads the limits of a job", func() {
+		Expect(fs.WriteFileString("/var/vcap/jobs/redis/resources.json", `{"cpu_shares": 512, "memory_max_mb": 256, "pids_max": 64}`)).To(Succeed())
+
+		limits, err := LoadLimits(fs, "/var/vcap/jobs/redis")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limits).To(Equal(Limits{CPUShares: 512, MemoryMaxMB: 256, PidsMax: 64}))
+	})
+
+	It("returns an error when the limits file cannot be parsed", func() {
+		Expect(fs.WriteFileString("/var/vcap/jobs/redis/resources.json", `{`)).To(Succeed())
+
+		_, err := LoadLimits(fs, "/var/vcap/jobs/redis")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing limits file /var/vcap/jobs/redis/resources.json"))
+	})
+
+	It("returns an error when cpu shares are out of range", func() {
+		Expect(fs.WriteFileString("/var/vcap/jobs/redis/resources.json", `{"cpu_shares": 1}`)).To(Succeed())
+
+		_, err := LoadLimits(fs, "/var/vcap/jobs/redis")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("CPU shares must be between 2 and 262144 in /var/vcap/jobs/redis/resources.json"))
+	})
+
+	It("returns an error when limits are negative", func() {
+		Expect(fs.WriteFileString("/var/vcap/jobs/redis/resources.json", `{"pids_max": -1}`)).To(Succeed())
+
+		_, err := LoadLimits(fs, "/var/vcap/jobs/redis")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Negative memory or pids limit in /var/vcap/jobs/redis/resources.json"))
+	})
+})
@@ -0,0 +1,300 @@
+package cgroup
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	managerLogTag = "cgroupManager"
+
+	// DefaultRoot is where the unified (v2) cgroup hierarchy is mounted
+	DefaultRoot = "/sys/fs/cgroup"
+
+	// jobsGroup holds one cgroup per job
+	jobsGroup = "bosh-jobs"
+
+	procRoot = "/proc"
+
+	controllers = "+cpu +memory +pids"
+)
+
+// Usage of the processes in a job's cgroup; zero limits mean unlimited
+type Usage struct {
+	MemoryBytes    int64
+	MemoryMaxBytes int64
+	CPUUsageUsec   int64
+	Pids           int
+	PidsMax        int
+}
+
+type Manager interface {
+	// Supported is false unless the cgroup v2 hierarchy is mounted
+	Supported() bool
+
+	// AddJob creates the cgroup of a job or updates its limits
+	AddJob(job string, limits Limits) error
+
+	// AddProcess moves a process and its descendants into a job's cgroup
+	AddProcess(job string, pid int) error
+
+	// ProcsFile is where a process writes its own pid to join a job's
+	// cgroup before it forks, e.g. to start a command already limited
+	ProcsFile(job string) string
+
+	Jobs() ([]string, error)
+	Usage(job string) (Usage, error)
+
+	// RemoveJob removes the cgroup of a job, which fails while it still
+	// has processes
+	RemoveJob(job string) error
+
+	// RemoveAllJobs removes the cgroups of jobs without processes
+	RemoveAllJobs()
+}
+
+type manager struct {
+	fs     boshsys.FileSystem
+	root   string
+	logger boshlog.Logger
+}
+
+func NewManager(fs boshsys.FileSystem, root string, logger boshlog.Logger) Manager {
+	return manager{fs: fs, root: root, logger: logger}
+}
+
+func (m manager) Supported() bool {
+	return m.fs.FileExists(path.Join(m.root, "cgroup.controllers"))
+}
+
+func (m manager) AddJob(job string, limits Limits) error {
+	if !m.Supported() {
+		return bosherr.Errorf("Cgroup v2 is not available at %s", m.root)
+	}
+
+	// Controllers have to be enabled for the children of each level
+	err := m.fs.WriteFileString(path.Join(m.root, "cgroup.subtree_control"), controllers)
+	if err != nil {
+		return bosherr.WrapError(err, "Enabling cgroup controllers")
+	}
+
+	err = m.fs.MkdirAll(m.jobsDir(), 0755)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating jobs cgroup")
+	}
+
+	err = m.fs.WriteFileString(path.Join(m.jobsDir(), "cgroup.subtree_control"), controllers)
+	if err != nil {
+		return bosherr.WrapError(err, "Enabling cgroup controllers for jobs")
+	}
+
+	jobDir := m.jobDir(job)
+
+	err = m.fs.MkdirAll(jobDir, 0755)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating cgroup of job %s", job)
+	}
+
+	// Limits are always written so that removed limits are reset
+	files := map[string]string{
+		"cpu.weight": strconv.Itoa(limits.cpuWeight()),
+		"memory.max": limitValue(int64(limits.MemoryMaxMB) * 1024 * 1024),
+		"pids.max":   limitValue(int64(limits.PidsMax)),
+	}
+
+	for name, value := range files {
+		err = m.fs.WriteFileString(path.Join(jobDir, name), value)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Setting %s of job %s", name, job)
+		}
+	}
+
+	return nil
+}
+
+func (m manager) AddProcess(job string, pid int) error {
+	procs := m.ProcsFile(job)
+
+	if !m.fs.FileExists(procs) {
+		return bosherr.Errorf("Missing cgroup of job %s", job)
+	}
+
+	for _, p := range m.descendants(pid) {
+		if m.inGroup(p, job) {
+			continue
+		}
+
+		err := m.fs.WriteFileQuietly(procs, []byte(strconv.Itoa(p)))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Moving process %d into cgroup of job %s", p, job)
+		}
+
+		m.logger.Debug(managerLogTag, "Moved process %d into cgroup of job %s", p, job)
+	}
+
+	return nil
+}
+
+func (m manager) ProcsFile(job string) string {
+	return path.Join(m.jobDir(job), "cgroup.procs")
+}
+
+func (m manager) Jobs() ([]string, error) {
+	paths, err := m.fs.Glob(path.Join(m.jobsDir(), "*", "cgroup.procs"))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing job cgroups")
+	}
+
+	jobs := []string{}
+	for _, p := range paths {
+		jobs = append(jobs, path.Base(path.Dir(p)))
+	}
+
+	sort.Strings(jobs)
+
+	return jobs, nil
+}
+
+func (m manager) Usage(job string) (Usage, error) {
+	jobDir := m.jobDir(job)
+
+	values := map[string]int64{}
+
+	for _, file := range []string{"memory.current", "memory.max", "pids.current", "pids.max"} {
+		value, err := m.readValue(path.Join(jobDir, file))
+		if err != nil {
+			return Usage{}, bosherr.WrapErrorf(err, "Reading usage of job %s", job)
+		}
+
+		values[file] = value
+	}
+
+	usage := Usage{
+		MemoryBytes:    values["memory.current"],
+		MemoryMaxBytes: values["memory.max"],
+		Pids:           int(values["pids.current"]),
+		PidsMax:        int(values["pids.max"]),
+	}
+
+	cpuStat, err := m.fs.ReadFileString(path.Join(jobDir, "cpu.stat"))
+	if err != nil {
+		return Usage{}, bosherr.WrapErrorf(err, "Reading usage of job %s", job)
+	}
+
+	for _, line := range strings.Split(cpuStat, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usage.CPUUsageUsec, _ = strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+
+	return usage, nil
+}
+
+func (m manager) RemoveJob(job string) error {
+	err := m.fs.RemoveAll(m.jobDir(job))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing cgroup of job %s", job)
+	}
+
+	return nil
+}
+
+func (m manager) RemoveAllJobs() {
+	jobs, err := m.Jobs()
+	if err != nil {
+		m.logger.Warn(managerLogTag, "Failed to list job cgroups: %s", err.Error())
+		return
+	}
+
+	// The kernel refuses to remove cgroups that still have processes;
+	// those are kept and updated when their jobs are added again
+	for _, job := range jobs {
+		err := m.fs.RemoveAll(m.jobDir(job))
+		if err != nil {
+			m.logger.Debug(managerLogTag, "Keeping cgroup of job %s: %s", job, err.Error())
+		}
+	}
+}
+
+func (m manager) jobsDir() string {
+	return path.Join(m.root, jobsGroup)
+}
+
+func (m manager) jobDir(job string) string {
+	return path.Join(m.jobsDir(), job)
+}
+
+// inGroup checks the unified hierarchy entry of /proc/<pid>/cgroup
+func (m manager) inGroup(pid int, job string) bool {
+	content, err := m.fs.ReadFileString(path.Join(procRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if line == "0::/"+jobsGroup+"/"+job {
+			return true
+		}
+	}
+
+	return false
+}
+
+// descendants returns pid and the processes it forked, which would
+// otherwise escape the limits if they were forked before pid was moved
+func (m manager) descendants(pid int) []int {
+	pids := []int{pid}
+
+	for i := 0; i < len(pids); i++ {
+		p := strconv.Itoa(pids[i])
+
+		content, err := m.fs.ReadFileString(path.Join(procRoot, p, "task", p, "children"))
+		if err != nil {
+			continue
+		}
+
+		for _, field := range strings.Fields(content) {
+			child, err := strconv.Atoi(field)
+			if err == nil {
+				pids = append(pids, child)
+			}
+		}
+	}
+
+	return pids
+}
+
+// readValue reads a single value file where "max" means unlimited
+func (m manager) readValue(file string) (int64, error) {
+	content, err := m.fs.ReadFileString(file)
+	if err != nil {
+		return 0, err
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "max" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseInt(content, 10, 64)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Parsing %s", file)
+	}
+
+	return value, nil
+}
+
+func limitValue(limit int64) string {
+	if limit == 0 {
+		return "max"
+	}
+
+	return strconv.FormatInt(limit, 10)
+}
@@ -0,0 +1,133 @@
+package cgroup_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Manager", func() {
+	var (
+		fs      *fakesys.FakeFileSystem
+		manager Manager
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		manager = NewManager(fs, "/sys/fs/cgroup", boshlog.NewLogger(boshlog.LevelNone))
+
+		Expect(fs.WriteFileString("/sys/fs/cgroup/cgroup.controllers", "cpu memory pids")).To(Succeed())
+	})
+
+	Describe("Supported", func() {
+		It("is supported when the cgroup v2 hierarchy is mounted", func() {
+			Expect(manager.Supported()).To(BeTrue())
+
+			Expect(fs.RemoveAll("/sys/fs/cgroup/cgroup.controllers")).To(Succeed())
+			Expect(manager.Supported()).To(BeFalse())
+		})
+	})
+
+	Describe("AddJob", func() {
+		It("enables controllers and writes the limits of the job", func() {
+			Expect(manager.AddJob("redis", Limits{CPUShares: 512, MemoryMaxMB: 256})).To(Succeed())
+
+			Expect(fs.ReadFileString("/sys/fs/cgroup/cgroup.subtree_control")).To(Equal("+cpu +memory +pids"))
+			Expect(fs.ReadFileString("/sys/fs/cgroup/bosh-jobs/cgroup.subtree_control")).To(Equal("+cpu +memory +pids"))
+			Expect(fs.ReadFileString("/sys/fs/cgroup/bosh-jobs/redis/cpu.weight")).To(Equal("20"))
+			Expect(fs.ReadFileString("/sys/fs/cgroup/bosh-jobs/redis/memory.max")).To(Equal("268435456"))
+			Expect(fs.ReadFileString("/sys/fs/cgroup/bosh-jobs/redis/pids.max")).To(Equal("max"))
+		})
+
+		It("gives jobs without cpu shares the default weight", func() {
+			Expect(manager.AddJob("redis", Limits{PidsMax: 64})).To(Succeed())
+
+			Expect(fs.ReadFileString("/sys/fs/cgroup/bosh-jobs/redis/cpu.weight")).To(Equal("39"))
+			Expect(fs.ReadFileString("/sys/fs/cgroup/bosh-jobs/redis/pids.max")).To(Equal("64"))
+		})
+
+		It("returns an error when cgroup v2 is not available", func() {
+			Expect(fs.RemoveAll("/sys/fs/cgroup/cgroup.controllers")).To(Succeed())
+
+			err := manager.AddJob("redis", Limits{PidsMax: 64})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Cgroup v2 is not available at /sys/fs/cgroup"))
+		})
+	})
+
+	Describe("AddProcess", func() {
+		It("moves the process and descendants outside the cgroup into it", func() {
+			Expect(fs.WriteFileString("/sys/fs/cgroup/bosh-jobs/redis/cgroup.procs", "")).To(Succeed())
+			Expect(fs.WriteFileString("/proc/100/task/100/children", "101 ")).To(Succeed())
+			Expect(fs.WriteFileString("/proc/101/cgroup", "0::/bosh-jobs/redis\n")).To(Succeed())
+
+			Expect(manager.AddProcess("redis", 100)).To(Succeed())
+
+			Expect(fs.WriteFileQuietlyCallCount).To(Equal(1))
+			Expect(fs.ReadFileString("/sys/fs/cgroup/bosh-jobs/redis/cgroup.procs")).To(Equal("100"))
+		})
+
+		It("returns an error when the job has no cgroup", func() {
+			err := manager.AddProcess("redis", 100)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Missing cgroup of job redis"))
+		})
+	})
+
+	Describe("ProcsFile", func() {
+		It("is the cgroup.procs file of the job's cgroup", func() {
+			Expect(manager.ProcsFile("redis")).To(Equal("/sys/fs/cgroup/bosh-jobs/redis/cgroup.procs"))
+		})
+	})
+
+	Describe("Usage", func() {
+		BeforeEach(func() {
+			Expect(fs.WriteFileString("/sys/fs/cgroup/bosh-jobs/redis/memory.current", "1048576\n")).To(Succeed())
+			Expect(fs.WriteFileString("/sys/fs/cgroup/bosh-jobs/redis/memory.max", "max\n")).To(Succeed())
+			Expect(fs.WriteFileString("/sys/fs/cgroup/bosh-jobs/redis/pids.current", "3\n")).To(Succeed())
+			Expect(fs.WriteFileString("/sys/fs/cgroup/bosh-jobs/redis/pids.max", "64\n")).To(Succeed())
+			Expect(fs.WriteFileString("/sys/fs/cgroup/bosh-jobs/redis/cpu.stat", "usage_usec 1500000\nuser_usec 1000000\n")).To(Succeed())
+		})
+
+		It("reads the usage of the job's cgroup", func() {
+			Expect(manager.Usage("redis")).To(Equal(Usage{
+				MemoryBytes:  1048576,
+				CPUUsageUsec: 1500000,
+				Pids:         3,
+				PidsMax:      64,
+			}))
+		})
+
+		It("returns an error when a usage file is invalid", func() {
+			Expect(fs.WriteFileString("/sys/fs/cgroup/bosh-jobs/redis/pids.current", "three")).To(Succeed())
+
+			_, err := manager.Usage("redis")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading usage of job redis"))
+		})
+	})
+
+	Describe("RemoveJob", func() {
+		It("removes the cgroup of the job", func() {
+			Expect(fs.WriteFileString("/sys/fs/cgroup/bosh-jobs/redis/cgroup.procs", "")).To(Succeed())
+
+			Expect(manager.RemoveJob("redis")).To(Succeed())
+			Expect(fs.FileExists("/sys/fs/cgroup/bosh-jobs/redis")).To(BeFalse())
+		})
+	})
+
+	Describe("RemoveAllJobs", func() {
+		It("removes the cgroups of all jobs", func() {
+			Expect(fs.WriteFileString("/sys/fs/cgroup/bosh-jobs/redis/cgroup.procs", "")).To(Succeed())
+			fs.SetGlob("/sys/fs/cgroup/bosh-jobs/*/cgroup.procs", []string{"/sys/fs/cgroup/bosh-jobs/redis/cgroup.procs"})
+
+			Expect(manager.Jobs()).To(Equal([]string{"redis"}))
+
+			manager.RemoveAllJobs()
+			Expect(fs.FileExists("/sys/fs/cgroup/bosh-jobs/redis/cgroup.procs")).To(BeFalse())
+		})
+	})
+})
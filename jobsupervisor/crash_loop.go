@@ -0,0 +1,156 @@
+package jobsupervisor
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Processes restarted crashLoopThreshold times within crashLoopWindow
+	// are in a crash loop and are kept stopped for a backoff period
+	crashLoopThreshold = 5
+	crashLoopWindow    = 5 * time.Minute
+
+	// The backoff doubles each time a process enters a crash loop again
+	// within crashLoopWindow of its previous backoff ending
+	crashLoopInitialBackoff = 30 * time.Second
+	crashLoopMaxBackoff     = 10 * time.Minute
+
+	processStateCrashLoop = "crashloop"
+)
+
+type crashLoop struct {
+	restarts []time.Time
+
+	backoff time.Duration
+
+	// until is zero unless the process is in a crash loop
+	until   time.Time
+	lastEnd time.Time
+}
+
+// crashLoopTracker counts the restarts of processes over a sliding window
+type crashLoopTracker struct {
+	lock      sync.Mutex
+	processes map[string]*crashLoop
+}
+
+func newCrashLoopTracker() *crashLoopTracker {
+	return &crashLoopTracker{processes: map[string]*crashLoop{}}
+}
+
+// RecordRestart returns the backoff and the time it ends at when the
+// restart puts the process into a crash loop
+func (t *crashLoopTracker) RecordRestart(name string, at time.Time) (bool, time.Duration, time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	process, found := t.processes[name]
+	if !found {
+		process = &crashLoop{}
+		t.processes[name] = process
+	}
+
+	if !process.until.IsZero() {
+		return false, 0, time.Time{}
+	}
+
+	restarts := []time.Time{}
+	for _, restart := range process.restarts {
+		if at.Sub(restart) < crashLoopWindow {
+			restarts = append(restarts, restart)
+		}
+	}
+
+	process.restarts = append(restarts, at)
+
+	if len(process.restarts) < crashLoopThreshold {
+		return false, 0, time.Time{}
+	}
+
+	switch {
+	case process.backoff == 0 || at.Sub(process.lastEnd) > crashLoopWindow:
+		process.backoff = crashLoopInitialBackoff
+	case process.backoff*2 > crashLoopMaxBackoff:
+		process.backoff = crashLoopMaxBackoff
+	default:
+		process.backoff *= 2
+	}
+
+	process.restarts = nil
+	process.until = at.Add(process.backoff)
+
+	return true, process.backoff, process.until
+}
+
+// End takes a process out of the crash loop ending at until; it returns
+// false when the crash loop was cleared in the meantime
+func (t *crashLoopTracker) End(name string, until time.Time) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	process, found := t.processes[name]
+	if !found || !process.until.Equal(until) {
+		return false
+	}
+
+	process.lastEnd = process.until
+	process.until = time.Time{}
+
+	return true
+}
+
+func (t *crashLoopTracker) InCrashLoop(name string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	process, found := t.processes[name]
+
+	return found && !process.until.IsZero()
+}
+
+func (t *crashLoopTracker) Any() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, process := range t.processes {
+		if !process.until.IsZero() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stopped returns the sorted names of the processes in a crash loop
+func (t *crashLoopTracker) Stopped() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	names := []string{}
+	for name, process := range t.processes {
+		if !process.until.IsZero() {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Clear forgets the restarts of a process, e.g. when it was started by hand
+func (t *crashLoopTracker) Clear(name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.processes, name)
+}
+
+func (t *crashLoopTracker) ClearAll() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.processes = map[string]*crashLoop{}
+}
@@ -1,5 +1,9 @@
 package jobsupervisor
 
+import (
+	"time"
+)
+
 type dummyJobSupervisor struct {
 	status    string
 	processes []Process
@@ -24,15 +28,27 @@ func (s *dummyJobSupervisor) Stop() error {
 	return nil
 }
 
-func (s *dummyJobSupervisor) StopAndWait() error {
+func (s *dummyJobSupervisor) StopAndWait() ([]JobStopResult, error) {
 	s.status = "stopped"
-	return nil
+	return nil, nil
 }
 
 func (s *dummyJobSupervisor) Unmonitor() error {
 	return nil
 }
 
+func (s *dummyJobSupervisor) StartProcess(name string) error {
+	return nil
+}
+
+func (s *dummyJobSupervisor) StopProcess(name string) error {
+	return nil
+}
+
+func (s *dummyJobSupervisor) RestartProcess(name string) error {
+	return nil
+}
+
 func (s *dummyJobSupervisor) Status() (status string) {
 	return s.status
 }
@@ -41,6 +57,14 @@ func (s *dummyJobSupervisor) Processes() ([]Process, error) {
 	return s.processes, nil
 }
 
+func (s *dummyJobSupervisor) StatusStaleSince() time.Time {
+	return time.Time{}
+}
+
+func (s *dummyJobSupervisor) WaitUntilReady(timeout time.Duration) error {
+	return nil
+}
+
 func (s *dummyJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
 	return nil
 }
@@ -53,5 +77,9 @@ func (s *dummyJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error
 	return nil
 }
 
+func (s *dummyJobSupervisor) MonitorJobEvents(handler JobEventHandler) error {
+	return nil
+}
+
 func (s *dummyJobSupervisor) HealthRecorder(status string) {
 }
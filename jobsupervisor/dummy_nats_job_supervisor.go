@@ -2,6 +2,7 @@ package jobsupervisor
 
 import (
 	"encoding/json"
+	"time"
 
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
@@ -91,14 +92,29 @@ func (d *dummyNatsJobSupervisor) Stop() error {
 	return nil
 }
 
-func (d *dummyNatsJobSupervisor) StopAndWait() error {
-	return d.Stop()
+func (d *dummyNatsJobSupervisor) StopAndWait() ([]JobStopResult, error) {
+	return nil, d.Stop()
 }
 
 func (d *dummyNatsJobSupervisor) Unmonitor() error {
 	return nil
 }
 
+func (d *dummyNatsJobSupervisor) StartProcess(name string) error {
+	if d.status == "fail_task" {
+		return bosherror.Error("fake-task-fail-error")
+	}
+	return nil
+}
+
+func (d *dummyNatsJobSupervisor) StopProcess(name string) error {
+	return nil
+}
+
+func (d *dummyNatsJobSupervisor) RestartProcess(name string) error {
+	return d.StartProcess(name)
+}
+
 func (d *dummyNatsJobSupervisor) RemoveAllJobs() error {
 	return nil
 }
@@ -111,6 +127,14 @@ func (d *dummyNatsJobSupervisor) Processes() ([]Process, error) {
 	return d.processes, nil
 }
 
+func (d *dummyNatsJobSupervisor) StatusStaleSince() time.Time {
+	return time.Time{}
+}
+
+func (d *dummyNatsJobSupervisor) WaitUntilReady(timeout time.Duration) error {
+	return nil
+}
+
 func (d *dummyNatsJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error {
 	d.jobFailureHandler = handler
 
@@ -119,6 +143,10 @@ func (d *dummyNatsJobSupervisor) MonitorJobFailures(handler JobFailureHandler) e
 	return nil
 }
 
+func (d *dummyNatsJobSupervisor) MonitorJobEvents(handler JobEventHandler) error {
+	return nil
+}
+
 func (d *dummyNatsJobSupervisor) statusHandler(req boshhandler.Request) boshhandler.Response {
 	switch req.Method {
 	case "set_dummy_status":
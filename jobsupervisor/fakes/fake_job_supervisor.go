@@ -4,6 +4,7 @@ import (
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	"sync"
+	"time"
 )
 
 type FakeJobSupervisor struct {
@@ -11,6 +12,7 @@ type FakeJobSupervisor struct {
 	ReloadErr error
 
 	AddJobArgs []AddJobArgs
+	AddJobErr  error
 
 	RemovedAllJobs    bool
 	RemovedAllJobsErr error
@@ -21,15 +23,35 @@ type FakeJobSupervisor struct {
 	Stopped          bool
 	StopErr          error
 	StoppedAndWaited bool
+	StopResults      []boshjobsuper.JobStopResult
 
 	Unmonitored  bool
 	UnmonitorErr error
 
+	// Processes may be started and stopped from other goroutines;
+	// read them with GetStartedProcesses and the like
+	StartedProcesses   []string
+	StartProcessErr    error
+	StoppedProcesses   []string
+	StopProcessErr     error
+	RestartedProcesses []string
+	RestartProcessErr  error
+	processesMutex     sync.Mutex
+
 	StatusStatus    string
 	ProcessesStatus []boshjobsuper.Process
 	ProcessesError  error
 
-	JobFailureAlert *boshalert.MonitAlert
+	StatusStaleSinceTime time.Time
+
+	WaitUntilReadyTimeout time.Duration
+	WaitUntilReadyErr     error
+
+	JobFailureAlert   *boshalert.MonitAlert
+	JobFailureHandler boshjobsuper.JobFailureHandler
+
+	JobEvents       []boshjobsuper.JobEvent
+	JobEventHandler boshjobsuper.JobEventHandler
 
 	HealthRecorded      int
 	HealthRecordedMutex sync.Mutex
@@ -61,7 +83,7 @@ func (m *FakeJobSupervisor) AddJob(jobName string, jobIndex int, configPath stri
 		ConfigPath: configPath,
 	}
 	m.AddJobArgs = append(m.AddJobArgs, args)
-	return nil
+	return m.AddJobErr
 }
 
 func (m *FakeJobSupervisor) RemoveAllJobs() error {
@@ -79,10 +101,10 @@ func (m *FakeJobSupervisor) Stop() error {
 	return m.StopErr
 }
 
-func (m *FakeJobSupervisor) StopAndWait() error {
+func (m *FakeJobSupervisor) StopAndWait() ([]boshjobsuper.JobStopResult, error) {
 	m.Stopped = true
 	m.StoppedAndWaited = true
-	return m.StopErr
+	return m.StopResults, m.StopErr
 }
 
 func (m *FakeJobSupervisor) Unmonitor() error {
@@ -90,6 +112,51 @@ func (m *FakeJobSupervisor) Unmonitor() error {
 	return m.UnmonitorErr
 }
 
+func (m *FakeJobSupervisor) StartProcess(name string) error {
+	m.processesMutex.Lock()
+	defer m.processesMutex.Unlock()
+
+	m.StartedProcesses = append(m.StartedProcesses, name)
+	return m.StartProcessErr
+}
+
+func (m *FakeJobSupervisor) GetStartedProcesses() []string {
+	m.processesMutex.Lock()
+	defer m.processesMutex.Unlock()
+
+	return append([]string{}, m.StartedProcesses...)
+}
+
+func (m *FakeJobSupervisor) StopProcess(name string) error {
+	m.processesMutex.Lock()
+	defer m.processesMutex.Unlock()
+
+	m.StoppedProcesses = append(m.StoppedProcesses, name)
+	return m.StopProcessErr
+}
+
+func (m *FakeJobSupervisor) GetStoppedProcesses() []string {
+	m.processesMutex.Lock()
+	defer m.processesMutex.Unlock()
+
+	return append([]string{}, m.StoppedProcesses...)
+}
+
+func (m *FakeJobSupervisor) RestartProcess(name string) error {
+	m.processesMutex.Lock()
+	defer m.processesMutex.Unlock()
+
+	m.RestartedProcesses = append(m.RestartedProcesses, name)
+	return m.RestartProcessErr
+}
+
+func (m *FakeJobSupervisor) GetRestartedProcesses() []string {
+	m.processesMutex.Lock()
+	defer m.processesMutex.Unlock()
+
+	return append([]string{}, m.RestartedProcesses...)
+}
+
 func (m *FakeJobSupervisor) Status() string {
 	return m.StatusStatus
 }
@@ -98,13 +165,31 @@ func (m *FakeJobSupervisor) Processes() ([]boshjobsuper.Process, error) {
 	return m.ProcessesStatus, m.ProcessesError
 }
 
+func (m *FakeJobSupervisor) StatusStaleSince() time.Time {
+	return m.StatusStaleSinceTime
+}
+
+func (m *FakeJobSupervisor) WaitUntilReady(timeout time.Duration) error {
+	m.WaitUntilReadyTimeout = timeout
+	return m.WaitUntilReadyErr
+}
+
 func (m *FakeJobSupervisor) MonitorJobFailures(handler boshjobsuper.JobFailureHandler) error {
+	m.JobFailureHandler = handler
 	if m.JobFailureAlert != nil {
 		return handler(*m.JobFailureAlert)
 	}
 	return nil
 }
 
+func (m *FakeJobSupervisor) MonitorJobEvents(handler boshjobsuper.JobEventHandler) error {
+	m.JobEventHandler = handler
+	for _, event := range m.JobEvents {
+		handler(event)
+	}
+	return nil
+}
+
 func (m *FakeJobSupervisor) HealthRecorder(status string) {
 	m.HealthRecordedMutex.Lock()
 	m.HealthRecorded += 1
@@ -0,0 +1,111 @@
+package jobsupervisor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Process states are compared within this interval to derive events
+const jobEventPollInterval = 5 * time.Second
+
+// jobEvents derives lifecycle events from successive process states
+type jobEvents struct {
+	lock    sync.Mutex
+	handler JobEventHandler
+
+	// states is nil until the first observation, which is not reported
+	// so that restarting the agent does not repeat events
+	states map[string]string
+}
+
+func newJobEvents() *jobEvents {
+	return &jobEvents{}
+}
+
+func (e *jobEvents) SetHandler(handler JobEventHandler) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.handler = handler
+}
+
+// Emit reports an event; events are dropped until a handler is set
+func (e *jobEvents) Emit(event JobEvent) {
+	e.lock.Lock()
+	handler := e.handler
+	e.lock.Unlock()
+
+	if handler != nil {
+		handler(event)
+	}
+}
+
+// Observe reports processes whose state changed since the last observation
+func (e *jobEvents) Observe(processes []Process, at time.Time) {
+	e.lock.Lock()
+
+	states := make(map[string]string, len(processes))
+	events := []JobEvent{}
+
+	for _, process := range processes {
+		states[process.Name] = process.State
+
+		if e.states == nil {
+			continue
+		}
+
+		previous, found := e.states[process.Name]
+		if found && jobEventType(previous) == jobEventType(process.State) {
+			continue
+		}
+
+		description := fmt.Sprintf("state is '%s'", process.State)
+		if found {
+			description = fmt.Sprintf("state changed from '%s' to '%s'", previous, process.State)
+		}
+
+		events = append(events, JobEvent{
+			Process:     process.Name,
+			Type:        jobEventType(process.State),
+			Timestamp:   at.Unix(),
+			Description: description,
+		})
+	}
+
+	e.states = states
+	handler := e.handler
+
+	e.lock.Unlock()
+
+	if handler == nil {
+		return
+	}
+
+	for _, event := range events {
+		handler(event)
+	}
+}
+
+// Reset forgets process states, e.g. when all jobs are removed
+func (e *jobEvents) Reset() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.states != nil {
+		e.states = map[string]string{}
+	}
+}
+
+func jobEventType(state string) string {
+	switch state {
+	case processStateRunning:
+		return JobEventRunning
+	case processStateStarting:
+		return JobEventStarting
+	case processStateNotMonitored:
+		return JobEventStopped
+	default:
+		return JobEventFailed
+	}
+}
@@ -1,15 +1,18 @@
 package jobsupervisor
 
 import (
+	"time"
+
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 )
 
 type Process struct {
-	Name   string       `json:"name"`
-	State  string       `json:"state"`
-	Uptime UptimeVitals `json:"uptime,omitempty"`
-	Memory MemoryVitals `json:"mem,omitempty"`
-	CPU    CPUVitals    `json:"cpu,omitempty"`
+	Name   string        `json:"name"`
+	State  string        `json:"state"`
+	Uptime UptimeVitals  `json:"uptime,omitempty"`
+	Memory MemoryVitals  `json:"mem,omitempty"`
+	CPU    CPUVitals     `json:"cpu,omitempty"`
+	CGroup *CGroupVitals `json:"cgroup,omitempty"`
 }
 
 type UptimeVitals struct {
@@ -25,15 +28,65 @@ type CPUVitals struct {
 	Total float64 `json:"total"`
 }
 
+// CGroupVitals is the usage of the cgroup shared by the processes of a
+// job; limits are omitted when the job is not limited
+type CGroupVitals struct {
+	Job           string  `json:"job"`
+	MemoryKb      int     `json:"mem_kb"`
+	MemoryLimitKb int     `json:"mem_limit_kb,omitempty"`
+	CPUUsageSecs  float64 `json:"cpu_secs"`
+	Pids          int     `json:"pids"`
+	PidsLimit     int     `json:"pids_limit,omitempty"`
+}
+
+// Outcomes of stopping a job
+const (
+	JobStopStateStopped    = "stopped"
+	JobStopStateTerminated = "terminated"
+	JobStopStateKilled     = "killed"
+	JobStopStateFailed     = "failed"
+)
+
+// JobStopResult reports how the processes of a job were stopped: within
+// the grace period, after SIGTERM or after SIGKILL
+type JobStopResult struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// Lifecycle events of the processes of added jobs
+const (
+	JobEventStarting  = "starting"
+	JobEventRunning   = "running"
+	JobEventFailed    = "failed"
+	JobEventStopped   = "stopped"
+	JobEventRestarted = "restarted"
+)
+
+// JobEvent is a change of the state of a process; Timestamp is in seconds
+// since the epoch like the creation time of alerts
+type JobEvent struct {
+	Process     string `json:"process"`
+	Type        string `json:"type"`
+	Timestamp   int64  `json:"timestamp"`
+	Description string `json:"description,omitempty"`
+}
+
 type JobFailureHandler func(boshalert.MonitAlert) error
 
+type JobEventHandler func(JobEvent)
+
 type JobSupervisor interface {
 	Reload() error
 
 	// Actions taken on all services
 	Start() error
 	Stop() error
-	StopAndWait() error
+
+	// StopAndWait stops jobs in reverse configuration order; supervisors
+	// that do not stop jobs one by one return no results
+	StopAndWait() ([]JobStopResult, error)
 
 	// Start and Stop should still function after Unmonitor.
 	// Calling Start after Unmonitor should re-monitor all jobs.
@@ -41,12 +94,40 @@ type JobSupervisor interface {
 	// (Monit complies to above requirements.)
 	Unmonitor() error
 
+	// Actions taken on a single process of an added job. Processes of a
+	// stopped instance cannot be started until Start is called; stopping
+	// a single process does not mark the instance as stopped.
+	StartProcess(name string) error
+	StopProcess(name string) error
+	RestartProcess(name string) error
+
 	Status() string
 	Processes() ([]Process, error)
+
+	// StatusStaleSince is when the status reported by Status and Processes
+	// was fetched if a cached status is reported because the supervisor
+	// did not respond, and zero otherwise
+	StatusStaleSince() time.Time
+
+	// WaitUntilReady blocks until the readiness probes of added jobs pass
+	WaitUntilReady(timeout time.Duration) error
+
 	// Job management
 	AddJob(jobName string, jobIndex int, configPath string) error
 	RemoveAllJobs() error
 
 	MonitorJobFailures(handler JobFailureHandler) error
+
+	// MonitorJobEvents blocks while reporting lifecycle events; supervisors
+	// that do not track process states return immediately
+	MonitorJobEvents(handler JobEventHandler) error
+
 	HealthRecorder(status string)
 }
+
+// ProcessesCache is implemented by supervisors whose process states are
+// expensive to fetch; CachedProcesses returns the processes as of the
+// status last fetched for Status or Processes, and when it was fetched
+type ProcessesCache interface {
+	CachedProcesses() ([]Process, time.Time)
+}
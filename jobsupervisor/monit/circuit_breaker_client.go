@@ -0,0 +1,100 @@
+package monit
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/http"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
+)
+
+const circuitBreakerLogTag = "monitCircuitBreaker"
+
+// CircuitBreaker fails requests to monit without sending them once
+// threshold requests in a row failed; after cooldown a single request is
+// let through and closes the circuit again if it succeeds
+type CircuitBreaker struct {
+	threshold   uint
+	cooldown    time.Duration
+	timeService clock.Clock
+	logger      boshlog.Logger
+
+	lock     sync.Mutex
+	failures uint
+	openedAt time.Time
+	trying   bool
+}
+
+func NewCircuitBreaker(threshold uint, cooldown time.Duration, timeService clock.Clock, logger boshlog.Logger) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold:   threshold,
+		cooldown:    cooldown,
+		timeService: timeService,
+		logger:      logger,
+	}
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+
+	if b.trying || b.timeService.Now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+
+	b.trying = true
+
+	return true
+}
+
+func (b *CircuitBreaker) record(err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.trying = false
+
+	if err == nil {
+		if b.failures >= b.threshold {
+			b.logger.Info(circuitBreakerLogTag, "Closing circuit after monit responded again")
+		}
+
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+
+	if b.failures >= b.threshold {
+		b.logger.Warn(circuitBreakerLogTag, "Opening circuit for %s after %d failed monit requests: %s", b.cooldown, b.failures, err.Error())
+		b.openedAt = b.timeService.Now()
+	}
+}
+
+type circuitBreakerClient struct {
+	delegate boshhttp.Client
+	breaker  *CircuitBreaker
+}
+
+// NewCircuitBreakerClient sends requests through breaker; clients talking
+// to the same monit should share a breaker
+func NewCircuitBreakerClient(delegate boshhttp.Client, breaker *CircuitBreaker) boshhttp.Client {
+	return circuitBreakerClient{delegate: delegate, breaker: breaker}
+}
+
+func (c circuitBreakerClient) Do(req *http.Request) (*http.Response, error) {
+	if !c.breaker.allow() {
+		return nil, bosherr.Error("Monit circuit breaker is open")
+	}
+
+	response, err := c.delegate.Do(req)
+	c.breaker.record(err)
+
+	return response, err
+}
@@ -0,0 +1,92 @@
+package monit_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
+	boshhttp "github.com/cloudfoundry/bosh-utils/http"
+	fakehttp "github.com/cloudfoundry/bosh-utils/http/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("circuitBreakerClient", func() {
+	var (
+		delegate    *fakehttp.FakeClient
+		timeService *fakeclock.FakeClock
+		client      boshhttp.Client
+		request     *http.Request
+	)
+
+	BeforeEach(func() {
+		delegate = fakehttp.NewFakeClient()
+		delegate.StatusCode = 200
+		timeService = fakeclock.NewFakeClock(time.Date(2016, time.March, 1, 10, 0, 0, 0, time.UTC))
+
+		breaker := NewCircuitBreaker(2, 30*time.Second, timeService, boshlog.NewLogger(boshlog.LevelNone))
+		client = NewCircuitBreakerClient(delegate, breaker)
+
+		var err error
+		request, err = http.NewRequest("GET", "http://localhost/_status2", nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	fail := func(times int) {
+		for i := 0; i < times; i++ {
+			delegate.AddDoBehavior(nil, errors.New("fake-connection-refused"))
+			_, err := client.Do(request)
+			Expect(err).To(HaveOccurred())
+		}
+	}
+
+	It("sends requests while monit responds", func() {
+		fail(1)
+
+		response, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(200))
+
+		fail(1)
+		Expect(delegate.CallCount).To(Equal(3))
+	})
+
+	It("fails requests without sending them after too many failures in a row", func() {
+		fail(2)
+
+		_, err := client.Do(request)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Monit circuit breaker is open"))
+		Expect(delegate.CallCount).To(Equal(2))
+	})
+
+	It("lets a request through after the cooldown and closes the circuit if it succeeds", func() {
+		fail(2)
+
+		timeService.Increment(30 * time.Second)
+
+		_, err := client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = client.Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(delegate.CallCount).To(Equal(4))
+	})
+
+	It("opens the circuit again when the request after the cooldown fails", func() {
+		fail(2)
+
+		timeService.Increment(30 * time.Second)
+		fail(1)
+
+		_, err := client.Do(request)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Monit circuit breaker is open"))
+		Expect(delegate.CallCount).To(Equal(3))
+	})
+})
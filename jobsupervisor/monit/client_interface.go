@@ -4,6 +4,11 @@ type Client interface {
 	ServicesInGroup(name string) (services []string, err error)
 	StartService(name string) (err error)
 	StopService(name string) (err error)
+	RestartService(name string) (err error)
 	UnmonitorService(name string) (err error)
 	Status() (status Status, err error)
+
+	// HeartbeatStatus is Status for periodic heartbeats, which may fail
+	// fast while monit is unresponsive instead of piling up requests
+	HeartbeatStatus() (status Status, err error)
 }
@@ -15,6 +15,9 @@ type FakeMonitClient struct {
 	StopServiceNames []string
 	StopServiceErr   error
 
+	RestartServiceNames []string
+	RestartServiceErr   error
+
 	UnmonitorServiceNames []string
 	UnmonitorServiceErrs  []error
 
@@ -23,6 +26,8 @@ type FakeMonitClient struct {
 
 	Incarnations      []int
 	StatusCalledTimes int
+
+	HeartbeatStatusCalledTimes int
 }
 
 func NewFakeMonitClient() *FakeMonitClient {
@@ -44,6 +49,11 @@ func (c *FakeMonitClient) StopService(name string) error {
 	return c.StopServiceErr
 }
 
+func (c *FakeMonitClient) RestartService(name string) error {
+	c.RestartServiceNames = append(c.RestartServiceNames, name)
+	return c.RestartServiceErr
+}
+
 func (c *FakeMonitClient) UnmonitorService(name string) error {
 	c.UnmonitorServiceNames = append(c.UnmonitorServiceNames, name)
 	return c.UnmonitorServiceErrs[len(c.UnmonitorServiceNames)-1]
@@ -59,3 +69,9 @@ func (c *FakeMonitClient) Status() (boshmonit.Status, error) {
 
 	return s, c.StatusErr
 }
+
+func (c *FakeMonitClient) HeartbeatStatus() (boshmonit.Status, error) {
+	c.HeartbeatStatusCalledTimes++
+
+	return c.StatusStatus, c.StatusErr
+}
@@ -20,6 +20,7 @@ type httpClient struct {
 	stopClient      boshhttp.Client
 	unmonitorClient boshhttp.Client
 	statusClient    boshhttp.Client
+	heartbeatClient boshhttp.Client
 	host            string
 	username        string
 	password        string
@@ -29,12 +30,25 @@ type httpClient struct {
 // NewHTTPClient creates a new monit client
 //
 // status & start use the shortClient
-// unmonitor & stop use the longClient
+// unmonitor, stop & restart use the longClient
 func NewHTTPClient(
 	host, username, password string,
 	shortClient boshhttp.Client,
 	longClient boshhttp.Client,
 	logger boshlog.Logger,
+) Client {
+	return NewHTTPClientWithHeartbeatClient(host, username, password, shortClient, longClient, shortClient, logger)
+}
+
+// NewHTTPClientWithHeartbeatClient is like NewHTTPClient but uses
+// heartbeatClient for HeartbeatStatus only, e.g. to fail heartbeats fast
+// while monit is unresponsive without failing requests that control services
+func NewHTTPClientWithHeartbeatClient(
+	host, username, password string,
+	shortClient boshhttp.Client,
+	longClient boshhttp.Client,
+	heartbeatClient boshhttp.Client,
+	logger boshlog.Logger,
 ) Client {
 	return httpClient{
 		host:            host,
@@ -44,12 +58,13 @@ func NewHTTPClient(
 		stopClient:      longClient,
 		unmonitorClient: longClient,
 		statusClient:    shortClient,
+		heartbeatClient: heartbeatClient,
 		logger:          logger,
 	}
 }
 
 func (c httpClient) ServicesInGroup(name string) (services []string, err error) {
-	status, err := c.status()
+	status, err := c.status(c.statusClient)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Getting status from Monit")
 	}
@@ -104,6 +119,26 @@ func (c httpClient) StopService(serviceName string) error {
 	return nil
 }
 
+func (c httpClient) RestartService(serviceName string) error {
+	response, err := c.makeRequest(c.stopClient, c.monitURL(serviceName), "POST", "action=restart")
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Sending restart request for service '%s'", serviceName)
+	}
+
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			c.logger.Warn("http-client", "Failed to close monit restart POST response body: %s", err.Error())
+		}
+	}()
+
+	err = c.validateResponse(response)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Restarting Monit service '%s'", serviceName)
+	}
+
+	return nil
+}
+
 func (c httpClient) UnmonitorService(serviceName string) error {
 	response, err := c.makeRequest(c.unmonitorClient, c.monitURL(serviceName), "POST", "action=unmonitor")
 	if err != nil {
@@ -125,15 +160,19 @@ func (c httpClient) UnmonitorService(serviceName string) error {
 }
 
 func (c httpClient) Status() (Status, error) {
-	return c.status()
+	return c.status(c.statusClient)
+}
+
+func (c httpClient) HeartbeatStatus() (Status, error) {
+	return c.status(c.heartbeatClient)
 }
 
-func (c httpClient) status() (status, error) {
+func (c httpClient) status(client boshhttp.Client) (status, error) {
 	c.logger.Debug("http-client", "status function called")
 	url := c.monitURL("_status2")
 	url.RawQuery = "format=xml"
 
-	response, err := c.makeRequest(c.statusClient, url, "GET", "")
+	response, err := c.makeRequest(client, url, "GET", "")
 	if err != nil {
 		return status{}, bosherr.WrapError(err, "Sending status request to monit")
 	}
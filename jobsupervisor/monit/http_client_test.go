@@ -111,6 +111,49 @@ var _ = Describe("httpClient", func() {
 		})
 	})
 
+	Describe("RestartService", func() {
+		It("restart service", func() {
+			var calledMonit bool
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calledMonit = true
+				Expect(r.Method).To(Equal("POST"))
+				Expect(r.URL.Path).To(Equal("/test-service"))
+				Expect(r.PostFormValue("action")).To(Equal("restart"))
+				Expect(r.Header.Get("Content-Type")).To(Equal("application/x-www-form-urlencoded"))
+
+				expectedAuthEncoded := base64.URLEncoding.EncodeToString([]byte("fake-user:fake-pass"))
+				Expect(r.Header.Get("Authorization")).To(Equal(fmt.Sprintf("Basic %s", expectedAuthEncoded)))
+			})
+
+			ts := httptest.NewServer(handler)
+			defer ts.Close()
+
+			client := newRealClient(ts.Listener.Addr().String())
+
+			err := client.RestartService("test-service")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calledMonit).To(BeTrue())
+		})
+
+		It("uses the longClient to send a restart request", func() {
+			shortClient := fakehttp.NewFakeClient()
+			longClient := fakehttp.NewFakeClient()
+			client := newFakeClient(shortClient, longClient)
+
+			longClient.StatusCode = 200
+
+			err := client.RestartService("test-service")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(shortClient.CallCount).To(Equal(0))
+			Expect(longClient.CallCount).To(Equal(1))
+
+			content := longClient.RequestBodies[0]
+			Expect(content).To(Equal("action=restart"))
+		})
+	})
+
 	Describe("UnmonitorService", func() {
 		It("issues a call to unmonitor service by name", func() {
 			var calledMonit bool
@@ -233,6 +276,37 @@ var _ = Describe("httpClient", func() {
 			Expect(req.URL.Path).To(Equal("/_status2"))
 			Expect(req.Method).To(Equal("GET"))
 		})
+
+		It("uses the heartbeatClient only to send heartbeat status requests", func() {
+			shortClient := fakehttp.NewFakeClient()
+			longClient := fakehttp.NewFakeClient()
+			heartbeatClient := fakehttp.NewFakeClient()
+			client := NewHTTPClientWithHeartbeatClient(
+				"agent.example.com",
+				"fake-user",
+				"fake-pass",
+				shortClient,
+				longClient,
+				heartbeatClient,
+				boshlog.NewLogger(boshlog.LevelNone),
+			)
+
+			heartbeatClient.StatusCode = 200
+			heartbeatClient.SetMessage(string(readFixture(statusWithMultipleServiceFixturePath)))
+			shortClient.StatusCode = 200
+			shortClient.SetMessage(string(readFixture(statusWithMultipleServiceFixturePath)))
+
+			_, err := client.HeartbeatStatus()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(heartbeatClient.CallCount).To(Equal(1))
+			Expect(shortClient.CallCount).To(Equal(0))
+
+			_, err = client.Status()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(heartbeatClient.CallCount).To(Equal(1))
+			Expect(shortClient.CallCount).To(Equal(1))
+			Expect(longClient.CallCount).To(Equal(0))
+		})
 	})
 })
 
@@ -1,9 +1,14 @@
 package monit
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"path"
 	"time"
 
+	"github.com/pivotal-golang/clock"
+
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshhttp "github.com/cloudfoundry/bosh-utils/http"
@@ -15,6 +20,12 @@ const (
 	longRetryStrategyAttempts  = uint(300)
 	retryDelay                 = 1 * time.Second
 	monitHost                  = "127.0.0.1:2822"
+
+	// Requests sent over the unix socket only need some host in their URL
+	monitSocketHost = "localhost"
+
+	circuitBreakerThreshold = uint(3)
+	circuitBreakerCooldown  = 30 * time.Second
 )
 
 type ClientProvider interface {
@@ -22,20 +33,40 @@ type ClientProvider interface {
 }
 
 type clientProvider struct {
-	platform        boshplatform.Platform
-	logger          boshlog.Logger
-	shortHTTPClient boshhttp.Client
-	longHTTPClient  boshhttp.Client
+	platform boshplatform.Platform
+	logger   boshlog.Logger
 }
 
 func NewProvider(platform boshplatform.Platform, logger boshlog.Logger) ClientProvider {
+	return clientProvider{
+		platform: platform,
+		logger:   logger,
+	}
+}
+
+// Get returns a client talking to monit over its unix socket when monit
+// was configured with "set httpd unixsocket" and over TCP otherwise
+func (p clientProvider) Get() (client Client, err error) {
+	monitUser, monitPassword, err := p.platform.GetMonitCredentials()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting monit credentials")
+	}
+
+	host := monitHost
 	httpClient := http.DefaultClient
 
+	socketPath := path.Join(p.platform.GetDirProvider().BaseDir(), "monit", "monit.sock")
+	if p.platform.GetFs().FileExists(socketPath) {
+		p.logger.Debug("monit-client-provider", "Using monit unix socket %s", socketPath)
+		host = monitSocketHost
+		httpClient = NewUnixSocketHTTPClient(socketPath)
+	}
+
 	shortHTTPClient := boshhttp.NewRetryClient(
 		httpClient,
 		shortRetryStrategyAttempts,
 		retryDelay,
-		logger,
+		p.logger,
 	)
 
 	longHTTPClient := NewMonitRetryClient(
@@ -43,29 +74,33 @@ func NewProvider(platform boshplatform.Platform, logger boshlog.Logger) ClientPr
 		longRetryStrategyAttempts,
 		shortRetryStrategyAttempts,
 		retryDelay,
-		logger,
+		p.logger,
 	)
 
-	return clientProvider{
-		platform:        platform,
-		logger:          logger,
-		shortHTTPClient: shortHTTPClient,
-		longHTTPClient:  longHTTPClient,
-	}
-}
-
-func (p clientProvider) Get() (client Client, err error) {
-	monitUser, monitPassword, err := p.platform.GetMonitCredentials()
-	if err != nil {
-		return nil, bosherr.WrapError(err, "Getting monit credentials")
-	}
+	// Only heartbeats, which poll monit periodically, fail fast while monit
+	// is unresponsive; retried requests count as a single failure
+	breaker := NewCircuitBreaker(circuitBreakerThreshold, circuitBreakerCooldown, clock.NewClock(), p.logger)
+	heartbeatHTTPClient := NewCircuitBreakerClient(shortHTTPClient, breaker)
 
-	return NewHTTPClient(
-		monitHost,
+	return NewHTTPClientWithHeartbeatClient(
+		host,
 		monitUser,
 		monitPassword,
-		p.shortHTTPClient,
-		p.longHTTPClient,
+		shortHTTPClient,
+		longHTTPClient,
+		heartbeatHTTPClient,
 		p.logger,
 	), nil
 }
+
+// NewUnixSocketHTTPClient sends all requests to the unix socket at socketPath
+func NewUnixSocketHTTPClient(socketPath string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+}
@@ -1,12 +1,19 @@
 package monit_test
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/pivotal-golang/clock"
+
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshhttp "github.com/cloudfoundry/bosh-utils/http"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -27,17 +34,77 @@ var _ = Describe("clientProvider", func() {
 
 		httpClient := http.DefaultClient
 
+		breaker := NewCircuitBreaker(3, 30*time.Second, clock.NewClock(), logger)
+
 		shortHTTPClient := boshhttp.NewRetryClient(httpClient, 20, 1*time.Second, logger)
 		longHTTPClient := NewMonitRetryClient(httpClient, 300, 20, 1*time.Second, logger)
+		heartbeatHTTPClient := NewCircuitBreakerClient(shortHTTPClient, breaker)
 
-		expectedClient := NewHTTPClient(
+		expectedClient := NewHTTPClientWithHeartbeatClient(
 			"127.0.0.1:2822",
 			"fake-user",
 			"fake-pass",
 			shortHTTPClient,
 			longHTTPClient,
+			heartbeatHTTPClient,
 			logger,
 		)
 		Expect(client).To(Equal(expectedClient))
 	})
+
+	It("uses the monit unix socket when monit listens on it", func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		platform := fakeplatform.NewFakePlatform()
+
+		platform.GetMonitCredentialsUsername = "fake-user"
+		platform.GetMonitCredentialsPassword = "fake-pass"
+
+		socketPath := filepath.Join(platform.GetDirProvider().BaseDir(), "monit", "monit.sock")
+		Expect(platform.Fs.WriteFileString(socketPath, "")).To(Succeed())
+
+		client, err := NewProvider(platform, logger).Get()
+		Expect(err).ToNot(HaveOccurred())
+
+		breaker := NewCircuitBreaker(3, 30*time.Second, clock.NewClock(), logger)
+
+		shortHTTPClient := boshhttp.NewRetryClient(http.DefaultClient, 20, 1*time.Second, logger)
+
+		tcpClient := NewHTTPClientWithHeartbeatClient(
+			"127.0.0.1:2822",
+			"fake-user",
+			"fake-pass",
+			shortHTTPClient,
+			NewMonitRetryClient(http.DefaultClient, 300, 20, 1*time.Second, logger),
+			NewCircuitBreakerClient(shortHTTPClient, breaker),
+			logger,
+		)
+		Expect(client).ToNot(Equal(tcpClient))
+	})
+})
+
+var _ = Describe("NewUnixSocketHTTPClient", func() {
+	It("sends requests to the unix socket", func() {
+		dir, err := ioutil.TempDir("", "monit-socket")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		socketPath := filepath.Join(dir, "monit.sock")
+
+		listener, err := net.Listen("unix", socketPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "path=%s", r.URL.Path)
+		})}
+		go server.Serve(listener)
+		defer server.Close()
+
+		response, err := NewUnixSocketHTTPClient(socketPath).Get("http://localhost/_status2")
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("path=/_status2"))
+	})
 })
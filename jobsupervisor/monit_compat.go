@@ -0,0 +1,28 @@
+package jobsupervisor
+
+import (
+	"fmt"
+	"time"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+)
+
+// Process states as reported by monit
+const (
+	processStateRunning      = "running"
+	processStateStarting     = "starting"
+	processStateFailing      = "failing"
+	processStateNotMonitored = "not monitored"
+)
+
+// newMonitrcAlert builds an alert shaped like the ones monit sends
+func newMonitrcAlert(now time.Time, service, event, action, description string) boshalert.MonitAlert {
+	return boshalert.MonitAlert{
+		ID:          fmt.Sprintf("%d@localhost", now.UnixNano()),
+		Service:     service,
+		Event:       event,
+		Action:      action,
+		Date:        now.Format(time.RFC1123Z),
+		Description: description,
+	}
+}
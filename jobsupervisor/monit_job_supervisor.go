@@ -2,15 +2,17 @@ package jobsupervisor
 
 import (
 	"fmt"
+	"net"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pivotal-golang/clock"
-	"github.com/pivotal/go-smtpd/smtpd"
 
-	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	boshmonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
+	boshmonitalert "github.com/cloudfoundry/bosh-agent/jobsupervisor/monitalert"
+	"github.com/cloudfoundry/bosh-agent/jobsupervisor/monitrc"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -19,15 +21,36 @@ import (
 
 const monitJobSupervisorLogTag = "monitJobSupervisor"
 
+// Mail server config the agent owns in the monit dir, which is included by the
+// stemcell's monitrc. It sorts before the stemcell's alerts.monitrc so that monit
+// tries the authenticated mail server before the one configured there.
+const monitAlertsConfigName = "agent_alerts.monitrc"
+
+const (
+	// Grace period of jobs whose stop programs do not set a longer
+	// timeout; monit itself waits as long for stop programs by default
+	monitStopGracePeriod = 30 * time.Second
+
+	// Time processes get to exit after SIGTERM before they are killed
+	monitStopKillTimeout = 10 * time.Second
+)
+
 type monitJobSupervisor struct {
-	fs                    boshsys.FileSystem
-	runner                boshsys.CmdRunner
-	client                boshmonit.Client
-	logger                boshlog.Logger
-	dirProvider           boshdir.Provider
-	jobFailuresServerPort int
-	reloadOptions         MonitReloadOptions
-	timeService           clock.Clock
+	fs                 boshsys.FileSystem
+	runner             boshsys.CmdRunner
+	client             boshmonit.Client
+	credentials        MonitCredentialsProvider
+	logger             boshlog.Logger
+	dirProvider        boshdir.Provider
+	alertServerOptions boshmonitalert.ServerOptions
+	reloadOptions      MonitReloadOptions
+	timeService        clock.Clock
+	statusCache        *monitStatusCache
+}
+
+// MonitCredentialsProvider is implemented by the platform
+type MonitCredentialsProvider interface {
+	GetMonitCredentials() (username, password string, err error)
 }
 
 type MonitReloadOptions struct {
@@ -46,21 +69,24 @@ func NewMonitJobSupervisor(
 	fs boshsys.FileSystem,
 	runner boshsys.CmdRunner,
 	client boshmonit.Client,
+	credentials MonitCredentialsProvider,
 	logger boshlog.Logger,
 	dirProvider boshdir.Provider,
-	jobFailuresServerPort int,
+	alertServerOptions boshmonitalert.ServerOptions,
 	reloadOptions MonitReloadOptions,
 	timeService clock.Clock,
 ) JobSupervisor {
 	return &monitJobSupervisor{
-		fs:                    fs,
-		runner:                runner,
-		client:                client,
-		logger:                logger,
-		dirProvider:           dirProvider,
-		jobFailuresServerPort: jobFailuresServerPort,
-		reloadOptions:         reloadOptions,
-		timeService:           timeService,
+		fs:                 fs,
+		runner:             runner,
+		client:             client,
+		credentials:        credentials,
+		logger:             logger,
+		dirProvider:        dirProvider,
+		alertServerOptions: alertServerOptions,
+		reloadOptions:      reloadOptions,
+		timeService:        timeService,
+		statusCache:        &monitStatusCache{},
 	}
 }
 
@@ -154,79 +180,56 @@ func (m monitJobSupervisor) Stop() error {
 	return nil
 }
 
-func (m monitJobSupervisor) StopAndWait() error {
+// StopAndWait stops one job at a time, starting with the last configured
+// one, so that jobs can rely on the jobs configured before them
+func (m monitJobSupervisor) StopAndWait() ([]JobStopResult, error) {
 	timer := m.timeService.NewTimer(5 * time.Minute)
+	defer timer.Stop()
 
-	for {
-		services, err := m.checkServices()
-		if err != nil {
-			return err
-		}
-
-		pendingServices := m.filterServices(services, func(service boshmonit.Service) bool {
-			return service.Pending
-		})
-
-		if len(pendingServices) == 0 {
-			break
-		}
-
-		select {
-		case <-timer.C():
-			return bosherr.Errorf("Timed out waiting for services '%s' to no longer be pending after 5 minutes", strings.Join(pendingServices, ", "))
-		default:
-		}
-
-		m.timeService.Sleep(500 * time.Millisecond)
+	services, err := m.waitForPendingServices(timer)
+	if err != nil {
+		return nil, err
 	}
 
-	_, _, _, err := m.runner.RunCommand("monit", "stop", "-g", "vcap")
+	jobs, err := m.stopOrder(services)
 	if err != nil {
-		stdout, stderr, _, summaryError := m.runner.RunCommand("monit", "summary")
-		if summaryError != nil {
-			m.logger.Error(monitJobSupervisorLogTag, "Failed to stop jobs: %s. Also failed to get monit summary: %s", err.Error(), summaryError.Error())
-		} else {
-			m.logger.Error(monitJobSupervisorLogTag, "Failed to stop jobs: %s. Current monit summary:\nstdout:\n%sstderr:\n%s", err.Error(), stdout, stderr)
-		}
-
-		return bosherr.WrapErrorf(err, "Stop all services")
+		m.logger.Warn(monitJobSupervisorLogTag, "Stopping all services at once: %s", err.Error())
+		return m.stopAllServices(services, timer)
 	}
 
+	// Jobs are given their own grace periods from here on
+	timer.Stop()
+
 	err = m.fs.WriteFileString(m.stoppedFilePath(), "")
 	if err != nil {
-		return bosherr.WrapError(err, "Creating stopped File")
+		return nil, bosherr.WrapError(err, "Creating stopped File")
 	}
-	m.logger.Debug(monitJobSupervisorLogTag, "Waiting for services to stop")
-	for {
-		services, err := m.checkServices()
-		if err != nil {
-			return err
-		}
-		erroredServices := m.filterServices(services, func(service boshmonit.Service) bool {
-			return service.Errored
-		})
-		servicesToStop := m.filterServices(services, func(service boshmonit.Service) bool {
-			return service.Monitored || service.Pending
-		})
 
-		if len(erroredServices) > 0 {
-			return bosherr.Errorf("Stopping services '%v' errored", erroredServices)
-		}
+	results := []JobStopResult{}
+	errs := []error{}
 
-		if len(servicesToStop) == 0 {
-			m.logger.Debug(monitJobSupervisorLogTag, "Successfully stopped all services")
-			return nil
+	// Keep stopping the remaining jobs when one fails to stop
+	for _, job := range jobs {
+		result, err := m.stopJob(job)
+		if err != nil {
+			result.State = JobStopStateFailed
+			result.Error = err.Error()
+			errs = append(errs, err)
 		}
 
-		select {
-		case <-timer.C():
-			return bosherr.Errorf("Timed out waiting for services '%s' to stop after 5 minutes", strings.Join(servicesToStop, ", "))
-		default:
-		}
+		m.logger.Debug(monitJobSupervisorLogTag, "Stopped job %s: %s", result.Name, result.State)
+		results = append(results, result)
+	}
 
-		m.logger.Debug(monitJobSupervisorLogTag, "Waiting for '%v' to stop", servicesToStop)
-		m.timeService.Sleep(500 * time.Millisecond)
+	if len(errs) == 1 {
+		return results, errs[0]
+	} else if len(errs) > 1 {
+		return results, bosherr.NewMultiError(errs...)
 	}
+
+	m.logger.Debug(monitJobSupervisorLogTag, "Successfully stopped all services")
+
+	return results, nil
 }
 
 func (m monitJobSupervisor) Unmonitor() error {
@@ -246,11 +249,59 @@ func (m monitJobSupervisor) Unmonitor() error {
 	return nil
 }
 
+func (m monitJobSupervisor) StartProcess(name string) error {
+	err := m.checkProcess(name, true)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Debug(monitJobSupervisorLogTag, "Starting service %s", name)
+
+	err = m.client.StartService(name)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Starting service %s", name)
+	}
+
+	return nil
+}
+
+func (m monitJobSupervisor) StopProcess(name string) error {
+	err := m.checkProcess(name, false)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Debug(monitJobSupervisorLogTag, "Stopping service %s", name)
+
+	err = m.client.StopService(name)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Stopping service %s", name)
+	}
+
+	return nil
+}
+
+func (m monitJobSupervisor) RestartProcess(name string) error {
+	err := m.checkProcess(name, true)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Debug(monitJobSupervisorLogTag, "Restarting service %s", name)
+
+	err = m.client.RestartService(name)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Restarting service %s", name)
+	}
+
+	return nil
+}
+
 func (m monitJobSupervisor) Status() (status string) {
 	status = "running"
 
 	m.logger.Debug(monitJobSupervisorLogTag, "Getting monit status")
-	monitStatus, err := m.client.Status()
+	monitStatus, err := m.reportedStatus()
 	if err != nil {
 		status = "unknown"
 		return
@@ -275,13 +326,28 @@ func (m monitJobSupervisor) Status() (status string) {
 }
 
 func (m monitJobSupervisor) Processes() (processes []Process, err error) {
-	processes = []Process{}
-
-	monitStatus, err := m.client.Status()
+	monitStatus, err := m.reportedStatus()
 	if err != nil {
-		return processes, bosherr.WrapError(err, "Getting service status")
+		return []Process{}, bosherr.WrapError(err, "Getting service status")
 	}
 
+	return monitProcesses(monitStatus), nil
+}
+
+// CachedProcesses returns the processes in the status heartbeats last
+// fetched, so that job events do not ask monit for its status as well
+func (m monitJobSupervisor) CachedProcesses() ([]Process, time.Time) {
+	monitStatus, fetchedAt := m.statusCache.Cached()
+	if monitStatus == nil {
+		return []Process{}, time.Time{}
+	}
+
+	return monitProcesses(monitStatus), fetchedAt
+}
+
+func monitProcesses(monitStatus boshmonit.Status) []Process {
+	processes := []Process{}
+
 	for _, service := range monitStatus.ServicesInGroup("vcap") {
 		process := Process{
 			Name:  service.Name,
@@ -300,7 +366,26 @@ func (m monitJobSupervisor) Processes() (processes []Process, err error) {
 		processes = append(processes, process)
 	}
 
-	return
+	return processes
+}
+
+func (m monitJobSupervisor) StatusStaleSince() time.Time {
+	return m.statusCache.StaleSince()
+}
+
+// reportedStatus is the status reported by Status and Processes; monit not
+// responding for a moment does not change the reported job state
+func (m monitJobSupervisor) reportedStatus() (boshmonit.Status, error) {
+	monitStatus, err := m.client.HeartbeatStatus()
+	if err != nil {
+		m.logger.Warn(monitJobSupervisorLogTag, "Getting monit status: %s", err.Error())
+	}
+
+	return m.statusCache.Refresh(monitStatus, err, m.timeService.Now())
+}
+
+func (m monitJobSupervisor) WaitUntilReady(timeout time.Duration) error {
+	return nil
 }
 
 func (m monitJobSupervisor) getIncarnation() (int, error) {
@@ -333,26 +418,107 @@ func (m monitJobSupervisor) RemoveAllJobs() error {
 	return m.fs.RemoveAll(m.dirProvider.MonitJobsDir())
 }
 
-func (m monitJobSupervisor) MonitorJobFailures(handler JobFailureHandler) (err error) {
-	alertHandler := func(smtpd.Connection, smtpd.MailAddress) (env smtpd.Envelope, err error) {
-		env = &alertEnvelope{
-			new(smtpd.BasicEnvelope),
-			handler,
-			new(boshalert.MonitAlert),
+// MonitorJobFailures receives the alert mails monit sends; monit has to
+// authenticate with the same credentials used for its HTTP interface,
+// which are rendered into the mail server config monit includes
+func (m monitJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error {
+	username, password, err := m.credentials.GetMonitCredentials()
+	if err != nil {
+		return bosherr.WrapError(err, "Getting monit credentials")
+	}
+
+	changed, err := m.writeAlertsConfig(username, password)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing monit alerts config")
+	}
+
+	if changed {
+		err = m.Reload()
+		if err != nil {
+			return bosherr.WrapError(err, "Reloading monit alerts config")
 		}
-		return
 	}
 
-	serv := &smtpd.Server{
-		Addr:      fmt.Sprintf("127.0.0.1:%d", m.jobFailuresServerPort),
-		OnNewMail: alertHandler,
+	server := boshmonitalert.NewServer(
+		m.alertServerOptions,
+		boshmonitalert.Credentials{Username: username, Password: password},
+		boshmonitalert.Handler(handler),
+		m.logger,
+	)
+
+	err = server.ListenAndServe()
+	if err != nil {
+		return bosherr.WrapError(err, "Listening for monit alerts")
+	}
+
+	return nil
+}
+
+// writeAlertsConfig points monit's mail server at the alert server and reports
+// whether the config changed; monit only picks it up on the next reload
+func (m monitJobSupervisor) writeAlertsConfig(username, password string) (bool, error) {
+	if m.alertServerOptions.Network != "tcp" {
+		m.logger.Warn(monitJobSupervisorLogTag, "Monit cannot send alerts over %s", m.alertServerOptions.Network)
+		return false, nil
+	}
+
+	host, port, err := net.SplitHostPort(m.alertServerOptions.Address)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Parsing alert server address %s", m.alertServerOptions.Address)
+	}
+
+	// monitrc strings cannot contain escaped quotes
+	if strings.Contains(username+password, `"`) {
+		return false, bosherr.Error("Monit credentials must not contain quotes")
+	}
+
+	configPath := path.Join(m.dirProvider.MonitDir(), monitAlertsConfigName)
+	config := fmt.Sprintf("set mailserver %s port %s username \"%s\" password \"%s\"\n", host, port, username, password)
+
+	if m.fs.FileExists(configPath) {
+		current, err := m.fs.ReadFileString(configPath)
+		if err == nil && current == config {
+			return false, nil
+		}
 	}
 
-	err = serv.ListenAndServe()
+	err = m.fs.WriteFileQuietly(configPath, []byte(config))
 	if err != nil {
-		err = bosherr.WrapError(err, "Listen for SMTP")
+		return false, bosherr.WrapErrorf(err, "Writing %s", configPath)
 	}
-	return
+
+	err = m.fs.Chmod(configPath, 0600)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Changing permissions of %s", configPath)
+	}
+
+	return true, nil
+}
+
+// MonitorJobEvents returns immediately since monit only reports failures
+func (m monitJobSupervisor) MonitorJobEvents(_ JobEventHandler) error {
+	return nil
+}
+
+// checkProcess returns an error unless name is a vcap service;
+// starting requires the instance not to be stopped
+func (m monitJobSupervisor) checkProcess(name string, starting bool) error {
+	if starting && m.fs.FileExists(m.stoppedFilePath()) {
+		return bosherr.Errorf("Cannot start process '%s' of a stopped instance", name)
+	}
+
+	services, err := m.client.ServicesInGroup("vcap")
+	if err != nil {
+		return bosherr.WrapError(err, "Getting vcap services")
+	}
+
+	for _, service := range services {
+		if service == name {
+			return nil
+		}
+	}
+
+	return bosherr.Errorf("Unknown process '%s'", name)
 }
 
 func (m monitJobSupervisor) stoppedFilePath() string {
@@ -383,3 +549,308 @@ func (m monitJobSupervisor) checkServices() ([]boshmonit.Service, error) {
 
 func (m monitJobSupervisor) HealthRecorder(status string) {
 }
+
+// waitForPendingServices waits for pending monit actions to finish before
+// stopping services since monit only acts on services without pending actions
+func (m monitJobSupervisor) waitForPendingServices(timer clock.Timer) ([]boshmonit.Service, error) {
+	for {
+		services, err := m.checkServices()
+		if err != nil {
+			return nil, err
+		}
+
+		pendingServices := m.filterServices(services, func(service boshmonit.Service) bool {
+			return service.Pending
+		})
+
+		if len(pendingServices) == 0 {
+			return services, nil
+		}
+
+		select {
+		case <-timer.C():
+			return nil, bosherr.Errorf("Timed out waiting for services '%s' to no longer be pending after 5 minutes", strings.Join(pendingServices, ", "))
+		default:
+		}
+
+		m.timeService.Sleep(500 * time.Millisecond)
+	}
+}
+
+// stopAllServices stops all vcap services with monit without regard to
+// their jobs, for when the job configs cannot be loaded to order them
+func (m monitJobSupervisor) stopAllServices(services []boshmonit.Service, timer clock.Timer) ([]JobStopResult, error) {
+	_, _, _, err := m.runner.RunCommand("monit", "stop", "-g", "vcap")
+	if err != nil {
+		stdout, stderr, _, summaryError := m.runner.RunCommand("monit", "summary")
+		if summaryError != nil {
+			m.logger.Error(monitJobSupervisorLogTag, "Failed to stop jobs: %s. Also failed to get monit summary: %s", err.Error(), summaryError.Error())
+		} else {
+			m.logger.Error(monitJobSupervisorLogTag, "Failed to stop jobs: %s. Current monit summary:\nstdout:\n%sstderr:\n%s", err.Error(), stdout, stderr)
+		}
+
+		return nil, bosherr.WrapErrorf(err, "Stop all services")
+	}
+
+	err = m.fs.WriteFileString(m.stoppedFilePath(), "")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Creating stopped File")
+	}
+
+	m.logger.Debug(monitJobSupervisorLogTag, "Waiting for services to stop")
+
+	for {
+		current, err := m.checkServices()
+		if err != nil {
+			return nil, err
+		}
+
+		erroredServices := m.filterServices(current, func(service boshmonit.Service) bool {
+			return service.Errored
+		})
+		servicesToStop := m.filterServices(current, func(service boshmonit.Service) bool {
+			return service.Monitored || service.Pending
+		})
+
+		if len(erroredServices) > 0 {
+			return nil, bosherr.Errorf("Stopping services '%v' errored", erroredServices)
+		}
+
+		if len(servicesToStop) == 0 {
+			break
+		}
+
+		select {
+		case <-timer.C():
+			return nil, bosherr.Errorf("Timed out waiting for services '%s' to stop after 5 minutes", strings.Join(servicesToStop, ", "))
+		default:
+		}
+
+		m.logger.Debug(monitJobSupervisorLogTag, "Waiting for '%v' to stop", servicesToStop)
+		m.timeService.Sleep(500 * time.Millisecond)
+	}
+
+	m.logger.Debug(monitJobSupervisorLogTag, "Successfully stopped all services")
+
+	results := []JobStopResult{}
+	for _, service := range services {
+		results = append(results, JobStopResult{Name: service.Name, State: JobStopStateStopped})
+	}
+
+	return results, nil
+}
+
+// monitStopJob is a job whose vcap services are stopped together
+type monitStopJob struct {
+	name        string
+	processes   []monitrc.Process
+	gracePeriod time.Duration
+}
+
+// stopOrder returns the jobs in reverse configuration order, followed by
+// the services that do not belong to any job config
+func (m monitJobSupervisor) stopOrder(services []boshmonit.Service) ([]monitStopJob, error) {
+	configs, err := loadMonitrcJobs(m.fs, m.dirProvider.MonitJobsDir())
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Loading job configs")
+	}
+
+	jobs := []monitStopJob{}
+	configured := map[string]bool{}
+
+	for i := len(configs) - 1; i >= 0; i-- {
+		job := monitStopJob{name: configs[i].Name, gracePeriod: monitStopGracePeriod}
+
+		for _, process := range configs[i].Processes {
+			if !process.InGroup("vcap") {
+				continue
+			}
+
+			// Wait for the stop program at least as long as monit does
+			if process.Stop.Timeout > job.gracePeriod {
+				job.gracePeriod = process.Stop.Timeout
+			}
+
+			job.processes = append(job.processes, process)
+			configured[process.Name] = true
+		}
+
+		if len(job.processes) > 0 {
+			jobs = append(jobs, job)
+		}
+	}
+
+	for _, service := range services {
+		if !configured[service.Name] {
+			jobs = append(jobs, monitStopJob{
+				name:        service.Name,
+				processes:   []monitrc.Process{{Name: service.Name}},
+				gracePeriod: monitStopGracePeriod,
+			})
+		}
+	}
+
+	return jobs, nil
+}
+
+// stopJob asks monit to stop the services of a job and escalates to
+// SIGTERM and then SIGKILL when they do not stop within the grace period
+func (m monitJobSupervisor) stopJob(job monitStopJob) (JobStopResult, error) {
+	result := JobStopResult{Name: job.name}
+
+	names := []string{}
+	for _, process := range job.processes {
+		names = append(names, process.Name)
+	}
+
+	for _, name := range names {
+		m.logger.Debug(monitJobSupervisorLogTag, "Stopping service %s", name)
+
+		err := m.client.StopService(name)
+		if err != nil {
+			return result, bosherr.WrapErrorf(err, "Stopping service %s", name)
+		}
+	}
+
+	running, errored, err := m.waitForServices(names, job.gracePeriod)
+	if err != nil {
+		return result, err
+	}
+
+	if len(running) == 0 && len(errored) == 0 {
+		result.State = JobStopStateStopped
+		return result, nil
+	}
+
+	pids := m.alivePids(m.readPids(job.processes, append(running, errored...)))
+	if len(pids) == 0 {
+		if len(errored) > 0 {
+			return result, bosherr.Errorf("Stopping services '%v' errored", errored)
+		}
+
+		return result, bosherr.Errorf("Timed out waiting for services '%s' to stop after %s", strings.Join(running, ", "), job.gracePeriod)
+	}
+
+	m.logger.Info(monitJobSupervisorLogTag, "Terminating job %s after services '%v' did not stop", job.name, append(running, errored...))
+	m.signal(pids, "TERM")
+
+	timer := m.timeService.NewTimer(monitStopKillTimeout)
+	defer timer.Stop()
+
+	for {
+		pids = m.alivePids(pids)
+		if len(pids) == 0 {
+			result.State = JobStopStateTerminated
+			return result, nil
+		}
+
+		select {
+		case <-timer.C():
+			m.logger.Info(monitJobSupervisorLogTag, "Killing job %s after processes %v did not terminate", job.name, pids)
+			m.signal(pids, "KILL")
+			result.State = JobStopStateKilled
+			return result, nil
+		default:
+		}
+
+		m.timeService.Sleep(500 * time.Millisecond)
+	}
+}
+
+// waitForServices polls monit until the services stopped, one of them
+// errored or the timeout elapsed, and returns the ones not stopped
+func (m monitJobSupervisor) waitForServices(names []string, timeout time.Duration) (running, errored []string, err error) {
+	isJobService := func(service boshmonit.Service) bool {
+		for _, name := range names {
+			if service.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	timer := m.timeService.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		services, err := m.checkServices()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		errored = m.filterServices(services, func(service boshmonit.Service) bool {
+			return isJobService(service) && service.Errored
+		})
+		running = m.filterServices(services, func(service boshmonit.Service) bool {
+			return isJobService(service) && !service.Errored && (service.Monitored || service.Pending)
+		})
+
+		if len(errored) > 0 || len(running) == 0 {
+			return running, errored, nil
+		}
+
+		select {
+		case <-timer.C():
+			return running, nil, nil
+		default:
+		}
+
+		m.logger.Debug(monitJobSupervisorLogTag, "Waiting for '%v' to stop", running)
+		m.timeService.Sleep(500 * time.Millisecond)
+	}
+}
+
+// readPids returns the pids in the pid files of the named processes
+func (m monitJobSupervisor) readPids(processes []monitrc.Process, names []string) []int {
+	pids := []int{}
+
+	for _, process := range processes {
+		if process.PidFile == "" {
+			continue
+		}
+
+		for _, name := range names {
+			if process.Name != name {
+				continue
+			}
+
+			content, err := m.fs.ReadFileString(process.PidFile)
+			if err != nil {
+				m.logger.Warn(monitJobSupervisorLogTag, "Reading pid file of service %s: %s", name, err.Error())
+				continue
+			}
+
+			pid, err := strconv.Atoi(strings.TrimSpace(content))
+			if err != nil || pid <= 0 {
+				m.logger.Warn(monitJobSupervisorLogTag, "Invalid pid file of service %s", name)
+				continue
+			}
+
+			pids = append(pids, pid)
+		}
+	}
+
+	return pids
+}
+
+func (m monitJobSupervisor) alivePids(pids []int) []int {
+	alive := []int{}
+
+	for _, pid := range pids {
+		_, _, _, err := m.runner.RunCommand("kill", "-0", strconv.Itoa(pid))
+		if err == nil {
+			alive = append(alive, pid)
+		}
+	}
+
+	return alive
+}
+
+func (m monitJobSupervisor) signal(pids []int, signal string) {
+	for _, pid := range pids {
+		_, _, _, err := m.runner.RunCommand("kill", "-s", signal, strconv.Itoa(pid))
+		if err != nil {
+			m.logger.Warn(monitJobSupervisorLogTag, "Sending SIG%s to %d: %s", signal, pid, err.Error())
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"net/smtp"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/pivotal-golang/clock/fakeclock"
@@ -19,6 +20,8 @@ import (
 	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshmonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
 	fakemonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit/fakes"
+	boshmonitalert "github.com/cloudfoundry/bosh-agent/jobsupervisor/monitalert"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
@@ -26,14 +29,15 @@ import (
 
 var _ = Describe("monitJobSupervisor", func() {
 	var (
-		fs                    *fakesys.FakeFileSystem
-		runner                *fakesys.FakeCmdRunner
-		client                *fakemonit.FakeMonitClient
-		logger                boshlog.Logger
-		dirProvider           boshdir.Provider
-		jobFailuresServerPort int
-		monit                 JobSupervisor
-		timeService           *fakeclock.FakeClock
+		fs                 *fakesys.FakeFileSystem
+		runner             *fakesys.FakeCmdRunner
+		client             *fakemonit.FakeMonitClient
+		platform           *fakeplatform.FakePlatform
+		logger             boshlog.Logger
+		dirProvider        boshdir.Provider
+		alertServerOptions boshmonitalert.ServerOptions
+		monit              JobSupervisor
+		timeService        *fakeclock.FakeClock
 	)
 
 	var jobFailureServerPort = 5000
@@ -49,16 +53,21 @@ var _ = Describe("monitJobSupervisor", func() {
 		client = fakemonit.NewFakeMonitClient()
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 		dirProvider = boshdir.NewProvider("/var/vcap")
-		jobFailuresServerPort = getJobFailureServerPort()
+		platform = fakeplatform.NewFakePlatform()
+		platform.GetMonitCredentialsUsername = "fake-monit-user"
+		platform.GetMonitCredentialsPassword = "fake-monit-password"
+		alertServerOptions = boshmonitalert.DefaultServerOptions()
+		alertServerOptions.Address = fmt.Sprintf("127.0.0.1:%d", getJobFailureServerPort())
 		timeService = fakeclock.NewFakeClock(time.Now())
 
 		monit = NewMonitJobSupervisor(
 			fs,
 			runner,
 			client,
+			platform,
 			logger,
 			dirProvider,
-			jobFailuresServerPort,
+			alertServerOptions,
 			MonitReloadOptions{
 				MaxTries:               3,
 				MaxCheckTries:          10,
@@ -68,20 +77,36 @@ var _ = Describe("monitJobSupervisor", func() {
 		)
 	})
 
-	doJobFailureEmail := func(email string, port int) error {
-		conn, err := smtp.Dial(fmt.Sprintf("localhost:%d", port))
-		for err != nil {
-			conn, err = smtp.Dial(fmt.Sprintf("localhost:%d", port))
+	doJobFailureEmail := func(email string, auth smtp.Auth) error {
+		var conn *smtp.Client
+		Eventually(func() (err error) {
+			conn, err = smtp.Dial(alertServerOptions.Address)
+			return
+		}).Should(Succeed())
+
+		defer conn.Close()
+
+		if auth != nil {
+			err := conn.Auth(auth)
+			if err != nil {
+				return err
+			}
 		}
 
-		conn.Mail("sender@example.org")
-		conn.Rcpt("recipient@example.net")
-		writeCloser, err := conn.Data()
+		err := conn.Mail("sender@example.org")
 		if err != nil {
 			return err
 		}
 
-		defer writeCloser.Close()
+		err = conn.Rcpt("recipient@example.net")
+		if err != nil {
+			return err
+		}
+
+		writeCloser, err := conn.Data()
+		if err != nil {
+			return err
+		}
 
 		buf := bytes.NewBufferString(fmt.Sprintf("%s\r\n", email))
 		_, err = buf.WriteTo(writeCloser)
@@ -89,9 +114,87 @@ var _ = Describe("monitJobSupervisor", func() {
 			return err
 		}
 
-		return nil
+		return writeCloser.Close()
 	}
 
+	monitAuth := func() smtp.Auth {
+		return smtp.PlainAuth("", "fake-monit-user", "fake-monit-password", "127.0.0.1")
+	}
+
+	Context("when the circuit breaker of monit heartbeats is open", func() {
+		var (
+			server      *httptest.Server
+			incarnation int32
+			started     int32
+			realClient  boshmonit.Client
+		)
+
+		BeforeEach(func() {
+			incarnation = 1
+			started = 0
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/test-service" && r.PostFormValue("action") == "start" {
+					atomic.AddInt32(&started, 1)
+					return
+				}
+
+				fmt.Fprintf(w, `<monit incarnation="%d"><services><service name="test-service"><monitor>1</monitor></service></services>`+
+					`<servicegroups><servicegroup name="vcap"><service>test-service</service></servicegroup></servicegroups></monit>`,
+					atomic.LoadInt32(&incarnation))
+			}))
+
+			runner.SetCmdCallback("monit reload", func() {
+				atomic.AddInt32(&incarnation, 1)
+			})
+
+			breaker := boshmonit.NewCircuitBreaker(3, 30*time.Second, timeService, logger)
+			realClient = boshmonit.NewHTTPClientWithHeartbeatClient(
+				server.Listener.Addr().String(),
+				"fake-user",
+				"fake-pass",
+				http.DefaultClient,
+				http.DefaultClient,
+				boshmonit.NewCircuitBreakerClient(failingHTTPClient{}, breaker),
+				logger,
+			)
+
+			monit = NewMonitJobSupervisor(
+				fs,
+				runner,
+				realClient,
+				platform,
+				logger,
+				dirProvider,
+				alertServerOptions,
+				MonitReloadOptions{MaxTries: 3, MaxCheckTries: 10},
+				timeService,
+			)
+
+			for i := 0; i < 3; i++ {
+				_, err := realClient.HeartbeatStatus()
+				Expect(err).To(MatchError(ContainSubstring("fake-heartbeat-error")))
+			}
+
+			_, err := realClient.HeartbeatStatus()
+			Expect(err).To(MatchError(ContainSubstring("circuit breaker is open")))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("still starts services through monit", func() {
+			Expect(monit.Start()).To(Succeed())
+			Expect(atomic.LoadInt32(&started)).To(Equal(int32(1)))
+		})
+
+		It("still reloads monit", func() {
+			Expect(monit.Reload()).To(Succeed())
+			Expect(atomic.LoadInt32(&incarnation)).To(Equal(int32(2)))
+		})
+	})
+
 	Describe("Reload", func() {
 		It("waits until the job is reloaded", func() {
 			client.Incarnations = []int{1, 1, 1, 2, 3}
@@ -199,12 +302,150 @@ var _ = Describe("monitJobSupervisor", func() {
 		})
 	})
 
+	Describe("StartProcess", func() {
+		BeforeEach(func() {
+			client.ServicesInGroupServices = []string{"fake-service", "other-service"}
+		})
+
+		It("starts the monit service", func() {
+			err := monit.StartProcess("fake-service")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(client.ServicesInGroupName).To(Equal("vcap"))
+			Expect(client.StartServiceNames).To(Equal([]string{"fake-service"}))
+		})
+
+		It("returns an error for services that are not in group vcap", func() {
+			err := monit.StartProcess("unknown-service")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown process 'unknown-service'"))
+			Expect(client.StartServiceNames).To(BeEmpty())
+		})
+
+		It("returns an error when the instance is stopped", func() {
+			fs.WriteFileString("/var/vcap/monit/stopped", "")
+
+			err := monit.StartProcess("fake-service")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Cannot start process 'fake-service' of a stopped instance"))
+			Expect(client.StartServiceNames).To(BeEmpty())
+		})
+
+		It("returns an error when monit fails to start the service", func() {
+			client.StartServiceErr = errors.New("fake-start-err")
+
+			err := monit.StartProcess("fake-service")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-start-err"))
+		})
+	})
+
+	Describe("StopProcess", func() {
+		BeforeEach(func() {
+			client.ServicesInGroupServices = []string{"fake-service"}
+		})
+
+		It("stops the monit service without creating the stopped file", func() {
+			err := monit.StopProcess("fake-service")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(client.StopServiceNames).To(Equal([]string{"fake-service"}))
+			Expect(fs.FileExists("/var/vcap/monit/stopped")).To(BeFalse())
+		})
+
+		It("stops services of a stopped instance", func() {
+			fs.WriteFileString("/var/vcap/monit/stopped", "")
+
+			err := monit.StopProcess("fake-service")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.StopServiceNames).To(Equal([]string{"fake-service"}))
+		})
+
+		It("returns an error for services that are not in group vcap", func() {
+			err := monit.StopProcess("unknown-service")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown process 'unknown-service'"))
+		})
+	})
+
+	Describe("RestartProcess", func() {
+		BeforeEach(func() {
+			client.ServicesInGroupServices = []string{"fake-service"}
+		})
+
+		It("restarts the monit service", func() {
+			err := monit.RestartProcess("fake-service")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(client.RestartServiceNames).To(Equal([]string{"fake-service"}))
+		})
+
+		It("returns an error when the instance is stopped", func() {
+			fs.WriteFileString("/var/vcap/monit/stopped", "")
+
+			err := monit.RestartProcess("fake-service")
+			Expect(err).To(HaveOccurred())
+			Expect(client.RestartServiceNames).To(BeEmpty())
+		})
+
+		It("returns an error when monit fails to restart the service", func() {
+			client.RestartServiceErr = errors.New("fake-restart-err")
+
+			err := monit.RestartProcess("fake-service")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Restarting service fake-service"))
+		})
+	})
+
 	Describe("StopAndWait", func() {
-		It("stop stops each monit service in group vcap", func() {
-			err := monit.StopAndWait()
+		stopAndWait := func() <-chan error {
+			errchan := make(chan error, 1)
+			go func() {
+				_, err := monit.StopAndWait()
+				errchan <- err
+			}()
+			return errchan
+		}
+
+		var jobConfigPaths []string
+
+		BeforeEach(func() {
+			jobConfigPaths = nil
+		})
+
+		writeJobConfigContent := func(filename, content string) {
+			jobConfigPath := dirProvider.MonitJobsDir() + "/" + filename
+			Expect(fs.WriteFileString(jobConfigPath, content)).To(Succeed())
+
+			jobConfigPaths = append(jobConfigPaths, jobConfigPath)
+			fs.SetGlob(dirProvider.MonitJobsDir()+"/*.monitrc", jobConfigPaths)
+		}
+
+		writeJobConfig := func(filename, process, stopTimeout string) {
+			writeJobConfigContent(filename, fmt.Sprintf(`
+check process %[1]s
+  with pidfile /var/vcap/sys/run/%[1]s/%[1]s.pid
+  start program "/var/vcap/jobs/%[1]s/bin/ctl start"
+  stop program "/var/vcap/jobs/%[1]s/bin/ctl stop"%[2]s
+  group vcap
+`, process, stopTimeout))
+		}
+
+		It("stops each monit service in group vcap and reports them as stopped", func() {
+			client.StatusStatus = fakemonit.FakeMonitStatus{
+				Services: []boshmonit.Service{
+					{Monitored: false, Name: "foo", Status: "unknown"},
+					{Monitored: false, Name: "bar", Status: "unknown"},
+				},
+			}
+
+			results, err := monit.StopAndWait()
 			Expect(err).ToNot(HaveOccurred())
-			Expect(len(runner.RunCommands)).To(Equal(1))
-			Expect(runner.RunCommands[0]).To(Equal([]string{"monit", "stop", "-g", "vcap"}))
+			Expect(client.StopServiceNames).To(Equal([]string{"foo", "bar"}))
+			Expect(results).To(Equal([]JobStopResult{
+				{Name: "foo", State: JobStopStateStopped},
+				{Name: "bar", State: JobStopStateStopped},
+			}))
 		})
 
 		It("stops", func() {
@@ -237,9 +478,10 @@ var _ = Describe("monitJobSupervisor", func() {
 				fs,
 				runner,
 				client,
+				platform,
 				logger,
 				dirProvider,
-				jobFailuresServerPort,
+				alertServerOptions,
 				MonitReloadOptions{
 					MaxTries:               3,
 					MaxCheckTries:          10,
@@ -248,28 +490,176 @@ var _ = Describe("monitJobSupervisor", func() {
 				timeService,
 			)
 
-			err := monit.StopAndWait()
+			_, err := monit.StopAndWait()
 			Expect(err).To(BeNil())
 		})
 
-		Describe("Waiting for pending services", func() {
-			It("waits for services to not be pending before attempting to stop", func() {
+		It("stops jobs in reverse configuration order followed by services without job config", func() {
+			writeJobConfig("0000_web.monitrc", "web", "")
+			writeJobConfig("0001_db.monitrc", "db", "")
+			writeJobConfig("0001_db_backup.monitrc", "db-backup", "")
+
+			client.StatusStatus = fakemonit.FakeMonitStatus{
+				Services: []boshmonit.Service{
+					{Monitored: false, Name: "web", Status: "unknown"},
+					{Monitored: false, Name: "other", Status: "unknown"},
+					{Monitored: false, Name: "db", Status: "unknown"},
+					{Monitored: false, Name: "db-backup", Status: "unknown"},
+				},
+			}
+
+			results, err := monit.StopAndWait()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.StopServiceNames).To(Equal([]string{"db-backup", "db", "web", "other"}))
+			Expect(results).To(Equal([]JobStopResult{
+				{Name: "db_backup", State: JobStopStateStopped},
+				{Name: "db", State: JobStopStateStopped},
+				{Name: "web", State: JobStopStateStopped},
+				{Name: "other", State: JobStopStateStopped},
+			}))
+		})
+
+		It("orders jobs whose processes monit matches by pattern", func() {
+			writeJobConfig("0000_web.monitrc", "web", "")
+			writeJobConfigContent("0001_worker.monitrc", `
+check process worker
+  matching "fake-worker"
+  group vcap
+`)
+
+			client.StatusStatus = fakemonit.FakeMonitStatus{
+				Services: []boshmonit.Service{
+					{Monitored: false, Name: "web", Status: "unknown"},
+					{Monitored: false, Name: "worker", Status: "unknown"},
+				},
+			}
+
+			_, err := monit.StopAndWait()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.StopServiceNames).To(Equal([]string{"worker", "web"}))
+		})
+
+		Context("when the job configs cannot be loaded", func() {
+			BeforeEach(func() {
+				writeJobConfigContent("0000_web.monitrc", "check process")
+			})
+
+			It("stops all vcap services with monit", func() {
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						{Monitored: false, Name: "foo", Status: "unknown"},
+						{Monitored: false, Name: "bar", Status: "unknown"},
+					},
+				}
+
+				results, err := monit.StopAndWait()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(runner.RunCommands).To(Equal([][]string{{"monit", "stop", "-g", "vcap"}}))
+				Expect(client.StopServiceNames).To(BeEmpty())
+				Expect(fs.FileExists("/var/vcap/monit/stopped")).To(BeTrue())
+				Expect(results).To(Equal([]JobStopResult{
+					{Name: "foo", State: JobStopStateStopped},
+					{Name: "bar", State: JobStopStateStopped},
+				}))
+			})
+
+			It("uses the same timer for waiting for pending and waiting for services to stop", func() {
 				client.StatusStatus = fakemonit.FakeMonitStatus{
 					Services: []boshmonit.Service{
 						{Monitored: false, Name: "foo", Status: "unknown", Pending: true},
-						{Monitored: true, Name: "bar", Status: "unknown", Pending: false},
 					},
 				}
 
+				errchan := stopAndWait()
+
+				Eventually(timeService.WatcherCount).Should(Equal(2)) // we hit the pending sleep
+
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						{Monitored: true, Name: "foo", Status: "unknown", Pending: false},
+					},
+				}
+				timeService.Increment(3 * time.Minute)
+
+				Eventually(timeService.WatcherCount).Should(Equal(2)) // we hit the stop sleep
+
+				timeService.Increment(3 * time.Minute)
+
+				Eventually(errchan).Should(Receive(Equal(errors.New("Timed out waiting for services 'foo' to stop after 5 minutes"))))
+			})
+
+			It("exits with an error after a timeout", func() {
+				var statusRequests int32
+				handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requestData := make(map[string]string)
+
+					if r.URL.Path == "/_status2" {
+						if atomic.AddInt32(&statusRequests, 1) == 1 {
+							w.Write(readFixture("monit/test_assets/monit_status_running.xml"))
+						} else {
+							w.Write(readFixture("monit/test_assets/monit_status_multiple.xml"))
+						}
+					} else {
+						Expect(r.Method).To(Equal("POST"))
+						requestData["action"] = r.PostFormValue("action")
+					}
+				})
+
+				ts := httptest.NewServer(handler)
+				defer ts.Close()
+
+				url := ts.Listener.Addr().String()
+				client := boshmonit.NewHTTPClient(
+					url,
+					"fake-user",
+					"fake-pass",
+					http.DefaultClient,
+					http.DefaultClient,
+					logger,
+				)
+
+				monit := NewMonitJobSupervisor(
+					fs,
+					runner,
+					client,
+					platform,
+					logger,
+					dirProvider,
+					alertServerOptions,
+					MonitReloadOptions{},
+					timeService,
+				)
+
 				errchan := make(chan error)
 				go func() {
-					errchan <- monit.StopAndWait()
+					_, err := monit.StopAndWait()
+					errchan <- err
 				}()
 
+				failureMessage := "Timed out waiting for services 'unmonitored-start-pending, initializing, running, running-stop-pending, unmonitored-stop-pending, failing' to stop after 5 minutes"
+
+				advanceTime(timeService, 5*time.Minute, 2)
+				Eventually(timeService.WatcherCount).Should(Equal(0))
+				Eventually(errchan).Should(Receive(Equal(errors.New(failureMessage))))
+				Expect(atomic.LoadInt32(&statusRequests)).To(Equal(int32(3)))
+			})
+		})
+
+		Describe("Waiting for pending services", func() {
+			It("waits for services to not be pending before attempting to stop", func() {
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						{Monitored: false, Name: "foo", Status: "unknown", Pending: true},
+						{Monitored: true, Name: "bar", Status: "unknown", Pending: false},
+					},
+				}
+
+				errchan := stopAndWait()
+
 				Eventually(timeService.WatcherCount).Should(Equal(2)) // we hit the sleep
 
 				// never called stop since 2 jobs pending
-				Expect(len(runner.RunCommands)).To(Equal(0))
+				Expect(client.StopServiceNames).To(BeEmpty())
 
 				client.StatusStatus = fakemonit.FakeMonitStatus{
 					Services: []boshmonit.Service{
@@ -282,7 +672,7 @@ var _ = Describe("monitJobSupervisor", func() {
 				Eventually(timeService.WatcherCount).Should(Equal(2)) // we hit the sleep
 
 				// never called stop since 1 job pending
-				Expect(len(runner.RunCommands)).To(Equal(0))
+				Expect(client.StopServiceNames).To(BeEmpty())
 
 				client.StatusStatus = fakemonit.FakeMonitStatus{
 					Services: []boshmonit.Service{
@@ -293,8 +683,7 @@ var _ = Describe("monitJobSupervisor", func() {
 				timeService.Increment(2 * time.Minute)
 
 				Eventually(errchan).Should(Receive(BeNil()))
-				Expect(len(runner.RunCommands)).To(Equal(1))
-				Expect(runner.RunCommands[0]).To(Equal([]string{"monit", "stop", "-g", "vcap"}))
+				Expect(client.StopServiceNames).To(Equal([]string{"foo", "bar"}))
 			})
 
 			It("times out if services take too long to no longer be pending", func() {
@@ -304,45 +693,112 @@ var _ = Describe("monitJobSupervisor", func() {
 					},
 				}
 
-				errchan := make(chan error)
-				go func() {
-					errchan <- monit.StopAndWait()
-				}()
+				errchan := stopAndWait()
 
 				failureMessage := "Timed out waiting for services 'foo' to no longer be pending after 5 minutes"
 
 				advanceTime(timeService, 10*time.Minute, 2)
 				Eventually(timeService.WatcherCount).Should(Equal(0))
 				Eventually(errchan).Should(Receive(Equal(errors.New(failureMessage))))
-				Expect(len(runner.RunCommands)).To(Equal(0)) // never called 'monit stop'
+				Expect(client.StopServiceNames).To(BeEmpty()) // never stopped services
 			})
+		})
 
-			It("uses the same timer for waiting for pending and waiting for services to stop", func() {
+		Describe("Escalating when a job does not stop within its grace period", func() {
+			BeforeEach(func() {
 				client.StatusStatus = fakemonit.FakeMonitStatus{
 					Services: []boshmonit.Service{
-						{Monitored: false, Name: "foo", Status: "unknown", Pending: true},
+						{Monitored: true, Name: "db", Status: "running"},
 					},
 				}
 
-				errchan := make(chan error)
+				Expect(fs.WriteFileString("/var/vcap/sys/run/db/db.pid", "1234\n")).To(Succeed())
+			})
+
+			runCommandsFor := func(name string) [][]string {
+				commands := [][]string{}
+				for _, command := range runner.RunCommands {
+					if command[0] == name {
+						commands = append(commands, command)
+					}
+				}
+				return commands
+			}
+
+			It("waits for the stop timeout of the job's stop programs before terminating it", func() {
+				writeJobConfig("0000_db.monitrc", "db", " with timeout 60 seconds")
+				runner.AddCmdResult("kill -0 1234", fakesys.FakeCmdResult{})
+				runner.AddCmdResult("kill -0 1234", fakesys.FakeCmdResult{Error: errors.New("No such process")})
+
+				var results []JobStopResult
+				errchan := make(chan error, 1)
 				go func() {
-					errchan <- monit.StopAndWait()
+					var err error
+					results, err = monit.StopAndWait()
+					errchan <- err
 				}()
 
-				Eventually(timeService.WatcherCount).Should(Equal(2)) // we hit the pending sleep
+				advanceTime(timeService, 30*time.Second, 2)
+				Eventually(timeService.WatcherCount).Should(Equal(2))
+				Expect(runCommandsFor("kill")).To(BeEmpty())
 
-				client.StatusStatus = fakemonit.FakeMonitStatus{
-					Services: []boshmonit.Service{
-						{Monitored: true, Name: "foo", Status: "unknown", Pending: false},
-					},
-				}
-				timeService.Increment(3 * time.Minute)
+				timeService.Increment(30 * time.Second)
 
-				Eventually(timeService.WatcherCount).Should(Equal(2)) // we hit the stop sleep
+				Eventually(errchan).Should(Receive(BeNil()))
+				Expect(runCommandsFor("kill")).To(Equal([][]string{
+					{"kill", "-0", "1234"},
+					{"kill", "-s", "TERM", "1234"},
+					{"kill", "-0", "1234"},
+				}))
+				Expect(results).To(Equal([]JobStopResult{{Name: "db", State: JobStopStateTerminated}}))
+			})
 
-				timeService.Increment(3 * time.Minute)
+			It("kills jobs that do not exit after SIGTERM", func() {
+				writeJobConfig("0000_db.monitrc", "db", "")
 
-				Eventually(errchan).Should(Receive(Equal(errors.New("Timed out waiting for services 'foo' to stop after 5 minutes"))))
+				var results []JobStopResult
+				errchan := make(chan error, 1)
+				go func() {
+					var err error
+					results, err = monit.StopAndWait()
+					errchan <- err
+				}()
+
+				terminated := make(chan struct{})
+				runner.SetCmdCallback("kill -s TERM 1234", func() { close(terminated) })
+
+				advanceTime(timeService, 30*time.Second, 2)
+				Eventually(terminated).Should(BeClosed())
+
+				advanceTime(timeService, 10*time.Second, 2)
+
+				Eventually(errchan).Should(Receive(BeNil()))
+				Expect(runCommandsFor("kill")).To(ContainElement([]string{"kill", "-s", "KILL", "1234"}))
+				Expect(results).To(Equal([]JobStopResult{{Name: "db", State: JobStopStateKilled}}))
+			})
+
+			It("returns an error when the job's processes are no longer running", func() {
+				writeJobConfig("0000_db.monitrc", "db", "")
+				runner.AddCmdResult("kill -0 1234", fakesys.FakeCmdResult{Error: errors.New("No such process")})
+
+				errchan := stopAndWait()
+
+				advanceTime(timeService, 30*time.Second, 2)
+
+				Eventually(errchan).Should(Receive(Equal(errors.New("Timed out waiting for services 'db' to stop after 30s"))))
+				Expect(runCommandsFor("kill")).To(Equal([][]string{{"kill", "-0", "1234"}}))
+			})
+
+			It("terminates jobs with errored services right away", func() {
+				writeJobConfig("0000_db.monitrc", "db", "")
+				client.StatusStatus.Services[0].Errored = true
+				runner.AddCmdResult("kill -0 1234", fakesys.FakeCmdResult{})
+				runner.AddCmdResult("kill -0 1234", fakesys.FakeCmdResult{Error: errors.New("No such process")})
+
+				results, err := monit.StopAndWait()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(runCommandsFor("kill")).To(ContainElement([]string{"kill", "-s", "TERM", "1234"}))
+				Expect(results).To(Equal([]JobStopResult{{Name: "db", State: JobStopStateTerminated}}))
 			})
 		})
 
@@ -354,10 +810,7 @@ var _ = Describe("monitJobSupervisor", func() {
 					},
 				}
 
-				errchan := make(chan error)
-				go func() {
-					errchan <- monit.StopAndWait()
-				}()
+				errchan := stopAndWait()
 
 				Eventually(timeService.WatcherCount).Should(Equal(2)) // we hit the sleep
 				client.StatusErr = errors.New("Error message")
@@ -368,7 +821,7 @@ var _ = Describe("monitJobSupervisor", func() {
 					err := <-errchan
 					return err.Error()
 				}).Should(Equal("Getting monit status: Error message"))
-				Expect(len(runner.RunCommands)).To(Equal(0)) // never called 'monit stop', the right loop is failing
+				Expect(client.StopServiceNames).To(BeEmpty()) // never stopped services, the right loop is failing
 			})
 
 			It("exits with an error message if it's waiting for services to stop", func() {
@@ -378,10 +831,7 @@ var _ = Describe("monitJobSupervisor", func() {
 					},
 				}
 
-				errchan := make(chan error)
-				go func() {
-					errchan <- monit.StopAndWait()
-				}()
+				errchan := stopAndWait()
 
 				Eventually(timeService.WatcherCount).Should(Equal(2)) // we hit the sleep
 				client.StatusErr = errors.New("Error message")
@@ -392,21 +842,25 @@ var _ = Describe("monitJobSupervisor", func() {
 					err := <-errchan
 					return err.Error()
 				}).Should(Equal("Getting monit status: Error message"))
-				Expect(len(runner.RunCommands)).To(Equal(1)) // called 'monit stop', the right loop is failing
+				Expect(client.StopServiceNames).To(Equal([]string{"foo"})) // stopped services, the right loop is failing
 			})
 		})
 
 		Context("when a stop service errors", func() {
 			It("exits with an error message", func() {
-				fakeErrorResult := fakesys.FakeCmdResult{
-					Error: errors.New("test error result"),
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						{Monitored: true, Name: "foo", Status: "running"},
+					},
 				}
+				client.StopServiceErr = errors.New("test error result")
 
-				runner.AddCmdResult("monit stop -g vcap", fakeErrorResult)
-
-				err := monit.StopAndWait()
+				results, err := monit.StopAndWait()
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal(fmt.Sprintf("%s%s", "Stop all services: ", fakeErrorResult.Error)))
+				Expect(err.Error()).To(Equal("Stopping service foo: test error result"))
+				Expect(results).To(Equal([]JobStopResult{
+					{Name: "foo", State: JobStopStateFailed, Error: "Stopping service foo: test error result"},
+				}))
 			})
 		})
 
@@ -441,9 +895,10 @@ var _ = Describe("monitJobSupervisor", func() {
 					fs,
 					runner,
 					client,
+					platform,
 					logger,
 					dirProvider,
-					jobFailuresServerPort,
+					alertServerOptions,
 					MonitReloadOptions{
 						MaxTries:               3,
 						MaxCheckTries:          10,
@@ -452,71 +907,35 @@ var _ = Describe("monitJobSupervisor", func() {
 					timeService,
 				)
 
-				err := monit.StopAndWait()
+				_, err := monit.StopAndWait()
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal("Stopping services '[test-service]' errored"))
 			})
-		})
-
-		Context("when a service takes too long to stop", func() {
-			It("exits with an error after a timeout", func() {
-				statusRequests := 0
-				handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					requestData := make(map[string]string)
-
-					if r.URL.Path == "/_status2" {
-						statusRequests++
-						if statusRequests == 1 {
-							w.Write(readFixture("monit/test_assets/monit_status_running.xml"))
-						} else {
-							w.Write(readFixture("monit/test_assets/monit_status_multiple.xml"))
-						}
-					} else {
-						Expect(r.Method).To(Equal("POST"))
-						requestData["action"] = r.PostFormValue("action")
-					}
-				})
-
-				ts := httptest.NewServer(handler)
-				defer ts.Close()
-
-				url := ts.Listener.Addr().String()
-				client := boshmonit.NewHTTPClient(
-					url,
-					"fake-user",
-					"fake-pass",
-					http.DefaultClient,
-					http.DefaultClient,
-					logger,
-				)
-
-				monit := NewMonitJobSupervisor(
-					fs,
-					runner,
-					client,
-					logger,
-					dirProvider,
-					jobFailuresServerPort,
-					MonitReloadOptions{},
-					timeService,
-				)
 
-				errchan := make(chan error)
-				go func() {
-					errchan <- monit.StopAndWait()
-				}()
+			It("keeps stopping the remaining jobs", func() {
+				writeJobConfig("0000_web.monitrc", "web", "")
+				writeJobConfig("0001_db.monitrc", "db", "")
 
-				failureMessage := "Timed out waiting for services 'unmonitored-start-pending, initializing, running, running-stop-pending, unmonitored-stop-pending, failing' to stop after 5 minutes"
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						{Monitored: false, Name: "web", Status: "unknown"},
+						{Monitored: false, Name: "db", Status: "failing", Errored: true},
+					},
+				}
 
-				advanceTime(timeService, 5*time.Minute, 2)
-				Eventually(timeService.WatcherCount).Should(Equal(0))
-				Eventually(errchan).Should(Receive(Equal(errors.New(failureMessage))))
-				Expect(statusRequests).To(Equal(3))
+				results, err := monit.StopAndWait()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Stopping services '[db]' errored"))
+				Expect(client.StopServiceNames).To(Equal([]string{"db", "web"}))
+				Expect(results).To(Equal([]JobStopResult{
+					{Name: "db", State: JobStopStateFailed, Error: "Stopping services '[db]' errored"},
+					{Name: "web", State: JobStopStateStopped},
+				}))
 			})
 		})
 
 		It("creates stopped file", func() {
-			err := monit.StopAndWait()
+			_, err := monit.StopAndWait()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(fs.FileExists("/var/vcap/monit/stopped")).To(BeTrue())
 		})
@@ -599,6 +1018,74 @@ var _ = Describe("monitJobSupervisor", func() {
 			status := monit.Status()
 			Expect(status).To(Equal("stopped"))
 		})
+
+		Context("when monit stops responding", func() {
+			var fetchedAt time.Time
+
+			BeforeEach(func() {
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						boshmonit.Service{Monitored: true, Status: "running"},
+					},
+				}
+
+				fetchedAt = timeService.Now()
+				Expect(monit.Status()).To(Equal("running"))
+				Expect(monit.StatusStaleSince()).To(BeZero())
+
+				client.StatusErr = errors.New("fake-monit-client-error")
+			})
+
+			It("returns the last status monit reported for up to 5 minutes", func() {
+				timeService.Increment(5 * time.Minute)
+
+				Expect(monit.Status()).To(Equal("running"))
+				Expect(monit.StatusStaleSince()).To(Equal(fetchedAt))
+			})
+
+			It("returns unknown once the last status is older than 5 minutes", func() {
+				timeService.Increment(5*time.Minute + time.Second)
+
+				Expect(monit.Status()).To(Equal("unknown"))
+				Expect(monit.StatusStaleSince()).To(BeZero())
+			})
+
+			It("is no longer stale once monit responds again", func() {
+				timeService.Increment(time.Minute)
+				Expect(monit.Status()).To(Equal("running"))
+
+				client.StatusErr = nil
+
+				Expect(monit.Status()).To(Equal("running"))
+				Expect(monit.StatusStaleSince()).To(BeZero())
+			})
+		})
+	})
+
+	Describe("CachedProcesses", func() {
+		It("returns the processes of the status last fetched without asking monit", func() {
+			client.StatusStatus = fakemonit.FakeMonitStatus{
+				Services: []boshmonit.Service{
+					{Name: "fake-service", Monitored: true, Status: "running"},
+				},
+			}
+
+			Expect(monit.Status()).To(Equal("running"))
+			fetchedAt := timeService.Now()
+			timeService.Increment(time.Minute)
+
+			processes, at := monit.(ProcessesCache).CachedProcesses()
+			Expect(processes).To(Equal([]Process{{Name: "fake-service", State: "running"}}))
+			Expect(at).To(Equal(fetchedAt))
+			Expect(client.HeartbeatStatusCalledTimes).To(Equal(1))
+		})
+
+		It("returns no processes before the status was fetched", func() {
+			processes, at := monit.(ProcessesCache).CachedProcesses()
+			Expect(processes).To(BeEmpty())
+			Expect(at.IsZero()).To(BeTrue())
+			Expect(client.HeartbeatStatusCalledTimes).To(Equal(0))
+		})
 	})
 
 	Describe("Processes", func() {
@@ -667,9 +1154,38 @@ var _ = Describe("monitJobSupervisor", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(processes).To(BeEmpty())
 		})
+
+		It("returns the last processes monit reported when it briefly stops responding", func() {
+			client.StatusStatus = fakemonit.FakeMonitStatus{
+				Services: []boshmonit.Service{
+					boshmonit.Service{Name: "fake-service-1", Monitored: true, Status: "running"},
+				},
+			}
+
+			_, err := monit.Processes()
+			Expect(err).ToNot(HaveOccurred())
+
+			client.StatusErr = errors.New("fake-monit-client-error")
+			timeService.Increment(time.Minute)
+
+			processes, err := monit.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(Equal([]Process{
+				Process{Name: "fake-service-1", State: "running"},
+			}))
+		})
 	})
 
 	Describe("MonitorJobFailures", func() {
+		var reloaded chan struct{}
+
+		BeforeEach(func() {
+			client.Incarnations = []int{1, 2}
+
+			reloaded = make(chan struct{})
+			runner.SetCmdCallback("monit reload", func() { close(reloaded) })
+		})
+
 		It("monitor job failures", func() {
 			var handledAlert boshalert.MonitAlert
 
@@ -687,7 +1203,7 @@ var _ = Describe("monitJobSupervisor", func() {
  Date: Sun, 22 May 2011 20:07:41 +0500
  Description: process is not running`
 
-			err := doJobFailureEmail(msg, jobFailuresServerPort)
+			err := doJobFailureEmail(msg, monitAuth())
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(handledAlert).To(Equal(boshalert.MonitAlert{
@@ -700,6 +1216,57 @@ var _ = Describe("monitJobSupervisor", func() {
 			}))
 		})
 
+		It("renders the mail server config monit authenticates with", func() {
+			go monit.MonitorJobFailures(func(boshalert.MonitAlert) error { return nil })
+
+			Expect(doJobFailureEmail("Service: nats", monitAuth())).To(Succeed())
+
+			configPath := "/var/vcap/monit/agent_alerts.monitrc"
+			Expect(fs.ReadFileString(configPath)).To(Equal(fmt.Sprintf(
+				"set mailserver 127.0.0.1 port %d username \"fake-monit-user\" password \"fake-monit-password\"\n",
+				jobFailureServerPort,
+			)))
+			Expect(fs.GetFileTestStat(configPath).FileMode).To(Equal(os.FileMode(0600)))
+			Expect(fs.FileExists("/var/vcap/monit/alerts.monitrc")).To(BeFalse())
+		})
+
+		It("reloads monit so that it uses the rendered mail server config", func() {
+			go monit.MonitorJobFailures(func(boshalert.MonitAlert) error { return nil })
+
+			Expect(doJobFailureEmail("Service: nats", monitAuth())).To(Succeed())
+			Expect(reloaded).To(BeClosed())
+		})
+
+		It("does not reload monit when the mail server config is unchanged", func() {
+			config := fmt.Sprintf(
+				"set mailserver 127.0.0.1 port %d username \"fake-monit-user\" password \"fake-monit-password\"\n",
+				jobFailureServerPort,
+			)
+			Expect(fs.WriteFileString("/var/vcap/monit/agent_alerts.monitrc", config)).To(Succeed())
+
+			go monit.MonitorJobFailures(func(boshalert.MonitAlert) error { return nil })
+
+			Expect(doJobFailureEmail("Service: nats", monitAuth())).To(Succeed())
+			Expect(reloaded).ToNot(BeClosed())
+		})
+
+		It("returns an error when monit does not reload", func() {
+			client.Incarnations = []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+			runner.SetCmdCallback("monit reload", func() {})
+
+			err := monit.MonitorJobFailures(func(boshalert.MonitAlert) error { return nil })
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reloading monit alerts config"))
+		})
+
+		It("returns an error when the monit credentials cannot be rendered", func() {
+			platform.GetMonitCredentialsPassword = `fake"password`
+
+			err := monit.MonitorJobFailures(func(boshalert.MonitAlert) error { return nil })
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Monit credentials must not contain quotes"))
+		})
+
 		It("ignores other emails", func() {
 			var didHandleAlert bool
 
@@ -710,10 +1277,71 @@ var _ = Describe("monitJobSupervisor", func() {
 
 			go monit.MonitorJobFailures(failureHandler)
 
-			err := doJobFailureEmail(`fake-other-email`, jobFailuresServerPort)
+			err := doJobFailureEmail(`fake-other-email`, monitAuth())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(didHandleAlert).To(BeFalse())
 		})
+
+		It("rejects mails from clients that did not authenticate", func() {
+			var didHandleAlert bool
+
+			failureHandler := func(alert boshalert.MonitAlert) (err error) {
+				didHandleAlert = true
+				return
+			}
+
+			go monit.MonitorJobFailures(failureHandler)
+
+			err := doJobFailureEmail("Service: nats", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Authentication required"))
+
+			err = doJobFailureEmail("Service: nats", smtp.PlainAuth("", "fake-monit-user", "wrong-password", "127.0.0.1"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Authentication credentials invalid"))
+
+			Expect(didHandleAlert).To(BeFalse())
+		})
+
+		It("returns an error when the alert handler fails so that monit retries", func() {
+			failureHandler := func(alert boshalert.MonitAlert) error {
+				return errors.New("fake-handler-error")
+			}
+
+			go monit.MonitorJobFailures(failureHandler)
+
+			err := doJobFailureEmail("Service: nats", monitAuth())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("451"))
+		})
+
+		It("returns an error when monit credentials cannot be read", func() {
+			platform.GetMonitCredentialsErr = errors.New("fake-credentials-error")
+
+			err := monit.MonitorJobFailures(func(boshalert.MonitAlert) error { return nil })
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-credentials-error"))
+		})
+
+		It("returns an error when asked to listen on a non-loopback address", func() {
+			alertServerOptions.Address = "0.0.0.0:2825"
+
+			monit = NewMonitJobSupervisor(
+				fs,
+				runner,
+				client,
+				platform,
+				logger,
+				dirProvider,
+				alertServerOptions,
+				MonitReloadOptions{MaxTries: 1, MaxCheckTries: 1},
+				timeService,
+			)
+
+			err := monit.MonitorJobFailures(func(boshalert.MonitAlert) error { return nil })
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Refusing to listen on non-loopback address 0.0.0.0:2825"))
+		})
 	})
 
 	Describe("AddJob", func() {
@@ -838,3 +1466,9 @@ func advanceTime(timeService *fakeclock.FakeClock, duration time.Duration, watch
 	Eventually(timeService.WatcherCount).Should(Equal(watcherCount))
 	timeService.Increment(duration)
 }
+
+type failingHTTPClient struct{}
+
+func (failingHTTPClient) Do(*http.Request) (*http.Response, error) {
+	return nil, errors.New("fake-heartbeat-error")
+}
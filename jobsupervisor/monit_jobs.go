@@ -0,0 +1,59 @@
+package jobsupervisor
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-agent/jobsupervisor/monitrc"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// monitrcJob is a job config copied to the monit jobs dir by AddJob
+type monitrcJob struct {
+	Name      string
+	Index     int
+	Processes []monitrc.Process
+}
+
+// loadMonitrcJobs returns the job configs in jobsDir in the order they were configured
+func loadMonitrcJobs(fs boshsys.FileSystem, jobsDir string) ([]monitrcJob, error) {
+	paths, err := fs.Glob(path.Join(jobsDir, "*.monitrc"))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing job configs")
+	}
+
+	// Config file names start with the zero padded job index
+	sort.Strings(paths)
+
+	jobs := []monitrcJob{}
+
+	for _, configPath := range paths {
+		// e.g. 0001_redis.monitrc
+		parts := strings.SplitN(strings.TrimSuffix(path.Base(configPath), ".monitrc"), "_", 2)
+		if len(parts) != 2 {
+			return nil, bosherr.Errorf("Unexpected job config '%s'", configPath)
+		}
+
+		index, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, bosherr.Errorf("Unexpected job config '%s'", configPath)
+		}
+
+		content, err := fs.ReadFile(configPath)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Reading job config '%s'", configPath)
+		}
+
+		processes, err := monitrc.Parse(content)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing job config '%s'", configPath)
+		}
+
+		jobs = append(jobs, monitrcJob{Name: parts[1], Index: index, Processes: processes})
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,64 @@
+package jobsupervisor
+
+import (
+	"sync"
+	"time"
+
+	boshmonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
+)
+
+// Status and Processes keep reporting the last status monit returned for
+// this long while monit does not respond, e.g. while it reloads
+const monitStatusMaxStaleness = 5 * time.Minute
+
+type monitStatusCache struct {
+	lock      sync.Mutex
+	status    boshmonit.Status
+	fetchedAt time.Time
+	stale     bool
+}
+
+// Refresh returns the fetched status, or the cached one when fetching
+// failed and the cached status is recent enough
+func (c *monitStatusCache) Refresh(status boshmonit.Status, err error, now time.Time) (boshmonit.Status, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err == nil {
+		c.status = status
+		c.fetchedAt = now
+		c.stale = false
+
+		return status, nil
+	}
+
+	if c.status == nil || now.Sub(c.fetchedAt) > monitStatusMaxStaleness {
+		c.stale = false
+		return nil, err
+	}
+
+	c.stale = true
+
+	return c.status, nil
+}
+
+// StaleSince is when the cached status was fetched while it is reported
+// in place of a current one
+func (c *monitStatusCache) StaleSince() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.stale {
+		return time.Time{}
+	}
+
+	return c.fetchedAt
+}
+
+// Cached returns the status last fetched and when, or nil before the first
+func (c *monitStatusCache) Cached() (boshmonit.Status, time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.status, c.fetchedAt
+}
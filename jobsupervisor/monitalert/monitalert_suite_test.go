@@ -0,0 +1,13 @@
+package monitalert_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMonitalert(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Monitalert Suite")
+}
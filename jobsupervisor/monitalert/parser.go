@@ -0,0 +1,101 @@
+package monitalert
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+)
+
+// Parse extracts the alert fields from the mail monit sends for an event.
+// Unknown and malformed lines are ignored. Lines following the description
+// up to the next blank line or known field are appended to it since monit
+// does not escape newlines in descriptions. ok is false if no field was found.
+func Parse(message []byte) (alert boshalert.MonitAlert, ok bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(message))
+	scanner.Buffer(make([]byte, 0, 1024), len(message)+1)
+
+	inDescription := false
+
+	for scanner.Scan() {
+		line := sanitize(scanner.Text())
+
+		if line == "" {
+			inDescription = false
+			continue
+		}
+
+		name, value := splitField(line)
+
+		switch name {
+		case "message-id":
+			if id, isID := messageID(value); isID {
+				alert.ID = id
+			}
+		case "service":
+			alert.Service = value
+		case "event":
+			alert.Event = value
+		case "action":
+			alert.Action = value
+		case "date":
+			alert.Date = value
+		case "description":
+			alert.Description = value
+			inDescription = true
+			continue
+		default:
+			if inDescription {
+				alert.Description = strings.TrimSpace(alert.Description + " " + line)
+				continue
+			}
+		}
+
+		inDescription = false
+	}
+
+	return alert, alert != boshalert.MonitAlert{}
+}
+
+// splitField splits "Name: value" lines into the lower-cased name and the
+// value; name is empty if the line is not a field
+func splitField(line string) (name, value string) {
+	i := strings.Index(line, ":")
+	if i <= 0 || strings.ContainsAny(line[:i], " ") {
+		return "", ""
+	}
+
+	return strings.ToLower(line[:i]), strings.TrimSpace(line[i+1:])
+}
+
+func messageID(value string) (string, bool) {
+	if len(value) < 3 || value[0] != '<' || value[len(value)-1] != '>' {
+		return "", false
+	}
+
+	id := value[1 : len(value)-1]
+	if strings.ContainsAny(id, "<> ") {
+		return "", false
+	}
+
+	return id, true
+}
+
+// sanitize replaces invalid UTF-8 and drops control characters so that
+// values are safe to forward in alerts
+func sanitize(line string) string {
+	line = strings.ToValidUTF8(line, "\uFFFD")
+
+	line = strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return ' '
+		}
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, line)
+
+	return strings.TrimSpace(line)
+}
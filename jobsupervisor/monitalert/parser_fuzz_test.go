@@ -0,0 +1,48 @@
+package monitalert_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	"github.com/cloudfoundry/bosh-agent/jobsupervisor/monitalert"
+)
+
+func FuzzParse(f *testing.F) {
+	f.Add([]byte("Message-id: <1304319946.0@localhost>\r\nService: nats\r\nEvent: does not exist\r\nAction: restart\r\nDate: Sun, 22 May 2011 20:07:41 +0500\r\nDescription: process is not running\r\n"))
+	f.Add([]byte("Description: first\n second\n\nService: nats"))
+	f.Add([]byte("Message-id: <<>>\nService:\nEvent:\x00\xff\n"))
+	f.Add([]byte(":\n:::\n\r\r\n"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, message []byte) {
+		alert, ok := monitalert.Parse(message)
+
+		if ok == (alert == boshalert.MonitAlert{}) {
+			t.Fatalf("ok is %t for alert %#v", ok, alert)
+		}
+
+		for _, value := range []string{alert.ID, alert.Service, alert.Event, alert.Action, alert.Date, alert.Description} {
+			if !utf8.ValidString(value) {
+				t.Fatalf("invalid UTF-8 in %q", value)
+			}
+
+			if strings.ContainsAny(value, "\r\n\x00") {
+				t.Fatalf("control characters in %q", value)
+			}
+
+			if value != strings.TrimSpace(value) {
+				t.Fatalf("surrounding whitespace in %q", value)
+			}
+
+			if len(value) > 3*len(message) {
+				t.Fatalf("value %q longer than message", value)
+			}
+		}
+
+		if strings.ContainsAny(alert.ID, "<>") {
+			t.Fatalf("brackets in message id %q", alert.ID)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package monitalert_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/monitalert"
+)
+
+var _ = Describe("Parse", func() {
+	It("parses the fields of a monit alert", func() {
+		alert, ok := Parse([]byte(
+			"Message-id: <1304319946.0@localhost>\r\n" +
+				"Subject: monit alert -- Does not exist nats\r\n" +
+				"\r\n" +
+				" Service: nats\r\n" +
+				" Event: does not exist\r\n" +
+				" Action: restart\r\n" +
+				" Date: Sun, 22 May 2011 20:07:41 +0500\r\n" +
+				" Description: process is not running\r\n",
+		))
+
+		Expect(ok).To(BeTrue())
+		Expect(alert).To(Equal(boshalert.MonitAlert{
+			ID:          "1304319946.0@localhost",
+			Service:     "nats",
+			Event:       "does not exist",
+			Action:      "restart",
+			Date:        "Sun, 22 May 2011 20:07:41 +0500",
+			Description: "process is not running",
+		}))
+	})
+
+	It("matches field names case insensitively", func() {
+		alert, ok := Parse([]byte("MESSAGE-ID: <1@localhost>\nservice:nats\n"))
+
+		Expect(ok).To(BeTrue())
+		Expect(alert.ID).To(Equal("1@localhost"))
+		Expect(alert.Service).To(Equal("nats"))
+	})
+
+	It("joins description lines up to the next blank line or field", func() {
+		alert, ok := Parse([]byte(
+			"Service: nats\n" +
+				"Description: failed to start (exit status 1)\n" +
+				"  error: address already in use\n" +
+				"\tretrying\n" +
+				"Action: restart\n" +
+				"\n" +
+				"Your faithful employee,\n",
+		))
+
+		Expect(ok).To(BeTrue())
+		Expect(alert.Description).To(Equal("failed to start (exit status 1) error: address already in use retrying"))
+		Expect(alert.Action).To(Equal("restart"))
+	})
+
+	It("ignores malformed message ids", func() {
+		alert, ok := Parse([]byte("Message-id: 1@localhost\nMessage-id: <>\nMessage-id: <a<b>\n"))
+
+		Expect(ok).To(BeFalse())
+		Expect(alert).To(Equal(boshalert.MonitAlert{}))
+	})
+
+	It("removes control characters and invalid UTF-8", func() {
+		alert, ok := Parse([]byte("Service: na\x1bts\xff\x00\n"))
+
+		Expect(ok).To(BeTrue())
+		Expect(alert.Service).To(Equal("nats\uFFFD"))
+	})
+
+	It("handles lines longer than the scanner buffer", func() {
+		description := strings.Repeat("x", 100*1024)
+
+		alert, ok := Parse([]byte("Description: " + description + "\n"))
+
+		Expect(ok).To(BeTrue())
+		Expect(alert.Description).To(Equal(description))
+	})
+
+	It("does not find an alert in other mails", func() {
+		_, ok := Parse([]byte("fake-other-email\r\n"))
+		Expect(ok).To(BeFalse())
+
+		_, ok = Parse(nil)
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,542 @@
+package monitalert
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	DefaultNetwork        = "tcp"
+	DefaultAddress        = "127.0.0.1:2825"
+	DefaultMaxMessageSize = 64 * 1024
+	DefaultTimeout        = 30 * time.Second
+
+	serverLogTag = "monitAlertServer"
+
+	// RFC 5321 limits command lines to 512 and text lines to 1000 octets
+	maxLineLength   = 1024
+	maxRecipients   = 16
+	maxAuthFailures = 3
+	maxConnections  = 16
+)
+
+var errLineTooLong = errors.New("Line too long")
+
+// Handler is called for every alert received; returning an error makes
+// monit keep the alert and retry delivery later
+type Handler func(boshalert.MonitAlert) error
+
+type Credentials struct {
+	Username string
+	Password string
+}
+
+type ServerOptions struct {
+	// Network is either tcp, in which case Address must be a loopback
+	// address, or unix
+	Network string
+	Address string
+
+	// MaxMessageSize limits the size of alert mails in bytes
+	MaxMessageSize int
+
+	// Timeout limits the time spent on each command and on receiving a mail
+	Timeout time.Duration
+}
+
+func DefaultServerOptions() ServerOptions {
+	return ServerOptions{
+		Network:        DefaultNetwork,
+		Address:        DefaultAddress,
+		MaxMessageSize: DefaultMaxMessageSize,
+		Timeout:        DefaultTimeout,
+	}
+}
+
+// Server implements the subset of SMTP monit uses to deliver alerts.
+// Clients must authenticate with the monit credentials before sending mail.
+type Server struct {
+	options     ServerOptions
+	credentials Credentials
+	handler     Handler
+	logger      boshlog.Logger
+
+	connections chan struct{}
+}
+
+func NewServer(options ServerOptions, credentials Credentials, handler Handler, logger boshlog.Logger) *Server {
+	return &Server{
+		options:     options,
+		credentials: credentials,
+		handler:     handler,
+		logger:      logger,
+		connections: make(chan struct{}, maxConnections),
+	}
+}
+
+func (s *Server) ListenAndServe() error {
+	if s.credentials.Username == "" || s.credentials.Password == "" {
+		return bosherr.Error("Monit credentials are required to accept alerts")
+	}
+
+	listener, err := Listen(s.options.Network, s.options.Address)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve accepts connections until listener is closed
+func (s *Server) Serve(listener net.Listener) error {
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return bosherr.WrapError(err, "Accepting connection")
+		}
+
+		select {
+		case s.connections <- struct{}{}:
+			go s.serveConn(conn)
+		default:
+			s.logger.Warn(serverLogTag, "Rejecting connection, too many connections")
+			_, _ = conn.Write([]byte("421 4.3.2 Too many connections\r\n"))
+			_ = conn.Close()
+		}
+	}
+}
+
+// Listen only listens on loopback or unix socket addresses
+func Listen(network, address string) (net.Listener, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		tcpAddr, err := net.ResolveTCPAddr(network, address)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Resolving address %s", address)
+		}
+
+		if tcpAddr.IP == nil || !tcpAddr.IP.IsLoopback() {
+			return nil, bosherr.Errorf("Refusing to listen on non-loopback address %s", address)
+		}
+
+		listener, err := net.ListenTCP(network, tcpAddr)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Listening on %s", address)
+		}
+
+		return listener, nil
+
+	case "unix":
+		err := removeStaleSocket(address)
+		if err != nil {
+			return nil, err
+		}
+
+		listener, err := net.Listen(network, address)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Listening on %s", address)
+		}
+
+		err = os.Chmod(address, 0600)
+		if err != nil {
+			_ = listener.Close()
+			return nil, bosherr.WrapErrorf(err, "Restricting permissions of %s", address)
+		}
+
+		return listener, nil
+
+	default:
+		return nil, bosherr.Errorf("Unsupported network %s", network)
+	}
+}
+
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return bosherr.WrapErrorf(err, "Checking %s", path)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return bosherr.Errorf("Refusing to replace %s, it is not a socket", path)
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing stale socket %s", path)
+	}
+
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() { <-s.connections }()
+	defer conn.Close()
+	defer s.logger.HandlePanic("Monit Alert Server")
+
+	sess := &session{
+		server: s,
+		conn:   conn,
+		reader: bufio.NewReaderSize(conn, maxLineLength),
+	}
+
+	err := sess.serve()
+	if err != nil {
+		s.logger.Debug(serverLogTag, "Closing connection: %s", err.Error())
+	}
+}
+
+type session struct {
+	server *Server
+	conn   net.Conn
+	reader *bufio.Reader
+
+	authenticated bool
+	authFailures  int
+
+	hasSender  bool
+	recipients int
+}
+
+func (c *session) serve() error {
+	err := c.reply("220 bosh-agent ESMTP")
+	if err != nil {
+		return err
+	}
+
+	for {
+		line, err := c.readCommand()
+		if err == errLineTooLong {
+			return c.reply("500 5.5.2 Line too long")
+		} else if err != nil {
+			return err
+		}
+
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			c.resetTransaction()
+			err = c.reply("250 bosh-agent")
+		case "EHLO":
+			c.resetTransaction()
+			err = c.reply(
+				"250-bosh-agent",
+				"250-AUTH PLAIN LOGIN",
+				fmt.Sprintf("250 SIZE %d", c.server.options.MaxMessageSize),
+			)
+		case "AUTH":
+			err = c.auth(arg)
+		case "MAIL":
+			err = c.mail(arg)
+		case "RCPT":
+			err = c.rcpt(arg)
+		case "DATA":
+			err = c.data()
+		case "RSET":
+			c.resetTransaction()
+			err = c.reply("250 2.0.0 OK")
+		case "NOOP":
+			err = c.reply("250 2.0.0 OK")
+		case "QUIT":
+			return c.reply("221 2.0.0 Bye")
+		default:
+			err = c.reply("502 5.5.2 Command not recognized")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *session) auth(arg string) error {
+	if c.authenticated {
+		return c.reply("503 5.5.1 Already authenticated")
+	}
+
+	mechanism, initial := arg, ""
+	if i := strings.IndexByte(arg, ' '); i >= 0 {
+		mechanism, initial = arg[:i], strings.TrimSpace(arg[i+1:])
+	}
+
+	var username, password string
+	var valid bool
+	var err error
+
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		username, password, valid, err = c.authPlain(initial)
+	case "LOGIN":
+		username, password, valid, err = c.authLogin(initial)
+	default:
+		return c.reply("504 5.5.4 Unrecognized authentication type")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return c.reply("501 5.5.2 Cannot decode response")
+	}
+
+	if !c.server.validCredentials(username, password) {
+		c.authFailures++
+		c.server.logger.Warn(serverLogTag, "Authentication failed")
+
+		if c.authFailures >= maxAuthFailures {
+			_ = c.reply("421 4.7.0 Too many authentication failures")
+			return errors.New("Too many authentication failures")
+		}
+
+		return c.reply("535 5.7.8 Authentication credentials invalid")
+	}
+
+	c.authenticated = true
+
+	return c.reply("235 2.7.0 Authentication successful")
+}
+
+func (c *session) authPlain(initial string) (username, password string, valid bool, err error) {
+	response, valid, err := c.authResponse(initial, "")
+	if err != nil || !valid {
+		return "", "", false, err
+	}
+
+	parts := strings.Split(response, "\x00")
+	if len(parts) != 3 || (parts[0] != "" && parts[0] != parts[1]) {
+		return "", "", false, nil
+	}
+
+	return parts[1], parts[2], true, nil
+}
+
+func (c *session) authLogin(initial string) (username, password string, valid bool, err error) {
+	username, valid, err = c.authResponse(initial, "VXNlcm5hbWU6")
+	if err != nil || !valid {
+		return "", "", false, err
+	}
+
+	password, valid, err = c.authResponse("", "UGFzc3dvcmQ6")
+	if err != nil || !valid {
+		return "", "", false, err
+	}
+
+	return username, password, true, nil
+}
+
+// authResponse decodes the initial response or asks the client for one
+func (c *session) authResponse(initial, challenge string) (string, bool, error) {
+	response := initial
+
+	if response == "" {
+		err := c.reply("334 " + challenge)
+		if err != nil {
+			return "", false, err
+		}
+
+		response, err = c.readCommand()
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	if response == "=" {
+		return "", true, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(response)
+	if err != nil {
+		return "", false, nil
+	}
+
+	return string(decoded), true, nil
+}
+
+func (s *Server) validCredentials(username, password string) bool {
+	usernameMatches := subtle.ConstantTimeCompare([]byte(username), []byte(s.credentials.Username))
+	passwordMatches := subtle.ConstantTimeCompare([]byte(password), []byte(s.credentials.Password))
+
+	return usernameMatches&passwordMatches == 1
+}
+
+func (c *session) mail(arg string) error {
+	if !c.authenticated {
+		return c.reply("530 5.7.0 Authentication required")
+	}
+
+	if c.hasSender {
+		return c.reply("503 5.5.1 Nested MAIL command")
+	}
+
+	if !strings.HasPrefix(strings.ToUpper(arg), "FROM:") {
+		return c.reply("501 5.5.4 Syntax: MAIL FROM:<address>")
+	}
+
+	for _, param := range strings.Fields(arg[len("FROM:"):]) {
+		if !strings.HasPrefix(strings.ToUpper(param), "SIZE=") {
+			continue
+		}
+
+		size, err := strconv.Atoi(param[len("SIZE="):])
+		if err != nil {
+			return c.reply("501 5.5.4 Invalid SIZE parameter")
+		}
+
+		if size > c.server.options.MaxMessageSize {
+			return c.reply("552 5.3.4 Message size exceeds fixed limit")
+		}
+	}
+
+	c.hasSender = true
+
+	return c.reply("250 2.1.0 OK")
+}
+
+func (c *session) rcpt(arg string) error {
+	if !c.hasSender {
+		return c.reply("503 5.5.1 Need MAIL command")
+	}
+
+	if !strings.HasPrefix(strings.ToUpper(arg), "TO:") {
+		return c.reply("501 5.5.4 Syntax: RCPT TO:<address>")
+	}
+
+	if c.recipients >= maxRecipients {
+		return c.reply("452 4.5.3 Too many recipients")
+	}
+
+	c.recipients++
+
+	return c.reply("250 2.1.5 OK")
+}
+
+func (c *session) data() error {
+	if c.recipients == 0 {
+		return c.reply("503 5.5.1 Need RCPT command")
+	}
+
+	err := c.reply("354 End data with <CR><LF>.<CR><LF>")
+	if err != nil {
+		return err
+	}
+
+	message, tooLarge, err := c.readData()
+	if err != nil {
+		return err
+	}
+
+	c.resetTransaction()
+
+	if tooLarge {
+		return c.reply("552 5.3.4 Message size exceeds fixed limit")
+	}
+
+	alert, ok := Parse(message)
+	if !ok {
+		c.server.logger.Debug(serverLogTag, "Ignoring mail without alert")
+		return c.reply("250 2.0.0 OK")
+	}
+
+	err = c.server.handler(alert)
+	if err != nil {
+		c.server.logger.Error(serverLogTag, "Handling alert: %s", err.Error())
+		return c.reply("451 4.3.0 Failed to handle alert")
+	}
+
+	return c.reply("250 2.0.0 OK")
+}
+
+// readData reads the mail up to the terminating line, removing dot-stuffing.
+// Mails exceeding the size limit are read to the end but discarded.
+func (c *session) readData() (message []byte, tooLarge bool, err error) {
+	err = c.conn.SetReadDeadline(time.Now().Add(c.server.options.Timeout))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var buf bytes.Buffer
+	atLineStart := true
+
+	for {
+		chunk, err := c.reader.ReadSlice('\n')
+		if err != nil && err != bufio.ErrBufferFull {
+			return nil, false, err
+		}
+
+		lineEnded := err == nil
+
+		if atLineStart {
+			if lineEnded && (string(chunk) == ".\r\n" || string(chunk) == ".\n") {
+				return buf.Bytes(), tooLarge, nil
+			}
+
+			if chunk[0] == '.' {
+				chunk = chunk[1:]
+			}
+		}
+
+		if buf.Len()+len(chunk) > c.server.options.MaxMessageSize {
+			tooLarge = true
+			buf.Reset()
+		}
+
+		if !tooLarge {
+			buf.Write(chunk)
+		}
+
+		atLineStart = lineEnded
+	}
+}
+
+func (c *session) readCommand() (string, error) {
+	err := c.conn.SetReadDeadline(time.Now().Add(c.server.options.Timeout))
+	if err != nil {
+		return "", err
+	}
+
+	line, err := c.reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", errLineTooLong
+	} else if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+func (c *session) reply(lines ...string) error {
+	err := c.conn.SetWriteDeadline(time.Now().Add(c.server.options.Timeout))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.conn.Write([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+
+	return err
+}
+
+func (c *session) resetTransaction() {
+	c.hasSender = false
+	c.recipients = 0
+}
@@ -0,0 +1,79 @@
+package monitalert_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	"github.com/cloudfoundry/bosh-agent/jobsupervisor/monitalert"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// FuzzServer checks that arbitrary client input never reaches the handler
+// without authenticating and never takes the server down
+func FuzzServer(f *testing.F) {
+	f.Add([]byte("AUTH PLAIN AGZha2UtdXNlcgBmYWtlLXBhc3N3b3Jk\r\nMAIL FROM:<a>\r\nRCPT TO:<b>\r\nDATA\r\nService: nats\r\n.\r\n"))
+	f.Add([]byte("EHLO localhost\r\nMAIL FROM:<a>\r\nRCPT TO:<b>\r\nDATA\r\nService: nats\r\n.\r\nQUIT\r\n"))
+	f.Add([]byte("AUTH PLAIN AGEAYg==\r\nAUTH LOGIN\r\n*\r\nAUTH PLAIN\r\n=\r\n"))
+	f.Add([]byte("DATA\r\n..\r\n.\r\nRSET\r\nNOOP\r\n\r\n \r\n"))
+	f.Add([]byte("MAIL FROM:<a> SIZE=-1\r\nMAIL FROM:<a> SIZE=99999999999999999999\r\n"))
+
+	options := monitalert.DefaultServerOptions()
+	options.Address = "127.0.0.1:0"
+	options.MaxMessageSize = 256
+	options.Timeout = time.Second
+
+	var handledAlerts int32
+
+	handler := func(boshalert.MonitAlert) error {
+		atomic.AddInt32(&handledAlerts, 1)
+		return nil
+	}
+
+	server := monitalert.NewServer(
+		options,
+		monitalert.Credentials{Username: "fake-user", Password: "fake-password"},
+		handler,
+		boshlog.NewLogger(boshlog.LevelNone),
+	)
+
+	listener, err := monitalert.Listen(options.Network, options.Address)
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer listener.Close()
+
+	go server.Serve(listener)
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		atomic.StoreInt32(&handledAlerts, 0)
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		_, _ = conn.Write(input)
+		_ = conn.(*net.TCPConn).CloseWrite()
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		// Connections may also be reset when closed with unread input
+		_, err = ioutil.ReadAll(conn)
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			t.Fatalf("server did not close the connection: %s", err)
+		}
+
+		// Either the PLAIN response or the LOGIN password has to be sent
+		authenticated := bytes.Contains(input, []byte("AGZha2UtdXNlcgBmYWtlLXBhc3N3b3Jk")) ||
+			bytes.Contains(input, []byte("ZmFrZS1wYXNzd29yZA=="))
+		if atomic.LoadInt32(&handledAlerts) > 0 && !authenticated {
+			t.Fatalf("alert handled without authentication")
+		}
+	})
+}
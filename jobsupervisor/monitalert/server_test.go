@@ -0,0 +1,336 @@
+package monitalert_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/monitalert"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		listener   net.Listener
+		options    ServerOptions
+		server     *Server
+		handlerErr error
+
+		alertsLock sync.Mutex
+		alerts     []boshalert.MonitAlert
+	)
+
+	handledAlerts := func() []boshalert.MonitAlert {
+		alertsLock.Lock()
+		defer alertsLock.Unlock()
+
+		return append([]boshalert.MonitAlert{}, alerts...)
+	}
+
+	BeforeEach(func() {
+		alerts = nil
+		handlerErr = nil
+
+		options = DefaultServerOptions()
+		options.Address = "127.0.0.1:0"
+		options.MaxMessageSize = 1024
+		options.Timeout = 5 * time.Second
+
+		handler := func(alert boshalert.MonitAlert) error {
+			alertsLock.Lock()
+			defer alertsLock.Unlock()
+
+			alerts = append(alerts, alert)
+			return handlerErr
+		}
+
+		server = NewServer(
+			options,
+			Credentials{Username: "fake-user", Password: "fake-password"},
+			handler,
+			boshlog.NewLogger(boshlog.LevelNone),
+		)
+
+		var err error
+		listener, err = Listen(options.Network, options.Address)
+		Expect(err).ToNot(HaveOccurred())
+
+		go server.Serve(listener)
+	})
+
+	AfterEach(func() {
+		_ = listener.Close()
+	})
+
+	dial := func() *textproto.Conn {
+		conn, err := textproto.Dial("tcp", listener.Addr().String())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(readReply(conn)).To(HavePrefix("220 "))
+
+		return conn
+	}
+
+	send := func(conn *textproto.Conn, line string) string {
+		Expect(conn.PrintfLine("%s", line)).To(Succeed())
+		return readReply(conn)
+	}
+
+	authenticatedConn := func() *textproto.Conn {
+		conn := dial()
+		Expect(send(conn, "EHLO localhost")).To(HavePrefix("250 "))
+		Expect(send(conn, "AUTH PLAIN AGZha2UtdXNlcgBmYWtlLXBhc3N3b3Jk")).To(HavePrefix("235 "))
+		return conn
+	}
+
+	sendMail := func(auth smtp.Auth, message string) error {
+		return smtp.SendMail(listener.Addr().String(), auth, "monit@localhost", []string{"bosh@localhost"}, []byte(message))
+	}
+
+	It("passes alerts to the handler", func() {
+		auth := smtp.PlainAuth("", "fake-user", "fake-password", "127.0.0.1")
+
+		err := sendMail(auth, "Message-id: <1@localhost>\r\n\r\nService: nats\r\nDescription: process is not running\r\n")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(handledAlerts()).To(Equal([]boshalert.MonitAlert{
+			{ID: "1@localhost", Service: "nats", Description: "process is not running"},
+		}))
+	})
+
+	It("advertises authentication mechanisms and the size limit", func() {
+		conn := dial()
+		defer conn.Close()
+
+		Expect(conn.PrintfLine("EHLO localhost")).To(Succeed())
+		_, message, err := conn.ReadResponse(250)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(message).To(ContainSubstring("AUTH PLAIN LOGIN"))
+		Expect(message).To(ContainSubstring("SIZE 1024"))
+	})
+
+	It("supports the LOGIN authentication mechanism", func() {
+		conn := dial()
+		defer conn.Close()
+
+		Expect(send(conn, "AUTH LOGIN")).To(Equal("334 VXNlcm5hbWU6"))
+		Expect(send(conn, "ZmFrZS11c2Vy")).To(Equal("334 UGFzc3dvcmQ6"))
+		Expect(send(conn, "ZmFrZS1wYXNzd29yZA==")).To(HavePrefix("235 "))
+	})
+
+	It("requires authentication before accepting mail", func() {
+		conn := dial()
+		defer conn.Close()
+
+		Expect(send(conn, "MAIL FROM:<monit@localhost>")).To(HavePrefix("530 "))
+	})
+
+	It("rejects invalid credentials and closes the connection after repeated failures", func() {
+		conn := dial()
+		defer conn.Close()
+
+		// \x00fake-user\x00wrong
+		Expect(send(conn, "AUTH PLAIN AGZha2UtdXNlcgB3cm9uZw==")).To(HavePrefix("535 "))
+		Expect(send(conn, "AUTH LOGIN ZmFrZS11c2Vy")).To(Equal("334 UGFzc3dvcmQ6"))
+		Expect(send(conn, "d3Jvbmc=")).To(HavePrefix("535 "))
+		Expect(send(conn, "AUTH PLAIN AGZha2UtdXNlcgB3cm9uZw==")).To(HavePrefix("421 "))
+
+		_, err := conn.ReadLine()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects undecodable authentication responses", func() {
+		conn := dial()
+		defer conn.Close()
+
+		Expect(send(conn, "AUTH PLAIN not-base64!")).To(HavePrefix("501 "))
+		Expect(send(conn, "AUTH CRAM-MD5")).To(HavePrefix("504 "))
+	})
+
+	It("rejects mails declared larger than the limit", func() {
+		conn := authenticatedConn()
+		defer conn.Close()
+
+		Expect(send(conn, "MAIL FROM:<monit@localhost> SIZE=1025")).To(HavePrefix("552 "))
+		Expect(send(conn, "MAIL FROM:<monit@localhost> SIZE=1024")).To(HavePrefix("250 "))
+	})
+
+	It("discards mails larger than the limit", func() {
+		conn := authenticatedConn()
+		defer conn.Close()
+
+		Expect(send(conn, "MAIL FROM:<monit@localhost>")).To(HavePrefix("250 "))
+		Expect(send(conn, "RCPT TO:<bosh@localhost>")).To(HavePrefix("250 "))
+		Expect(send(conn, "DATA")).To(HavePrefix("354 "))
+
+		body := "Service: nats\r\nDescription: " + strings.Repeat("x", 2000) + "\r\n."
+		Expect(send(conn, body)).To(HavePrefix("552 "))
+
+		Expect(send(conn, "NOOP")).To(HavePrefix("250 "))
+		Expect(handledAlerts()).To(BeEmpty())
+	})
+
+	It("removes dot-stuffing from mails", func() {
+		conn := authenticatedConn()
+		defer conn.Close()
+
+		Expect(send(conn, "MAIL FROM:<monit@localhost>")).To(HavePrefix("250 "))
+		Expect(send(conn, "RCPT TO:<bosh@localhost>")).To(HavePrefix("250 "))
+		Expect(send(conn, "DATA")).To(HavePrefix("354 "))
+		Expect(send(conn, "Description: first\r\n..second\r\n.")).To(HavePrefix("250 "))
+
+		Expect(handledAlerts()).To(Equal([]boshalert.MonitAlert{{Description: "first .second"}}))
+	})
+
+	It("enforces the order of commands", func() {
+		conn := authenticatedConn()
+		defer conn.Close()
+
+		Expect(send(conn, "RCPT TO:<bosh@localhost>")).To(HavePrefix("503 "))
+		Expect(send(conn, "DATA")).To(HavePrefix("503 "))
+		Expect(send(conn, "MAIL FROM:<monit@localhost>")).To(HavePrefix("250 "))
+		Expect(send(conn, "MAIL FROM:<monit@localhost>")).To(HavePrefix("503 "))
+		Expect(send(conn, "RSET")).To(HavePrefix("250 "))
+		Expect(send(conn, "VRFY bosh")).To(HavePrefix("502 "))
+		Expect(send(conn, "QUIT")).To(HavePrefix("221 "))
+	})
+
+	It("replies with a temporary error when the handler fails", func() {
+		handlerErr = fmt.Errorf("fake-handler-error")
+
+		err := sendMail(smtp.PlainAuth("", "fake-user", "fake-password", "127.0.0.1"), "Service: nats\r\n")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("451"))
+	})
+
+	It("closes connections sending overlong lines", func() {
+		conn := dial()
+		defer conn.Close()
+
+		Expect(send(conn, "NOOP "+strings.Repeat("x", 2000))).To(HavePrefix("500 "))
+	})
+
+	It("closes idle connections", func() {
+		_ = listener.Close()
+
+		options.Timeout = 50 * time.Millisecond
+		server = NewServer(options, Credentials{Username: "fake-user", Password: "fake-password"}, nil, boshlog.NewLogger(boshlog.LevelNone))
+
+		var err error
+		listener, err = Listen(options.Network, options.Address)
+		Expect(err).ToNot(HaveOccurred())
+
+		go server.Serve(listener)
+
+		conn := dial()
+		defer conn.Close()
+
+		_, err = conn.ReadLine()
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("ListenAndServe", func() {
+		It("requires credentials", func() {
+			server = NewServer(options, Credentials{}, nil, boshlog.NewLogger(boshlog.LevelNone))
+
+			err := server.ListenAndServe()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Monit credentials are required to accept alerts"))
+		})
+	})
+})
+
+var _ = Describe("Listen", func() {
+	It("refuses to listen on addresses other than loopback", func() {
+		_, err := Listen("tcp", "0.0.0.0:0")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Refusing to listen on non-loopback address 0.0.0.0:0"))
+
+		_, err = Listen("tcp", ":0")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("listens on IPv6 loopback addresses", func() {
+		listener, err := Listen("tcp", "[::1]:0")
+		if err != nil && strings.Contains(err.Error(), "cannot assign requested address") {
+			Skip("IPv6 is not available")
+		}
+		Expect(err).ToNot(HaveOccurred())
+		_ = listener.Close()
+	})
+
+	It("refuses unsupported networks", func() {
+		_, err := Listen("udp", "127.0.0.1:0")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Unsupported network udp"))
+	})
+
+	Context("with unix sockets", func() {
+		var tmpDir string
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = ioutil.TempDir("", "monitalert")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		It("creates a socket only accessible to the owner", func() {
+			socketPath := filepath.Join(tmpDir, "alerts.sock")
+
+			listener, err := Listen("unix", socketPath)
+			Expect(err).ToNot(HaveOccurred())
+			defer listener.Close()
+
+			info, err := os.Stat(socketPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+
+		It("replaces stale sockets", func() {
+			socketPath := filepath.Join(tmpDir, "alerts.sock")
+
+			stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+			Expect(err).ToNot(HaveOccurred())
+			stale.SetUnlinkOnClose(false)
+			Expect(stale.Close()).To(Succeed())
+
+			listener, err := Listen("unix", socketPath)
+			Expect(err).ToNot(HaveOccurred())
+			_ = listener.Close()
+		})
+
+		It("refuses to replace other files", func() {
+			filePath := filepath.Join(tmpDir, "alerts.sock")
+			Expect(ioutil.WriteFile(filePath, []byte("fake-content"), 0600)).To(Succeed())
+
+			_, err := Listen("unix", filePath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("it is not a socket"))
+		})
+	})
+})
+
+func readReply(conn *textproto.Conn) string {
+	code, message, err := conn.ReadResponse(0)
+	if err != nil {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("%d %s", code, bytes.TrimSpace([]byte(message)))
+}
@@ -0,0 +1,461 @@
+package monitrc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type Resource string
+
+const (
+	ResourceMemory      Resource = "memory"
+	ResourceTotalMemory Resource = "totalmem"
+	ResourceCPU         Resource = "cpu"
+	ResourceTotalCPU    Resource = "totalcpu"
+)
+
+const (
+	ActionAlert     = "alert"
+	ActionRestart   = "restart"
+	ActionStop      = "stop"
+	ActionUnmonitor = "unmonitor"
+)
+
+// Process is a `check process` entry of a monit control file, e.g.
+//
+//   check process redis
+//     with pidfile /var/vcap/sys/run/redis/redis.pid
+//     start program "/var/vcap/jobs/redis/bin/ctl start" with timeout 60 seconds
+//     stop program "/var/vcap/jobs/redis/bin/ctl stop"
+//     group vcap
+//     if totalmem > 512 Mb for 5 cycles then restart
+type Process struct {
+	Name    string
+	PidFile string
+
+	// Matching is the pattern of `matching` checks, which have no pidfile
+	Matching string
+
+	Start          Program
+	Stop           Program
+	Groups         []string
+	ResourceLimits []ResourceLimit
+}
+
+type Program struct {
+	Command string
+
+	// Timeout is zero unless set with `with timeout N seconds`
+	Timeout time.Duration
+}
+
+type ResourceLimit struct {
+	Resource Resource
+
+	// Threshold is in kilobytes for memory resources unless Percent is set
+	Threshold float64
+	Percent   bool
+
+	// Cycles is the number of consecutive checks the limit
+	// has to be exceeded before Action is taken
+	Cycles int
+	Action string
+}
+
+func (p Process) InGroup(group string) bool {
+	for _, g := range p.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// Supervisable returns an error unless the process has what is needed to
+// start and track it without monit, i.e. a pidfile and a start program
+func (p Process) Supervisable() error {
+	if p.PidFile == "" {
+		return bosherr.Errorf("Missing pidfile for process '%s'", p.Name)
+	}
+	if p.Start.Command == "" {
+		return bosherr.Errorf("Missing start program for process '%s'", p.Name)
+	}
+	return nil
+}
+
+// Parse returns the processes declared in a monit control file.
+// Statements that the agent does not act on (other check types, ports,
+// dependencies, alerts, etc.) are skipped. Processes that monit accepts
+// but that lack a pidfile or start program are returned as well.
+func Parse(content []byte) ([]Process, error) {
+	p := &parser{tokens: tokenize(string(content))}
+	return p.parse()
+}
+
+// PrefixStartPrograms returns content with the start program of each
+// process prefixed by the command returned for it, leaving the rest of
+// the control file as is; an empty prefix leaves a start program as is
+func PrefixStartPrograms(content []byte, prefix func(Process) string) ([]byte, error) {
+	runes := []rune(string(content))
+
+	p := &parser{tokens: tokenize(string(content))}
+
+	processes, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	prefixed := []rune{}
+	last := 0
+
+	for _, start := range p.starts {
+		commandPrefix := prefix(processes[start.process])
+		if commandPrefix == "" {
+			continue
+		}
+
+		command := p.tokens[start.token]
+		wrapped := commandPrefix + " " + command.text
+
+		// monitrc strings cannot contain their own quote
+		quote := `"`
+		if strings.Contains(wrapped, quote) {
+			quote = "'"
+		}
+		if strings.Contains(wrapped, quote) {
+			return nil, bosherr.Errorf("Cannot quote start program of process '%s'", processes[start.process].Name)
+		}
+
+		prefixed = append(prefixed, runes[last:command.start]...)
+		prefixed = append(prefixed, []rune(quote+wrapped+quote)...)
+		last = command.end
+	}
+
+	prefixed = append(prefixed, runes[last:]...)
+
+	return []byte(string(prefixed)), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+
+	// starts are the command tokens of start programs
+	starts []startProgram
+}
+
+type startProgram struct {
+	process int
+	token   int
+}
+
+// token is a word or quoted string of a control file; start and end
+// are the rune offsets of the token including its quotes
+type token struct {
+	text  string
+	start int
+	end   int
+}
+
+func (p *parser) parse() ([]Process, error) {
+	processes := []Process{}
+	var current *Process
+
+	for p.more() {
+		token := strings.ToLower(p.next())
+
+		if token == "check" {
+			kind := strings.ToLower(p.next())
+			name := p.next()
+
+			if kind != "process" {
+				current = nil
+				continue
+			}
+
+			if name == "" {
+				return nil, bosherr.Error("Missing name for check process")
+			}
+
+			processes = append(processes, Process{Name: name})
+			current = &processes[len(processes)-1]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		switch token {
+		case "pidfile":
+			current.PidFile = p.next()
+
+		case "matching":
+			current.Matching = p.next()
+
+		case "start", "stop":
+			program, commandToken, err := p.parseProgram()
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Parsing %s program for process '%s'", token, current.Name)
+			}
+
+			if token == "start" {
+				current.Start = program
+				p.starts = append(p.starts, startProgram{process: len(processes) - 1, token: commandToken})
+			} else {
+				current.Stop = program
+			}
+
+		case "group":
+			current.Groups = append(current.Groups, p.next())
+
+		case "if":
+			limit, ok, err := p.parseCondition()
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Parsing condition for process '%s'", current.Name)
+			}
+
+			if ok {
+				current.ResourceLimits = append(current.ResourceLimits, limit)
+			}
+		}
+	}
+
+	return processes, nil
+}
+
+// parseProgram parses the rest of `start program = "cmd" [as uid x and gid y] [with timeout N seconds]`
+// and also returns the index of the command token
+func (p *parser) parseProgram() (Program, int, error) {
+	var program Program
+
+	p.skip("program")
+	p.skip("=")
+
+	commandToken := p.pos
+
+	program.Command = p.next()
+	if program.Command == "" {
+		return program, commandToken, bosherr.Error("Missing command")
+	}
+
+	for p.more() {
+		switch strings.ToLower(p.peek()) {
+		case "as":
+			p.next()
+			p.skip("uid", "user")
+			p.next()
+		case "and":
+			p.next()
+			if p.skip("gid", "group") {
+				p.next()
+			}
+		case "with":
+			p.next()
+			if !p.skip("timeout") {
+				continue
+			}
+
+			seconds, err := strconv.Atoi(p.next())
+			if err != nil {
+				return program, commandToken, bosherr.WrapError(err, "Parsing timeout")
+			}
+
+			p.skip("seconds", "second", "cycles", "cycle")
+			program.Timeout = time.Duration(seconds) * time.Second
+		default:
+			return program, commandToken, nil
+		}
+	}
+
+	return program, commandToken, nil
+}
+
+// parseCondition parses the rest of `if <resource> > <value> [for N cycles] then <action>`;
+// ok is false for conditions that are not resource limits
+func (p *parser) parseCondition() (ResourceLimit, bool, error) {
+	var condition []string
+
+	for p.more() && !strings.EqualFold(p.peek(), "then") {
+		condition = append(condition, p.next())
+	}
+
+	p.next()
+	action := strings.ToLower(p.next())
+
+	if action == "exec" {
+		p.next()
+	}
+
+	limit := ResourceLimit{Cycles: 1, Action: action}
+
+	if len(condition) < 3 {
+		return limit, false, nil
+	}
+
+	switch Resource(strings.ToLower(condition[0])) {
+	case ResourceMemory, ResourceTotalMemory, ResourceCPU, ResourceTotalCPU:
+		limit.Resource = Resource(strings.ToLower(condition[0]))
+	default:
+		return limit, false, nil
+	}
+
+	switch strings.ToLower(condition[1]) {
+	case ">", "gt", "greater":
+	default:
+		return limit, false, nil
+	}
+
+	rest := condition[2:]
+
+	value, unit := splitNumber(rest[0])
+	rest = rest[1:]
+
+	if unit == "" && len(rest) > 0 && !isNumber(rest[0]) && !strings.EqualFold(rest[0], "for") {
+		unit = rest[0]
+		rest = rest[1:]
+	}
+
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return limit, false, bosherr.WrapErrorf(err, "Parsing %s threshold", limit.Resource)
+	}
+
+	switch strings.ToLower(unit) {
+	case "%":
+		limit.Percent = true
+		limit.Threshold = threshold
+	case "b", "byte", "bytes":
+		limit.Threshold = threshold / 1024
+	case "", "k", "kb", "kilobyte", "kilobytes":
+		limit.Threshold = threshold
+	case "m", "mb", "megabyte", "megabytes":
+		limit.Threshold = threshold * 1024
+	case "g", "gb", "gigabyte", "gigabytes":
+		limit.Threshold = threshold * 1024 * 1024
+	default:
+		return limit, false, bosherr.Errorf("Unknown unit '%s'", unit)
+	}
+
+	if (limit.Resource == ResourceCPU || limit.Resource == ResourceTotalCPU) && !limit.Percent {
+		return limit, false, bosherr.Errorf("Expected %s threshold in percent", limit.Resource)
+	}
+
+	// `for N cycles` or `N times within M cycles`
+	for i, token := range rest {
+		if !isNumber(token) {
+			continue
+		}
+
+		cycles, err := strconv.Atoi(token)
+		if err == nil && i+1 < len(rest) {
+			next := strings.ToLower(rest[i+1])
+			if next == "cycles" || next == "cycle" || next == "times" {
+				limit.Cycles = cycles
+				break
+			}
+		}
+	}
+
+	return limit, true, nil
+}
+
+func (p *parser) more() bool {
+	return p.pos < len(p.tokens)
+}
+
+func (p *parser) peek() string {
+	if !p.more() {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *parser) next() string {
+	token := p.peek()
+	if p.more() {
+		p.pos++
+	}
+	return token
+}
+
+// skip consumes the next token if it is one of the given words
+func (p *parser) skip(words ...string) bool {
+	for _, word := range words {
+		if strings.EqualFold(p.peek(), word) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize splits on whitespace while keeping quoted strings together and dropping comments
+func tokenize(content string) []token {
+	var tokens []token
+	var current []rune
+	var quote rune
+	inToken := false
+	start := 0
+
+	flush := func(end int) {
+		if inToken {
+			tokens = append(tokens, token{text: string(current), start: start, end: end})
+		}
+		current = current[:0]
+		inToken = false
+	}
+
+	runes := []rune(content)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if !inToken && quote == 0 {
+			start = i
+		}
+
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current = append(current, r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case r == '#':
+			flush(i)
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case unicode.IsSpace(r):
+			flush(i)
+		default:
+			current = append(current, r)
+			inToken = true
+		}
+	}
+
+	flush(len(runes))
+
+	return tokens
+}
+
+func splitNumber(token string) (string, string) {
+	i := 0
+	for i < len(token) && (token[i] == '.' || (token[i] >= '0' && token[i] <= '9')) {
+		i++
+	}
+	return token[:i], token[i:]
+}
+
+func isNumber(token string) bool {
+	value, unit := splitNumber(token)
+	return value != "" && unit == ""
+}
@@ -0,0 +1,13 @@
+package monitrc_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMonitrc(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Monitrc Suite")
+}
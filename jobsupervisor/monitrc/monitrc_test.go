@@ -0,0 +1,188 @@
+package monitrc_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/monitrc"
+)
+
+var _ = Describe("Parse", func() {
+	It("parses check process entries", func() {
+		processes, err := Parse([]byte(`
+# redis server
+check process redis
+  with pidfile /var/vcap/sys/run/redis/redis.pid
+  start program "/var/vcap/jobs/redis/bin/ctl start" with timeout 60 seconds
+  stop program "/var/vcap/jobs/redis/bin/ctl stop"
+  group vcap
+  if totalmem > 512 Mb for 5 cycles then restart
+  if cpu > 90% then alert
+
+check file redis-config with path /var/vcap/jobs/redis/config/redis.conf
+  if changed checksum then alert
+
+check process redis-exporter
+  with pidfile /var/vcap/sys/run/redis/exporter.pid
+  start program = "/bin/sh -c 'exec /var/vcap/jobs/redis/bin/exporter'" as uid vcap and gid vcap
+  stop program = "/var/vcap/jobs/redis/bin/exporter_ctl stop"
+  depends on redis
+  group vcap
+  group redis
+  mode manual
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(processes).To(Equal([]Process{
+			{
+				Name:    "redis",
+				PidFile: "/var/vcap/sys/run/redis/redis.pid",
+				Start:   Program{Command: "/var/vcap/jobs/redis/bin/ctl start", Timeout: 60 * time.Second},
+				Stop:    Program{Command: "/var/vcap/jobs/redis/bin/ctl stop"},
+				Groups:  []string{"vcap"},
+				ResourceLimits: []ResourceLimit{
+					{Resource: ResourceTotalMemory, Threshold: 512 * 1024, Cycles: 5, Action: ActionRestart},
+					{Resource: ResourceCPU, Threshold: 90, Percent: true, Cycles: 1, Action: ActionAlert},
+				},
+			},
+			{
+				Name:    "redis-exporter",
+				PidFile: "/var/vcap/sys/run/redis/exporter.pid",
+				Start:   Program{Command: "/bin/sh -c 'exec /var/vcap/jobs/redis/bin/exporter'"},
+				Stop:    Program{Command: "/var/vcap/jobs/redis/bin/exporter_ctl stop"},
+				Groups:  []string{"vcap", "redis"},
+			},
+		}))
+
+		Expect(processes[1].InGroup("vcap")).To(BeTrue())
+		Expect(processes[1].InGroup("fake-group")).To(BeFalse())
+	})
+
+	It("converts memory units to kilobytes", func() {
+		processes, err := Parse([]byte(`
+check process fake-process
+  with pidfile /fake.pid
+  start program "/fake start"
+  if memory > 2GB then alert
+  if memory gt 100 kB then alert
+  if totalmem > 2048 B for 2 times within 3 cycles then restart
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		limits := processes[0].ResourceLimits
+		Expect(limits[0].Threshold).To(Equal(2.0 * 1024 * 1024))
+		Expect(limits[1].Threshold).To(Equal(100.0))
+		Expect(limits[2].Threshold).To(Equal(2.0))
+		Expect(limits[2].Cycles).To(Equal(2))
+	})
+
+	It("ignores conditions that are not resource limits", func() {
+		processes, err := Parse([]byte(`
+check process fake-process
+  with pidfile /fake.pid
+  start program "/fake start"
+  if failed port 80 protocol http then restart
+  if 5 restarts within 5 cycles then timeout
+  if children > 10 then exec "/fake alert"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(processes[0].ResourceLimits).To(BeEmpty())
+	})
+
+	It("parses processes that are matched by pattern", func() {
+		processes, err := Parse([]byte(`
+check process fake-process
+  matching "fake-pattern"
+  start program "/fake start"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(processes[0].PidFile).To(BeEmpty())
+		Expect(processes[0].Matching).To(Equal("fake-pattern"))
+	})
+
+	It("parses processes without a start program", func() {
+		processes, err := Parse([]byte(`check process fake-process with pidfile /fake.pid`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(processes[0].Name).To(Equal("fake-process"))
+		Expect(processes[0].Start.Command).To(BeEmpty())
+	})
+
+	It("returns an error for unknown units", func() {
+		_, err := Parse([]byte(`
+check process fake-process
+  with pidfile /fake.pid
+  start program "/fake start"
+  if totalmem > 10 parsecs then restart
+`))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unknown unit 'parsecs'"))
+	})
+})
+
+var _ = Describe("Supervisable", func() {
+	It("returns an error when a process has no pidfile", func() {
+		err := Process{Name: "fake-process", Matching: "fake", Start: Program{Command: "/fake start"}}.Supervisable()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Missing pidfile for process 'fake-process'"))
+	})
+
+	It("returns an error when a process has no start program", func() {
+		err := Process{Name: "fake-process", PidFile: "/fake.pid"}.Supervisable()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Missing start program for process 'fake-process'"))
+	})
+
+	It("returns nil for processes with a pidfile and start program", func() {
+		Expect(Process{Name: "fake-process", PidFile: "/fake.pid", Start: Program{Command: "/fake start"}}.Supervisable()).To(Succeed())
+	})
+})
+
+var _ = Describe("PrefixStartPrograms", func() {
+	It("prefixes the start programs of processes and keeps the rest of the file", func() {
+		content, err := PrefixStartPrograms([]byte(`# fake job
+check process fake-process
+  with pidfile /fake.pid
+  start program = "/fake start" with timeout 60 seconds
+  stop program "/fake stop"
+  group vcap
+
+check process other-process
+  with pidfile /other.pid
+  start program '/bin/sh -c "/other start"'
+  if failed port 80 then start
+`), func(process Process) string {
+			return "/fake-prefix " + process.Name
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal(`# fake job
+check process fake-process
+  with pidfile /fake.pid
+  start program = "/fake-prefix fake-process /fake start" with timeout 60 seconds
+  stop program "/fake stop"
+  group vcap
+
+check process other-process
+  with pidfile /other.pid
+  start program '/fake-prefix other-process /bin/sh -c "/other start"'
+  if failed port 80 then start
+`))
+	})
+
+	It("leaves start programs without prefix as is", func() {
+		original := `check process fake-process start program /fake-start`
+
+		content, err := PrefixStartPrograms([]byte(original), func(Process) string { return "" })
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal(original))
+	})
+
+	It("returns an error when the prefixed start program cannot be quoted", func() {
+		_, err := PrefixStartPrograms([]byte(`check process fake-process start program "/bin/sh -c 'fake'"`), func(Process) string {
+			return `/fake-prefix "fake"`
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Cannot quote start program of process 'fake-process'"))
+	})
+})
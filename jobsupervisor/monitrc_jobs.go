@@ -0,0 +1,81 @@
+// +build !windows
+
+package jobsupervisor
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/cloudfoundry/bosh-agent/jobsupervisor/monitrc"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// Helpers for supervisors that run jobs from their monit control files
+// without running monit itself
+
+// Only processes in this group are started and supervised, like `monit -g vcap`
+const monitrcServiceGroup = "vcap"
+
+const monitrcJobsLogTag = "monitrcJobs"
+
+// addMonitrcJob validates a job's monit config and copies it to jobsDir
+func addMonitrcJob(fs boshsys.FileSystem, jobsDir, jobName string, jobIndex int, configPath string) error {
+	targetFilename := fmt.Sprintf("%04d_%s.monitrc", jobIndex, jobName)
+	targetConfigPath := path.Join(jobsDir, targetFilename)
+
+	configContent, err := fs.ReadFile(configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading job config from file")
+	}
+
+	// Fail early instead of on the next reload
+	_, err = monitrc.Parse(configContent)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing job config '%s'", configPath)
+	}
+
+	err = fs.WriteFile(targetConfigPath, configContent)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing to job config file")
+	}
+
+	return nil
+}
+
+// loadMonitrcProcesses returns the vcap processes of all job configs in jobsDir in job order.
+// Processes that cannot be run without monit, e.g. `matching` checks, are skipped
+// so that they do not keep the processes of other jobs from being supervised.
+func loadMonitrcProcesses(fs boshsys.FileSystem, jobsDir string, logger boshlog.Logger) ([]monitrc.Process, error) {
+	jobs, err := loadMonitrcJobs(fs, jobsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	processes := []monitrc.Process{}
+	names := map[string]bool{}
+
+	for _, job := range jobs {
+		for _, process := range job.Processes {
+			if !process.InGroup(monitrcServiceGroup) {
+				continue
+			}
+
+			err := process.Supervisable()
+			if err != nil {
+				logger.Warn(monitrcJobsLogTag, "Skipping process of job '%s': %s", job.Name, err.Error())
+				continue
+			}
+
+			if names[process.Name] {
+				return nil, bosherr.Errorf("Duplicate process '%s'", process.Name)
+			}
+
+			names[process.Name] = true
+			processes = append(processes, process)
+		}
+	}
+
+	return processes, nil
+}
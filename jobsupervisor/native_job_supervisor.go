@@ -0,0 +1,826 @@
+// +build !windows
+
+package jobsupervisor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	sigar "github.com/cloudfoundry/gosigar"
+	"github.com/pivotal-golang/clock"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	"github.com/cloudfoundry/bosh-agent/jobsupervisor/monitrc"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	nativeJobSupervisorLogTag = "nativeJobSupervisor"
+
+	// Monit runs programs with a minimal PATH as well
+	nativeProgramPath = "/bin:/usr/bin:/sbin:/usr/sbin"
+)
+
+type NativeSupervisorOptions struct {
+	// Time between process checks, equivalent of a monit cycle
+	CheckInterval time.Duration
+
+	// Used for start and stop programs without `with timeout`
+	ProgramTimeout time.Duration
+
+	// Restarts of failing processes are delayed exponentially
+	// from MinRestartDelay up to MaxRestartDelay
+	MinRestartDelay time.Duration
+	MaxRestartDelay time.Duration
+
+	// Restart delay is reset once a process has been running this long
+	StableUptime time.Duration
+
+	StopTimeout time.Duration
+}
+
+func DefaultNativeSupervisorOptions() NativeSupervisorOptions {
+	return NativeSupervisorOptions{
+		CheckInterval:   10 * time.Second,
+		ProgramTimeout:  30 * time.Second,
+		MinRestartDelay: 1 * time.Second,
+		MaxRestartDelay: 1 * time.Minute,
+		StableUptime:    5 * time.Minute,
+		StopTimeout:     5 * time.Minute,
+	}
+}
+
+type nativeProcessStats struct {
+	MemoryKb      uint64
+	MemoryPercent float64
+
+	// CPUTime is the total user and system time consumed so far
+	CPUTime time.Duration
+}
+
+type nativeService struct {
+	config monitrc.Process
+
+	monitored bool
+	failing   bool
+	starting  bool
+
+	// busy is set while a start or stop program runs
+	busy bool
+
+	pid       int
+	startedAt time.Time
+
+	restartDelay time.Duration
+	nextRestart  time.Time
+
+	stats       nativeProcessStats
+	cpuPercent  float64
+	lastSample  time.Time
+	limitCycles []int
+}
+
+// nativeJobSupervisor supervises processes declared in monit control files
+// without running monit. Every CheckInterval it checks the pidfile of each
+// monitored process and restarts processes that are gone with an exponential
+// backoff; resource limits from `if ... then` statements are enforced from
+// the same loop. Failures are passed to the handler given to MonitorJobFailures
+// in the same shape as monit alerts.
+//
+// All state is guarded by lock, which is only released while start and stop
+// programs run; the service is marked busy meanwhile.
+type nativeJobSupervisor struct {
+	fs          boshsys.FileSystem
+	runner      boshsys.CmdRunner
+	logger      boshlog.Logger
+	dirProvider boshdir.Provider
+	timeService clock.Clock
+	options     NativeSupervisorOptions
+
+	pidAlive     func(pid int) bool
+	processStats func(pid int) (nativeProcessStats, error)
+
+	lock          sync.Mutex
+	idle          *sync.Cond
+	services      map[string]*nativeService
+	names         []string
+	handler       JobFailureHandler
+	pendingAlerts []boshalert.MonitAlert
+	stopCh        chan struct{}
+}
+
+func NewNativeJobSupervisor(
+	fs boshsys.FileSystem,
+	runner boshsys.CmdRunner,
+	logger boshlog.Logger,
+	dirProvider boshdir.Provider,
+	timeService clock.Clock,
+	options NativeSupervisorOptions,
+) JobSupervisor {
+	s := &nativeJobSupervisor{
+		fs:           fs,
+		runner:       runner,
+		logger:       logger,
+		dirProvider:  dirProvider,
+		timeService:  timeService,
+		options:      options,
+		pidAlive:     pidAlive,
+		processStats: sigarProcessStats,
+		services:     map[string]*nativeService{},
+		stopCh:       make(chan struct{}),
+	}
+
+	s.idle = sync.NewCond(&s.lock)
+
+	return s
+}
+
+func (s *nativeJobSupervisor) Reload() error {
+	configs, err := loadMonitrcProcesses(s.fs, s.dirProvider.MonitJobsDir(), s.logger)
+	if err != nil {
+		return err
+	}
+
+	stopped := s.fs.FileExists(s.stoppedFilePath())
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	services := map[string]*nativeService{}
+	names := []string{}
+
+	for _, config := range configs {
+		// Keep the state of known processes; like monit, processes that
+		// are no longer configured are forgotten but not stopped
+		service, found := s.services[config.Name]
+		if !found {
+			service = &nativeService{monitored: !stopped}
+		}
+
+		service.config = config
+		service.limitCycles = make([]int, len(config.ResourceLimits))
+
+		services[config.Name] = service
+		names = append(names, config.Name)
+	}
+
+	s.services = services
+	s.names = names
+
+	s.logger.Debug(nativeJobSupervisorLogTag, "Reloaded processes: %v", names)
+
+	return nil
+}
+
+func (s *nativeJobSupervisor) Start() error {
+	err := s.eachService(func(name string, service *nativeService) error {
+		service.monitored = true
+		service.restartDelay = 0
+
+		err := s.startService(name, service)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Starting service %s", name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.fs.RemoveAll(s.stoppedFilePath())
+	if err != nil {
+		return bosherr.WrapError(err, "Removing stopped File")
+	}
+
+	return nil
+}
+
+func (s *nativeJobSupervisor) Stop() error {
+	err := s.eachService(func(name string, service *nativeService) error {
+		service.monitored = false
+
+		err := s.stopService(name, service)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Stopping service %s", name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.fs.WriteFileString(s.stoppedFilePath(), "")
+	if err != nil {
+		return bosherr.WrapError(err, "Creating stopped File")
+	}
+
+	return nil
+}
+
+func (s *nativeJobSupervisor) StopAndWait() ([]JobStopResult, error) {
+	err := s.Stop()
+	if err != nil {
+		return nil, err
+	}
+
+	timer := s.timeService.NewTimer(s.options.StopTimeout)
+	defer timer.Stop()
+
+	s.logger.Debug(nativeJobSupervisorLogTag, "Waiting for services to stop")
+
+	for {
+		running := []string{}
+
+		_ = s.eachService(func(name string, service *nativeService) error {
+			if s.pidAlive(s.readPid(service.config.PidFile)) {
+				running = append(running, name)
+			}
+			return nil
+		})
+
+		if len(running) == 0 {
+			s.logger.Debug(nativeJobSupervisorLogTag, "Successfully stopped all services")
+			return nil, nil
+		}
+
+		select {
+		case <-timer.C():
+			return nil, bosherr.Errorf("Timed out waiting for services '%s' to stop after %s", strings.Join(running, ", "), s.options.StopTimeout)
+		default:
+		}
+
+		s.logger.Debug(nativeJobSupervisorLogTag, "Waiting for '%v' to stop", running)
+		s.timeService.Sleep(500 * time.Millisecond)
+	}
+}
+
+func (s *nativeJobSupervisor) Unmonitor() error {
+	return s.eachService(func(name string, service *nativeService) error {
+		s.logger.Debug(nativeJobSupervisorLogTag, "Unmonitoring service %s", name)
+		service.monitored = false
+		return nil
+	})
+}
+
+func (s *nativeJobSupervisor) StartProcess(name string) error {
+	if s.fs.FileExists(s.stoppedFilePath()) {
+		return bosherr.Errorf("Cannot start process '%s' of a stopped instance", name)
+	}
+
+	return s.withService(name, func(service *nativeService) error {
+		service.monitored = true
+		service.restartDelay = 0
+
+		err := s.startService(name, service)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Starting service %s", name)
+		}
+
+		return nil
+	})
+}
+
+func (s *nativeJobSupervisor) StopProcess(name string) error {
+	return s.withService(name, func(service *nativeService) error {
+		service.monitored = false
+
+		err := s.stopService(name, service)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Stopping service %s", name)
+		}
+
+		return nil
+	})
+}
+
+func (s *nativeJobSupervisor) RestartProcess(name string) error {
+	if s.fs.FileExists(s.stoppedFilePath()) {
+		return bosherr.Errorf("Cannot start process '%s' of a stopped instance", name)
+	}
+
+	return s.withService(name, func(service *nativeService) error {
+		service.monitored = true
+		service.restartDelay = 0
+
+		err := s.stopService(name, service)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Stopping service %s", name)
+		}
+
+		err = s.waitForExit(name, service)
+		if err != nil {
+			return err
+		}
+
+		err = s.startService(name, service)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Starting service %s", name)
+		}
+
+		return nil
+	})
+}
+
+func (s *nativeJobSupervisor) Status() string {
+	if s.fs.FileExists(s.stoppedFilePath()) {
+		return "stopped"
+	}
+
+	status := "running"
+
+	for _, process := range s.processes() {
+		if process.State == processStateStarting {
+			return "starting"
+		}
+		if process.State != processStateRunning {
+			status = "failing"
+		}
+	}
+
+	return status
+}
+
+func (s *nativeJobSupervisor) Processes() ([]Process, error) {
+	return s.processes(), nil
+}
+
+// StatusStaleSince is always zero since processes are supervised in-process
+func (s *nativeJobSupervisor) StatusStaleSince() time.Time {
+	return time.Time{}
+}
+
+func (s *nativeJobSupervisor) WaitUntilReady(timeout time.Duration) error {
+	return nil
+}
+
+func (s *nativeJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+	return addMonitrcJob(s.fs, s.dirProvider.MonitJobsDir(), jobName, jobIndex, configPath)
+}
+
+func (s *nativeJobSupervisor) RemoveAllJobs() error {
+	return s.fs.RemoveAll(s.dirProvider.MonitJobsDir())
+}
+
+// MonitorJobFailures supervises processes and blocks forever
+func (s *nativeJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error {
+	s.lock.Lock()
+	s.handler = handler
+	s.lock.Unlock()
+
+	for {
+		_ = s.eachService(func(name string, service *nativeService) error {
+			if service.monitored {
+				s.checkService(name, service)
+			}
+			return nil
+		})
+
+		select {
+		case <-s.stopCh:
+			return nil
+		case <-s.timeService.NewTimer(s.options.CheckInterval).C():
+		}
+	}
+}
+
+// MonitorJobEvents returns immediately; process states are polled by the wrapper
+func (s *nativeJobSupervisor) MonitorJobEvents(_ JobEventHandler) error {
+	return nil
+}
+
+func (s *nativeJobSupervisor) HealthRecorder(status string) {
+}
+
+// withService is eachService for the single service called name
+func (s *nativeJobSupervisor) withService(name string, fn func(*nativeService) error) error {
+	found := false
+
+	err := s.eachService(func(serviceName string, service *nativeService) error {
+		if serviceName != name {
+			return nil
+		}
+
+		found = true
+
+		return fn(service)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return bosherr.Errorf("Unknown process '%s'", name)
+	}
+
+	return nil
+}
+
+// eachService calls fn with the lock held for every service that is not busy
+// and sends alerts raised meanwhile once the lock is released
+func (s *nativeJobSupervisor) eachService(fn func(string, *nativeService) error) error {
+	s.lock.Lock()
+
+	var err error
+
+	for _, name := range append([]string{}, s.names...) {
+		service, found := s.services[name]
+		for found && service.busy {
+			s.idle.Wait()
+			service, found = s.services[name]
+		}
+
+		if !found {
+			continue
+		}
+
+		err = fn(name, service)
+		if err != nil {
+			break
+		}
+	}
+
+	alerts := s.pendingAlerts
+	s.pendingAlerts = nil
+	handler := s.handler
+
+	s.lock.Unlock()
+
+	for _, alert := range alerts {
+		if handler == nil {
+			break
+		}
+
+		handlerErr := handler(alert)
+		if handlerErr != nil {
+			s.logger.Error(nativeJobSupervisorLogTag, "Handling failure of service %s: %s", alert.Service, handlerErr.Error())
+		}
+	}
+
+	return err
+}
+
+func (s *nativeJobSupervisor) checkService(name string, service *nativeService) {
+	now := s.timeService.Now()
+	pid := s.readPid(service.config.PidFile)
+
+	if !s.pidAlive(pid) {
+		if !service.failing {
+			service.failing = true
+			s.alert(name, "Does not exist", monitrc.ActionRestart, "process is not running")
+		}
+
+		if now.Before(service.nextRestart) {
+			return
+		}
+
+		s.logger.Info(nativeJobSupervisorLogTag, "Restarting service %s", name)
+
+		err := s.startService(name, service)
+		if err != nil {
+			s.logger.Error(nativeJobSupervisorLogTag, "Restarting service %s: %s", name, err.Error())
+		}
+
+		if service.restartDelay == 0 {
+			service.restartDelay = s.options.MinRestartDelay
+		} else {
+			service.restartDelay *= 2
+			if service.restartDelay > s.options.MaxRestartDelay {
+				service.restartDelay = s.options.MaxRestartDelay
+			}
+		}
+
+		service.nextRestart = s.timeService.Now().Add(service.restartDelay)
+
+		return
+	}
+
+	if pid != service.pid {
+		service.pid = pid
+		service.startedAt = now
+		service.lastSample = time.Time{}
+	}
+
+	service.failing = false
+
+	if now.Sub(service.startedAt) >= s.options.StableUptime {
+		service.restartDelay = 0
+	}
+
+	s.checkResourceLimits(name, service, now)
+}
+
+func (s *nativeJobSupervisor) checkResourceLimits(name string, service *nativeService, now time.Time) {
+	stats, err := s.processStats(service.pid)
+	if err != nil {
+		s.logger.Debug(nativeJobSupervisorLogTag, "Getting stats of service %s: %s", name, err.Error())
+		return
+	}
+
+	if !service.lastSample.IsZero() && now.After(service.lastSample) {
+		elapsed := now.Sub(service.lastSample)
+		service.cpuPercent = float64(stats.CPUTime-service.stats.CPUTime) / float64(elapsed) * 100
+	}
+
+	service.stats = stats
+	service.lastSample = now
+
+	for i, limit := range service.config.ResourceLimits {
+		// Child processes are not accounted for, so totalmem and totalcpu
+		// are checked against the process itself
+		var value float64
+		var unit string
+
+		switch limit.Resource {
+		case monitrc.ResourceMemory, monitrc.ResourceTotalMemory:
+			if limit.Percent {
+				value, unit = stats.MemoryPercent, "%"
+			} else {
+				value, unit = float64(stats.MemoryKb), " kB"
+			}
+		case monitrc.ResourceCPU, monitrc.ResourceTotalCPU:
+			value, unit = service.cpuPercent, "%"
+		}
+
+		if value <= limit.Threshold {
+			service.limitCycles[i] = 0
+			continue
+		}
+
+		service.limitCycles[i]++
+		if service.limitCycles[i] < limit.Cycles {
+			continue
+		}
+
+		service.limitCycles[i] = 0
+
+		description := fmt.Sprintf("%s of %.1f%s matches resource limit [%s > %.1f%s]", limit.Resource, value, unit, limit.Resource, limit.Threshold, unit)
+		s.alert(name, "Resource limit matched", limit.Action, description)
+
+		s.applyLimitAction(name, service, limit.Action)
+
+		return
+	}
+}
+
+func (s *nativeJobSupervisor) applyLimitAction(name string, service *nativeService, action string) {
+	var err error
+
+	switch action {
+	case monitrc.ActionRestart:
+		err = s.stopService(name, service)
+		if err == nil {
+			err = s.startService(name, service)
+		}
+	case monitrc.ActionStop:
+		service.monitored = false
+		err = s.stopService(name, service)
+	case monitrc.ActionUnmonitor:
+		service.monitored = false
+	}
+
+	if err != nil {
+		s.logger.Error(nativeJobSupervisorLogTag, "Running %s action for service %s: %s", action, name, err.Error())
+	}
+}
+
+func (s *nativeJobSupervisor) startService(name string, service *nativeService) error {
+	if s.pidAlive(s.readPid(service.config.PidFile)) {
+		return nil
+	}
+
+	s.logger.Debug(nativeJobSupervisorLogTag, "Starting service %s", name)
+
+	service.starting = true
+	err := s.runProgram(service, service.config.Start)
+	service.starting = false
+
+	if err != nil {
+		service.failing = true
+		s.alert(name, "Execution failed", "start", err.Error())
+		return err
+	}
+
+	pid := s.readPid(service.config.PidFile)
+	if s.pidAlive(pid) {
+		service.pid = pid
+		service.startedAt = s.timeService.Now()
+		service.lastSample = time.Time{}
+		service.failing = false
+	}
+
+	return nil
+}
+
+func (s *nativeJobSupervisor) stopService(name string, service *nativeService) error {
+	if service.config.Stop.Command == "" {
+		pid := s.readPid(service.config.PidFile)
+		if s.pidAlive(pid) {
+			return syscall.Kill(pid, syscall.SIGTERM)
+		}
+		return nil
+	}
+
+	s.logger.Debug(nativeJobSupervisorLogTag, "Stopping service %s", name)
+
+	err := s.runProgram(service, service.config.Stop)
+	if err != nil {
+		s.alert(name, "Execution failed", "stop", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// waitForExit must be called with the lock held; it is released
+// until the process of the service is gone or StopTimeout passed
+func (s *nativeJobSupervisor) waitForExit(name string, service *nativeService) error {
+	pidFile := service.config.PidFile
+
+	service.busy = true
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		service.busy = false
+		s.idle.Broadcast()
+	}()
+
+	timer := s.timeService.NewTimer(s.options.StopTimeout)
+	defer timer.Stop()
+
+	for s.pidAlive(s.readPid(pidFile)) {
+		select {
+		case <-timer.C():
+			return bosherr.Errorf("Timed out waiting for service %s to stop after %s", name, s.options.StopTimeout)
+		default:
+		}
+
+		s.timeService.Sleep(100 * time.Millisecond)
+	}
+
+	return nil
+}
+
+// runProgram must be called with the lock held; it is
+// released while the program runs and the service is busy
+func (s *nativeJobSupervisor) runProgram(service *nativeService, program monitrc.Program) error {
+	timeout := program.Timeout
+	if timeout == 0 {
+		timeout = s.options.ProgramTimeout
+	}
+
+	service.busy = true
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		service.busy = false
+		s.idle.Broadcast()
+	}()
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return bosherr.WrapError(err, "Opening null device")
+	}
+
+	defer func() { _ = devNull.Close() }()
+
+	// Output goes to a file so that daemons started in the
+	// background do not keep the command from finishing
+	process, err := s.runner.RunComplexCommandAsync(boshsys.Command{
+		Name:           "/bin/sh",
+		Args:           []string{"-c", program.Command},
+		Env:            map[string]string{"PATH": nativeProgramPath},
+		UseIsolatedEnv: true,
+		Stdout:         devNull,
+		Stderr:         devNull,
+	})
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Running '%s'", program.Command)
+	}
+
+	timer := s.timeService.NewTimer(timeout)
+	defer timer.Stop()
+
+	resultCh := process.Wait()
+
+	select {
+	case result := <-resultCh:
+		if result.Error != nil {
+			return bosherr.WrapErrorf(result.Error, "Running '%s'", program.Command)
+		}
+		return nil
+
+	case <-timer.C():
+		_ = process.TerminateNicely(5 * time.Second)
+		<-resultCh
+		return bosherr.Errorf("Running '%s' timed out after %s", program.Command, timeout)
+	}
+}
+
+// alert must be called with the lock held; alerts are sent by eachService
+func (s *nativeJobSupervisor) alert(name, event, action, description string) {
+	now := s.timeService.Now()
+
+	s.logger.Info(nativeJobSupervisorLogTag, "Service %s: %s - %s: %s", name, event, action, description)
+
+	s.pendingAlerts = append(s.pendingAlerts, newMonitrcAlert(now, name, event, action, description))
+}
+
+func (s *nativeJobSupervisor) processes() []Process {
+	processes := []Process{}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.timeService.Now()
+
+	for _, name := range s.names {
+		service := s.services[name]
+
+		process := Process{Name: name}
+
+		switch {
+		case service.starting:
+			process.State = processStateStarting
+		case !service.monitored:
+			process.State = processStateNotMonitored
+		case service.failing || service.pid == 0:
+			process.State = processStateFailing
+		default:
+			process.State = processStateRunning
+			process.Uptime.Secs = int(now.Sub(service.startedAt).Seconds())
+			process.Memory.Kb = int(service.stats.MemoryKb)
+			process.Memory.Percent = service.stats.MemoryPercent
+			process.CPU.Total = service.cpuPercent
+		}
+
+		processes = append(processes, process)
+	}
+
+	return processes
+}
+
+func (s *nativeJobSupervisor) readPid(pidFile string) int {
+	content, err := s.fs.ReadFileString(pidFile)
+	if err != nil {
+		return 0
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(content))
+	if err != nil {
+		return 0
+	}
+
+	return pid
+}
+
+func (s *nativeJobSupervisor) stoppedFilePath() string {
+	return filepath.Join(s.dirProvider.MonitDir(), "stopped")
+}
+
+func pidAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+func sigarProcessStats(pid int) (nativeProcessStats, error) {
+	var stats nativeProcessStats
+
+	procMem := sigar.ProcMem{}
+	err := procMem.Get(pid)
+	if err != nil {
+		return stats, bosherr.WrapErrorf(err, "Getting memory of process %d", pid)
+	}
+
+	procTime := sigar.ProcTime{}
+	err = procTime.Get(pid)
+	if err != nil {
+		return stats, bosherr.WrapErrorf(err, "Getting cpu time of process %d", pid)
+	}
+
+	stats.MemoryKb = procMem.Resident / 1024
+	stats.CPUTime = time.Duration(procTime.Total) * time.Millisecond
+
+	mem := sigar.Mem{}
+	if mem.Get() == nil && mem.Total > 0 {
+		stats.MemoryPercent = float64(procMem.Resident) / float64(mem.Total) * 100
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,17 @@
+// +build !windows
+
+package jobsupervisor
+
+import (
+	"time"
+)
+
+func StopNativeMonitoring(s JobSupervisor) {
+	close(s.(*nativeJobSupervisor).stopCh)
+}
+
+func SetNativeProcessStats(s JobSupervisor, memoryKb uint64, cpuTime time.Duration) {
+	s.(*nativeJobSupervisor).processStats = func(int) (nativeProcessStats, error) {
+		return nativeProcessStats{MemoryKb: memoryKb, CPUTime: cpuTime}, nil
+	}
+}
@@ -0,0 +1,373 @@
+// +build !windows
+
+package jobsupervisor_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const nativeCtlScript = `#!/bin/sh
+case $1 in
+  start)
+    echo start >> %[1]s/starts
+    if [ -f %[1]s/fail ]; then exit 1; fi
+    sleep 30 >/dev/null 2>&1 &
+    echo $! > %[1]s/fake.pid
+    ;;
+  stop)
+    kill $(cat %[1]s/fake.pid)
+    rm -f %[1]s/fake.pid
+    ;;
+esac
+`
+
+const nativeMonitConfig = `
+check process fake-process
+  with pidfile %[1]s/fake.pid
+  start program "%[1]s/ctl start" with timeout 5 seconds
+  stop program "%[1]s/ctl stop"
+  group vcap
+  if totalmem > 100 Mb for 2 cycles then restart
+`
+
+var _ = Describe("nativeJobSupervisor", func() {
+	var (
+		baseDir     string
+		jobDir      string
+		fs          boshsys.FileSystem
+		dirProvider boshdir.Provider
+		options     NativeSupervisorOptions
+		supervisor  JobSupervisor
+		monitoring  bool
+
+		alertsLock sync.Mutex
+		alerts     []boshalert.MonitAlert
+	)
+
+	receivedAlerts := func() []boshalert.MonitAlert {
+		alertsLock.Lock()
+		defer alertsLock.Unlock()
+		return append([]boshalert.MonitAlert{}, alerts...)
+	}
+
+	alertEvents := func() []string {
+		events := []string{}
+		for _, alert := range receivedAlerts() {
+			events = append(events, alert.Event)
+		}
+		return events
+	}
+
+	startCount := func() int {
+		content, err := ioutil.ReadFile(filepath.Join(jobDir, "starts"))
+		if err != nil {
+			return 0
+		}
+		return strings.Count(string(content), "start")
+	}
+
+	readPid := func() string {
+		content, _ := ioutil.ReadFile(filepath.Join(jobDir, "fake.pid"))
+		return strings.TrimSpace(string(content))
+	}
+
+	buildSupervisor := func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs = boshsys.NewOsFileSystem(logger)
+		supervisor = NewNativeJobSupervisor(
+			fs,
+			boshsys.NewExecCmdRunner(logger),
+			logger,
+			dirProvider,
+			clock.NewClock(),
+			options,
+		)
+	}
+
+	addJob := func() {
+		Expect(supervisor.AddJob("fake-job", 0, filepath.Join(jobDir, "fake-job.monit"))).To(Succeed())
+		Expect(supervisor.Reload()).To(Succeed())
+	}
+
+	monitor := func() {
+		monitoring = true
+		go func() {
+			defer GinkgoRecover()
+
+			err := supervisor.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				alerts = append(alerts, alert)
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}()
+	}
+
+	BeforeEach(func() {
+		var err error
+		baseDir, err = ioutil.TempDir("", "native-job-supervisor")
+		Expect(err).ToNot(HaveOccurred())
+
+		jobDir = filepath.Join(baseDir, "fake-job")
+		Expect(os.MkdirAll(jobDir, 0700)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(baseDir, "monit"), 0700)).To(Succeed())
+
+		err = ioutil.WriteFile(filepath.Join(jobDir, "ctl"), []byte(fmt.Sprintf(nativeCtlScript, jobDir)), 0700)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ioutil.WriteFile(filepath.Join(jobDir, "fake-job.monit"), []byte(fmt.Sprintf(nativeMonitConfig, jobDir)), 0600)
+		Expect(err).ToNot(HaveOccurred())
+
+		dirProvider = boshdir.NewProvider(baseDir)
+		options = NativeSupervisorOptions{
+			CheckInterval:   20 * time.Millisecond,
+			ProgramTimeout:  5 * time.Second,
+			MinRestartDelay: 10 * time.Millisecond,
+			MaxRestartDelay: 40 * time.Millisecond,
+			StableUptime:    time.Hour,
+			StopTimeout:     5 * time.Second,
+		}
+		monitoring = false
+		alerts = nil
+
+		buildSupervisor()
+	})
+
+	AfterEach(func() {
+		if monitoring {
+			StopNativeMonitoring(supervisor)
+		}
+		_ = supervisor.Stop()
+		_ = os.RemoveAll(baseDir)
+	})
+
+	Describe("AddJob", func() {
+		It("copies the job config into the monit jobs dir", func() {
+			addJob()
+
+			Expect(filepath.Join(dirProvider.MonitJobsDir(), "0000_fake-job.monitrc")).To(BeAnExistingFile())
+		})
+
+		It("returns an error for invalid configs", func() {
+			configPath := filepath.Join(jobDir, "broken.monit")
+			Expect(ioutil.WriteFile(configPath, []byte("check process"), 0600)).To(Succeed())
+
+			err := supervisor.AddJob("broken", 0, configPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Missing name for check process"))
+		})
+	})
+
+	Describe("Start", func() {
+		It("runs start programs of vcap processes", func() {
+			addJob()
+
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(readPid()).ToNot(BeEmpty())
+			Expect(supervisor.Status()).To(Equal("running"))
+
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(HaveLen(1))
+			Expect(processes[0].Name).To(Equal("fake-process"))
+			Expect(processes[0].State).To(Equal("running"))
+		})
+
+		It("does not start processes that are already running", func() {
+			addJob()
+
+			Expect(supervisor.Start()).To(Succeed())
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(startCount()).To(Equal(1))
+		})
+
+		It("returns an error when the start program fails", func() {
+			addJob()
+			Expect(ioutil.WriteFile(filepath.Join(jobDir, "fail"), []byte{}, 0600)).To(Succeed())
+
+			err := supervisor.Start()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Starting service fake-process"))
+			Expect(supervisor.Status()).To(Equal("failing"))
+		})
+	})
+
+	Describe("StopAndWait", func() {
+		It("runs stop programs and reports the processes as stopped", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(supervisor.StopAndWait()).To(BeEmpty())
+
+			Expect(readPid()).To(BeEmpty())
+			Expect(supervisor.Status()).To(Equal("stopped"))
+
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes[0].State).To(Equal("not monitored"))
+		})
+
+		It("keeps processes stopped after a reload", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			Expect(supervisor.StopAndWait()).To(BeEmpty())
+
+			buildSupervisor()
+			Expect(supervisor.Reload()).To(Succeed())
+			monitor()
+
+			Consistently(startCount, 200*time.Millisecond).Should(Equal(1))
+		})
+	})
+
+	Describe("StopProcess", func() {
+		It("stops a single process without stopping the instance", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(supervisor.StopProcess("fake-process")).To(Succeed())
+
+			Expect(readPid()).To(BeEmpty())
+			Expect(supervisor.Status()).To(Equal("failing"))
+
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes[0].State).To(Equal("not monitored"))
+		})
+
+		It("returns an error for unknown processes", func() {
+			addJob()
+
+			err := supervisor.StopProcess("unknown-process")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown process 'unknown-process'"))
+		})
+	})
+
+	Describe("StartProcess", func() {
+		It("starts and monitors a stopped process", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			Expect(supervisor.StopProcess("fake-process")).To(Succeed())
+
+			Expect(supervisor.StartProcess("fake-process")).To(Succeed())
+
+			Expect(readPid()).ToNot(BeEmpty())
+			Expect(startCount()).To(Equal(2))
+			Expect(supervisor.Status()).To(Equal("running"))
+		})
+
+		It("returns an error when the instance is stopped", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			Expect(supervisor.StopAndWait()).To(BeEmpty())
+
+			err := supervisor.StartProcess("fake-process")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Cannot start process 'fake-process' of a stopped instance"))
+			Expect(startCount()).To(Equal(1))
+		})
+	})
+
+	Describe("RestartProcess", func() {
+		It("stops the process and starts it again", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			pid := readPid()
+
+			Expect(supervisor.RestartProcess("fake-process")).To(Succeed())
+
+			Expect(startCount()).To(Equal(2))
+			Expect(readPid()).ToNot(BeEmpty())
+			Expect(readPid()).ToNot(Equal(pid))
+			Expect(supervisor.Status()).To(Equal("running"))
+		})
+	})
+
+	Describe("MonitorJobFailures", func() {
+		It("restarts processes that exited and reports the failure", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			monitor()
+
+			Expect(os.Remove(filepath.Join(jobDir, "fake.pid"))).To(Succeed())
+
+			Eventually(startCount).Should(Equal(2))
+			Eventually(supervisor.Status).Should(Equal("running"))
+
+			Eventually(receivedAlerts).Should(HaveLen(1))
+			Expect(receivedAlerts()[0].Service).To(Equal("fake-process"))
+			Expect(receivedAlerts()[0].Event).To(Equal("Does not exist"))
+			Expect(receivedAlerts()[0].Action).To(Equal("restart"))
+
+			_, err := time.Parse(time.RFC1123Z, receivedAlerts()[0].Date)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("backs off restarting processes that keep failing", func() {
+			options.MinRestartDelay = 200 * time.Millisecond
+			options.MaxRestartDelay = 400 * time.Millisecond
+			buildSupervisor()
+
+			addJob()
+			Expect(ioutil.WriteFile(filepath.Join(jobDir, "fail"), []byte{}, 0600)).To(Succeed())
+			monitor()
+
+			Eventually(startCount).Should(BeNumerically(">=", 2))
+			Consistently(startCount, 300*time.Millisecond).Should(BeNumerically("<=", 4))
+
+			Eventually(alertEvents).Should(ContainElement("Does not exist"))
+			Eventually(alertEvents).Should(ContainElement("Execution failed"))
+
+			Expect(os.Remove(filepath.Join(jobDir, "fail"))).To(Succeed())
+			Eventually(supervisor.Status, 2*time.Second).Should(Equal("running"))
+		})
+
+		It("restarts processes that exceed resource limits", func() {
+			SetNativeProcessStats(supervisor, 200*1024, 0)
+
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			monitor()
+
+			Eventually(startCount).Should(BeNumerically(">=", 2))
+			Eventually(receivedAlerts).ShouldNot(BeEmpty())
+
+			Expect(receivedAlerts()[0].Event).To(Equal("Resource limit matched"))
+			Expect(receivedAlerts()[0].Action).To(Equal("restart"))
+			Expect(receivedAlerts()[0].Description).To(ContainSubstring("totalmem of 204800.0 kB"))
+		})
+
+		It("does not restart unmonitored processes", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			Expect(supervisor.Unmonitor()).To(Succeed())
+			monitor()
+
+			Expect(os.Remove(filepath.Join(jobDir, "fake.pid"))).To(Succeed())
+
+			Consistently(startCount, 200*time.Millisecond).Should(Equal(1))
+			Expect(receivedAlerts()).To(BeEmpty())
+		})
+	})
+})
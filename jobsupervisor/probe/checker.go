@@ -0,0 +1,103 @@
+package probe
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const execKillGracePeriod = 5 * time.Second
+
+// Checker runs the action of a probe once
+type Checker interface {
+	Check() error
+}
+
+func NewChecker(config Config, runner boshsys.CmdRunner) Checker {
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+
+	switch {
+	case config.HTTPGet != nil:
+		return httpChecker{url: config.HTTPGet.URL, client: &http.Client{Timeout: timeout}}
+	case config.TCPSocket != nil:
+		return tcpChecker{address: config.TCPSocket.Address, timeout: timeout}
+	default:
+		return execChecker{command: config.Exec.Command, timeout: timeout, runner: runner}
+	}
+}
+
+type httpChecker struct {
+	url    string
+	client *http.Client
+}
+
+func (c httpChecker) Check() error {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Requesting %s", c.url)
+	}
+
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return bosherr.Errorf("Requesting %s: unexpected status %d", c.url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+type tcpChecker struct {
+	address string
+	timeout time.Duration
+}
+
+func (c tcpChecker) Check() error {
+	conn, err := net.DialTimeout("tcp", c.address, c.timeout)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Connecting to %s", c.address)
+	}
+
+	return conn.Close()
+}
+
+type execChecker struct {
+	command []string
+	timeout time.Duration
+	runner  boshsys.CmdRunner
+}
+
+func (c execChecker) Check() error {
+	process, err := c.runner.RunComplexCommandAsync(boshsys.Command{
+		Name:  c.command[0],
+		Args:  c.command[1:],
+		Quiet: true,
+	})
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Running %s", c.command[0])
+	}
+
+	resultCh := process.Wait()
+
+	select {
+	case result := <-resultCh:
+		if result.Error != nil {
+			return bosherr.WrapErrorf(result.Error, "Running %s", c.command[0])
+		}
+		if result.ExitStatus != 0 {
+			return bosherr.Errorf("Running %s: exit status %d", c.command[0], result.ExitStatus)
+		}
+		return nil
+
+	case <-time.After(c.timeout):
+		_ = process.TerminateNicely(execKillGracePeriod)
+		<-resultCh
+		return bosherr.Errorf("Running %s: timed out after %s", c.command[0], c.timeout)
+	}
+}
@@ -0,0 +1,143 @@
+package probe_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/probe"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Checker", func() {
+	var runner *fakesys.FakeCmdRunner
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+	})
+
+	Describe("http_get", func() {
+		var (
+			server *httptest.Server
+			status int
+		)
+
+		BeforeEach(func() {
+			status = http.StatusOK
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		check := func(url string) error {
+			config := Config{HTTPGet: &HTTPGetAction{URL: url}, TimeoutSeconds: 1}
+			return NewChecker(config, runner).Check()
+		}
+
+		It("succeeds on successful responses", func() {
+			Expect(check(server.URL)).To(Succeed())
+
+			status = http.StatusFound
+			Expect(check(server.URL)).To(Succeed())
+		})
+
+		It("fails on error responses", func() {
+			status = http.StatusServiceUnavailable
+
+			err := check(server.URL)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unexpected status 503"))
+		})
+
+		It("fails when the request fails", func() {
+			server.Close()
+
+			err := check(server.URL)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Requesting " + server.URL))
+		})
+	})
+
+	Describe("tcp_socket", func() {
+		It("succeeds when a connection can be established", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			defer listener.Close()
+
+			config := Config{TCPSocket: &TCPSocketAction{Address: listener.Addr().String()}, TimeoutSeconds: 1}
+			Expect(NewChecker(config, runner).Check()).To(Succeed())
+		})
+
+		It("fails when the connection is refused", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			address := listener.Addr().String()
+			Expect(listener.Close()).To(Succeed())
+
+			config := Config{TCPSocket: &TCPSocketAction{Address: address}, TimeoutSeconds: 1}
+
+			err = NewChecker(config, runner).Check()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Connecting to " + address))
+		})
+	})
+
+	Describe("exec", func() {
+		var checker Checker
+
+		BeforeEach(func() {
+			config := Config{Exec: &ExecAction{Command: []string{"/bin/check", "--quick"}}, TimeoutSeconds: 1}
+			checker = NewChecker(config, runner)
+		})
+
+		It("succeeds when the command exits with 0", func() {
+			runner.AddProcess("/bin/check --quick", &fakesys.FakeProcess{})
+
+			Expect(checker.Check()).To(Succeed())
+			Expect(runner.RunComplexCommands).To(Equal([]boshsys.Command{
+				{Name: "/bin/check", Args: []string{"--quick"}, Quiet: true},
+			}))
+		})
+
+		It("fails when the command exits with another status", func() {
+			runner.AddProcess("/bin/check --quick", &fakesys.FakeProcess{
+				WaitResult: boshsys.Result{ExitStatus: 2},
+			})
+
+			err := checker.Check()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Running /bin/check: exit status 2"))
+		})
+
+		It("fails when the command cannot be started", func() {
+			runner.AddProcess("/bin/check --quick", &fakesys.FakeProcess{StartErr: errors.New("fake-start-error")})
+
+			err := checker.Check()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-start-error"))
+		})
+
+		It("terminates the command after the timeout", func() {
+			process := &fakesys.FakeProcess{
+				TerminatedNicelyCallBack: func(p *fakesys.FakeProcess) {
+					p.WaitCh <- boshsys.Result{ExitStatus: 143}
+				},
+			}
+			runner.AddProcess("/bin/check --quick", process)
+
+			err := checker.Check()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Running /bin/check: timed out after 1s"))
+			Expect(process.TerminatedNicely).To(BeTrue())
+		})
+	})
+})
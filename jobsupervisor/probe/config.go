@@ -0,0 +1,172 @@
+package probe
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"path/filepath"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// FileName is the sidecar file in a job directory declaring the probes of
+// the job's processes; jobs usually render it from their spec properties
+const FileName = "probes.json"
+
+const (
+	KindReadiness = "readiness"
+	KindLiveness  = "liveness"
+
+	DefaultPeriodSeconds    = 10
+	DefaultTimeoutSeconds   = 1
+	DefaultFailureThreshold = 3
+)
+
+type Config struct {
+	Name string `json:"name"`
+
+	// Process is the name of the supervised process the probe checks
+	Process string `json:"process"`
+
+	// Kind is either readiness, i.e. the process is able to serve, or
+	// liveness, i.e. the process is not hung
+	Kind string `json:"kind"`
+
+	// Exactly one of HTTPGet, TCPSocket and Exec must be set
+	HTTPGet   *HTTPGetAction   `json:"http_get,omitempty"`
+	TCPSocket *TCPSocketAction `json:"tcp_socket,omitempty"`
+	Exec      *ExecAction      `json:"exec,omitempty"`
+
+	PeriodSeconds  int `json:"period_seconds,omitempty"`
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+
+	// FailureThreshold is the number of consecutive failures after which
+	// the probe is considered failing
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+}
+
+// HTTPGetAction succeeds on 2xx and 3xx responses
+type HTTPGetAction struct {
+	URL string `json:"url"`
+}
+
+type TCPSocketAction struct {
+	Address string `json:"address"`
+}
+
+// ExecAction succeeds if the command exits with 0
+type ExecAction struct {
+	Command []string `json:"command"`
+}
+
+type configFile struct {
+	Probes []Config `json:"probes"`
+}
+
+// LoadConfigs reads the probes declared in jobDir; a job without a probes
+// file has no probes
+func LoadConfigs(fs boshsys.FileSystem, jobDir string) ([]Config, error) {
+	path := filepath.Join(jobDir, FileName)
+	if !fs.FileExists(path) {
+		return nil, nil
+	}
+
+	contents, err := fs.ReadFile(path)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading probes file %s", path)
+	}
+
+	var file configFile
+
+	err = json.Unmarshal(contents, &file)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing probes file %s", path)
+	}
+
+	names := map[string]bool{}
+
+	for i := range file.Probes {
+		config := &file.Probes[i]
+
+		err = config.validate()
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Validating probe %d in %s", i, path)
+		}
+
+		if names[config.Name] {
+			return nil, bosherr.Errorf("Duplicate probe '%s' in %s", config.Name, path)
+		}
+		names[config.Name] = true
+
+		config.applyDefaults()
+	}
+
+	return file.Probes, nil
+}
+
+func (c Config) validate() error {
+	if c.Name == "" {
+		return bosherr.Error("Missing name")
+	}
+
+	if c.Process == "" {
+		return bosherr.Errorf("Missing process of probe '%s'", c.Name)
+	}
+
+	if c.Kind != KindReadiness && c.Kind != KindLiveness {
+		return bosherr.Errorf("Unknown kind '%s' of probe '%s', expected readiness or liveness", c.Kind, c.Name)
+	}
+
+	if c.PeriodSeconds < 0 || c.TimeoutSeconds < 0 || c.FailureThreshold < 0 {
+		return bosherr.Errorf("Negative period, timeout or failure threshold of probe '%s'", c.Name)
+	}
+
+	actions := 0
+
+	if c.HTTPGet != nil {
+		actions++
+
+		u, err := url.Parse(c.HTTPGet.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return bosherr.Errorf("Invalid URL '%s' of probe '%s'", c.HTTPGet.URL, c.Name)
+		}
+	}
+
+	if c.TCPSocket != nil {
+		actions++
+
+		_, _, err := net.SplitHostPort(c.TCPSocket.Address)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Invalid address of probe '%s'", c.Name)
+		}
+	}
+
+	if c.Exec != nil {
+		actions++
+
+		if len(c.Exec.Command) == 0 || c.Exec.Command[0] == "" {
+			return bosherr.Errorf("Missing command of probe '%s'", c.Name)
+		}
+	}
+
+	if actions != 1 {
+		return bosherr.Errorf("Probe '%s' must have exactly one of http_get, tcp_socket and exec", c.Name)
+	}
+
+	return nil
+}
+
+func (c *Config) applyDefaults() {
+	if c.PeriodSeconds == 0 {
+		c.PeriodSeconds = DefaultPeriodSeconds
+	}
+
+	if c.TimeoutSeconds == 0 {
+		c.TimeoutSeconds = DefaultTimeoutSeconds
+	}
+
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = DefaultFailureThreshold
+	}
+}
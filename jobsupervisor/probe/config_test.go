@@ -0,0 +1,133 @@
+package probe_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/probe"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("LoadConfigs", func() {
+	var fs *fakesys.FakeFileSystem
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+	})
+
+	writeProbes := func(contents string) {
+		Expect(fs.WriteFileString("/var/vcap/jobs/redis/probes.json", contents)).To(Succeed())
+	}
+
+	It("loads the probes declared in the job directory", func() {
+		writeProbes(`{"probes": [
+			{"name": "ping", "process": "redis", "kind": "liveness", "tcp_socket": {"address": "127.0.0.1:6379"}, "period_seconds": 5},
+			{"name": "ready", "process": "redis", "kind": "readiness", "http_get": {"url": "http://127.0.0.1:8080/ready"}, "timeout_seconds": 2, "failure_threshold": 1},
+			{"name": "check", "process": "redis", "kind": "liveness", "exec": {"command": ["/var/vcap/jobs/redis/bin/check", "--quick"]}}
+		]}`)
+
+		configs, err := LoadConfigs(fs, "/var/vcap/jobs/redis")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configs).To(Equal([]Config{
+			{
+				Name:             "ping",
+				Process:          "redis",
+				Kind:             KindLiveness,
+				TCPSocket:        &TCPSocketAction{Address: "127.0.0.1:6379"},
+				PeriodSeconds:    5,
+				TimeoutSeconds:   DefaultTimeoutSeconds,
+				FailureThreshold: DefaultFailureThreshold,
+			},
+			{
+				Name:             "ready",
+				Process:          "redis",
+				Kind:             KindReadiness,
+				HTTPGet:          &HTTPGetAction{URL: "http://127.0.0.1:8080/ready"},
+				PeriodSeconds:    DefaultPeriodSeconds,
+				TimeoutSeconds:   2,
+				FailureThreshold: 1,
+			},
+			{
+				Name:             "check",
+				Process:          "redis",
+				Kind:             KindLiveness,
+				Exec:             &ExecAction{Command: []string{"/var/vcap/jobs/redis/bin/check", "--quick"}},
+				PeriodSeconds:    DefaultPeriodSeconds,
+				TimeoutSeconds:   DefaultTimeoutSeconds,
+				FailureThreshold: DefaultFailureThreshold,
+			},
+		}))
+	})
+
+	It("returns no probes when the job does not declare any", func() {
+		configs, err := LoadConfigs(fs, "/var/vcap/jobs/redis")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configs).To(BeEmpty())
+	})
+
+	It("returns an error when the probes file cannot be read", func() {
+		writeProbes(`{}`)
+		fs.ReadFileError = errors.New("fake-read-error")
+
+		_, err := LoadConfigs(fs, "/var/vcap/jobs/redis")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-read-error"))
+	})
+
+	It("returns an error when the probes file is not valid JSON", func() {
+		writeProbes(`{"probes": [`)
+
+		_, err := LoadConfigs(fs, "/var/vcap/jobs/redis")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing probes file /var/vcap/jobs/redis/probes.json"))
+	})
+
+	DescribeTable("rejects invalid probes",
+		func(probe, expectedErr string) {
+			writeProbes(`{"probes": [` + probe + `]}`)
+
+			_, err := LoadConfigs(fs, "/var/vcap/jobs/redis")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(expectedErr))
+		},
+		Entry("without name",
+			`{"process": "redis", "kind": "liveness", "tcp_socket": {"address": "127.0.0.1:6379"}}`,
+			"Missing name"),
+		Entry("without process",
+			`{"name": "ping", "kind": "liveness", "tcp_socket": {"address": "127.0.0.1:6379"}}`,
+			"Missing process of probe 'ping'"),
+		Entry("with unknown kind",
+			`{"name": "ping", "process": "redis", "kind": "startup", "tcp_socket": {"address": "127.0.0.1:6379"}}`,
+			"Unknown kind 'startup' of probe 'ping'"),
+		Entry("without action",
+			`{"name": "ping", "process": "redis", "kind": "liveness"}`,
+			"Probe 'ping' must have exactly one of http_get, tcp_socket and exec"),
+		Entry("with multiple actions",
+			`{"name": "ping", "process": "redis", "kind": "liveness", "tcp_socket": {"address": "127.0.0.1:6379"}, "exec": {"command": ["true"]}}`,
+			"Probe 'ping' must have exactly one of http_get, tcp_socket and exec"),
+		Entry("with invalid URL",
+			`{"name": "ping", "process": "redis", "kind": "liveness", "http_get": {"url": "ftp://127.0.0.1/"}}`,
+			"Invalid URL 'ftp://127.0.0.1/' of probe 'ping'"),
+		Entry("with invalid address",
+			`{"name": "ping", "process": "redis", "kind": "liveness", "tcp_socket": {"address": "127.0.0.1"}}`,
+			"Invalid address of probe 'ping'"),
+		Entry("with empty command",
+			`{"name": "ping", "process": "redis", "kind": "liveness", "exec": {"command": []}}`,
+			"Missing command of probe 'ping'"),
+		Entry("with negative period",
+			`{"name": "ping", "process": "redis", "kind": "liveness", "exec": {"command": ["true"]}, "period_seconds": -1}`,
+			"Negative period, timeout or failure threshold of probe 'ping'"),
+	)
+
+	It("rejects duplicate probe names", func() {
+		probe := `{"name": "ping", "process": "redis", "kind": "liveness", "tcp_socket": {"address": "127.0.0.1:6379"}}`
+		writeProbes(`{"probes": [` + probe + `,` + probe + `]}`)
+
+		_, err := LoadConfigs(fs, "/var/vcap/jobs/redis")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Duplicate probe 'ping'"))
+	})
+})
@@ -0,0 +1,102 @@
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	boshprobe "github.com/cloudfoundry/bosh-agent/jobsupervisor/probe"
+)
+
+type FakeMonitor struct {
+	lock sync.Mutex
+
+	AddedJobDirs []string
+	AddJobErr    error
+
+	RemovedAllJobs bool
+
+	// Transitions are passed to the handler given to Run
+	Transitions []boshprobe.Transition
+	running     bool
+	stopCh      chan struct{}
+
+	ProcessStatuses map[string]boshprobe.Status
+	AllStatus       boshprobe.Status
+
+	WaitUntilReadyTimeout time.Duration
+	WaitUntilReadyErr     error
+}
+
+func NewFakeMonitor() *FakeMonitor {
+	return &FakeMonitor{
+		ProcessStatuses: map[string]boshprobe.Status{},
+		AllStatus:       boshprobe.Status{Ready: true, Live: true},
+		stopCh:          make(chan struct{}),
+	}
+}
+
+func (m *FakeMonitor) AddJob(jobDir string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.AddedJobDirs = append(m.AddedJobDirs, jobDir)
+	return m.AddJobErr
+}
+
+func (m *FakeMonitor) RemoveAllJobs() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.RemovedAllJobs = true
+}
+
+func (m *FakeMonitor) Run(handler boshprobe.TransitionHandler) {
+	m.lock.Lock()
+	m.running = true
+	transitions := m.Transitions
+	m.lock.Unlock()
+
+	for _, transition := range transitions {
+		handler(transition)
+	}
+
+	<-m.stopCh
+}
+
+func (m *FakeMonitor) Stop() {
+	close(m.stopCh)
+}
+
+func (m *FakeMonitor) Running() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.running
+}
+
+func (m *FakeMonitor) ProcessStatus(process string) boshprobe.Status {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	status, found := m.ProcessStatuses[process]
+	if !found {
+		return boshprobe.Status{Ready: true, Live: true}
+	}
+
+	return status
+}
+
+func (m *FakeMonitor) Status() boshprobe.Status {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.AllStatus
+}
+
+func (m *FakeMonitor) WaitUntilReady(timeout time.Duration) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.WaitUntilReadyTimeout = timeout
+	return m.WaitUntilReadyErr
+}
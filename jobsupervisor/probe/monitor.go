@@ -0,0 +1,322 @@
+package probe
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	monitorLogTag = "probeMonitor"
+
+	checkInterval = 1 * time.Second
+)
+
+// Status of the probes of a process; processes without readiness probes
+// are always ready and processes without liveness probes always live
+type Status struct {
+	Ready bool
+	Live  bool
+}
+
+// Transition is reported when a liveness probe starts or stops failing
+type Transition struct {
+	Probe   Config
+	Passing bool
+	Err     error
+	At      time.Time
+}
+
+type TransitionHandler func(Transition)
+
+type Monitor interface {
+	// AddJob loads the probes declared in a job directory; adding a
+	// directory again keeps its probes unchanged
+	AddJob(jobDir string) error
+	RemoveAllJobs()
+
+	// Run checks the probes when they are due until Stop is called
+	Run(handler TransitionHandler)
+	Stop()
+
+	ProcessStatus(process string) Status
+	Status() Status
+
+	// WaitUntilReady checks readiness probes until all pass
+	WaitUntilReady(timeout time.Duration) error
+}
+
+type probeState struct {
+	config  Config
+	checker Checker
+
+	nextCheck time.Time
+	passing   bool
+	failures  int
+	lastErr   error
+}
+
+type monitor struct {
+	fs          boshsys.FileSystem
+	runner      boshsys.CmdRunner
+	timeService clock.Clock
+	logger      boshlog.Logger
+
+	lock   sync.Mutex
+	jobs   map[string][]*probeState
+	stopCh chan struct{}
+}
+
+func NewMonitor(fs boshsys.FileSystem, runner boshsys.CmdRunner, timeService clock.Clock, logger boshlog.Logger) Monitor {
+	return &monitor{
+		fs:          fs,
+		runner:      runner,
+		timeService: timeService,
+		logger:      logger,
+		jobs:        map[string][]*probeState{},
+	}
+}
+
+func (m *monitor) AddJob(jobDir string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, found := m.jobs[jobDir]; found {
+		return nil
+	}
+
+	configs, err := LoadConfigs(m.fs, jobDir)
+	if err != nil {
+		return err
+	}
+
+	var probes []*probeState
+
+	for _, config := range configs {
+		probes = append(probes, &probeState{
+			config:  config,
+			checker: NewChecker(config, m.runner),
+			// Processes are assumed to be live until proven otherwise
+			// but only ready once a readiness probe passed
+			passing: config.Kind == KindLiveness,
+		})
+	}
+
+	m.jobs[jobDir] = probes
+
+	return nil
+}
+
+func (m *monitor) RemoveAllJobs() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.jobs = map[string][]*probeState{}
+}
+
+func (m *monitor) Run(handler TransitionHandler) {
+	stopCh := m.stopChan()
+
+	for {
+		for _, transition := range m.checkDue() {
+			handler(transition)
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-m.timeService.NewTimer(checkInterval).C():
+		}
+	}
+}
+
+func (m *monitor) Stop() {
+	close(m.stopChan())
+}
+
+// stopChan is created lazily so that monitors can be compared in tests
+func (m *monitor) stopChan() chan struct{} {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopCh == nil {
+		m.stopCh = make(chan struct{})
+	}
+
+	return m.stopCh
+}
+
+func (m *monitor) ProcessStatus(process string) Status {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.status(func(p *probeState) bool { return p.config.Process == process })
+}
+
+func (m *monitor) Status() Status {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.status(func(*probeState) bool { return true })
+}
+
+func (m *monitor) WaitUntilReady(timeout time.Duration) error {
+	deadline := m.timeService.Now().Add(timeout)
+
+	for {
+		failing := m.checkReadiness()
+		if len(failing) == 0 {
+			return nil
+		}
+
+		if !m.timeService.Now().Before(deadline) {
+			return bosherr.Errorf("Timed out waiting for readiness probes %v", failing)
+		}
+
+		m.timeService.Sleep(checkInterval)
+	}
+}
+
+// status must be called with the lock held
+func (m *monitor) status(matches func(*probeState) bool) Status {
+	status := Status{Ready: true, Live: true}
+
+	for _, probes := range m.jobs {
+		for _, p := range probes {
+			if !matches(p) || p.passing {
+				continue
+			}
+
+			if p.config.Kind == KindReadiness {
+				status.Ready = false
+			} else {
+				status.Live = false
+			}
+		}
+	}
+
+	return status
+}
+
+// checkDue runs the probes whose period elapsed and returns the liveness
+// probes that started or stopped failing
+func (m *monitor) checkDue() []Transition {
+	now := m.timeService.Now()
+
+	var due []*probeState
+
+	m.lock.Lock()
+	for _, probes := range m.jobs {
+		for _, p := range probes {
+			if !now.Before(p.nextCheck) {
+				p.nextCheck = now.Add(time.Duration(p.config.PeriodSeconds) * time.Second)
+				due = append(due, p)
+			}
+		}
+	}
+	m.lock.Unlock()
+
+	var transitions []Transition
+
+	for i, err := range m.check(due) {
+		p := due[i]
+
+		m.lock.Lock()
+		changed := p.record(err)
+		transition := Transition{Probe: p.config, Passing: p.passing, Err: p.lastErr, At: m.timeService.Now()}
+		m.lock.Unlock()
+
+		if err != nil {
+			m.logger.Debug(monitorLogTag, "Probe '%s' of process '%s' failed: %s", p.config.Name, p.config.Process, err.Error())
+		}
+
+		if changed && p.config.Kind == KindLiveness {
+			transitions = append(transitions, transition)
+		}
+	}
+
+	return transitions
+}
+
+// checkReadiness runs all readiness probes that are not passing yet and
+// returns the names of those still failing
+func (m *monitor) checkReadiness() []string {
+	var pending []*probeState
+
+	m.lock.Lock()
+	for _, probes := range m.jobs {
+		for _, p := range probes {
+			if p.config.Kind == KindReadiness && !p.passing {
+				pending = append(pending, p)
+			}
+		}
+	}
+	m.lock.Unlock()
+
+	var failing []string
+
+	for i, err := range m.check(pending) {
+		p := pending[i]
+
+		m.lock.Lock()
+		p.record(err)
+		if !p.passing {
+			failing = append(failing, p.config.Name)
+		}
+		m.lock.Unlock()
+	}
+
+	sort.Strings(failing)
+
+	return failing
+}
+
+// check runs the probes concurrently since each may take up to its timeout
+func (m *monitor) check(probes []*probeState) []error {
+	errs := make([]error, len(probes))
+
+	var wg sync.WaitGroup
+
+	for i, p := range probes {
+		wg.Add(1)
+
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			defer m.logger.HandlePanic("Probe Check")
+
+			errs[i] = checker.Check()
+		}(i, p.checker)
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// record updates the state with the result of a check and returns whether
+// the probe started or stopped passing; must be called with the lock held
+func (p *probeState) record(err error) bool {
+	p.lastErr = err
+
+	if err == nil {
+		p.failures = 0
+		changed := !p.passing
+		p.passing = true
+		return changed
+	}
+
+	p.failures++
+
+	if p.passing && p.failures >= p.config.FailureThreshold {
+		p.passing = false
+		return true
+	}
+
+	return false
+}
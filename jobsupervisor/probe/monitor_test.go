@@ -0,0 +1,182 @@
+package probe_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/probe"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Monitor", func() {
+	var (
+		fs          *fakesys.FakeFileSystem
+		runner      *fakesys.FakeCmdRunner
+		timeService *fakeclock.FakeClock
+		monitor     Monitor
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		runner = fakesys.NewFakeCmdRunner()
+		timeService = fakeclock.NewFakeClock(time.Date(2016, time.March, 1, 10, 0, 0, 0, time.UTC))
+
+		monitor = NewMonitor(fs, runner, timeService, boshlog.NewLogger(boshlog.LevelNone))
+
+		err := fs.WriteFileString("/var/vcap/jobs/redis/probes.json", `{"probes": [
+			{"name": "ready", "process": "redis", "kind": "readiness", "exec": {"command": ["ready"]}},
+			{"name": "live", "process": "redis", "kind": "liveness", "exec": {"command": ["live"]}, "failure_threshold": 2}
+		]}`)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// results queues the exit statuses of the next runs of a probe command
+	results := func(command string, exitStatuses ...int) {
+		for _, exitStatus := range exitStatuses {
+			runner.AddProcess(command, &fakesys.FakeProcess{WaitResult: boshsys.Result{ExitStatus: exitStatus}})
+		}
+	}
+
+	It("reports processes without probes as ready and live", func() {
+		Expect(monitor.Status()).To(Equal(Status{Ready: true, Live: true}))
+		Expect(monitor.ProcessStatus("redis")).To(Equal(Status{Ready: true, Live: true}))
+		Expect(monitor.WaitUntilReady(0)).To(Succeed())
+	})
+
+	It("reports processes with readiness probes as not ready until a probe passed", func() {
+		Expect(monitor.AddJob("/var/vcap/jobs/redis")).To(Succeed())
+
+		Expect(monitor.Status()).To(Equal(Status{Ready: false, Live: true}))
+		Expect(monitor.ProcessStatus("redis")).To(Equal(Status{Ready: false, Live: true}))
+		Expect(monitor.ProcessStatus("nginx")).To(Equal(Status{Ready: true, Live: true}))
+	})
+
+	It("keeps probes when a job is added again and removes them with all jobs", func() {
+		Expect(monitor.AddJob("/var/vcap/jobs/redis")).To(Succeed())
+
+		results("ready", 0)
+		Expect(monitor.WaitUntilReady(0)).To(Succeed())
+
+		Expect(monitor.AddJob("/var/vcap/jobs/redis")).To(Succeed())
+		Expect(monitor.Status().Ready).To(BeTrue())
+
+		monitor.RemoveAllJobs()
+		Expect(monitor.AddJob("/var/vcap/jobs/redis")).To(Succeed())
+		Expect(monitor.Status().Ready).To(BeFalse())
+	})
+
+	It("returns an error when the probes of a job are invalid", func() {
+		Expect(fs.WriteFileString("/var/vcap/jobs/nginx/probes.json", `{"probes": [{}]}`)).To(Succeed())
+
+		err := monitor.AddJob("/var/vcap/jobs/nginx")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Missing name"))
+	})
+
+	Describe("WaitUntilReady", func() {
+		BeforeEach(func() {
+			Expect(monitor.AddJob("/var/vcap/jobs/redis")).To(Succeed())
+		})
+
+		It("checks readiness probes until they pass", func() {
+			results("ready", 1, 0)
+
+			errCh := make(chan error)
+			go func() { errCh <- monitor.WaitUntilReady(time.Minute) }()
+
+			timeService.WaitForWatcherAndIncrement(time.Second)
+
+			Eventually(errCh).Should(Receive(BeNil()))
+			Expect(monitor.Status()).To(Equal(Status{Ready: true, Live: true}))
+		})
+
+		It("returns an error naming the failing probes after the timeout", func() {
+			results("ready", 1)
+
+			err := monitor.WaitUntilReady(0)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Timed out waiting for readiness probes [ready]"))
+		})
+	})
+
+	Describe("Run", func() {
+		var (
+			transitionsLock sync.Mutex
+			transitions     []Transition
+		)
+
+		reportedTransitions := func() []Transition {
+			transitionsLock.Lock()
+			defer transitionsLock.Unlock()
+
+			return append([]Transition{}, transitions...)
+		}
+
+		BeforeEach(func() {
+			transitions = nil
+			Expect(monitor.AddJob("/var/vcap/jobs/redis")).To(Succeed())
+		})
+
+		AfterEach(func() {
+			monitor.Stop()
+		})
+
+		run := func() {
+			go monitor.Run(func(transition Transition) {
+				transitionsLock.Lock()
+				defer transitionsLock.Unlock()
+
+				transitions = append(transitions, transition)
+			})
+		}
+
+		It("checks probes every period and reports liveness probes reaching the failure threshold", func() {
+			results("ready", 0, 0, 0)
+			results("live", 1, 1, 0)
+
+			run()
+
+			Eventually(monitor.Status).Should(Equal(Status{Ready: true, Live: true}))
+			Expect(reportedTransitions()).To(BeEmpty())
+
+			timeService.WaitForWatcherAndIncrement(DefaultPeriodSeconds * time.Second)
+
+			Eventually(reportedTransitions).Should(HaveLen(1))
+			Expect(monitor.ProcessStatus("redis")).To(Equal(Status{Ready: true, Live: false}))
+
+			failed := reportedTransitions()[0]
+			Expect(failed.Probe.Name).To(Equal("live"))
+			Expect(failed.Passing).To(BeFalse())
+			Expect(failed.Err.Error()).To(Equal("Running live: exit status 1"))
+			Expect(failed.At).To(Equal(timeService.Now()))
+
+			timeService.WaitForWatcherAndIncrement(DefaultPeriodSeconds * time.Second)
+
+			Eventually(reportedTransitions).Should(HaveLen(2))
+			Expect(reportedTransitions()[1].Passing).To(BeTrue())
+			Expect(reportedTransitions()[1].Err).ToNot(HaveOccurred())
+			Expect(monitor.Status()).To(Equal(Status{Ready: true, Live: true}))
+		})
+
+		It("does not check probes before their period elapsed", func() {
+			results("ready", 0)
+			results("live", 0)
+
+			run()
+
+			Eventually(monitor.Status).Should(Equal(Status{Ready: true, Live: true}))
+
+			timeService.WaitForWatcherAndIncrement(time.Second)
+			timeService.WaitForWatcherAndIncrement(time.Second)
+
+			Expect(runner.RunComplexCommands).To(HaveLen(2))
+		})
+	})
+})
@@ -1,3 +1,4 @@
+//go:build !windows
 // +build !windows
 
 package jobsupervisor
@@ -8,15 +9,17 @@ import (
 	"github.com/pivotal-golang/clock"
 
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
 	boshmonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
+	boshmonitalert "github.com/cloudfoundry/bosh-agent/jobsupervisor/monitalert"
+	boshprobe "github.com/cloudfoundry/bosh-agent/jobsupervisor/probe"
+	boshsystemd "github.com/cloudfoundry/bosh-agent/jobsupervisor/systemd"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
-const jobSupervisorListenPort = 2825
-
 type Provider struct {
 	supervisors map[string]JobSupervisor
 }
@@ -35,9 +38,10 @@ func NewProvider(
 		fs,
 		runner,
 		client,
+		platform,
 		logger,
 		dirProvider,
-		jobSupervisorListenPort,
+		boshmonitalert.DefaultServerOptions(),
 		MonitReloadOptions{
 			MaxTries:               3,
 			MaxCheckTries:          6,
@@ -46,8 +50,34 @@ func NewProvider(
 		timeService,
 	)
 
+	nativeJobSupervisor := NewNativeJobSupervisor(
+		fs,
+		runner,
+		logger,
+		dirProvider,
+		timeService,
+		DefaultNativeSupervisorOptions(),
+	)
+
+	systemdJobSupervisor := NewSystemdJobSupervisor(
+		fs,
+		boshsystemd.NewSystemBusManager(logger),
+		logger,
+		dirProvider,
+		timeService,
+		DefaultSystemdSupervisorOptions(),
+	)
+
+	newProbeMonitor := func() boshprobe.Monitor {
+		return boshprobe.NewMonitor(fs, runner, timeService, logger)
+	}
+
+	cgroups := boshcgroup.NewManager(fs, boshcgroup.DefaultRoot, logger)
+
 	p.supervisors = map[string]JobSupervisor{
-		"monit":      NewWrapperJobSupervisor(monitJobSupervisor, fs, dirProvider, logger),
+		"monit":      NewWrapperJobSupervisor(monitJobSupervisor, fs, dirProvider, newProbeMonitor(), cgroups, timeService, logger),
+		"native":     NewWrapperJobSupervisor(nativeJobSupervisor, fs, dirProvider, newProbeMonitor(), cgroups, timeService, logger),
+		"systemd":    NewWrapperJobSupervisor(systemdJobSupervisor, fs, dirProvider, newProbeMonitor(), cgroups, timeService, logger),
 		"dummy":      NewDummyJobSupervisor(),
 		"dummy-nats": NewDummyNatsJobSupervisor(handler),
 	}
@@ -7,7 +7,10 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
 	fakemonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit/fakes"
+	boshmonitalert "github.com/cloudfoundry/bosh-agent/jobsupervisor/monitalert"
+	boshprobe "github.com/cloudfoundry/bosh-agent/jobsupervisor/probe"
 	fakembus "github.com/cloudfoundry/bosh-agent/mbus/fakes"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -19,15 +22,14 @@ import (
 func init() {
 	Describe("provider", func() {
 		var (
-			platform              *fakeplatform.FakePlatform
-			client                *fakemonit.FakeMonitClient
-			logger                boshlog.Logger
-			dirProvider           boshdir.Provider
-			jobFailuresServerPort int
-			handler               *fakembus.FakeHandler
-			provider              Provider
-			timeService           clock.Clock
-			jobSupervisorName     string
+			platform          *fakeplatform.FakePlatform
+			client            *fakemonit.FakeMonitClient
+			logger            boshlog.Logger
+			dirProvider       boshdir.Provider
+			handler           *fakembus.FakeHandler
+			provider          Provider
+			timeService       clock.Clock
+			jobSupervisorName string
 		)
 
 		BeforeEach(func() {
@@ -35,7 +37,6 @@ func init() {
 			client = fakemonit.NewFakeMonitClient()
 			logger = boshlog.NewLogger(boshlog.LevelNone)
 			dirProvider = boshdir.NewProvider("/fake-base-dir")
-			jobFailuresServerPort = 2825
 			handler = &fakembus.FakeHandler{}
 			timeService = clock.NewClock()
 
@@ -62,9 +63,10 @@ func init() {
 					platform.Fs,
 					platform.Runner,
 					client,
+					platform,
 					logger,
 					dirProvider,
-					jobFailuresServerPort,
+					boshmonitalert.DefaultServerOptions(),
 					MonitReloadOptions{
 						MaxTries:               3,
 						MaxCheckTries:          6,
@@ -77,6 +79,9 @@ func init() {
 					delegateSupervisor,
 					platform.Fs,
 					dirProvider,
+					boshprobe.NewMonitor(platform.Fs, platform.Runner, timeService, logger),
+					boshcgroup.NewManager(platform.Fs, boshcgroup.DefaultRoot, logger),
+					timeService,
 					logger,
 				)
 
@@ -84,6 +89,26 @@ func init() {
 			}
 		})
 
+		It("provides a native job supervisor on linux", func() {
+			if runtime.GOOS == "windows" {
+				Skip("native job supervisor is not available on windows")
+			}
+
+			actualSupervisor, err := provider.Get("native")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actualSupervisor).ToNot(BeNil())
+		})
+
+		It("provides a systemd job supervisor on linux", func() {
+			if runtime.GOOS == "windows" {
+				Skip("systemd job supervisor is not available on windows")
+			}
+
+			actualSupervisor, err := provider.Get("systemd")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actualSupervisor).ToNot(BeNil())
+		})
+
 		It("provides a dummy job supervisor", func() {
 			actualSupervisor, err := provider.Get("dummy")
 			Expect(err).ToNot(HaveOccurred())
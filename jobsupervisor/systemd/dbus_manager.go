@@ -0,0 +1,235 @@
+package systemd
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	dbusManagerLogTag = "systemdDBusManager"
+
+	systemdDestination = "org.freedesktop.systemd1"
+	systemdPath        = dbus.ObjectPath("/org/freedesktop/systemd1")
+
+	managerInterface    = "org.freedesktop.systemd1.Manager"
+	unitInterface       = "org.freedesktop.systemd1.Unit"
+	serviceInterface    = "org.freedesktop.systemd1.Service"
+	propertiesInterface = "org.freedesktop.DBus.Properties"
+
+	// Replaces conflicting queued jobs, e.g. a pending stop when starting
+	jobModeReplace = "replace"
+)
+
+// Conn is satisfied by *dbus.Conn
+type Conn interface {
+	Object(dest string, path dbus.ObjectPath) dbus.BusObject
+}
+
+// connectedConn is implemented by connections that know when they are
+// closed, like *dbus.Conn
+type connectedConn interface {
+	Connected() bool
+}
+
+type dbusManager struct {
+	connect func() (Conn, error)
+	logger  boshlog.Logger
+
+	lock sync.Mutex
+	conn Conn
+}
+
+// NewSystemBusManager talks to systemd on the system bus. The bus is only
+// connected on first use so that agents on hosts without systemd can still
+// construct it.
+func NewSystemBusManager(logger boshlog.Logger) Manager {
+	return &dbusManager{
+		connect: func() (Conn, error) { return dbus.SystemBus() },
+		logger:  logger,
+	}
+}
+
+// NewDBusManager calls connect whenever it needs a new connection,
+// initially and after the connection was closed
+func NewDBusManager(connect func() (Conn, error), logger boshlog.Logger) Manager {
+	return &dbusManager{
+		connect: connect,
+		logger:  logger,
+	}
+}
+
+func (m *dbusManager) Reload() error {
+	manager, err := m.manager()
+	if err != nil {
+		return err
+	}
+
+	m.logger.Debug(dbusManagerLogTag, "Reloading unit files")
+
+	err = m.checkConn(manager.Call(managerInterface+".Reload", 0).Err)
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading systemd")
+	}
+
+	return nil
+}
+
+func (m *dbusManager) StartUnit(name string) error {
+	return m.enqueueJob("StartUnit", name)
+}
+
+func (m *dbusManager) StopUnit(name string) error {
+	return m.enqueueJob("StopUnit", name)
+}
+
+func (m *dbusManager) RestartUnit(name string) error {
+	return m.enqueueJob("RestartUnit", name)
+}
+
+func (m *dbusManager) ResetFailedUnit(name string) error {
+	manager, err := m.manager()
+	if err != nil {
+		return err
+	}
+
+	err = m.checkConn(manager.Call(managerInterface+".ResetFailedUnit", 0, name).Err)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Resetting failed unit %s", name)
+	}
+
+	return nil
+}
+
+func (m *dbusManager) UnitStatus(name string) (UnitStatus, error) {
+	status := UnitStatus{Name: name}
+
+	conn, err := m.bus()
+	if err != nil {
+		return status, err
+	}
+
+	manager := conn.Object(systemdDestination, systemdPath)
+
+	// LoadUnit unlike GetUnit also succeeds for units that are not loaded yet
+	var unitPath dbus.ObjectPath
+
+	err = m.checkConn(manager.Call(managerInterface+".LoadUnit", 0, name).Store(&unitPath))
+	if err != nil {
+		return status, bosherr.WrapErrorf(err, "Loading unit %s", name)
+	}
+
+	unit := conn.Object(systemdDestination, unitPath)
+
+	unitProps, err := m.properties(unit, unitInterface)
+	if err != nil {
+		return status, bosherr.WrapErrorf(err, "Getting properties of unit %s", name)
+	}
+
+	serviceProps, err := m.properties(unit, serviceInterface)
+	if err != nil {
+		return status, bosherr.WrapErrorf(err, "Getting service properties of unit %s", name)
+	}
+
+	status.ActiveState, _ = unitProps["ActiveState"].Value().(string)
+	status.SubState, _ = unitProps["SubState"].Value().(string)
+	status.Result, _ = serviceProps["Result"].Value().(string)
+
+	if pid, ok := serviceProps["MainPID"].Value().(uint32); ok {
+		status.MainPID = int(pid)
+	}
+
+	if restarts, ok := serviceProps["NRestarts"].Value().(uint32); ok {
+		status.NRestarts = int(restarts)
+	}
+
+	// systemd reports 2^64-1 for values it does not track
+	if memory, ok := serviceProps["MemoryCurrent"].Value().(uint64); ok && memory != math.MaxUint64 {
+		status.MemoryCurrent = memory
+	}
+
+	if cpu, ok := serviceProps["CPUUsageNSec"].Value().(uint64); ok && cpu != math.MaxUint64 {
+		status.CPUUsage = time.Duration(cpu)
+	}
+
+	if usec, ok := unitProps["ActiveEnterTimestamp"].Value().(uint64); ok && usec > 0 {
+		status.ActiveEnterTimestamp = time.Unix(0, int64(usec)*int64(time.Microsecond))
+	}
+
+	return status, nil
+}
+
+func (m *dbusManager) enqueueJob(method, name string) error {
+	manager, err := m.manager()
+	if err != nil {
+		return err
+	}
+
+	m.logger.Debug(dbusManagerLogTag, "Calling %s for unit %s", method, name)
+
+	var jobPath dbus.ObjectPath
+
+	err = m.checkConn(manager.Call(managerInterface+"."+method, 0, name, jobModeReplace).Store(&jobPath))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Calling %s for unit %s", method, name)
+	}
+
+	return nil
+}
+
+func (m *dbusManager) properties(object dbus.BusObject, iface string) (map[string]dbus.Variant, error) {
+	props := map[string]dbus.Variant{}
+
+	err := object.Call(propertiesInterface+".GetAll", 0, iface).Store(&props)
+	if err != nil {
+		return nil, err
+	}
+
+	return props, nil
+}
+
+func (m *dbusManager) manager() (dbus.BusObject, error) {
+	conn, err := m.bus()
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.Object(systemdDestination, systemdPath), nil
+}
+
+func (m *dbusManager) bus() (Conn, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if conn, ok := m.conn.(connectedConn); ok && !conn.Connected() {
+		m.conn = nil
+	}
+
+	if m.conn == nil {
+		conn, err := m.connect()
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Connecting to systemd")
+		}
+
+		m.conn = conn
+	}
+
+	return m.conn, nil
+}
+
+// checkConn forgets the connection when err says that it was closed
+// so that the next call reconnects
+func (m *dbusManager) checkConn(err error) error {
+	if err == dbus.ErrClosed {
+		m.lock.Lock()
+		m.conn = nil
+		m.lock.Unlock()
+	}
+
+	return err
+}
@@ -0,0 +1,378 @@
+package systemd_test
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/systemd"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const privateBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// fakeSystemd implements the parts of the org.freedesktop.systemd1.Manager
+// interface used by the agent
+type fakeSystemd struct {
+	conn *dbus.Conn
+
+	lock  sync.Mutex
+	calls []string
+	units map[string]*fakeUnit
+}
+
+func (f *fakeSystemd) Reload() *dbus.Error {
+	f.record("Reload")
+	return nil
+}
+
+func (f *fakeSystemd) StartUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	f.record("StartUnit " + name + " " + mode)
+
+	unit, err := f.unit(name)
+	if err != nil {
+		return "", err
+	}
+
+	unit.set("ActiveState", ActiveStateActive)
+
+	return "/org/freedesktop/systemd1/job/1", nil
+}
+
+func (f *fakeSystemd) StopUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	f.record("StopUnit " + name + " " + mode)
+
+	unit, err := f.unit(name)
+	if err != nil {
+		return "", err
+	}
+
+	unit.set("ActiveState", ActiveStateInactive)
+
+	return "/org/freedesktop/systemd1/job/2", nil
+}
+
+func (f *fakeSystemd) RestartUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	f.record("RestartUnit " + name + " " + mode)
+
+	unit, err := f.unit(name)
+	if err != nil {
+		return "", err
+	}
+
+	unit.set("ActiveState", ActiveStateActive)
+
+	return "/org/freedesktop/systemd1/job/3", nil
+}
+
+func (f *fakeSystemd) ResetFailedUnit(name string) *dbus.Error {
+	f.record("ResetFailedUnit " + name)
+	return nil
+}
+
+func (f *fakeSystemd) LoadUnit(name string) (dbus.ObjectPath, *dbus.Error) {
+	unit, err := f.unit(name)
+	if err != nil {
+		return "", err
+	}
+
+	return unit.path, nil
+}
+
+func (f *fakeSystemd) record(call string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.calls = append(f.calls, call)
+}
+
+func (f *fakeSystemd) recordedCalls() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return append([]string{}, f.calls...)
+}
+
+func (f *fakeSystemd) unit(name string) (*fakeUnit, *dbus.Error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if strings.HasPrefix(name, "missing") {
+		return nil, dbus.NewError("org.freedesktop.systemd1.NoSuchUnit", []interface{}{"Unit " + name + " not found."})
+	}
+
+	unit, found := f.units[name]
+	if found {
+		return unit, nil
+	}
+
+	unit = &fakeUnit{
+		path: dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/systemd1/unit/u%d", len(f.units))),
+		props: map[string]interface{}{
+			"ActiveState":          ActiveStateInactive,
+			"SubState":             "dead",
+			"ActiveEnterTimestamp": uint64(0),
+			"Result":               "success",
+			"MainPID":              uint32(0),
+			"NRestarts":            uint32(0),
+			"MemoryCurrent":        uint64(math.MaxUint64),
+			"CPUUsageNSec":         uint64(math.MaxUint64),
+		},
+	}
+
+	err := f.conn.Export(unit, unit.path, "org.freedesktop.DBus.Properties")
+	if err != nil {
+		return nil, dbus.MakeFailedError(err)
+	}
+
+	f.units[name] = unit
+
+	return unit, nil
+}
+
+// fakeUnit serves the properties of a unit; systemd splits them into
+// the Unit and Service interfaces but the agent only reads them
+type fakeUnit struct {
+	path dbus.ObjectPath
+
+	lock  sync.Mutex
+	props map[string]interface{}
+}
+
+func (u *fakeUnit) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	unitProps := map[string]bool{"ActiveState": true, "SubState": true, "ActiveEnterTimestamp": true}
+
+	props := map[string]dbus.Variant{}
+
+	for name, value := range u.props {
+		if unitProps[name] == (iface == "org.freedesktop.systemd1.Unit") {
+			props[name] = dbus.MakeVariant(value)
+		}
+	}
+
+	return props, nil
+}
+
+func (u *fakeUnit) set(name string, value interface{}) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.props[name] = value
+}
+
+var _ = Describe("dbusManager", func() {
+	var (
+		tmpDir    string
+		busDaemon *exec.Cmd
+		conns     []*dbus.Conn
+		systemd   *fakeSystemd
+		address   string
+		connects  int
+		manager   Manager
+	)
+
+	connect := func(address string) *dbus.Conn {
+		var conn *dbus.Conn
+
+		Eventually(func() error {
+			var err error
+			conn, err = dbus.Connect(address)
+			return err
+		}).Should(Succeed())
+
+		conns = append(conns, conn)
+
+		return conn
+	}
+
+	BeforeEach(func() {
+		daemonPath, err := exec.LookPath("dbus-daemon")
+		if err != nil {
+			Skip("dbus-daemon is required to run a private bus")
+		}
+
+		tmpDir, err = ioutil.TempDir("", "dbus-manager")
+		Expect(err).ToNot(HaveOccurred())
+
+		configPath := filepath.Join(tmpDir, "bus.conf")
+		config := fmt.Sprintf(privateBusConfig, filepath.Join(tmpDir, "bus.sock"))
+		Expect(ioutil.WriteFile(configPath, []byte(config), 0600)).To(Succeed())
+
+		busDaemon = exec.Command(daemonPath, "--config-file="+configPath, "--nofork", "--print-address")
+		stdout, err := busDaemon.StdoutPipe()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(busDaemon.Start()).To(Succeed())
+
+		address, err = bufio.NewReader(stdout).ReadString('\n')
+		Expect(err).ToNot(HaveOccurred())
+		address = strings.TrimSpace(address)
+
+		systemd = &fakeSystemd{conn: connect(address), units: map[string]*fakeUnit{}}
+
+		err = systemd.conn.Export(systemd, "/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager")
+		Expect(err).ToNot(HaveOccurred())
+
+		reply, err := systemd.conn.RequestName("org.freedesktop.systemd1", dbus.NameFlagDoNotQueue)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reply).To(Equal(dbus.RequestNameReplyPrimaryOwner))
+
+		connects = 0
+		manager = NewDBusManager(func() (Conn, error) {
+			connects++
+			return connect(address), nil
+		}, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	AfterEach(func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+		conns = nil
+
+		if busDaemon != nil {
+			_ = busDaemon.Process.Kill()
+			_ = busDaemon.Wait()
+			busDaemon = nil
+		}
+
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	Describe("Reload", func() {
+		It("reloads unit files", func() {
+			Expect(manager.Reload()).To(Succeed())
+			Expect(systemd.recordedCalls()).To(Equal([]string{"Reload"}))
+		})
+	})
+
+	It("reuses the connection", func() {
+		Expect(manager.Reload()).To(Succeed())
+		Expect(manager.Reload()).To(Succeed())
+		Expect(connects).To(Equal(1))
+	})
+
+	It("reconnects after the connection was closed", func() {
+		Expect(manager.Reload()).To(Succeed())
+		Expect(conns[len(conns)-1].Close()).To(Succeed())
+
+		Expect(manager.Reload()).To(Succeed())
+		Expect(connects).To(Equal(2))
+		Expect(systemd.recordedCalls()).To(Equal([]string{"Reload", "Reload"}))
+	})
+
+	Describe("StartUnit", func() {
+		It("enqueues a start job replacing conflicting jobs", func() {
+			Expect(manager.StartUnit("bosh-redis.service")).To(Succeed())
+			Expect(systemd.recordedCalls()).To(Equal([]string{"StartUnit bosh-redis.service replace"}))
+		})
+
+		It("returns errors from systemd", func() {
+			err := manager.StartUnit("missing.service")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Calling StartUnit for unit missing.service"))
+			Expect(err.Error()).To(ContainSubstring("Unit missing.service not found."))
+		})
+	})
+
+	Describe("StopUnit", func() {
+		It("enqueues a stop job replacing conflicting jobs", func() {
+			Expect(manager.StopUnit("bosh-redis.service")).To(Succeed())
+			Expect(systemd.recordedCalls()).To(Equal([]string{"StopUnit bosh-redis.service replace"}))
+		})
+	})
+
+	Describe("RestartUnit", func() {
+		It("enqueues a restart job replacing conflicting jobs", func() {
+			Expect(manager.RestartUnit("bosh-redis.service")).To(Succeed())
+			Expect(systemd.recordedCalls()).To(Equal([]string{"RestartUnit bosh-redis.service replace"}))
+		})
+	})
+
+	Describe("ResetFailedUnit", func() {
+		It("resets the unit", func() {
+			Expect(manager.ResetFailedUnit("bosh-redis.service")).To(Succeed())
+			Expect(systemd.recordedCalls()).To(Equal([]string{"ResetFailedUnit bosh-redis.service"}))
+		})
+	})
+
+	Describe("UnitStatus", func() {
+		It("returns unit and service properties", func() {
+			unit, dbusErr := systemd.unit("bosh-redis.service")
+			Expect(dbusErr).To(BeNil())
+
+			activeEnter := time.Date(2016, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+			unit.set("ActiveState", ActiveStateActive)
+			unit.set("SubState", "running")
+			unit.set("ActiveEnterTimestamp", uint64(activeEnter.UnixNano()/int64(time.Microsecond)))
+			unit.set("MainPID", uint32(1234))
+			unit.set("NRestarts", uint32(2))
+			unit.set("MemoryCurrent", uint64(4096))
+			unit.set("CPUUsageNSec", uint64(1500000000))
+
+			status, err := manager.UnitStatus("bosh-redis.service")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(status.Name).To(Equal("bosh-redis.service"))
+			Expect(status.ActiveState).To(Equal(ActiveStateActive))
+			Expect(status.SubState).To(Equal("running"))
+			Expect(status.Result).To(Equal("success"))
+			Expect(status.MainPID).To(Equal(1234))
+			Expect(status.NRestarts).To(Equal(2))
+			Expect(status.MemoryCurrent).To(Equal(uint64(4096)))
+			Expect(status.CPUUsage).To(Equal(1500 * time.Millisecond))
+			Expect(status.ActiveEnterTimestamp.Equal(activeEnter)).To(BeTrue())
+		})
+
+		It("ignores values systemd does not track", func() {
+			status, err := manager.UnitStatus("bosh-redis.service")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(status.ActiveState).To(Equal(ActiveStateInactive))
+			Expect(status.MemoryCurrent).To(BeZero())
+			Expect(status.CPUUsage).To(BeZero())
+			Expect(status.ActiveEnterTimestamp.IsZero()).To(BeTrue())
+		})
+
+		It("reflects jobs run for the unit", func() {
+			Expect(manager.StartUnit("bosh-redis.service")).To(Succeed())
+
+			status, err := manager.UnitStatus("bosh-redis.service")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.ActiveState).To(Equal(ActiveStateActive))
+		})
+
+		It("returns an error when the unit cannot be loaded", func() {
+			_, err := manager.UnitStatus("missing.service")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Loading unit missing.service"))
+		})
+	})
+})
@@ -0,0 +1,153 @@
+package fakes
+
+import (
+	"sync"
+
+	boshsystemd "github.com/cloudfoundry/bosh-agent/jobsupervisor/systemd"
+)
+
+// FakeManager keeps unit states in memory; started units become active
+// and stopped units inactive immediately
+type FakeManager struct {
+	lock sync.Mutex
+
+	ReloadCallCount int
+	ReloadErr       error
+
+	StartUnitNames []string
+	StartUnitErr   error
+
+	StopUnitNames []string
+	StopUnitErr   error
+
+	RestartUnitNames []string
+	RestartUnitErr   error
+
+	ResetFailedUnitNames []string
+
+	Units         map[string]boshsystemd.UnitStatus
+	UnitStatusErr error
+}
+
+func NewFakeManager() *FakeManager {
+	return &FakeManager{Units: map[string]boshsystemd.UnitStatus{}}
+}
+
+func (m *FakeManager) Reload() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.ReloadCallCount++
+	return m.ReloadErr
+}
+
+func (m *FakeManager) StartUnit(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.StartUnitNames = append(m.StartUnitNames, name)
+	if m.StartUnitErr != nil {
+		return m.StartUnitErr
+	}
+
+	status := m.Units[name]
+	status.Name = name
+	status.ActiveState = boshsystemd.ActiveStateActive
+	status.SubState = "running"
+	m.Units[name] = status
+
+	return nil
+}
+
+func (m *FakeManager) StopUnit(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.StopUnitNames = append(m.StopUnitNames, name)
+	if m.StopUnitErr != nil {
+		return m.StopUnitErr
+	}
+
+	status := m.Units[name]
+	status.Name = name
+	status.ActiveState = boshsystemd.ActiveStateInactive
+	status.SubState = "dead"
+	status.MainPID = 0
+	m.Units[name] = status
+
+	return nil
+}
+
+func (m *FakeManager) RestartUnit(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.RestartUnitNames = append(m.RestartUnitNames, name)
+	if m.RestartUnitErr != nil {
+		return m.RestartUnitErr
+	}
+
+	status := m.Units[name]
+	status.Name = name
+	status.ActiveState = boshsystemd.ActiveStateActive
+	status.SubState = "running"
+	m.Units[name] = status
+
+	return nil
+}
+
+func (m *FakeManager) ResetFailedUnit(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.ResetFailedUnitNames = append(m.ResetFailedUnitNames, name)
+
+	status := m.Units[name]
+	if status.ActiveState == boshsystemd.ActiveStateFailed {
+		status.ActiveState = boshsystemd.ActiveStateInactive
+		m.Units[name] = status
+	}
+
+	return nil
+}
+
+func (m *FakeManager) UnitStatus(name string) (boshsystemd.UnitStatus, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	status, found := m.Units[name]
+	if !found {
+		status = boshsystemd.UnitStatus{Name: name, ActiveState: boshsystemd.ActiveStateInactive, SubState: "dead"}
+	}
+
+	return status, m.UnitStatusErr
+}
+
+// SetUnit replaces the state of a unit, e.g. to simulate systemd restarting it
+func (m *FakeManager) SetUnit(status boshsystemd.UnitStatus) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.Units[status.Name] = status
+}
+
+func (m *FakeManager) StartedUnits() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]string{}, m.StartUnitNames...)
+}
+
+func (m *FakeManager) StoppedUnits() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]string{}, m.StopUnitNames...)
+}
+
+func (m *FakeManager) ReloadCount() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.ReloadCallCount
+}
@@ -0,0 +1,47 @@
+package systemd
+
+import (
+	"time"
+)
+
+const (
+	ActiveStateActive       = "active"
+	ActiveStateReloading    = "reloading"
+	ActiveStateInactive     = "inactive"
+	ActiveStateFailed       = "failed"
+	ActiveStateActivating   = "activating"
+	ActiveStateDeactivating = "deactivating"
+)
+
+type UnitStatus struct {
+	Name string
+
+	ActiveState string
+	SubState    string
+
+	// Result of the last run, e.g. success, exit-code, signal, oom-kill
+	Result string
+
+	MainPID   int
+	NRestarts int
+
+	// MemoryCurrent is zero when memory accounting is not enabled
+	MemoryCurrent uint64
+	CPUUsage      time.Duration
+
+	// ActiveEnterTimestamp is zero if the unit never became active
+	ActiveEnterTimestamp time.Time
+}
+
+type Manager interface {
+	// Reload makes systemd pick up changed unit files (daemon-reload)
+	Reload() error
+
+	// StartUnit, StopUnit and RestartUnit enqueue a job and return without waiting for it
+	StartUnit(name string) error
+	StopUnit(name string) error
+	RestartUnit(name string) error
+	ResetFailedUnit(name string) error
+
+	UnitStatus(name string) (UnitStatus, error)
+}
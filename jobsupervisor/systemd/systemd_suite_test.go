@@ -0,0 +1,13 @@
+package systemd_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSystemd(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Systemd Suite")
+}
@@ -0,0 +1,155 @@
+package systemd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-agent/jobsupervisor/monitrc"
+)
+
+const (
+	// SliceName groups all generated units so that they can be accounted
+	// for and limited together
+	SliceName = "bosh.slice"
+
+	unitPrefix = "bosh-"
+	unitSuffix = ".service"
+
+	// Monit runs programs with a minimal PATH as well
+	programPath = "/bin:/usr/bin:/sbin:/usr/sbin"
+)
+
+// UnitName returns the name of the service unit generated for a monit process
+func UnitName(processName string) string {
+	return unitPrefix + escapeUnitName(processName) + unitSuffix
+}
+
+// IsGeneratedUnit reports whether name was returned by UnitName
+func IsGeneratedUnit(name string) bool {
+	return strings.HasPrefix(name, unitPrefix) && strings.HasSuffix(name, unitSuffix)
+}
+
+func SliceUnit() string {
+	return "# Generated by bosh-agent\n" +
+		"[Unit]\n" +
+		"Description=Processes of BOSH jobs\n" +
+		"Before=slices.target\n"
+}
+
+// ServiceUnit translates a monit process into a service unit. systemd takes
+// over restarting the process when it exits; memory limits that monit would
+// restart the process for become MemoryMax so that the kernel enforces them.
+// Other resource limits and alert-only conditions have no equivalent and
+// are dropped.
+func ServiceUnit(process monitrc.Process) string {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "# Generated by bosh-agent from monit process '%s'\n", process.Name)
+	fmt.Fprintf(buf, "[Unit]\n")
+	fmt.Fprintf(buf, "Description=BOSH process %s\n", process.Name)
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "[Service]\n")
+	fmt.Fprintf(buf, "Slice=%s\n", SliceName)
+
+	// ctl scripts daemonize and write the pidfile monit watches
+	fmt.Fprintf(buf, "Type=forking\n")
+	fmt.Fprintf(buf, "PIDFile=%s\n", process.PidFile)
+	fmt.Fprintf(buf, "Environment=PATH=%s\n", programPath)
+
+	fmt.Fprintf(buf, "ExecStart=/bin/sh -c %s\n", quoteCommand(process.Start.Command))
+	if process.Start.Timeout > 0 {
+		fmt.Fprintf(buf, "TimeoutStartSec=%d\n", int(process.Start.Timeout.Seconds()))
+	}
+
+	if process.Stop.Command != "" {
+		fmt.Fprintf(buf, "ExecStop=/bin/sh -c %s\n", quoteCommand(process.Stop.Command))
+	}
+	if process.Stop.Timeout > 0 {
+		fmt.Fprintf(buf, "TimeoutStopSec=%d\n", int(process.Stop.Timeout.Seconds()))
+	}
+
+	fmt.Fprintf(buf, "Restart=always\n")
+	fmt.Fprintf(buf, "RestartSec=1\n")
+
+	if memoryMax := memoryMax(process.ResourceLimits); memoryMax != "" {
+		fmt.Fprintf(buf, "MemoryAccounting=yes\n")
+		fmt.Fprintf(buf, "MemoryMax=%s\n", memoryMax)
+	}
+
+	return buf.String()
+}
+
+// UnmonitoredDropIn keeps systemd from restarting a unit while
+// the agent has it unmonitored
+func UnmonitoredDropIn() string {
+	return "# Generated by bosh-agent\n" +
+		"[Service]\n" +
+		"Restart=no\n"
+}
+
+// memoryMax returns the lowest memory limit that restarts the process
+func memoryMax(limits []monitrc.ResourceLimit) string {
+	var kb, percent float64
+
+	for _, limit := range limits {
+		if limit.Action != monitrc.ActionRestart {
+			continue
+		}
+
+		if limit.Resource != monitrc.ResourceMemory && limit.Resource != monitrc.ResourceTotalMemory {
+			continue
+		}
+
+		if limit.Percent {
+			if percent == 0 || limit.Threshold < percent {
+				percent = limit.Threshold
+			}
+		} else if kb == 0 || limit.Threshold < kb {
+			kb = limit.Threshold
+		}
+	}
+
+	switch {
+	case kb > 0:
+		return fmt.Sprintf("%dK", int64(kb))
+	case percent > 0:
+		return fmt.Sprintf("%g%%", percent)
+	default:
+		return ""
+	}
+}
+
+// quoteCommand quotes a command for use as a single argument in Exec lines
+// where backslashes and quotes are escaped and % and $ would be expanded
+// by systemd before the shell sees them
+func quoteCommand(command string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		`%`, `%%`,
+		`$`, `$$`,
+	)
+	return `"` + replacer.Replace(command) + `"`
+}
+
+// escapeUnitName escapes characters not allowed in unit names the way
+// systemd-escape does; dashes are kept since names are not paths
+func escapeUnitName(name string) string {
+	buf := &bytes.Buffer{}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			buf.WriteByte(c)
+		case c == ':' || c == '_' || c == '-' || (c == '.' && i > 0):
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(buf, `\x%02x`, c)
+		}
+	}
+
+	return buf.String()
+}
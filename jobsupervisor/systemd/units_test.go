@@ -0,0 +1,84 @@
+package systemd_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-agent/jobsupervisor/monitrc"
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/systemd"
+)
+
+var _ = Describe("UnitName", func() {
+	It("prefixes process names", func() {
+		Expect(UnitName("redis-server")).To(Equal("bosh-redis-server.service"))
+		Expect(IsGeneratedUnit(UnitName("redis-server"))).To(BeTrue())
+		Expect(IsGeneratedUnit("sshd.service")).To(BeFalse())
+	})
+
+	It("escapes characters that are not allowed in unit names", func() {
+		Expect(UnitName("redis server/1")).To(Equal(`bosh-redis\x20server\x2f1.service`))
+	})
+})
+
+var _ = Describe("ServiceUnit", func() {
+	var process monitrc.Process
+
+	BeforeEach(func() {
+		process = monitrc.Process{
+			Name:    "redis",
+			PidFile: "/var/vcap/sys/run/redis/redis.pid",
+			Start: monitrc.Program{
+				Command: "/var/vcap/jobs/redis/bin/ctl start",
+				Timeout: 60 * time.Second,
+			},
+			Stop: monitrc.Program{
+				Command: "/var/vcap/jobs/redis/bin/ctl stop",
+			},
+			Groups: []string{"vcap"},
+		}
+	})
+
+	It("runs the start and stop programs of forking processes in the bosh slice", func() {
+		unit := ServiceUnit(process)
+
+		Expect(unit).To(ContainSubstring("Slice=bosh.slice\n"))
+		Expect(unit).To(ContainSubstring("Type=forking\n"))
+		Expect(unit).To(ContainSubstring("PIDFile=/var/vcap/sys/run/redis/redis.pid\n"))
+		Expect(unit).To(ContainSubstring(`ExecStart=/bin/sh -c "/var/vcap/jobs/redis/bin/ctl start"` + "\n"))
+		Expect(unit).To(ContainSubstring("TimeoutStartSec=60\n"))
+		Expect(unit).To(ContainSubstring(`ExecStop=/bin/sh -c "/var/vcap/jobs/redis/bin/ctl stop"` + "\n"))
+		Expect(unit).To(ContainSubstring("Restart=always\n"))
+		Expect(unit).ToNot(ContainSubstring("TimeoutStopSec"))
+		Expect(unit).ToNot(ContainSubstring("MemoryMax"))
+	})
+
+	It("escapes commands for systemd", func() {
+		process.Start.Command = `echo "100%" $HOME \n`
+
+		Expect(ServiceUnit(process)).To(ContainSubstring(`ExecStart=/bin/sh -c "echo \"100%%\" $$HOME \\n"` + "\n"))
+	})
+
+	It("limits memory to the lowest limit the process is restarted for", func() {
+		process.ResourceLimits = []monitrc.ResourceLimit{
+			{Resource: monitrc.ResourceTotalMemory, Threshold: 512 * 1024, Cycles: 5, Action: monitrc.ActionRestart},
+			{Resource: monitrc.ResourceMemory, Threshold: 256 * 1024, Cycles: 1, Action: monitrc.ActionAlert},
+			{Resource: monitrc.ResourceMemory, Threshold: 384 * 1024, Cycles: 1, Action: monitrc.ActionRestart},
+			{Resource: monitrc.ResourceCPU, Threshold: 90, Percent: true, Cycles: 1, Action: monitrc.ActionRestart},
+		}
+
+		unit := ServiceUnit(process)
+
+		Expect(unit).To(ContainSubstring("MemoryAccounting=yes\n"))
+		Expect(unit).To(ContainSubstring("MemoryMax=393216K\n"))
+	})
+
+	It("limits memory in percent", func() {
+		process.ResourceLimits = []monitrc.ResourceLimit{
+			{Resource: monitrc.ResourceTotalMemory, Threshold: 12.5, Percent: true, Cycles: 1, Action: monitrc.ActionRestart},
+		}
+
+		Expect(ServiceUnit(process)).To(ContainSubstring("MemoryMax=12.5%\n"))
+	})
+})
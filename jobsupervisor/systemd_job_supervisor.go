@@ -0,0 +1,637 @@
+// +build !windows
+
+package jobsupervisor
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	sigar "github.com/cloudfoundry/gosigar"
+	"github.com/pivotal-golang/clock"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshsystemd "github.com/cloudfoundry/bosh-agent/jobsupervisor/systemd"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	systemdJobSupervisorLogTag = "systemdJobSupervisor"
+
+	systemdUnmonitoredDropIn = "50-bosh-unmonitored.conf"
+
+	// systemd sets the result to oom-kill when the kernel
+	// killed the process for exceeding MemoryMax
+	systemdResultOOMKill = "oom-kill"
+)
+
+type SystemdSupervisorOptions struct {
+	// Directory generated units are written to. Units under /run do not
+	// survive a reboot and are generated again by the next reload.
+	UnitsDir string
+
+	// Time between unit checks in MonitorJobFailures
+	CheckInterval time.Duration
+
+	StopTimeout time.Duration
+}
+
+func DefaultSystemdSupervisorOptions() SystemdSupervisorOptions {
+	return SystemdSupervisorOptions{
+		UnitsDir:      "/run/systemd/system",
+		CheckInterval: 10 * time.Second,
+		StopTimeout:   5 * time.Minute,
+	}
+}
+
+type systemdUnit struct {
+	process string
+	name    string
+
+	monitored bool
+
+	// restarts is the last seen restart count of the unit; failed is set
+	// once the failure of a unit that systemd gave up on has been reported
+	restarts int
+	failed   bool
+
+	lastCPUUsage time.Duration
+	lastSample   time.Time
+	cpuPercent   float64
+}
+
+// systemdJobSupervisor runs the processes of monit control files as
+// service units in the bosh slice. systemd restarts processes that exit;
+// MonitorJobFailures polls the units over D-Bus and reports restarts and
+// units systemd gave up on as monit alerts.
+type systemdJobSupervisor struct {
+	fs          boshsys.FileSystem
+	manager     boshsystemd.Manager
+	logger      boshlog.Logger
+	dirProvider boshdir.Provider
+	timeService clock.Clock
+	options     SystemdSupervisorOptions
+
+	lock   sync.Mutex
+	units  []*systemdUnit
+	loaded bool
+	stopCh chan struct{}
+}
+
+func NewSystemdJobSupervisor(
+	fs boshsys.FileSystem,
+	manager boshsystemd.Manager,
+	logger boshlog.Logger,
+	dirProvider boshdir.Provider,
+	timeService clock.Clock,
+	options SystemdSupervisorOptions,
+) JobSupervisor {
+	return &systemdJobSupervisor{
+		fs:          fs,
+		manager:     manager,
+		logger:      logger,
+		dirProvider: dirProvider,
+		timeService: timeService,
+		options:     options,
+		stopCh:      make(chan struct{}),
+	}
+}
+
+func (s *systemdJobSupervisor) Reload() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.reload()
+}
+
+func (s *systemdJobSupervisor) Start() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.removeUnmonitoredDropIns(s.units)
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range s.units {
+		unit.monitored = true
+		unit.failed = false
+
+		// Units systemd gave up on refuse to start until reset
+		err := s.manager.ResetFailedUnit(unit.name)
+		if err != nil {
+			s.logger.Debug(systemdJobSupervisorLogTag, "Ignoring failure to reset unit %s: %s", unit.name, err.Error())
+		}
+
+		err = s.manager.StartUnit(unit.name)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Starting service %s", unit.process)
+		}
+	}
+
+	err = s.fs.RemoveAll(s.stoppedFilePath())
+	if err != nil {
+		return bosherr.WrapError(err, "Removing stopped File")
+	}
+
+	return nil
+}
+
+func (s *systemdJobSupervisor) Stop() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, unit := range s.units {
+		unit.monitored = false
+
+		err := s.manager.StopUnit(unit.name)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Stopping service %s", unit.process)
+		}
+	}
+
+	err := s.fs.WriteFileString(s.stoppedFilePath(), "")
+	if err != nil {
+		return bosherr.WrapError(err, "Creating stopped File")
+	}
+
+	return nil
+}
+
+func (s *systemdJobSupervisor) StopAndWait() ([]JobStopResult, error) {
+	err := s.Stop()
+	if err != nil {
+		return nil, err
+	}
+
+	timer := s.timeService.NewTimer(s.options.StopTimeout)
+	defer timer.Stop()
+
+	s.logger.Debug(systemdJobSupervisorLogTag, "Waiting for services to stop")
+
+	for {
+		running := []string{}
+
+		for _, unit := range s.unitNames() {
+			status, err := s.manager.UnitStatus(unit)
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Getting status of unit %s", unit)
+			}
+
+			if status.ActiveState != boshsystemd.ActiveStateInactive && status.ActiveState != boshsystemd.ActiveStateFailed {
+				running = append(running, unit)
+			}
+		}
+
+		if len(running) == 0 {
+			s.logger.Debug(systemdJobSupervisorLogTag, "Successfully stopped all services")
+			return nil, nil
+		}
+
+		select {
+		case <-timer.C():
+			return nil, bosherr.Errorf("Timed out waiting for units '%s' to stop after %s", strings.Join(running, ", "), s.options.StopTimeout)
+		default:
+		}
+
+		s.logger.Debug(systemdJobSupervisorLogTag, "Waiting for '%v' to stop", running)
+		s.timeService.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Unmonitor keeps processes running but stops systemd from restarting them
+func (s *systemdJobSupervisor) Unmonitor() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, unit := range s.units {
+		s.logger.Debug(systemdJobSupervisorLogTag, "Unmonitoring service %s", unit.process)
+
+		dropInPath := path.Join(s.dropInDir(unit.name), systemdUnmonitoredDropIn)
+
+		err := s.fs.WriteFileString(dropInPath, boshsystemd.UnmonitoredDropIn())
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing drop-in for unit %s", unit.name)
+		}
+
+		unit.monitored = false
+	}
+
+	err := s.manager.Reload()
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading units")
+	}
+
+	return nil
+}
+
+func (s *systemdJobSupervisor) StartProcess(name string) error {
+	return s.startProcess(name, "Starting", s.manager.StartUnit)
+}
+
+func (s *systemdJobSupervisor) StopProcess(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	unit, err := s.findUnit(name)
+	if err != nil {
+		return err
+	}
+
+	unit.monitored = false
+
+	err = s.manager.StopUnit(unit.name)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Stopping service %s", name)
+	}
+
+	return nil
+}
+
+func (s *systemdJobSupervisor) RestartProcess(name string) error {
+	return s.startProcess(name, "Restarting", s.manager.RestartUnit)
+}
+
+func (s *systemdJobSupervisor) Status() string {
+	if s.fs.FileExists(s.stoppedFilePath()) {
+		return "stopped"
+	}
+
+	processes, err := s.Processes()
+	if err != nil {
+		s.logger.Error(systemdJobSupervisorLogTag, "Getting processes: %s", err.Error())
+		return "unknown"
+	}
+
+	status := "running"
+
+	for _, process := range processes {
+		if process.State == processStateStarting {
+			return "starting"
+		}
+		if process.State != processStateRunning {
+			status = "failing"
+		}
+	}
+
+	return status
+}
+
+func (s *systemdJobSupervisor) Processes() ([]Process, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	processes := []Process{}
+	now := s.timeService.Now()
+
+	var memTotal uint64
+
+	mem := sigar.Mem{}
+	if mem.Get() == nil {
+		memTotal = mem.Total
+	}
+
+	for _, unit := range s.units {
+		status, err := s.manager.UnitStatus(unit.name)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Getting status of unit %s", unit.name)
+		}
+
+		process := Process{Name: unit.process}
+
+		switch {
+		case !unit.monitored && status.ActiveState != boshsystemd.ActiveStateActive:
+			process.State = processStateNotMonitored
+		case status.ActiveState == boshsystemd.ActiveStateActivating || status.ActiveState == boshsystemd.ActiveStateReloading:
+			process.State = processStateStarting
+		case status.ActiveState != boshsystemd.ActiveStateActive:
+			process.State = processStateFailing
+		default:
+			process.State = processStateRunning
+
+			if !status.ActiveEnterTimestamp.IsZero() {
+				process.Uptime.Secs = int(now.Sub(status.ActiveEnterTimestamp).Seconds())
+			}
+
+			process.Memory.Kb = int(status.MemoryCurrent / 1024)
+			if memTotal > 0 {
+				process.Memory.Percent = float64(status.MemoryCurrent) / float64(memTotal) * 100
+			}
+
+			if !unit.lastSample.IsZero() && now.After(unit.lastSample) && status.CPUUsage >= unit.lastCPUUsage {
+				unit.cpuPercent = float64(status.CPUUsage-unit.lastCPUUsage) / float64(now.Sub(unit.lastSample)) * 100
+			}
+
+			process.CPU.Total = unit.cpuPercent
+		}
+
+		unit.lastCPUUsage = status.CPUUsage
+		unit.lastSample = now
+
+		processes = append(processes, process)
+	}
+
+	return processes, nil
+}
+
+func (s *systemdJobSupervisor) StatusStaleSince() time.Time {
+	return time.Time{}
+}
+
+func (s *systemdJobSupervisor) WaitUntilReady(timeout time.Duration) error {
+	return nil
+}
+
+func (s *systemdJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+	return addMonitrcJob(s.fs, s.dirProvider.MonitJobsDir(), jobName, jobIndex, configPath)
+}
+
+// RemoveAllJobs removes job configs; their units are removed by the next reload
+func (s *systemdJobSupervisor) RemoveAllJobs() error {
+	return s.fs.RemoveAll(s.dirProvider.MonitJobsDir())
+}
+
+// MonitorJobFailures checks units and blocks forever
+func (s *systemdJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error {
+	for {
+		for _, alert := range s.checkUnits() {
+			err := handler(alert)
+			if err != nil {
+				s.logger.Error(systemdJobSupervisorLogTag, "Handling failure of service %s: %s", alert.Service, err.Error())
+			}
+		}
+
+		select {
+		case <-s.stopCh:
+			return nil
+		case <-s.timeService.NewTimer(s.options.CheckInterval).C():
+		}
+	}
+}
+
+func (s *systemdJobSupervisor) MonitorJobEvents(_ JobEventHandler) error {
+	return nil
+}
+
+func (s *systemdJobSupervisor) HealthRecorder(status string) {
+}
+
+// startProcess monitors the unit of a process again and starts it with startUnit
+func (s *systemdJobSupervisor) startProcess(name, verb string, startUnit func(string) error) error {
+	if s.fs.FileExists(s.stoppedFilePath()) {
+		return bosherr.Errorf("Cannot start process '%s' of a stopped instance", name)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	unit, err := s.findUnit(name)
+	if err != nil {
+		return err
+	}
+
+	err = s.removeUnmonitoredDropIns([]*systemdUnit{unit})
+	if err != nil {
+		return err
+	}
+
+	unit.monitored = true
+	unit.failed = false
+
+	err = s.manager.ResetFailedUnit(unit.name)
+	if err != nil {
+		s.logger.Debug(systemdJobSupervisorLogTag, "Ignoring failure to reset unit %s: %s", unit.name, err.Error())
+	}
+
+	err = startUnit(unit.name)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "%s service %s", verb, name)
+	}
+
+	return nil
+}
+
+// findUnit must be called with the lock held
+func (s *systemdJobSupervisor) findUnit(process string) (*systemdUnit, error) {
+	for _, unit := range s.units {
+		if unit.process == process {
+			return unit, nil
+		}
+	}
+
+	return nil, bosherr.Errorf("Unknown process '%s'", process)
+}
+
+func (s *systemdJobSupervisor) checkUnits() []boshalert.MonitAlert {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Generated units do not outlive a reboot; bring them
+	// back for jobs that were applied before the agent started
+	if !s.loaded {
+		err := s.reload()
+		if err != nil {
+			s.logger.Error(systemdJobSupervisorLogTag, "Loading units: %s", err.Error())
+			return nil
+		}
+	}
+
+	alerts := []boshalert.MonitAlert{}
+
+	for _, unit := range s.units {
+		status, err := s.manager.UnitStatus(unit.name)
+		if err != nil {
+			s.logger.Error(systemdJobSupervisorLogTag, "Getting status of unit %s: %s", unit.name, err.Error())
+			continue
+		}
+
+		restarted := status.NRestarts > unit.restarts
+		unit.restarts = status.NRestarts
+
+		if !unit.monitored {
+			continue
+		}
+
+		if restarted {
+			if status.Result == systemdResultOOMKill {
+				alerts = append(alerts, s.alert(unit, "Resource limit matched", "restart", "memory limit exceeded, restarted by systemd"))
+			} else {
+				alerts = append(alerts, s.alert(unit, "Does not exist", "restart", "process is not running, restarted by systemd"))
+			}
+		}
+
+		switch status.ActiveState {
+		case boshsystemd.ActiveStateFailed:
+			if !unit.failed {
+				unit.failed = true
+				alerts = append(alerts, s.alert(unit, "Execution failed", "alert", "systemd stopped restarting the process: "+status.Result))
+			}
+
+		case boshsystemd.ActiveStateInactive:
+			// Like monit, start monitored processes that are not running
+			err := s.manager.StartUnit(unit.name)
+			if err != nil {
+				alerts = append(alerts, s.alert(unit, "Execution failed", "start", err.Error()))
+			}
+
+		default:
+			unit.failed = false
+		}
+	}
+
+	return alerts
+}
+
+// reload must be called with the lock held
+func (s *systemdJobSupervisor) reload() error {
+	processes, err := loadMonitrcProcesses(s.fs, s.dirProvider.MonitJobsDir(), s.logger)
+	if err != nil {
+		return err
+	}
+
+	err = s.fs.MkdirAll(s.options.UnitsDir, 0755)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating units dir")
+	}
+
+	err = s.fs.WriteFileString(path.Join(s.options.UnitsDir, boshsystemd.SliceName), boshsystemd.SliceUnit())
+	if err != nil {
+		return bosherr.WrapError(err, "Writing slice unit")
+	}
+
+	stopped := s.fs.FileExists(s.stoppedFilePath())
+
+	existing := map[string]*systemdUnit{}
+	for _, unit := range s.units {
+		existing[unit.name] = unit
+	}
+
+	units := []*systemdUnit{}
+	names := map[string]bool{}
+	loaded := []string{}
+
+	for _, process := range processes {
+		name := boshsystemd.UnitName(process.Name)
+
+		err := s.fs.WriteFileString(path.Join(s.options.UnitsDir, name), boshsystemd.ServiceUnit(process))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing unit %s", name)
+		}
+
+		unit, found := existing[name]
+		if !found {
+			unit = &systemdUnit{process: process.Name, name: name, monitored: !stopped}
+		}
+
+		units = append(units, unit)
+		names[name] = true
+		loaded = append(loaded, name)
+	}
+
+	err = s.removeStaleUnits(names)
+	if err != nil {
+		return err
+	}
+
+	err = s.manager.Reload()
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading units")
+	}
+
+	s.units = units
+	s.loaded = true
+
+	s.logger.Debug(systemdJobSupervisorLogTag, "Reloaded units: %v", loaded)
+
+	return nil
+}
+
+// removeStaleUnits removes generated units of processes that are no longer configured;
+// like monit, their processes are not stopped
+func (s *systemdJobSupervisor) removeStaleUnits(keep map[string]bool) error {
+	paths, err := s.fs.Glob(path.Join(s.options.UnitsDir, "*.service"))
+	if err != nil {
+		return bosherr.WrapError(err, "Listing units")
+	}
+
+	for _, unitPath := range paths {
+		name := filepath.Base(unitPath)
+		if keep[name] || !boshsystemd.IsGeneratedUnit(name) {
+			continue
+		}
+
+		s.logger.Debug(systemdJobSupervisorLogTag, "Removing unit %s", name)
+
+		err := s.fs.RemoveAll(unitPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing unit %s", name)
+		}
+
+		err = s.fs.RemoveAll(s.dropInDir(name))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing drop-ins of unit %s", name)
+		}
+	}
+
+	return nil
+}
+
+// removeUnmonitoredDropIns must be called with the lock held
+func (s *systemdJobSupervisor) removeUnmonitoredDropIns(units []*systemdUnit) error {
+	removed := false
+
+	for _, unit := range units {
+		dropInPath := path.Join(s.dropInDir(unit.name), systemdUnmonitoredDropIn)
+		if !s.fs.FileExists(dropInPath) {
+			continue
+		}
+
+		err := s.fs.RemoveAll(dropInPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing drop-in of unit %s", unit.name)
+		}
+
+		removed = true
+	}
+
+	if !removed {
+		return nil
+	}
+
+	err := s.manager.Reload()
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading units")
+	}
+
+	return nil
+}
+
+func (s *systemdJobSupervisor) alert(unit *systemdUnit, event, action, description string) boshalert.MonitAlert {
+	s.logger.Info(systemdJobSupervisorLogTag, "Service %s: %s - %s: %s", unit.process, event, action, description)
+	return newMonitrcAlert(s.timeService.Now(), unit.process, event, action, description)
+}
+
+func (s *systemdJobSupervisor) unitNames() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	names := []string{}
+	for _, unit := range s.units {
+		names = append(names, unit.name)
+	}
+
+	return names
+}
+
+func (s *systemdJobSupervisor) dropInDir(unitName string) string {
+	return path.Join(s.options.UnitsDir, unitName+".d")
+}
+
+func (s *systemdJobSupervisor) stoppedFilePath() string {
+	return filepath.Join(s.dirProvider.MonitDir(), "stopped")
+}
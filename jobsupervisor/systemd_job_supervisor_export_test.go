@@ -0,0 +1,7 @@
+// +build !windows
+
+package jobsupervisor
+
+func StopSystemdMonitoring(s JobSupervisor) {
+	close(s.(*systemdJobSupervisor).stopCh)
+}
@@ -0,0 +1,412 @@
+// +build !windows
+
+package jobsupervisor_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshsystemd "github.com/cloudfoundry/bosh-agent/jobsupervisor/systemd"
+	fakesystemd "github.com/cloudfoundry/bosh-agent/jobsupervisor/systemd/fakes"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const systemdMonitConfig = `
+check process fake-process
+  with pidfile /var/vcap/sys/run/fake-job/fake.pid
+  start program "/var/vcap/jobs/fake-job/bin/ctl start"
+  stop program "/var/vcap/jobs/fake-job/bin/ctl stop"
+  group vcap
+
+check process fake-helper
+  with pidfile /var/vcap/sys/run/fake-job/helper.pid
+  start program "/var/vcap/jobs/fake-job/bin/helper start"
+  group tools
+`
+
+var _ = Describe("systemdJobSupervisor", func() {
+	const unitName = "bosh-fake-process.service"
+
+	var (
+		baseDir     string
+		unitsDir    string
+		configPath  string
+		dirProvider boshdir.Provider
+		manager     *fakesystemd.FakeManager
+		supervisor  JobSupervisor
+		monitoring  bool
+
+		alertsLock sync.Mutex
+		alerts     []boshalert.MonitAlert
+	)
+
+	receivedAlerts := func() []boshalert.MonitAlert {
+		alertsLock.Lock()
+		defer alertsLock.Unlock()
+		return append([]boshalert.MonitAlert{}, alerts...)
+	}
+
+	buildSupervisor := func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		supervisor = NewSystemdJobSupervisor(
+			boshsys.NewOsFileSystem(logger),
+			manager,
+			logger,
+			dirProvider,
+			clock.NewClock(),
+			SystemdSupervisorOptions{
+				UnitsDir:      unitsDir,
+				CheckInterval: 10 * time.Millisecond,
+				StopTimeout:   time.Second,
+			},
+		)
+	}
+
+	addJob := func() {
+		Expect(supervisor.AddJob("fake-job", 0, configPath)).To(Succeed())
+		Expect(supervisor.Reload()).To(Succeed())
+	}
+
+	monitor := func() {
+		monitoring = true
+
+		// The next test builds another supervisor while this one stops monitoring
+		monitored := supervisor
+		go func() {
+			defer GinkgoRecover()
+
+			err := monitored.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				alerts = append(alerts, alert)
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}()
+	}
+
+	BeforeEach(func() {
+		var err error
+		baseDir, err = ioutil.TempDir("", "systemd-job-supervisor")
+		Expect(err).ToNot(HaveOccurred())
+
+		unitsDir = filepath.Join(baseDir, "units")
+		Expect(os.MkdirAll(filepath.Join(baseDir, "monit"), 0700)).To(Succeed())
+
+		configPath = filepath.Join(baseDir, "fake-job.monit")
+		Expect(ioutil.WriteFile(configPath, []byte(systemdMonitConfig), 0600)).To(Succeed())
+
+		dirProvider = boshdir.NewProvider(baseDir)
+		manager = fakesystemd.NewFakeManager()
+		monitoring = false
+		alerts = nil
+
+		buildSupervisor()
+	})
+
+	AfterEach(func() {
+		if monitoring {
+			StopSystemdMonitoring(supervisor)
+		}
+		_ = os.RemoveAll(baseDir)
+	})
+
+	Describe("Reload", func() {
+		It("generates units for vcap processes and reloads systemd", func() {
+			addJob()
+
+			Expect(filepath.Join(unitsDir, "bosh.slice")).To(BeAnExistingFile())
+			Expect(filepath.Join(unitsDir, "bosh-fake-helper.service")).ToNot(BeAnExistingFile())
+
+			content, err := ioutil.ReadFile(filepath.Join(unitsDir, unitName))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("PIDFile=/var/vcap/sys/run/fake-job/fake.pid"))
+
+			Expect(manager.ReloadCount()).To(Equal(1))
+		})
+
+		It("removes units of processes that are no longer configured", func() {
+			staleUnit := filepath.Join(unitsDir, "bosh-old-process.service")
+			otherUnit := filepath.Join(unitsDir, "other.service")
+			Expect(os.MkdirAll(filepath.Join(unitsDir, "bosh-old-process.service.d"), 0700)).To(Succeed())
+			Expect(ioutil.WriteFile(staleUnit, []byte{}, 0600)).To(Succeed())
+			Expect(ioutil.WriteFile(otherUnit, []byte{}, 0600)).To(Succeed())
+
+			addJob()
+
+			Expect(staleUnit).ToNot(BeAnExistingFile())
+			Expect(staleUnit + ".d").ToNot(BeAnExistingFile())
+			Expect(otherUnit).To(BeAnExistingFile())
+		})
+
+		It("skips processes matched by pattern instead of failing for every job", func() {
+			matchingConfigPath := filepath.Join(baseDir, "matching-job.monit")
+			Expect(ioutil.WriteFile(matchingConfigPath, []byte(`
+check process matching-process
+  matching "fake-pattern"
+  start program "/fake start"
+  group vcap
+`), 0600)).To(Succeed())
+
+			Expect(supervisor.AddJob("matching-job", 1, matchingConfigPath)).To(Succeed())
+			addJob()
+
+			Expect(filepath.Join(unitsDir, unitName)).To(BeAnExistingFile())
+			Expect(filepath.Join(unitsDir, "bosh-matching-process.service")).ToNot(BeAnExistingFile())
+		})
+
+		It("returns an error when systemd fails to reload", func() {
+			manager.ReloadErr = errors.New("fake-reload-err")
+
+			Expect(supervisor.AddJob("fake-job", 0, configPath)).To(Succeed())
+
+			err := supervisor.Reload()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-reload-err"))
+		})
+	})
+
+	Describe("Start", func() {
+		It("starts units and reports them as running", func() {
+			addJob()
+
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(manager.StartedUnits()).To(Equal([]string{unitName}))
+			Expect(manager.ResetFailedUnitNames).To(Equal([]string{unitName}))
+			Expect(supervisor.Status()).To(Equal("running"))
+
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(HaveLen(1))
+			Expect(processes[0].Name).To(Equal("fake-process"))
+			Expect(processes[0].State).To(Equal("running"))
+		})
+
+		It("reports memory and uptime of running units", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+
+			manager.SetUnit(boshsystemd.UnitStatus{
+				Name:                 unitName,
+				ActiveState:          boshsystemd.ActiveStateActive,
+				MemoryCurrent:        2048 * 1024,
+				ActiveEnterTimestamp: time.Now().Add(-time.Minute),
+			})
+
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes[0].Memory.Kb).To(Equal(2048))
+			Expect(processes[0].Uptime.Secs).To(BeNumerically("~", 60, 5))
+		})
+
+		It("re-monitors unmonitored units", func() {
+			addJob()
+			Expect(supervisor.Unmonitor()).To(Succeed())
+
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(filepath.Join(unitsDir, unitName+".d", "50-bosh-unmonitored.conf")).ToNot(BeAnExistingFile())
+		})
+
+		It("returns an error when a unit fails to start", func() {
+			addJob()
+			manager.StartUnitErr = errors.New("fake-start-err")
+
+			err := supervisor.Start()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Starting service fake-process"))
+		})
+	})
+
+	Describe("StopAndWait", func() {
+		It("stops units and reports the processes as stopped", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(supervisor.StopAndWait()).To(BeEmpty())
+
+			Expect(manager.StoppedUnits()).To(Equal([]string{unitName}))
+			Expect(supervisor.Status()).To(Equal("stopped"))
+
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes[0].State).To(Equal("not monitored"))
+		})
+
+		It("returns an error when a unit fails to stop", func() {
+			addJob()
+			manager.StopUnitErr = errors.New("fake-stop-err")
+
+			_, err := supervisor.StopAndWait()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Stopping service fake-process"))
+		})
+	})
+
+	Describe("Unmonitor", func() {
+		It("keeps systemd from restarting units", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(supervisor.Unmonitor()).To(Succeed())
+
+			content, err := ioutil.ReadFile(filepath.Join(unitsDir, unitName+".d", "50-bosh-unmonitored.conf"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("Restart=no"))
+			Expect(manager.ReloadCount()).To(Equal(2))
+		})
+	})
+
+	Describe("StopProcess", func() {
+		It("stops the unit of the process without stopping the instance", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(supervisor.StopProcess("fake-process")).To(Succeed())
+
+			Expect(manager.StoppedUnits()).To(Equal([]string{unitName}))
+			Expect(filepath.Join(baseDir, "monit", "stopped")).ToNot(BeAnExistingFile())
+			Expect(supervisor.Status()).To(Equal("failing"))
+		})
+
+		It("returns an error for unknown processes", func() {
+			addJob()
+
+			err := supervisor.StopProcess("fake-helper")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown process 'fake-helper'"))
+		})
+	})
+
+	Describe("StartProcess", func() {
+		It("re-monitors and starts the unit of the process", func() {
+			addJob()
+			Expect(supervisor.Unmonitor()).To(Succeed())
+
+			Expect(supervisor.StartProcess("fake-process")).To(Succeed())
+
+			Expect(manager.StartedUnits()).To(Equal([]string{unitName}))
+			Expect(filepath.Join(unitsDir, unitName+".d", "50-bosh-unmonitored.conf")).ToNot(BeAnExistingFile())
+			Expect(supervisor.Status()).To(Equal("running"))
+		})
+
+		It("returns an error when the instance is stopped", func() {
+			addJob()
+			Expect(supervisor.Stop()).To(Succeed())
+
+			err := supervisor.StartProcess("fake-process")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Cannot start process 'fake-process' of a stopped instance"))
+			Expect(manager.StartedUnits()).To(BeEmpty())
+		})
+	})
+
+	Describe("RestartProcess", func() {
+		It("restarts the unit of the process", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+
+			Expect(supervisor.RestartProcess("fake-process")).To(Succeed())
+
+			Expect(manager.RestartUnitNames).To(Equal([]string{unitName}))
+		})
+
+		It("returns an error when systemd fails to restart the unit", func() {
+			addJob()
+			manager.RestartUnitErr = errors.New("fake-restart-err")
+
+			err := supervisor.RestartProcess("fake-process")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Restarting service fake-process"))
+		})
+	})
+
+	Describe("MonitorJobFailures", func() {
+		It("generates units of previously added jobs", func() {
+			Expect(supervisor.AddJob("fake-job", 0, configPath)).To(Succeed())
+
+			monitor()
+
+			Eventually(func() string { return filepath.Join(unitsDir, unitName) }).Should(BeAnExistingFile())
+		})
+
+		It("reports processes systemd restarted", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			monitor()
+
+			manager.SetUnit(boshsystemd.UnitStatus{Name: unitName, ActiveState: boshsystemd.ActiveStateActive, NRestarts: 1, Result: "exit-code"})
+
+			Eventually(receivedAlerts).Should(HaveLen(1))
+			Expect(receivedAlerts()[0].Service).To(Equal("fake-process"))
+			Expect(receivedAlerts()[0].Event).To(Equal("Does not exist"))
+			Expect(receivedAlerts()[0].Action).To(Equal("restart"))
+
+			_, err := time.Parse(time.RFC1123Z, receivedAlerts()[0].Date)
+			Expect(err).ToNot(HaveOccurred())
+
+			Consistently(receivedAlerts, 50*time.Millisecond).Should(HaveLen(1))
+		})
+
+		It("reports processes killed for exceeding their memory limit", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			monitor()
+
+			manager.SetUnit(boshsystemd.UnitStatus{Name: unitName, ActiveState: boshsystemd.ActiveStateActive, NRestarts: 1, Result: "oom-kill"})
+
+			Eventually(receivedAlerts).Should(HaveLen(1))
+			Expect(receivedAlerts()[0].Event).To(Equal("Resource limit matched"))
+		})
+
+		It("reports units systemd gave up on once", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			monitor()
+
+			manager.SetUnit(boshsystemd.UnitStatus{Name: unitName, ActiveState: boshsystemd.ActiveStateFailed, Result: "start-limit-hit"})
+
+			Eventually(receivedAlerts).Should(HaveLen(1))
+			Expect(receivedAlerts()[0].Event).To(Equal("Execution failed"))
+			Expect(receivedAlerts()[0].Description).To(ContainSubstring("start-limit-hit"))
+
+			Consistently(receivedAlerts, 50*time.Millisecond).Should(HaveLen(1))
+			Expect(supervisor.Status()).To(Equal("failing"))
+		})
+
+		It("starts monitored units that are not running", func() {
+			addJob()
+			monitor()
+
+			Eventually(manager.StartedUnits).Should(ContainElement(unitName))
+		})
+
+		It("does not start or report units after they were stopped", func() {
+			addJob()
+			Expect(supervisor.Start()).To(Succeed())
+			Expect(supervisor.Stop()).To(Succeed())
+
+			buildSupervisor()
+			monitor()
+
+			manager.SetUnit(boshsystemd.UnitStatus{Name: unitName, ActiveState: boshsystemd.ActiveStateInactive, NRestarts: 3})
+
+			Consistently(manager.StartedUnits, 100*time.Millisecond).Should(HaveLen(1))
+			Expect(receivedAlerts()).To(BeEmpty())
+		})
+	})
+})
@@ -262,9 +262,9 @@ func (w *windowsJobSupervisor) Stop() error {
 	return nil
 }
 
-func (w *windowsJobSupervisor) StopAndWait() error {
+func (w *windowsJobSupervisor) StopAndWait() ([]JobStopResult, error) {
 	// Stop already does this for us
-	return w.Stop()
+	return nil, w.Stop()
 }
 
 func (w *windowsJobSupervisor) Unmonitor() error {
@@ -272,6 +272,38 @@ func (w *windowsJobSupervisor) Unmonitor() error {
 	return w.mgr.Unmonitor()
 }
 
+func (w *windowsJobSupervisor) StartProcess(name string) error {
+	if w.fs.FileExists(w.stoppedFilePath()) {
+		return bosherr.Errorf("Cannot start process '%s' of a stopped instance", name)
+	}
+
+	if err := w.mgr.StartService(name); err != nil {
+		return bosherr.WrapErrorf(err, "Starting service %s", name)
+	}
+	return nil
+}
+
+func (w *windowsJobSupervisor) StopProcess(name string) error {
+	if err := w.mgr.StopService(name); err != nil {
+		return bosherr.WrapErrorf(err, "Stopping service %s", name)
+	}
+	return nil
+}
+
+func (w *windowsJobSupervisor) RestartProcess(name string) error {
+	if w.fs.FileExists(w.stoppedFilePath()) {
+		return bosherr.Errorf("Cannot start process '%s' of a stopped instance", name)
+	}
+
+	if err := w.mgr.StopService(name); err != nil {
+		return bosherr.WrapErrorf(err, "Stopping service %s", name)
+	}
+	if err := w.mgr.StartService(name); err != nil {
+		return bosherr.WrapErrorf(err, "Starting service %s", name)
+	}
+	return nil
+}
+
 func (w *windowsJobSupervisor) Status() (status string) {
 	if w.fs.FileExists(w.stoppedFilePath()) {
 		return "stopped"
@@ -332,6 +364,14 @@ func (w *windowsJobSupervisor) Processes() ([]Process, error) {
 	return procs, nil
 }
 
+func (w *windowsJobSupervisor) StatusStaleSince() time.Time {
+	return time.Time{}
+}
+
+func (w *windowsJobSupervisor) WaitUntilReady(timeout time.Duration) error {
+	return nil
+}
+
 func (w *windowsJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
 	configFileContents, err := w.fs.ReadFile(configPath)
 	if err != nil {
@@ -516,6 +556,10 @@ func (w *windowsJobSupervisor) MonitorJobFailures(handler JobFailureHandler) err
 	return nil
 }
 
+func (w *windowsJobSupervisor) MonitorJobEvents(_ JobEventHandler) error {
+	return nil
+}
+
 func (w *windowsJobSupervisor) stoppedFilePath() string {
 	return filepath.Join(w.dirProvider.MonitDir(), "stopped")
 }
@@ -744,7 +744,7 @@ var _ = Describe("WindowsJobSupervisor", func() {
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(jobSupervisor.Start()).To(Succeed())
-				Expect(jobSupervisor.StopAndWait()).To(Succeed())
+				Expect(jobSupervisor.StopAndWait()).To(BeEmpty())
 
 				for _, proc := range conf.Processes {
 					st, err := GetServiceState(proc.Name)
@@ -756,7 +756,7 @@ var _ = Describe("WindowsJobSupervisor", func() {
 			It("stops flapping service", func() {
 				conf, err := AddFlappingJob("flapping")
 				Expect(err).To(Succeed())
-				Expect(jobSupervisor.StopAndWait()).To(Succeed())
+				Expect(jobSupervisor.StopAndWait()).To(BeEmpty())
 
 				Consistently(func() bool {
 					stopped := true
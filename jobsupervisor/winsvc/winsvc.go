@@ -96,6 +96,27 @@ func (m *Mgr) services() ([]*mgr.Service, error) {
 	return svcs, nil
 }
 
+// service, returns the monitored service named name.  The caller must
+// close the service.
+func (m *Mgr) service(name string) (*mgr.Service, error) {
+	svcs, err := m.services()
+	if err != nil {
+		return nil, err
+	}
+	var found *mgr.Service
+	for _, s := range svcs {
+		if found == nil && s.Name == name {
+			found = s
+			continue
+		}
+		s.Close()
+	}
+	if found == nil {
+		return nil, fmt.Errorf("winsvc: service %s is not monitored", name)
+	}
+	return found, nil
+}
+
 // iter, calls function fn concurrently on each service matched by Mgr.
 // The service is closed for fn and the first error, if any, is returned.
 //
@@ -391,6 +412,16 @@ func (m *Mgr) Start() (first error) {
 	return m.iter(m.startRetry)
 }
 
+// StartService starts the monitored service named name.
+func (m *Mgr) StartService(name string) error {
+	s, err := m.service(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return m.startRetry(s)
+}
+
 func (m *Mgr) doStop(s *mgr.Service) error {
 	const Timeout = time.Second * 30
 
@@ -469,6 +500,17 @@ func (m *Mgr) Stop() (first error) {
 	return m.iter(m.doStop)
 }
 
+// StopService stops the monitored service named name and waits for the
+// stop to complete.
+func (m *Mgr) StopService(name string) error {
+	s, err := m.service(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return m.doStop(s)
+}
+
 func (m *Mgr) doDelete(s *mgr.Service) error {
 	const Timeout = time.Second * 60
 
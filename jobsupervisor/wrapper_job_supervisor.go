@@ -7,28 +7,87 @@ import (
 	//boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	//boshsys "github.com/cloudfoundry/bosh-utils/system"
 	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	"github.com/cloudfoundry/bosh-agent/jobsupervisor/monitrc"
+	boshprobe "github.com/cloudfoundry/bosh-agent/jobsupervisor/probe"
 	"github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/cloudfoundry/bosh-utils/system"
-	"path/filepath"
 )
 
-const wrapperJobSupervisorLogTag = "wrapperJobSupervisor"
+const (
+	wrapperJobSupervisorLogTag = "wrapperJobSupervisor"
+
+	// Processes started before their jobs were added with resource limits
+	// are moved into the cgroups of their jobs within this interval
+	cgroupPlacementInterval = 5 * time.Second
+)
+
+// cgroupExecScript makes start programs join the cgroup of their job
+// before running, so that every process they start is limited
+const cgroupExecScript = `#!/bin/sh
+# Usage: cgroup-exec CGROUP_PROCS_FILE COMMAND [ARGUMENTS...]
+echo $$ > "$1" || echo "Not joining cgroup of $1" >&2
+shift
+exec "$@"
+`
 
+// wrapperJobSupervisor records the health of the instance, folds the
+// results of the jobs' probes into the state reported by the delegate,
+// confines the processes of jobs declaring resource limits to cgroups,
+// backs off restarting processes that are in a crash loop and reports the
+// lifecycle events of processes
 type wrapperJobSupervisor struct {
-	delegate      JobSupervisor
-	fs            system.FileSystem
-	dirProvider   directories.Provider
-	logger        boshlog.Logger
-	pollRunning   bool
-	pollUnmonitor bool
+	delegate       JobSupervisor
+	fs             system.FileSystem
+	dirProvider    directories.Provider
+	probes         boshprobe.Monitor
+	cgroups        boshcgroup.Manager
+	crashLoops     *crashLoopTracker
+	crashLoopsLock sync.Mutex
+	events         *jobEvents
+	timeService    clock.Clock
+	logger         boshlog.Logger
+	pollRunning    bool
+	pollUnmonitor  bool
 }
 
-func NewWrapperJobSupervisor(delegate JobSupervisor, fs system.FileSystem, dirProvider directories.Provider, logger boshlog.Logger) JobSupervisor {
+// cgroupProcess is a process placed into the cgroup of its job; they are
+// persisted since jobs are only added again on the next apply
+type cgroupProcess struct {
+	Job     string `json:"job"`
+	PidFile string `json:"pid_file"`
+}
+
+func NewWrapperJobSupervisor(
+	delegate JobSupervisor,
+	fs system.FileSystem,
+	dirProvider directories.Provider,
+	probes boshprobe.Monitor,
+	cgroups boshcgroup.Manager,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) JobSupervisor {
 	return &wrapperJobSupervisor{
 		delegate:    delegate,
 		fs:          fs,
 		dirProvider: dirProvider,
+		probes:      probes,
+		cgroups:     cgroups,
+		crashLoops:  newCrashLoopTracker(),
+		events:      newJobEvents(),
+		timeService: timeService,
 		logger:      logger,
 	}
 }
@@ -37,19 +96,27 @@ func (w *wrapperJobSupervisor) Reload() error {
 	return w.delegate.Reload()
 }
 func (w *wrapperJobSupervisor) Start() error {
+	w.crashLoops.ClearAll()
+	w.saveCrashLoops()
 
 	err := w.delegate.Start()
-	w.HealthRecorder(w.delegate.Status())
+	w.HealthRecorder(w.Status())
 
 	return err
 }
 func (w *wrapperJobSupervisor) Stop() error {
+	w.crashLoops.ClearAll()
+	w.saveCrashLoops()
+
 	err := w.delegate.Stop()
-	w.HealthRecorder(w.delegate.Status())
+	w.HealthRecorder(w.Status())
 
 	return err
 }
-func (w *wrapperJobSupervisor) StopAndWait() error {
+func (w *wrapperJobSupervisor) StopAndWait() ([]JobStopResult, error) {
+	w.crashLoops.ClearAll()
+	w.saveCrashLoops()
+
 	return w.delegate.StopAndWait()
 }
 func (w *wrapperJobSupervisor) Unmonitor() error {
@@ -58,23 +125,197 @@ func (w *wrapperJobSupervisor) Unmonitor() error {
 		return err
 	}
 
-	w.HealthRecorder(w.delegate.Status())
+	w.HealthRecorder(w.Status())
+	return err
+}
+func (w *wrapperJobSupervisor) StartProcess(name string) error {
+	w.crashLoops.Clear(name)
+	w.saveCrashLoops()
+
+	err := w.delegate.StartProcess(name)
+	w.HealthRecorder(w.Status())
+
+	return err
+}
+func (w *wrapperJobSupervisor) StopProcess(name string) error {
+	w.crashLoops.Clear(name)
+	w.saveCrashLoops()
+
+	err := w.delegate.StopProcess(name)
+	w.HealthRecorder(w.Status())
+
+	return err
+}
+func (w *wrapperJobSupervisor) RestartProcess(name string) error {
+	w.crashLoops.Clear(name)
+	w.saveCrashLoops()
+
+	err := w.delegate.RestartProcess(name)
+	w.HealthRecorder(w.Status())
+
 	return err
 }
 func (w *wrapperJobSupervisor) Status() string {
-	return w.delegate.Status()
+	status := w.delegate.Status()
+
+	// Processes in a crash loop are stopped, which the delegate reports as failing
+	if status == processStateRunning || status == processStateFailing {
+		if w.crashLoops.Any() {
+			return processStateCrashLoop
+		}
+	}
+
+	if status != processStateRunning {
+		return status
+	}
+
+	return probedState(status, w.probes.Status())
 }
 func (w *wrapperJobSupervisor) Processes() ([]Process, error) {
-	return w.delegate.Processes()
+	processes, err := w.delegate.Processes()
+
+	w.foldStates(processes)
+
+	cgroupProcesses := w.loadCGroupProcesses()
+
+	for i, process := range processes {
+		cgroupProcess, found := cgroupProcesses[process.Name]
+		if !found {
+			continue
+		}
+
+		usage, usageErr := w.cgroups.Usage(cgroupProcess.Job)
+		if usageErr != nil {
+			w.logger.Warn(wrapperJobSupervisorLogTag, "Getting cgroup usage of job %s: %s", cgroupProcess.Job, usageErr.Error())
+			continue
+		}
+
+		processes[i].CGroup = &CGroupVitals{
+			Job:           cgroupProcess.Job,
+			MemoryKb:      int(usage.MemoryBytes / 1024),
+			MemoryLimitKb: int(usage.MemoryMaxBytes / 1024),
+			CPUUsageSecs:  float64(usage.CPUUsageUsec) / float64(time.Second/time.Microsecond),
+			Pids:          usage.Pids,
+			PidsLimit:     usage.PidsMax,
+		}
+	}
+
+	return processes, err
+}
+
+// foldStates reports processes in a crash loop and the results of probes
+// in the states of processes
+func (w *wrapperJobSupervisor) foldStates(processes []Process) {
+	for i, process := range processes {
+		if w.crashLoops.InCrashLoop(process.Name) {
+			processes[i].State = processStateCrashLoop
+		} else if process.State == processStateRunning {
+			processes[i].State = probedState(process.State, w.probes.ProcessStatus(process.Name))
+		}
+	}
+}
+func (w *wrapperJobSupervisor) StatusStaleSince() time.Time {
+	return w.delegate.StatusStaleSince()
+}
+func (w *wrapperJobSupervisor) WaitUntilReady(timeout time.Duration) error {
+	err := w.delegate.WaitUntilReady(timeout)
+	if err != nil {
+		return err
+	}
+
+	return w.probes.WaitUntilReady(timeout)
 }
 func (w *wrapperJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
-	return w.delegate.AddJob(jobName, jobIndex, configPath)
+	supervisedConfigPath, err := w.addJobCGroup(jobName, configPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Limiting resources of job %s", jobName)
+	}
+
+	err = w.delegate.AddJob(jobName, jobIndex, supervisedConfigPath)
+	if err != nil {
+		return err
+	}
+
+	// Probes are declared next to the monit file of the job
+	err = w.probes.AddJob(filepath.Dir(configPath))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Loading probes of job %s", jobName)
+	}
+
+	return nil
 }
 func (w *wrapperJobSupervisor) RemoveAllJobs() error {
+	w.probes.RemoveAllJobs()
+	w.crashLoops.ClearAll()
+	w.saveCrashLoops()
+	w.events.Reset()
+
+	err := w.fs.RemoveAll(w.cgroupProcessesPath())
+	if err != nil {
+		return bosherr.WrapError(err, "Removing cgroup processes")
+	}
+
+	err = w.fs.RemoveAll(w.cgroupConfigsDir())
+	if err != nil {
+		return bosherr.WrapError(err, "Removing cgroup job configs")
+	}
+
+	w.cgroups.RemoveAllJobs()
+
 	return w.delegate.RemoveAllJobs()
 }
 func (w *wrapperJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error {
-	return w.delegate.MonitorJobFailures(handler)
+	w.startCrashLoopProcesses()
+
+	if w.cgroups.Supported() {
+		go w.placeProcesses()
+	}
+
+	go w.probes.Run(func(transition boshprobe.Transition) {
+		// Probes are expected to fail while the job is not running
+		if w.delegate.Status() != processStateRunning {
+			return
+		}
+
+		err := handler(probeAlert(transition))
+		if err != nil {
+			w.logger.Error(wrapperJobSupervisorLogTag, "Handling probe alert for %s: %s", transition.Probe.Process, err.Error())
+		}
+	})
+
+	return w.delegate.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+		return w.handleAlert(alert, handler)
+	})
+}
+
+// MonitorJobEvents compares the states of processes periodically and blocks
+// forever; states of delegates caching them are compared whenever their
+// cache was refreshed instead of fetching them once more
+func (w *wrapperJobSupervisor) MonitorJobEvents(handler JobEventHandler) error {
+	w.events.SetHandler(handler)
+
+	cache, cached := w.delegate.(ProcessesCache)
+	var observedAt time.Time
+
+	for {
+		if cached {
+			processes, fetchedAt := cache.CachedProcesses()
+			if fetchedAt.After(observedAt) {
+				w.foldStates(processes)
+				w.events.Observe(processes, fetchedAt)
+				observedAt = fetchedAt
+			}
+		} else {
+			processes, err := w.Processes()
+			if err != nil {
+				w.logger.Warn(wrapperJobSupervisorLogTag, "Getting processes for job events: %s", err.Error())
+			} else {
+				w.events.Observe(processes, w.timeService.Now())
+			}
+		}
+
+		w.timeService.Sleep(jobEventPollInterval)
+	}
 }
 
 func (w *wrapperJobSupervisor) HealthRecorder(status string) {
@@ -88,3 +329,319 @@ func (w *wrapperJobSupervisor) HealthRecorder(status string) {
 		w.logger.Error(wrapperJobSupervisorLogTag, err.Error())
 	}
 }
+
+// handleAlert tracks restarts reported by the delegate; the alert of the
+// restart putting a process into a crash loop is replaced by a single
+// crash loop alert and later alerts are dropped until the backoff ended
+func (w *wrapperJobSupervisor) handleAlert(alert boshalert.MonitAlert, handler JobFailureHandler) error {
+	if w.crashLoops.InCrashLoop(alert.Service) {
+		w.logger.Debug(wrapperJobSupervisorLogTag, "Dropping alert for %s in crash loop: %s", alert.Service, alert.Event)
+		return nil
+	}
+
+	if alert.Action != monitrc.ActionRestart {
+		return handler(alert)
+	}
+
+	now := w.timeService.Now()
+
+	crashLooping, backoff, until := w.crashLoops.RecordRestart(alert.Service, now)
+	if !crashLooping {
+		w.events.Emit(JobEvent{
+			Process:     alert.Service,
+			Type:        JobEventRestarted,
+			Timestamp:   now.Unix(),
+			Description: alert.Description,
+		})
+
+		return handler(alert)
+	}
+
+	w.logger.Warn(wrapperJobSupervisorLogTag, "Process %s is in a crash loop, restarting it in %s", alert.Service, backoff)
+
+	err := w.delegate.StopProcess(alert.Service)
+	if err != nil {
+		w.logger.Error(wrapperJobSupervisorLogTag, "Stopping process %s in crash loop: %s", alert.Service, err.Error())
+	}
+
+	w.saveCrashLoops()
+	w.HealthRecorder(w.Status())
+
+	go w.endCrashLoop(alert.Service, backoff, until)
+
+	description := fmt.Sprintf("restarted %d times within %s, restarting again in %s", crashLoopThreshold, crashLoopWindow, backoff)
+
+	return handler(newMonitrcAlert(now, alert.Service, "Crash loop", monitrc.ActionStop, description))
+}
+
+// endCrashLoop starts a process in a crash loop again after its backoff
+// unless it was started, stopped or removed in the meantime
+func (w *wrapperJobSupervisor) endCrashLoop(name string, backoff time.Duration, until time.Time) {
+	w.timeService.Sleep(backoff)
+
+	if !w.crashLoops.End(name, until) {
+		return
+	}
+
+	w.saveCrashLoops()
+
+	w.logger.Info(wrapperJobSupervisorLogTag, "Restarting process %s after crash loop backoff", name)
+
+	err := w.delegate.StartProcess(name)
+	if err != nil {
+		w.logger.Error(wrapperJobSupervisorLogTag, "Starting process %s after crash loop: %s", name, err.Error())
+	}
+
+	w.HealthRecorder(w.Status())
+}
+
+// startCrashLoopProcesses starts the processes that were kept stopped for
+// a crash loop when the agent restarted, since their backoffs ended with it
+func (w *wrapperJobSupervisor) startCrashLoopProcesses() {
+	if !w.fs.FileExists(w.crashLoopProcessesPath()) {
+		return
+	}
+
+	contents, err := w.fs.ReadFile(w.crashLoopProcessesPath())
+	if err != nil {
+		w.logger.Warn(wrapperJobSupervisorLogTag, "Reading crash loop processes: %s", err.Error())
+		return
+	}
+
+	var names []string
+
+	err = json.Unmarshal(contents, &names)
+	if err != nil {
+		w.logger.Warn(wrapperJobSupervisorLogTag, "Parsing crash loop processes: %s", err.Error())
+	}
+
+	for _, name := range names {
+		w.logger.Info(wrapperJobSupervisorLogTag, "Restarting process %s stopped for a crash loop before the agent restarted", name)
+
+		err := w.delegate.StartProcess(name)
+		if err != nil {
+			w.logger.Error(wrapperJobSupervisorLogTag, "Starting process %s after crash loop: %s", name, err.Error())
+		}
+	}
+
+	w.saveCrashLoops()
+	w.HealthRecorder(w.Status())
+}
+
+// saveCrashLoops persists the processes in a crash loop so that they are
+// not left stopped when the agent restarts before their backoffs end
+func (w *wrapperJobSupervisor) saveCrashLoops() {
+	w.crashLoopsLock.Lock()
+	defer w.crashLoopsLock.Unlock()
+
+	names := w.crashLoops.Stopped()
+	if len(names) == 0 {
+		err := w.fs.RemoveAll(w.crashLoopProcessesPath())
+		if err != nil {
+			w.logger.Warn(wrapperJobSupervisorLogTag, "Removing crash loop processes: %s", err.Error())
+		}
+		return
+	}
+
+	contents, err := json.Marshal(names)
+	if err != nil {
+		w.logger.Warn(wrapperJobSupervisorLogTag, "Marshalling crash loop processes: %s", err.Error())
+		return
+	}
+
+	err = w.fs.WriteFile(w.crashLoopProcessesPath(), contents)
+	if err != nil {
+		w.logger.Warn(wrapperJobSupervisorLogTag, "Writing crash loop processes: %s", err.Error())
+	}
+}
+
+// addJobCGroup creates the cgroup of a job declaring resource limits next
+// to its monit file, records which processes belong to it and returns the
+// path of a copy of the config whose start programs join the cgroup
+func (w *wrapperJobSupervisor) addJobCGroup(jobName, configPath string) (string, error) {
+	jobDir := filepath.Dir(configPath)
+
+	limits, err := boshcgroup.LoadLimits(w.fs, jobDir)
+	if err != nil {
+		return "", err
+	}
+
+	if limits.Empty() {
+		return configPath, nil
+	}
+
+	// Sub-jobs from additional monit files share the cgroup of their job
+	job := filepath.Base(jobDir)
+
+	if !w.cgroups.Supported() {
+		w.logger.Warn(wrapperJobSupervisorLogTag, "Not limiting resources of job %s since cgroup v2 is not available", job)
+		return configPath, nil
+	}
+
+	err = w.cgroups.AddJob(job, limits)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := w.fs.ReadFile(configPath)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Reading job config from file")
+	}
+
+	processes, err := monitrc.Parse(content)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Parsing job config '%s'", configPath)
+	}
+
+	cgroupProcesses := w.loadCGroupProcesses()
+
+	for _, process := range processes {
+		if process.PidFile != "" {
+			cgroupProcesses[process.Name] = cgroupProcess{Job: job, PidFile: process.PidFile}
+		}
+	}
+
+	contents, err := json.Marshal(cgroupProcesses)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Marshalling cgroup processes")
+	}
+
+	err = w.fs.WriteFile(w.cgroupProcessesPath(), contents)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Writing cgroup processes")
+	}
+
+	return w.writeCGroupConfig(jobName, job, content)
+}
+
+// writeCGroupConfig writes a copy of a job config whose start programs
+// run through cgroup-exec
+func (w *wrapperJobSupervisor) writeCGroupConfig(jobName, job string, content []byte) (string, error) {
+	err := w.fs.WriteFileString(w.cgroupExecPath(), cgroupExecScript)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Writing cgroup-exec")
+	}
+
+	err = w.fs.Chmod(w.cgroupExecPath(), 0755)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Making cgroup-exec executable")
+	}
+
+	prefix := w.cgroupExecPath() + " " + w.cgroups.ProcsFile(job)
+
+	prefixed, err := monitrc.PrefixStartPrograms(content, func(monitrc.Process) string { return prefix })
+	if err != nil {
+		return "", bosherr.WrapError(err, "Running start programs in cgroup")
+	}
+
+	configPath := filepath.Join(w.cgroupConfigsDir(), jobName+".monitrc")
+
+	err = w.fs.WriteFile(configPath, prefixed)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Writing cgroup job config")
+	}
+
+	return configPath, nil
+}
+
+// placeProcesses periodically moves the processes of jobs into their
+// cgroups, which catches processes started before their start programs
+// ran through cgroup-exec, e.g. by an earlier version of the job config
+func (w *wrapperJobSupervisor) placeProcesses() {
+	for {
+		for name, process := range w.loadCGroupProcesses() {
+			pid, err := w.readPid(process.PidFile)
+			if err != nil {
+				// Processes without pid files are not running
+				continue
+			}
+
+			err = w.cgroups.AddProcess(process.Job, pid)
+			if err != nil {
+				w.logger.Warn(wrapperJobSupervisorLogTag, "Moving process %s into cgroup of job %s: %s", name, process.Job, err.Error())
+			}
+		}
+
+		w.timeService.Sleep(cgroupPlacementInterval)
+	}
+}
+
+func (w *wrapperJobSupervisor) loadCGroupProcesses() map[string]cgroupProcess {
+	cgroupProcesses := map[string]cgroupProcess{}
+
+	if !w.fs.FileExists(w.cgroupProcessesPath()) {
+		return cgroupProcesses
+	}
+
+	contents, err := w.fs.ReadFile(w.cgroupProcessesPath())
+	if err != nil {
+		w.logger.Warn(wrapperJobSupervisorLogTag, "Reading cgroup processes: %s", err.Error())
+		return cgroupProcesses
+	}
+
+	err = json.Unmarshal(contents, &cgroupProcesses)
+	if err != nil {
+		w.logger.Warn(wrapperJobSupervisorLogTag, "Parsing cgroup processes: %s", err.Error())
+	}
+
+	return cgroupProcesses
+}
+
+func (w *wrapperJobSupervisor) cgroupProcessesPath() string {
+	return filepath.Join(w.dirProvider.BoshDir(), "cgroup_processes.json")
+}
+
+func (w *wrapperJobSupervisor) crashLoopProcessesPath() string {
+	return filepath.Join(w.dirProvider.BoshDir(), "crash_loop_processes.json")
+}
+
+func (w *wrapperJobSupervisor) cgroupConfigsDir() string {
+	return filepath.Join(w.dirProvider.BoshDir(), "cgroup_jobs")
+}
+
+func (w *wrapperJobSupervisor) cgroupExecPath() string {
+	return filepath.Join(w.dirProvider.BoshBinDir(), "cgroup-exec")
+}
+
+func (w *wrapperJobSupervisor) readPid(pidFile string) (int, error) {
+	content, err := w.fs.ReadFileString(pidFile)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(content))
+}
+
+// probedState reports running processes that failed liveness probes as
+// failing and those that did not pass readiness probes yet as starting
+func probedState(state string, status boshprobe.Status) string {
+	switch {
+	case !status.Live:
+		return processStateFailing
+	case !status.Ready:
+		return processStateStarting
+	default:
+		return state
+	}
+}
+
+// probeAlert reports liveness probe failures like monit reports failed
+// connection and program checks
+func probeAlert(transition boshprobe.Transition) boshalert.MonitAlert {
+	event := "Connection"
+	if transition.Probe.Exec != nil {
+		event = "Execution"
+	}
+
+	if transition.Passing {
+		description := fmt.Sprintf("liveness probe '%s' succeeded", transition.Probe.Name)
+		return newMonitrcAlert(transition.At, transition.Probe.Process, event+" succeeded", "alert", description)
+	}
+
+	description := fmt.Sprintf("liveness probe '%s' failed", transition.Probe.Name)
+	if transition.Err != nil {
+		description = fmt.Sprintf("%s: %s", description, transition.Err.Error())
+	}
+
+	return newMonitrcAlert(transition.At, transition.Probe.Process, event+" failed", "alert", description)
+}
@@ -5,57 +5,57 @@ import (
 
 	"encoding/json"
 	"errors"
+	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/pivotal-golang/clock/fakeclock"
+
 	"github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	fakecgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup/fakes"
 	"github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
-	fakemonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit/fakes"
+	boshprobe "github.com/cloudfoundry/bosh-agent/jobsupervisor/probe"
+	fakeprobe "github.com/cloudfoundry/bosh-agent/jobsupervisor/probe/fakes"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/pivotal-golang/clock/fakeclock"
 )
 
 var _ = Describe("WrapperJobSupervisor", func() {
 
 	var (
-		fs                    *fakesys.FakeFileSystem
-		runner                *fakesys.FakeCmdRunner
-		client                *fakemonit.FakeMonitClient
-		logger                boshlog.Logger
-		dirProvider           boshdir.Provider
-		jobFailuresServerPort int
-		fakeSupervisor        *fakes.FakeJobSupervisor
-		wrapper               JobSupervisor
-		timeService           *fakeclock.FakeClock
+		fs             *fakesys.FakeFileSystem
+		logger         boshlog.Logger
+		dirProvider    boshdir.Provider
+		fakeSupervisor *fakes.FakeJobSupervisor
+		probeMonitor   *fakeprobe.FakeMonitor
+		cgroups        *fakecgroup.FakeManager
+		timeService    *fakeclock.FakeClock
+		wrapper        JobSupervisor
 	)
 
-	var jobFailureServerPort = 5000
-
-	getJobFailureServerPort := func() int {
-		jobFailureServerPort++
-		return jobFailureServerPort
-	}
-
 	BeforeEach(func() {
 		fs = fakesys.NewFakeFileSystem()
 		fs.MkdirAll("/var/vcap/instance", 666)
-		runner = fakesys.NewFakeCmdRunner()
-		client = fakemonit.NewFakeMonitClient()
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 		dirProvider = boshdir.NewProvider("/var/vcap")
-		jobFailuresServerPort = getJobFailureServerPort()
-		timeService = fakeclock.NewFakeClock(time.Now())
 
 		fakeSupervisor = fakes.NewFakeJobSupervisor()
+		probeMonitor = fakeprobe.NewFakeMonitor()
+		cgroups = fakecgroup.NewFakeManager()
+		timeService = fakeclock.NewFakeClock(time.Date(2016, time.March, 1, 10, 0, 0, 0, time.UTC))
 
 		wrapper = NewWrapperJobSupervisor(
 			fakeSupervisor,
 			fs,
 			dirProvider,
+			probeMonitor,
+			cgroups,
+			timeService,
 			logger,
 		)
 	})
@@ -101,7 +101,7 @@ var _ = Describe("WrapperJobSupervisor", func() {
 	It("StopAndWait should delegate to the underlying job supervisor", func() {
 		error := errors.New("BOOM")
 		fakeSupervisor.StopErr = error
-		err := wrapper.StopAndWait()
+		_, err := wrapper.StopAndWait()
 		Expect(fakeSupervisor.StoppedAndWaited).To(BeTrue())
 		Expect(err).To(Equal(error))
 	})
@@ -129,6 +129,69 @@ var _ = Describe("WrapperJobSupervisor", func() {
 
 	})
 
+	Describe("StartProcess", func() {
+		It("should delegate to the underlying job supervisor", func() {
+			error := errors.New("BOOM")
+			fakeSupervisor.StartProcessErr = error
+			err := wrapper.StartProcess("fake-process")
+			Expect(fakeSupervisor.GetStartedProcesses()).To(Equal([]string{"fake-process"}))
+			Expect(err).To(Equal(error))
+		})
+
+		It("write the health json", func() {
+			fakeSupervisor.StatusStatus = "running"
+			_ = wrapper.StartProcess("fake-process")
+
+			healthRaw, err := fs.ReadFile(filepath.Join(dirProvider.InstanceDir(), "health.json"))
+			Expect(err).ToNot(HaveOccurred())
+			health := &Health{}
+			json.Unmarshal(healthRaw, health)
+			Expect(health.State).To(Equal("running"))
+		})
+	})
+
+	Describe("StopProcess", func() {
+		It("should delegate to the underlying job supervisor", func() {
+			error := errors.New("BOOM")
+			fakeSupervisor.StopProcessErr = error
+			err := wrapper.StopProcess("fake-process")
+			Expect(fakeSupervisor.GetStoppedProcesses()).To(Equal([]string{"fake-process"}))
+			Expect(err).To(Equal(error))
+		})
+
+		It("write the health json", func() {
+			fakeSupervisor.StatusStatus = "failing"
+			_ = wrapper.StopProcess("fake-process")
+
+			healthRaw, err := fs.ReadFile(filepath.Join(dirProvider.InstanceDir(), "health.json"))
+			Expect(err).ToNot(HaveOccurred())
+			health := &Health{}
+			json.Unmarshal(healthRaw, health)
+			Expect(health.State).To(Equal("failing"))
+		})
+	})
+
+	Describe("RestartProcess", func() {
+		It("should delegate to the underlying job supervisor", func() {
+			error := errors.New("BOOM")
+			fakeSupervisor.RestartProcessErr = error
+			err := wrapper.RestartProcess("fake-process")
+			Expect(fakeSupervisor.GetRestartedProcesses()).To(Equal([]string{"fake-process"}))
+			Expect(err).To(Equal(error))
+		})
+
+		It("write the health json", func() {
+			fakeSupervisor.StatusStatus = "running"
+			_ = wrapper.RestartProcess("fake-process")
+
+			healthRaw, err := fs.ReadFile(filepath.Join(dirProvider.InstanceDir(), "health.json"))
+			Expect(err).ToNot(HaveOccurred())
+			health := &Health{}
+			json.Unmarshal(healthRaw, health)
+			Expect(health.State).To(Equal("running"))
+		})
+	})
+
 	It("Status should delegate to the underlying job supervisor", func() {
 		fakeSupervisor.StatusStatus = "my-status"
 		status := wrapper.Status()
@@ -176,4 +239,619 @@ var _ = Describe("WrapperJobSupervisor", func() {
 		})
 		Expect(testAlert).To(Equal(fakeSupervisor.JobFailureAlert))
 	})
+
+	Describe("probes", func() {
+		It("loads the probes of added jobs from the job directory", func() {
+			err := wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(probeMonitor.AddedJobDirs).To(Equal([]string{"/var/vcap/jobs/fake-job"}))
+		})
+
+		It("returns an error when probes cannot be loaded", func() {
+			probeMonitor.AddJobErr = errors.New("fake-probes-error")
+
+			err := wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Loading probes of job fake-job: fake-probes-error"))
+		})
+
+		It("does not load probes when the job cannot be added", func() {
+			fakeSupervisor.AddJobErr = errors.New("fake-add-job-error")
+
+			err := wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")
+			Expect(err).To(Equal(fakeSupervisor.AddJobErr))
+			Expect(probeMonitor.AddedJobDirs).To(BeEmpty())
+		})
+
+		It("removes the probes of all jobs", func() {
+			_ = wrapper.RemoveAllJobs()
+			Expect(probeMonitor.RemovedAllJobs).To(BeTrue())
+		})
+
+		Describe("Status", func() {
+			BeforeEach(func() {
+				fakeSupervisor.StatusStatus = "running"
+			})
+
+			It("reports running instances failing liveness probes as failing", func() {
+				probeMonitor.AllStatus = boshprobe.Status{Ready: false, Live: false}
+				Expect(wrapper.Status()).To(Equal("failing"))
+			})
+
+			It("reports running instances not passing readiness probes yet as starting", func() {
+				probeMonitor.AllStatus = boshprobe.Status{Ready: false, Live: true}
+				Expect(wrapper.Status()).To(Equal("starting"))
+			})
+
+			It("reports the state of the underlying job supervisor when probes pass", func() {
+				Expect(wrapper.Status()).To(Equal("running"))
+			})
+
+			It("ignores probes unless the instance is running", func() {
+				fakeSupervisor.StatusStatus = "stopped"
+				probeMonitor.AllStatus = boshprobe.Status{Ready: false, Live: false}
+				Expect(wrapper.Status()).To(Equal("stopped"))
+			})
+
+			It("records the probed state as health", func() {
+				probeMonitor.AllStatus = boshprobe.Status{Ready: true, Live: false}
+				_ = wrapper.Start()
+
+				healthRaw, err := fs.ReadFile(filepath.Join(dirProvider.InstanceDir(), "health.json"))
+				Expect(err).ToNot(HaveOccurred())
+				health := &Health{}
+				json.Unmarshal(healthRaw, health)
+				Expect(health.State).To(Equal("failing"))
+			})
+		})
+
+		It("folds probe results into the state of running processes", func() {
+			fakeSupervisor.ProcessesStatus = []Process{
+				{Name: "hung", State: "running"},
+				{Name: "warming-up", State: "running"},
+				{Name: "healthy", State: "running"},
+				{Name: "stopped", State: "not monitored"},
+			}
+			probeMonitor.ProcessStatuses["hung"] = boshprobe.Status{Ready: true, Live: false}
+			probeMonitor.ProcessStatuses["warming-up"] = boshprobe.Status{Ready: false, Live: true}
+			probeMonitor.ProcessStatuses["stopped"] = boshprobe.Status{Ready: false, Live: false}
+
+			processes, err := wrapper.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(Equal([]Process{
+				{Name: "hung", State: "failing"},
+				{Name: "warming-up", State: "starting"},
+				{Name: "healthy", State: "running"},
+				{Name: "stopped", State: "not monitored"},
+			}))
+		})
+
+		Describe("WaitUntilReady", func() {
+			It("waits for the readiness probes", func() {
+				err := wrapper.WaitUntilReady(time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(probeMonitor.WaitUntilReadyTimeout).To(Equal(time.Minute))
+			})
+
+			It("returns errors from the probes", func() {
+				probeMonitor.WaitUntilReadyErr = errors.New("fake-readiness-error")
+				Expect(wrapper.WaitUntilReady(time.Minute)).To(Equal(probeMonitor.WaitUntilReadyErr))
+			})
+		})
+
+		Describe("MonitorJobFailures", func() {
+			var (
+				alertsLock sync.Mutex
+				alerts     []alert.MonitAlert
+			)
+
+			handledAlerts := func() []alert.MonitAlert {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+
+				return append([]alert.MonitAlert{}, alerts...)
+			}
+
+			BeforeEach(func() {
+				alerts = nil
+				at := time.Date(2016, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+				probeMonitor.Transitions = []boshprobe.Transition{
+					{
+						Probe: boshprobe.Config{Name: "ping", Process: "redis", Kind: boshprobe.KindLiveness, TCPSocket: &boshprobe.TCPSocketAction{}},
+						Err:   errors.New("fake-connection-error"),
+						At:    at,
+					},
+					{
+						Probe:   boshprobe.Config{Name: "check", Process: "nginx", Kind: boshprobe.KindLiveness, Exec: &boshprobe.ExecAction{}},
+						Passing: true,
+						At:      at,
+					},
+				}
+			})
+
+			AfterEach(func() {
+				probeMonitor.Stop()
+			})
+
+			monitor := func() {
+				_ = wrapper.MonitorJobFailures(func(a alert.MonitAlert) error {
+					alertsLock.Lock()
+					defer alertsLock.Unlock()
+
+					alerts = append(alerts, a)
+					return nil
+				})
+			}
+
+			It("reports liveness probes starting and stopping to fail like monit", func() {
+				fakeSupervisor.StatusStatus = "running"
+
+				monitor()
+
+				Eventually(handledAlerts).Should(HaveLen(2))
+				Expect(handledAlerts()[0]).To(Equal(alert.MonitAlert{
+					ID:          "1456826400000000000@localhost",
+					Service:     "redis",
+					Event:       "Connection failed",
+					Action:      "alert",
+					Date:        "Tue, 01 Mar 2016 10:00:00 +0000",
+					Description: "liveness probe 'ping' failed: fake-connection-error",
+				}))
+				Expect(handledAlerts()[1].Service).To(Equal("nginx"))
+				Expect(handledAlerts()[1].Event).To(Equal("Execution succeeded"))
+				Expect(handledAlerts()[1].Description).To(Equal("liveness probe 'check' succeeded"))
+			})
+
+			It("does not report probes while the instance is not running", func() {
+				fakeSupervisor.StatusStatus = "stopped"
+
+				monitor()
+
+				Eventually(probeMonitor.Running).Should(BeTrue())
+				Consistently(handledAlerts).Should(BeEmpty())
+			})
+		})
+	})
+
+	Describe("cgroups", func() {
+		BeforeEach(func() {
+			Expect(fs.WriteFileString("/var/vcap/jobs/fake-job/monit", `
+check process fake-process
+  with pidfile /var/vcap/sys/run/fake-job/fake-process.pid
+  start program "/var/vcap/jobs/fake-job/bin/ctl start"
+  stop program "/var/vcap/jobs/fake-job/bin/ctl stop"
+  group vcap
+`)).To(Succeed())
+
+			fakeSupervisor.ProcessesStatus = []Process{{Name: "fake-process", State: "running"}}
+		})
+
+		writeLimits := func(limits string) {
+			Expect(fs.WriteFileString("/var/vcap/jobs/fake-job/resources.json", limits)).To(Succeed())
+		}
+
+		It("does not create cgroups for jobs without resource limits", func() {
+			Expect(wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")).To(Succeed())
+			Expect(cgroups.AddedJobs).To(BeEmpty())
+			Expect(fakeSupervisor.AddJobArgs[0].ConfigPath).To(Equal("/var/vcap/jobs/fake-job/monit"))
+
+			processes, err := wrapper.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes[0].CGroup).To(BeNil())
+		})
+
+		It("creates the cgroup of jobs declaring resource limits", func() {
+			writeLimits(`{"cpu_shares": 512, "memory_max_mb": 256, "pids_max": 64}`)
+
+			Expect(wrapper.AddJob("fake-job_worker", 0, "/var/vcap/jobs/fake-job/monit")).To(Succeed())
+			Expect(cgroups.AddedJobs).To(Equal(map[string]boshcgroup.Limits{
+				"fake-job": {CPUShares: 512, MemoryMaxMB: 256, PidsMax: 64},
+			}))
+		})
+
+		It("runs the start programs of jobs declaring resource limits in their cgroup", func() {
+			writeLimits(`{"memory_max_mb": 256}`)
+
+			Expect(wrapper.AddJob("fake-job_worker", 0, "/var/vcap/jobs/fake-job/monit")).To(Succeed())
+
+			configPath := "/var/vcap/bosh/cgroup_jobs/fake-job_worker.monitrc"
+			Expect(fakeSupervisor.AddJobArgs[0].ConfigPath).To(Equal(configPath))
+
+			content, err := fs.ReadFileString(configPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(ContainSubstring(`start program "/var/vcap/bosh/bin/cgroup-exec /fake-cgroup/bosh-jobs/fake-job/cgroup.procs /var/vcap/jobs/fake-job/bin/ctl start"`))
+			Expect(content).To(ContainSubstring(`stop program "/var/vcap/jobs/fake-job/bin/ctl stop"`))
+
+			script, err := fs.ReadFileString("/var/vcap/bosh/bin/cgroup-exec")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(script).To(ContainSubstring(`echo $$ > "$1"`))
+			Expect(fs.GetFileTestStat("/var/vcap/bosh/bin/cgroup-exec").FileMode).To(Equal(os.FileMode(0755)))
+		})
+
+		It("reports the usage of the job's cgroup for its processes", func() {
+			writeLimits(`{"memory_max_mb": 256}`)
+			cgroups.Usages["fake-job"] = boshcgroup.Usage{
+				MemoryBytes:    64 * 1024 * 1024,
+				MemoryMaxBytes: 256 * 1024 * 1024,
+				CPUUsageUsec:   1500000,
+				Pids:           3,
+			}
+
+			Expect(wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")).To(Succeed())
+
+			processes, err := wrapper.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes[0].CGroup).To(Equal(&CGroupVitals{
+				Job:           "fake-job",
+				MemoryKb:      64 * 1024,
+				MemoryLimitKb: 256 * 1024,
+				CPUUsageSecs:  1.5,
+				Pids:          3,
+			}))
+		})
+
+		It("does not limit jobs when cgroup v2 is not available", func() {
+			writeLimits(`{"memory_max_mb": 256}`)
+			cgroups.IsSupported = false
+
+			Expect(wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")).To(Succeed())
+			Expect(cgroups.AddedJobs).To(BeEmpty())
+		})
+
+		It("returns an error when the resource limits are invalid", func() {
+			writeLimits(`{"memory_max_mb": -1}`)
+
+			err := wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Limiting resources of job fake-job"))
+		})
+
+		It("returns an error when the cgroup cannot be created", func() {
+			writeLimits(`{"memory_max_mb": 256}`)
+			cgroups.AddJobErr = errors.New("fake-cgroup-error")
+
+			err := wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Limiting resources of job fake-job: fake-cgroup-error"))
+		})
+
+		It("periodically moves running processes into the cgroups of their jobs", func() {
+			writeLimits(`{"memory_max_mb": 256}`)
+			Expect(wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")).To(Succeed())
+
+			Expect(wrapper.MonitorJobFailures(func(alert.MonitAlert) error { return nil })).To(Succeed())
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+			Expect(cgroups.AddedProcessesOf("fake-job")).To(BeEmpty())
+
+			Expect(fs.WriteFileString("/var/vcap/sys/run/fake-job/fake-process.pid", "1234\n")).To(Succeed())
+			timeService.Increment(5 * time.Second)
+
+			Eventually(func() []int { return cgroups.AddedProcessesOf("fake-job") }).Should(Equal([]int{1234}))
+		})
+
+		It("forgets the cgroups of all jobs", func() {
+			writeLimits(`{"memory_max_mb": 256}`)
+			Expect(wrapper.AddJob("fake-job", 0, "/var/vcap/jobs/fake-job/monit")).To(Succeed())
+
+			Expect(wrapper.RemoveAllJobs()).To(Succeed())
+			Expect(cgroups.RemovedAllJobs).To(BeTrue())
+			Expect(fs.FileExists("/var/vcap/bosh/cgroup_jobs/fake-job.monitrc")).To(BeFalse())
+
+			processes, err := wrapper.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes[0].CGroup).To(BeNil())
+		})
+	})
+
+	Describe("crash loops", func() {
+		var (
+			alertsLock sync.Mutex
+			alerts     []alert.MonitAlert
+		)
+
+		handledAlerts := func() []alert.MonitAlert {
+			alertsLock.Lock()
+			defer alertsLock.Unlock()
+
+			return append([]alert.MonitAlert{}, alerts...)
+		}
+
+		restart := func(times int) {
+			for i := 0; i < times; i++ {
+				err := fakeSupervisor.JobFailureHandler(alert.MonitAlert{Service: "redis", Event: "Does not exist", Action: "restart"})
+				Expect(err).ToNot(HaveOccurred())
+			}
+		}
+
+		BeforeEach(func() {
+			alerts = nil
+			fakeSupervisor.StatusStatus = "running"
+			fakeSupervisor.ProcessesStatus = []Process{
+				{Name: "redis", State: "running"},
+				{Name: "nginx", State: "running"},
+			}
+
+			Expect(wrapper.MonitorJobFailures(func(a alert.MonitAlert) error {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+
+				alerts = append(alerts, a)
+				return nil
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			probeMonitor.Stop()
+		})
+
+		It("forwards the alerts of restarts below the threshold", func() {
+			restart(4)
+
+			Expect(handledAlerts()).To(HaveLen(4))
+			Expect(fakeSupervisor.GetStoppedProcesses()).To(BeEmpty())
+			Expect(wrapper.Status()).To(Equal("running"))
+		})
+
+		It("stops processes restarted too often and sends a single crash loop alert", func() {
+			restart(5)
+
+			// The delegate reports stopped processes as failing
+			fakeSupervisor.StatusStatus = "failing"
+
+			Expect(fakeSupervisor.GetStoppedProcesses()).To(Equal([]string{"redis"}))
+			Expect(handledAlerts()).To(HaveLen(5))
+			Expect(handledAlerts()[4]).To(Equal(alert.MonitAlert{
+				ID:          "1456826400000000000@localhost",
+				Service:     "redis",
+				Event:       "Crash loop",
+				Action:      "stop",
+				Date:        "Tue, 01 Mar 2016 10:00:00 +0000",
+				Description: "restarted 5 times within 5m0s, restarting again in 30s",
+			}))
+
+			Expect(wrapper.Status()).To(Equal("crashloop"))
+
+			processes, err := wrapper.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes[0].State).To(Equal("crashloop"))
+			Expect(processes[1].State).To(Equal("running"))
+
+			restart(3)
+			Expect(handledAlerts()).To(HaveLen(5))
+		})
+
+		It("only counts restarts within the window", func() {
+			restart(4)
+			timeService.Increment(5 * time.Minute)
+			restart(1)
+
+			Expect(fakeSupervisor.GetStoppedProcesses()).To(BeEmpty())
+			Expect(handledAlerts()).To(HaveLen(5))
+		})
+
+		It("starts processes again after a backoff that doubles when they keep crashing", func() {
+			restart(5)
+
+			timeService.WaitForWatcherAndIncrement(30 * time.Second)
+
+			Eventually(fakeSupervisor.GetStartedProcesses).Should(Equal([]string{"redis"}))
+			Expect(wrapper.Status()).To(Equal("running"))
+
+			// Wait for the backoff to end before restarting the process again
+			healthFile := filepath.Join(dirProvider.InstanceDir(), "health.json")
+			Eventually(func() (string, error) { return fs.ReadFileString(healthFile) }).Should(ContainSubstring(`"running"`))
+
+			restart(5)
+
+			Expect(fakeSupervisor.GetStoppedProcesses()).To(Equal([]string{"redis", "redis"}))
+			Expect(handledAlerts()[9].Description).To(Equal("restarted 5 times within 5m0s, restarting again in 1m0s"))
+		})
+
+		It("persists the processes in a crash loop until their backoff ends", func() {
+			restart(5)
+
+			crashLoopsPath := "/var/vcap/bosh/crash_loop_processes.json"
+			Expect(fs.ReadFileString(crashLoopsPath)).To(Equal(`["redis"]`))
+
+			timeService.WaitForWatcherAndIncrement(30 * time.Second)
+
+			Eventually(func() bool { return fs.FileExists(crashLoopsPath) }).Should(BeFalse())
+		})
+
+		It("starts the processes stopped for a crash loop when the agent restarted before their backoff ended", func() {
+			restart(5)
+
+			restarted := NewWrapperJobSupervisor(
+				fakeSupervisor,
+				fs,
+				dirProvider,
+				probeMonitor,
+				cgroups,
+				timeService,
+				logger,
+			)
+			Expect(restarted.MonitorJobFailures(func(alert.MonitAlert) error { return nil })).To(Succeed())
+
+			Expect(fakeSupervisor.GetStartedProcesses()).To(Equal([]string{"redis"}))
+			Expect(fs.FileExists("/var/vcap/bosh/crash_loop_processes.json")).To(BeFalse())
+			Expect(restarted.Status()).To(Equal("running"))
+		})
+
+		It("forgets crash loops of processes started by hand", func() {
+			restart(5)
+
+			Expect(wrapper.StartProcess("redis")).To(Succeed())
+			Expect(wrapper.Status()).To(Equal("running"))
+			Expect(fs.FileExists("/var/vcap/bosh/crash_loop_processes.json")).To(BeFalse())
+
+			timeService.WaitForWatcherAndIncrement(30 * time.Second)
+
+			Consistently(fakeSupervisor.GetStartedProcesses).Should(Equal([]string{"redis"}))
+		})
+	})
+
+	Describe("MonitorJobEvents", func() {
+		var (
+			eventsLock sync.Mutex
+			events     []JobEvent
+		)
+
+		reportedEvents := func() []JobEvent {
+			eventsLock.Lock()
+			defer eventsLock.Unlock()
+
+			return append([]JobEvent{}, events...)
+		}
+
+		BeforeEach(func() {
+			events = nil
+			fakeSupervisor.StatusStatus = "running"
+			fakeSupervisor.ProcessesStatus = []Process{
+				{Name: "redis", State: "running"},
+				{Name: "nginx", State: "starting"},
+			}
+
+			go func() {
+				defer GinkgoRecover()
+
+				_ = wrapper.MonitorJobEvents(func(event JobEvent) {
+					eventsLock.Lock()
+					defer eventsLock.Unlock()
+
+					events = append(events, event)
+				})
+			}()
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+		})
+
+		It("reports processes whose state changed since the last check", func() {
+			Expect(reportedEvents()).To(BeEmpty())
+
+			fakeSupervisor.ProcessesStatus = []Process{
+				{Name: "redis", State: "failing"},
+				{Name: "nginx", State: "running"},
+				{Name: "worker", State: "not monitored"},
+			}
+			timeService.WaitForWatcherAndIncrement(5 * time.Second)
+
+			Eventually(reportedEvents).Should(Equal([]JobEvent{
+				{Process: "redis", Type: "failed", Timestamp: timeService.Now().Unix(), Description: "state changed from 'running' to 'failing'"},
+				{Process: "nginx", Type: "running", Timestamp: timeService.Now().Unix(), Description: "state changed from 'starting' to 'running'"},
+				{Process: "worker", Type: "stopped", Timestamp: timeService.Now().Unix(), Description: "state is 'not monitored'"},
+			}))
+		})
+
+		It("does not report changes between states of the same event", func() {
+			fakeSupervisor.ProcessesStatus = []Process{
+				{Name: "redis", State: "running"},
+				{Name: "nginx", State: "starting"},
+			}
+			timeService.WaitForWatcherAndIncrement(5 * time.Second)
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+			Expect(reportedEvents()).To(BeEmpty())
+		})
+
+		Context("when the delegate caches process states", func() {
+			var delegate *cachingJobSupervisor
+
+			BeforeEach(func() {
+				delegate = &cachingJobSupervisor{FakeJobSupervisor: fakeSupervisor}
+				delegate.Cache([]Process{{Name: "redis", State: "running"}}, timeService.Now())
+
+				wrapper = NewWrapperJobSupervisor(delegate, fs, dirProvider, probeMonitor, cgroups, timeService, logger)
+
+				go func() {
+					defer GinkgoRecover()
+
+					_ = wrapper.MonitorJobEvents(func(event JobEvent) {
+						eventsLock.Lock()
+						defer eventsLock.Unlock()
+
+						events = append(events, event)
+					})
+				}()
+
+				// Both the wrapper from above and this one are sleeping
+				Eventually(timeService.WatcherCount).Should(Equal(2))
+			})
+
+			It("compares the cached states whenever the cache was refreshed", func() {
+				fakeSupervisor.ProcessesStatus = []Process{{Name: "redis", State: "running"}}
+
+				timeService.Increment(time.Second)
+				delegate.Cache([]Process{{Name: "redis", State: "failing"}}, timeService.Now())
+				fetchedAt := timeService.Now()
+
+				timeService.Increment(5 * time.Second)
+
+				Eventually(reportedEvents).Should(Equal([]JobEvent{
+					{Process: "redis", Type: "failed", Timestamp: fetchedAt.Unix(), Description: "state changed from 'running' to 'failing'"},
+				}))
+				Expect(delegate.ProcessesCalled()).To(BeFalse())
+			})
+		})
+
+		It("reports restarts reported by the delegate", func() {
+			Expect(wrapper.MonitorJobFailures(func(alert.MonitAlert) error { return nil })).To(Succeed())
+
+			err := fakeSupervisor.JobFailureHandler(alert.MonitAlert{
+				Service:     "redis",
+				Event:       "Does not exist",
+				Action:      "restart",
+				Description: "process is not running",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reportedEvents()).To(Equal([]JobEvent{
+				{Process: "redis", Type: "restarted", Timestamp: timeService.Now().Unix(), Description: "process is not running"},
+			}))
+
+			probeMonitor.Stop()
+		})
+	})
 })
+
+// cachingJobSupervisor is a delegate whose process states are cached
+type cachingJobSupervisor struct {
+	*fakes.FakeJobSupervisor
+
+	lock            sync.Mutex
+	processes       []Process
+	fetchedAt       time.Time
+	processesCalled bool
+}
+
+func (s *cachingJobSupervisor) Cache(processes []Process, fetchedAt time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.processes = processes
+	s.fetchedAt = fetchedAt
+}
+
+func (s *cachingJobSupervisor) CachedProcesses() ([]Process, time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]Process{}, s.processes...), s.fetchedAt
+}
+
+func (s *cachingJobSupervisor) Processes() ([]Process, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.processesCalled = true
+
+	return s.FakeJobSupervisor.Processes()
+}
+
+func (s *cachingJobSupervisor) ProcessesCalled() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.processesCalled
+}
@@ -16,7 +16,7 @@ import (
 
 const mainLogTag = "main"
 
-func runAgent(opts boshapp.Options, logger logger.Logger) chan error {
+func runAgent(app boshapp.App, opts boshapp.Options, logger logger.Logger) chan error {
 	errCh := make(chan error, 1)
 
 	go func() {
@@ -24,9 +24,6 @@ func runAgent(opts boshapp.Options, logger logger.Logger) chan error {
 
 		logger.Debug(mainLogTag, "Starting agent")
 
-		fs := boshsys.NewOsFileSystem(logger)
-		app := boshapp.New(logger, fs)
-
 		err := app.Setup(opts)
 		if err != nil {
 			logger.Error(mainLogTag, "App setup %s", err.Error())
@@ -58,10 +55,17 @@ func startAgent(logger logger.Logger) error {
 
 	sigCh := make(chan os.Signal, 8)
 	signal.Notify(sigCh, syscall.SIGTERM, os.Interrupt, os.Kill)
-	errCh := runAgent(opts, logger)
+	fs := boshsys.NewOsFileSystem(logger)
+	app := boshapp.New(logger, fs)
+
+	errCh := runAgent(app, opts, logger)
 	for {
 		select {
 		case sig := <-sigCh:
+			err := app.Stop()
+			if err != nil {
+				logger.Error(mainLogTag, "App stop %s", err.Error())
+			}
 			return fmt.Errorf("received signal (%s): stopping now", sig)
 		case err := <-errCh:
 			return err
@@ -70,8 +74,7 @@ func startAgent(logger logger.Logger) error {
 }
 
 func main() {
-	asyncLog := boshlog.NewAsyncWriterLogger(boshlog.LevelDebug, os.Stdout, os.Stderr)
-	logger := newSignalableLogger(asyncLog)
+	logger := newSignalableLogger(newLogger(os.Args))
 
 	exitCode := 0
 	if err := startAgent(logger); err != nil {
@@ -82,6 +85,17 @@ func main() {
 	os.Exit(exitCode)
 }
 
+func newLogger(args []string) logger.Logger {
+	// Invalid options are reported by startAgent once a logger exists
+	opts, _ := boshapp.ParseOptions(args)
+
+	if opts.LogFormat == boshapp.LogFormatJSON {
+		return agentlogger.NewJSONLogger(boshlog.LevelDebug, os.Stdout, os.Stderr)
+	}
+
+	return boshlog.NewAsyncWriterLogger(boshlog.LevelDebug, os.Stdout, os.Stderr)
+}
+
 func newSignalableLogger(logger logger.Logger) logger.Logger {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGSEGV)
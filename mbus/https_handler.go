@@ -78,7 +78,7 @@ func (h HTTPSHandler) agentHandler(handlerFunc boshhandler.Func) func(http.Respo
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			w.WriteHeader(404)
-			h.generateCEFLog(r, 404, "")
+			h.generateCEFLog(r, 404, "", "")
 
 			return
 		}
@@ -88,12 +88,12 @@ func (h HTTPSHandler) agentHandler(handlerFunc boshhandler.Func) func(http.Respo
 			err = bosherr.WrapError(err, "Reading http body")
 			h.logger.Error(httpsHandlerLogTag, err.Error())
 			w.WriteHeader(400)
-			h.generateCEFLog(r, 400, "")
+			h.generateCEFLog(r, 400, "", "")
 
 			return
 		}
 
-		respBytes, _, err := boshhandler.PerformHandlerWithJSON(
+		respBytes, req, err := boshhandler.PerformHandlerWithJSON(
 			rawJSONPayload,
 			handlerFunc,
 			boshhandler.UnlimitedResponseLength,
@@ -104,7 +104,7 @@ func (h HTTPSHandler) agentHandler(handlerFunc boshhandler.Func) func(http.Respo
 			err = bosherr.WrapError(err, "Running handler in a nice JSON sandwich")
 			h.logger.Error(httpsHandlerLogTag, err.Error())
 			w.WriteHeader(500)
-			h.generateCEFLog(r, 500, "")
+			h.generateCEFLog(r, 500, "", req.RequestID)
 
 			return
 		}
@@ -114,7 +114,7 @@ func (h HTTPSHandler) agentHandler(handlerFunc boshhandler.Func) func(http.Respo
 			err = bosherr.WrapError(err, "Writing response")
 			h.logger.Error(httpsHandlerLogTag, err.Error())
 		}
-		h.generateCEFLog(r, 200, "")
+		h.generateCEFLog(r, 200, "", req.RequestID)
 	}
 }
 
@@ -127,7 +127,7 @@ func (h HTTPSHandler) blobsHandler() (blobsHandler func(http.ResponseWriter, *ht
 			h.putBlob(w, r)
 		default:
 			w.WriteHeader(404)
-			h.generateCEFLog(r, 404, "")
+			h.generateCEFLog(r, 404, "", "")
 		}
 		return
 	}
@@ -141,7 +141,7 @@ func (h HTTPSHandler) putBlob(w http.ResponseWriter, r *http.Request) {
 	err := blobManager.Write(blobID, r.Body)
 	if err != nil {
 		w.WriteHeader(500)
-		h.generateCEFLog(r, 500, "")
+		h.generateCEFLog(r, 500, "", "")
 		if _, wErr := w.Write([]byte(err.Error())); wErr != nil {
 			h.logger.Error(httpsHandlerLogTag, "Failed to write response body: %s", wErr.Error())
 		}
@@ -149,7 +149,7 @@ func (h HTTPSHandler) putBlob(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(201)
-	h.generateCEFLog(r, 201, "")
+	h.generateCEFLog(r, 201, "", "")
 }
 
 func (h HTTPSHandler) getBlob(w http.ResponseWriter, r *http.Request) {
@@ -173,13 +173,13 @@ func (h HTTPSHandler) getBlob(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	h.generateCEFLog(r, statusCode, "")
+	h.generateCEFLog(r, statusCode, "", "")
 }
 
-func (h HTTPSHandler) generateCEFLog(r *http.Request, respStatusCode int, respJSON string) {
+func (h HTTPSHandler) generateCEFLog(r *http.Request, respStatusCode int, respJSON string, requestID string) {
 	cef := boshhandler.NewCommonEventFormat()
 
-	cefString, err := cef.ProduceHTTPRequestEventLog(r, respStatusCode, respJSON)
+	cefString, err := cef.ProduceHTTPRequestEventLog(r, respStatusCode, respJSON, requestID)
 	if err != nil {
 		h.logger.Error(httpsHandlerLogTag, err.Error())
 		return
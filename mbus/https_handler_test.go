@@ -115,7 +115,7 @@ var _ = Describe("HTTPSHandler", func() {
 
 		Describe("POST /agent", func() {
 			It("receives request and responds", func() {
-				postBody := `{"method":"ping","arguments":["foo","bar"], "reply_to": "reply to me!"}`
+				postBody := `{"method":"ping","arguments":["foo","bar"], "reply_to": "reply to me!", "request_id": "fake-request-id"}`
 				postPayload := strings.NewReader(postBody)
 
 				httpResponse, err := httpClient.Post(serverURL+"/agent", "application/json", postPayload)
@@ -127,11 +127,12 @@ var _ = Describe("HTTPSHandler", func() {
 
 				Expect(receivedRequest.ReplyTo).To(Equal("reply to me!"))
 				Expect(receivedRequest.Method).To(Equal("ping"))
+				Expect(receivedRequest.RequestID).To(Equal("fake-request-id"))
 				Expect(receivedRequest.GetPayload()).To(Equal([]byte(postBody)))
 
 				httpBody, readErr := ioutil.ReadAll(httpResponse.Body)
 				Expect(readErr).ToNot(HaveOccurred())
-				Expect(httpBody).To(Equal([]byte(`{"value":"expected value"}`)))
+				Expect(httpBody).To(Equal([]byte(`{"value":"expected value","request_id":"fake-request-id"}`)))
 			})
 
 			Context("when incorrect http method is used", func() {
@@ -164,20 +164,20 @@ func (h *natsHandler) handleNatsMsg(natsMsg *yagnats.Message, handlerFunc boshha
 
 	if err != nil {
 		h.logger.Error(h.logTag, "Running handler: %s", err)
-		h.generateCEFLog(natsMsg, 7, err.Error())
+		h.generateCEFLog(natsMsg, 7, err.Error(), req.RequestID)
 		return
 	}
 
 	if len(respBytes) > 0 {
 		err = h.client.Publish(req.ReplyTo, respBytes)
 		if err != nil {
-			h.generateCEFLog(natsMsg, 7, err.Error())
-			h.logger.Error(h.logTag, "Publishing to the client: %s", err.Error())
+			h.generateCEFLog(natsMsg, 7, err.Error(), req.RequestID)
+			h.logger.Error(h.logTag, "Publishing response to request %s: %s", req.RequestID, err.Error())
 			return
 		}
 	}
 
-	h.generateCEFLog(natsMsg, 1, "")
+	h.generateCEFLog(natsMsg, 1, "", req.RequestID)
 }
 
 func (h *natsHandler) runUntilInterrupted() {
@@ -220,7 +220,7 @@ func (h *natsHandler) getConnectionInfo() (*yagnats.ConnectionInfo, error) {
 	return connInfo, nil
 }
 
-func (h *natsHandler) generateCEFLog(natsMsg *yagnats.Message, severity int, statusReason string) {
+func (h *natsHandler) generateCEFLog(natsMsg *yagnats.Message, severity int, statusReason string, requestID string) {
 	cef := boshhandler.NewCommonEventFormat()
 
 	settings := h.settingsService.GetSettings()
@@ -241,7 +241,7 @@ func (h *natsHandler) generateCEFLog(natsMsg *yagnats.Message, severity int, sta
 	if err != nil {
 		h.logger.Error(natsHandlerLogTag, err.Error())
 	}
-	cefString, err := cef.ProduceNATSRequestEventLog(ip, hostSplit[1], payload.ReplyTo, payload.Method, severity, natsMsg.Subject, statusReason)
+	cefString, err := cef.ProduceNATSRequestEventLog(ip, hostSplit[1], payload.ReplyTo, payload.Method, severity, natsMsg.Subject, statusReason, requestID)
 
 	if err != nil {
 		h.logger.Error(natsHandlerLogTag, err.Error())
@@ -63,7 +63,7 @@ func init() {
 				subscriptions := client.Subscriptions("agent.my-agent-id")
 				Expect(len(subscriptions)).To(Equal(1))
 
-				expectedPayload := []byte(`{"method":"ping","arguments":["foo","bar"], "reply_to": "reply to me!"}`)
+				expectedPayload := []byte(`{"method":"ping","arguments":["foo","bar"], "reply_to": "reply to me!", "request_id": "fake-request-id"}`)
 				subscription := subscriptions[0]
 				subscription.Callback(&yagnats.Message{
 					Subject: "agent.my-agent-id",
@@ -71,15 +71,38 @@ func init() {
 				})
 
 				Expect(receivedRequest).To(Equal(boshhandler.Request{
-					ReplyTo: "reply to me!",
-					Method:  "ping",
-					Payload: expectedPayload,
+					ReplyTo:   "reply to me!",
+					Method:    "ping",
+					Payload:   expectedPayload,
+					RequestID: "fake-request-id",
 				}))
 
 				Expect(client.PublishedMessageCount()).To(Equal(1))
 				messages := client.PublishedMessages("reply to me!")
 				Expect(len(messages)).To(Equal(1))
-				Expect(messages[0].Payload).To(Equal([]byte(`{"value":"expected value"}`)))
+				Expect(messages[0].Payload).To(Equal([]byte(`{"value":"expected value","request_id":"fake-request-id"}`)))
+			})
+
+			It("generates a request id when the request does not include one", func() {
+				var receivedRequest boshhandler.Request
+
+				handler.Start(func(req boshhandler.Request) (resp boshhandler.Response) {
+					receivedRequest = req
+					return boshhandler.NewValueResponse("expected value")
+				})
+				defer handler.Stop()
+
+				subscription := client.Subscriptions("agent.my-agent-id")[0]
+				subscription.Callback(&yagnats.Message{
+					Subject: "agent.my-agent-id",
+					Payload: []byte(`{"method":"ping","arguments":[], "reply_to": "reply to me!"}`),
+				})
+
+				Expect(receivedRequest.RequestID).ToNot(BeEmpty())
+
+				messages := client.PublishedMessages("reply to me!")
+				Expect(len(messages)).To(Equal(1))
+				Expect(string(messages[0].Payload)).To(ContainSubstring(`"request_id":"` + receivedRequest.RequestID + `"`))
 			})
 
 			It("cleans up ip-mac address cache for nats configured with ip address", func() {
@@ -138,7 +161,7 @@ func init() {
 
 					switch req.Method {
 					case "small":
-						size = 1024*1024 - 12
+						size = 1024*1024 - 12 - len(`,"request_id":"fake-request-id"`)
 					case "big":
 						size = 1024 * 1024
 					default:
@@ -157,12 +180,12 @@ func init() {
 				subscription := client.Subscriptions("agent.my-agent-id")[0]
 				subscription.Callback(&yagnats.Message{
 					Subject: "agent.my-agent-id",
-					Payload: []byte(`{"method":"small","arguments":[], "reply_to": "fake-reply-to"}`),
+					Payload: []byte(`{"method":"small","arguments":[], "reply_to": "fake-reply-to", "request_id": "fake-request-id"}`),
 				})
 
 				subscription.Callback(&yagnats.Message{
 					Subject: "agent.my-agent-id",
-					Payload: []byte(`{"method":"big","arguments":[], "reply_to": "fake-reply-to"}`),
+					Payload: []byte(`{"method":"big","arguments":[], "reply_to": "fake-reply-to", "request_id": "fake-request-id"}`),
 				})
 
 				Expect(client.PublishedMessageCount()).To(Equal(1))
@@ -187,7 +210,7 @@ func init() {
 					return boshhandler.NewValueResponse("second-handler-resp")
 				})
 
-				expectedPayload := []byte(`{"method":"ping","arguments":["foo","bar"], "reply_to": "fake-reply-to"}`)
+				expectedPayload := []byte(`{"method":"ping","arguments":["foo","bar"], "reply_to": "fake-reply-to", "request_id": "fake-request-id"}`)
 
 				subscription := client.Subscriptions("agent.my-agent-id")[0]
 				subscription.Callback(&yagnats.Message{
@@ -197,23 +220,25 @@ func init() {
 
 				// Expected requests received by both handlers
 				Expect(firstHandlerReq).To(Equal(boshhandler.Request{
-					ReplyTo: "fake-reply-to",
-					Method:  "ping",
-					Payload: expectedPayload,
+					ReplyTo:   "fake-reply-to",
+					Method:    "ping",
+					Payload:   expectedPayload,
+					RequestID: "fake-request-id",
 				}))
 
 				Expect(secondHandlerRequest).To(Equal(boshhandler.Request{
-					ReplyTo: "fake-reply-to",
-					Method:  "ping",
-					Payload: expectedPayload,
+					ReplyTo:   "fake-reply-to",
+					Method:    "ping",
+					Payload:   expectedPayload,
+					RequestID: "fake-request-id",
 				}))
 
 				// Bosh handler responses were sent
 				Expect(client.PublishedMessageCount()).To(Equal(1))
 				messages := client.PublishedMessages("fake-reply-to")
 				Expect(len(messages)).To(Equal(2))
-				Expect(messages[0].Payload).To(Equal([]byte(`{"value":"first-handler-resp"}`)))
-				Expect(messages[1].Payload).To(Equal([]byte(`{"value":"second-handler-resp"}`)))
+				Expect(messages[0].Payload).To(Equal([]byte(`{"value":"first-handler-resp","request_id":"fake-request-id"}`)))
+				Expect(messages[1].Payload).To(Equal([]byte(`{"value":"second-handler-resp","request_id":"fake-request-id"}`)))
 			})
 
 			It("has the correct connection info", func() {
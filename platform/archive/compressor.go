@@ -0,0 +1,450 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compressor creates and extracts tar archives in process instead of
+// shelling out to tar. Archives are decompressed in whichever format their
+// magic bytes indicate; boshcmd.Compressor methods compress with gzip.
+type Compressor interface {
+	boshcmd.Compressor
+
+	// CompressFilesInDirAs compresses dir in the given format. Unless
+	// algorithm is nil it also returns the digest of the archive, computed
+	// while the archive is written.
+	CompressFilesInDirAs(dir string, format Format, algorithm boshcrypto.Algorithm) (path string, digest boshcrypto.Digest, err error)
+}
+
+type compressor struct {
+	fs boshsys.FileSystem
+}
+
+func NewCompressor(fs boshsys.FileSystem) Compressor {
+	return compressor{fs: fs}
+}
+
+func (c compressor) CompressFilesInDir(dir string) (string, error) {
+	return c.CompressSpecificFilesInDir(dir, []string{"."})
+}
+
+func (c compressor) CompressSpecificFilesInDir(dir string, files []string) (string, error) {
+	path, _, err := c.compress(dir, files, FormatGzip, nil)
+	return path, err
+}
+
+func (c compressor) CompressFilesInDirAs(dir string, format Format, algorithm boshcrypto.Algorithm) (string, boshcrypto.Digest, error) {
+	return c.compress(dir, []string{"."}, format, algorithm)
+}
+
+func (c compressor) CleanUp(path string) error {
+	return c.fs.RemoveAll(path)
+}
+
+func (c compressor) compress(dir string, files []string, format Format, algorithm boshcrypto.Algorithm) (string, boshcrypto.Digest, error) {
+	file, err := c.fs.TempFile("bosh-agent-archive-Compressor-Compress")
+	if err != nil {
+		return "", nil, bosherr.WrapError(err, "Creating temporary file for archive")
+	}
+
+	path := file.Name()
+
+	digest, err := c.writeArchive(file, dir, files, format, algorithm)
+
+	closeErr := file.Close()
+	if err == nil && closeErr != nil {
+		err = bosherr.WrapError(closeErr, "Closing archive")
+	}
+
+	if err != nil {
+		_ = c.fs.RemoveAll(path)
+		return "", nil, err
+	}
+
+	return path, digest, nil
+}
+
+func (c compressor) writeArchive(out io.Writer, dir string, files []string, format Format, algorithm boshcrypto.Algorithm) (boshcrypto.Digest, error) {
+	var digester *streamingDigest
+
+	if algorithm != nil {
+		digester = newStreamingDigest(algorithm)
+		out = io.MultiWriter(out, digester)
+	}
+
+	err := c.writeTar(out, dir, files, format)
+
+	if digester == nil {
+		return nil, err
+	}
+
+	digest, digestErr := digester.Finish(err)
+	if err != nil {
+		return nil, err
+	}
+
+	if digestErr != nil {
+		return nil, bosherr.WrapError(digestErr, "Calculating archive digest")
+	}
+
+	return digest, nil
+}
+
+func (c compressor) writeTar(out io.Writer, dir string, files []string, format Format) error {
+	compressed, err := newCompressingWriter(out, format)
+	if err != nil {
+		return err
+	}
+
+	tarWriter := tar.NewWriter(compressed)
+
+	for _, file := range files {
+		err = c.addFiles(tarWriter, dir, file)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Adding '%s' to archive", file)
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return bosherr.WrapError(err, "Writing archive")
+	}
+
+	err = compressed.Close()
+	if err != nil {
+		return bosherr.WrapError(err, "Compressing archive")
+	}
+
+	return nil
+}
+
+// addFiles names entries the way tar -C dir does, e.g. ./bin/run for "."
+func (c compressor) addFiles(tarWriter *tar.Writer, dir, file string) error {
+	root := filepath.Join(dir, file)
+
+	return c.fs.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Like tar, skip sockets instead of failing
+		if info.Mode()&os.ModeSocket != 0 {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(file)
+		if relPath != "." {
+			name += "/" + filepath.ToSlash(relPath)
+		}
+
+		var linkTarget string
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			linkTarget, err = c.fs.Readlink(path)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Reading symlink '%s'", path)
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, linkTarget)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Building archive header for '%s'", path)
+		}
+
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		err = tarWriter.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		src, err := c.fs.OpenFile(path, os.O_RDONLY, 0)
+		if err != nil {
+			return err
+		}
+
+		defer src.Close()
+
+		_, err = io.Copy(tarWriter, src)
+
+		return err
+	})
+}
+
+func (c compressor) DecompressFileToDir(path string, dir string, options boshcmd.CompressorOptions) error {
+	file, err := c.fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return bosherr.WrapError(err, "Opening archive")
+	}
+
+	defer file.Close()
+
+	reader, err := newDecompressingReader(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	err = c.extract(tar.NewReader(reader), dir, options)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Extracting archive '%s'", path)
+	}
+
+	return nil
+}
+
+func (c compressor) extract(tarReader *tar.Reader, dir string, options boshcmd.CompressorOptions) error {
+	type extractedDir struct {
+		path   string
+		header *tar.Header
+	}
+
+	var dirs []extractedDir
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return bosherr.WrapError(err, "Reading archive")
+		}
+
+		path, err := c.entryPath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag == tar.TypeDir {
+			err = c.fs.MkdirAll(path, os.FileMode(0755))
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Creating directory '%s'", header.Name)
+			}
+
+			dirs = append(dirs, extractedDir{path: path, header: header})
+			continue
+		}
+
+		err = c.fs.MkdirAll(filepath.Dir(path), os.FileMode(0755))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating parent directory of '%s'", header.Name)
+		}
+
+		// Replace what is there without following it if it is a symlink
+		if _, err := c.fs.Lstat(path); err == nil {
+			err = c.fs.RemoveAll(path)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Replacing '%s'", header.Name)
+			}
+		}
+
+		switch {
+		case header.Typeflag == tar.TypeSymlink:
+			err = c.fs.Symlink(header.Linkname, path)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Creating symlink '%s'", header.Name)
+			}
+
+			if options.SameOwner {
+				err = lchown(path, header.Uid, header.Gid)
+				if err != nil {
+					return bosherr.WrapErrorf(err, "Changing owner of '%s'", header.Name)
+				}
+			}
+
+			continue
+
+		case header.Typeflag == tar.TypeLink:
+			target, err := c.entryPath(dir, header.Linkname)
+			if err != nil {
+				return err
+			}
+
+			err = os.Link(target, path)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Creating hard link '%s'", header.Name)
+			}
+
+			continue
+
+		case header.FileInfo().Mode().IsRegular():
+			err = c.writeFile(path, tarReader)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Writing '%s'", header.Name)
+			}
+
+		default:
+			// Like tar without root privileges, skip devices and fifos
+			continue
+		}
+
+		err = c.setAttributes(path, header, options)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Setting attributes of '%s'", header.Name)
+		}
+	}
+
+	// Set attributes of directories last so that extracting their contents
+	// neither fails on read-only directories nor changes their times
+	for i := len(dirs) - 1; i >= 0; i-- {
+		err := c.setAttributes(dirs[i].path, dirs[i].header, options)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Setting attributes of '%s'", dirs[i].header.Name)
+		}
+	}
+
+	return nil
+}
+
+// entryPath keeps archive entries within dir, also refusing to write
+// through symlinks that were extracted earlier
+func (c compressor) entryPath(dir, name string) (string, error) {
+	relPath := filepath.Clean(filepath.FromSlash(strings.TrimLeft(name, "/")))
+
+	if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) || filepath.IsAbs(relPath) {
+		return "", bosherr.Errorf("Archive entry '%s' is outside of the target directory", name)
+	}
+
+	if relPath == "." {
+		return dir, nil
+	}
+
+	parent := dir
+
+	for _, part := range strings.Split(filepath.Dir(relPath), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+
+		parent = filepath.Join(parent, part)
+
+		info, err := c.fs.Lstat(parent)
+		if err != nil {
+			break
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", bosherr.Errorf("Archive entry '%s' is below a symlink", name)
+		}
+	}
+
+	return filepath.Join(dir, relPath), nil
+}
+
+func (c compressor) writeFile(path string, contents io.Reader) error {
+	dst, err := c.fs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(0600))
+	if err != nil {
+		return err
+	}
+
+	defer dst.Close()
+
+	_, err = io.Copy(dst, contents)
+
+	return err
+}
+
+func (c compressor) setAttributes(path string, header *tar.Header, options boshcmd.CompressorOptions) error {
+	if options.SameOwner {
+		err := lchown(path, header.Uid, header.Gid)
+		if err != nil {
+			return err
+		}
+	}
+
+	mode := header.FileInfo().Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+
+	err := c.fs.Chmod(path, mode)
+	if err != nil {
+		return err
+	}
+
+	return os.Chtimes(path, header.ModTime, header.ModTime)
+}
+
+func newCompressingWriter(out io.Writer, format Format) (io.WriteCloser, error) {
+	switch format {
+	case "", FormatGzip:
+		return gzip.NewWriter(out), nil
+
+	case FormatZstd:
+		writer, err := zstd.NewWriter(out)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Creating zstd writer")
+		}
+
+		return writer, nil
+
+	case FormatTar:
+		return nopWriteCloser{out}, nil
+
+	default:
+		return nil, bosherr.Errorf("Compressing archives as '%s' is not supported", format)
+	}
+}
+
+func newDecompressingReader(in *bufio.Reader) (io.ReadCloser, error) {
+	// Short archives are left to the tar reader to reject
+	header, _ := in.Peek(8)
+
+	switch detectFormat(header) {
+	case FormatGzip:
+		reader, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Reading gzip header")
+		}
+
+		return reader, nil
+
+	case FormatZstd:
+		reader, err := zstd.NewReader(in)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Reading zstd header")
+		}
+
+		return reader.IOReadCloser(), nil
+
+	case FormatXz:
+		reader, err := xz.NewReader(in)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Reading xz header")
+		}
+
+		return ioutil.NopCloser(reader), nil
+
+	default:
+		return ioutil.NopCloser(in), nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
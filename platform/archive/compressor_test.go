@@ -0,0 +1,260 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-agent/platform/archive"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/ulikunitz/xz"
+)
+
+type tarEntry struct {
+	name     string
+	contents string
+
+	// target makes the entry a symlink
+	target string
+}
+
+var _ = Describe("compressor", func() {
+	var (
+		fs         boshsys.FileSystem
+		compressor archive.Compressor
+		tmpDir     string
+		srcDir     string
+		dstDir     string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		fs = boshsys.NewOsFileSystem(boshlog.NewLogger(boshlog.LevelNone))
+		compressor = archive.NewCompressor(fs)
+
+		tmpDir, err = ioutil.TempDir("", "archive")
+		Expect(err).ToNot(HaveOccurred())
+
+		srcDir = filepath.Join(tmpDir, "src")
+		dstDir = filepath.Join(tmpDir, "dst")
+		Expect(fs.MkdirAll(dstDir, os.FileMode(0755))).To(Succeed())
+
+		Expect(fs.WriteFileString(filepath.Join(srcDir, "app.stdout.log"), "fake-stdout")).To(Succeed())
+		Expect(fs.WriteFileString(filepath.Join(srcDir, "bin", "run"), "fake-run")).To(Succeed())
+		Expect(fs.Chmod(filepath.Join(srcDir, "bin", "run"), os.FileMode(0750))).To(Succeed())
+		Expect(fs.MkdirAll(filepath.Join(srcDir, "empty"), os.FileMode(0700))).To(Succeed())
+		Expect(fs.Symlink("run", filepath.Join(srcDir, "bin", "start"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	tarEntries := func(reader io.Reader) []string {
+		var names []string
+
+		tarReader := tar.NewReader(reader)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				return names
+			}
+			Expect(err).ToNot(HaveOccurred())
+			names = append(names, header.Name)
+		}
+	}
+
+	writeTar := func(out io.Writer, entries ...tarEntry) {
+		tarWriter := tar.NewWriter(out)
+
+		for _, entry := range entries {
+			header := &tar.Header{Name: entry.name, Linkname: entry.target, Mode: 0644, Typeflag: tar.TypeSymlink}
+			if entry.target == "" {
+				header.Typeflag = tar.TypeReg
+				header.Size = int64(len(entry.contents))
+			}
+
+			Expect(tarWriter.WriteHeader(header)).To(Succeed())
+
+			_, err := tarWriter.Write([]byte(entry.contents))
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(tarWriter.Close()).To(Succeed())
+	}
+
+	expectExtracted := func() {
+		Expect(fs.ReadFileString(filepath.Join(dstDir, "app.stdout.log"))).To(Equal("fake-stdout"))
+		Expect(fs.ReadFileString(filepath.Join(dstDir, "bin", "run"))).To(Equal("fake-run"))
+		Expect(fs.Readlink(filepath.Join(dstDir, "bin", "start"))).To(Equal("run"))
+		Expect(fs.FileExists(filepath.Join(dstDir, "empty"))).To(BeTrue())
+
+		if runtime.GOOS != "windows" {
+			info, err := fs.Stat(filepath.Join(dstDir, "bin", "run"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0750)))
+
+			info, err = fs.Stat(filepath.Join(dstDir, "empty"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+		}
+	}
+
+	Describe("CompressFilesInDir", func() {
+		It("creates a gzipped tarball of the dir with entries named like tar does", func() {
+			path, err := compressor.CompressFilesInDir(srcDir)
+			Expect(err).ToNot(HaveOccurred())
+			defer compressor.CleanUp(path)
+
+			file, err := os.Open(path)
+			Expect(err).ToNot(HaveOccurred())
+			defer file.Close()
+
+			gzipReader, err := gzip.NewReader(file)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tarEntries(gzipReader)).To(ConsistOf(
+				"./", "./app.stdout.log", "./bin/", "./bin/run", "./bin/start", "./empty/",
+			))
+		})
+	})
+
+	Describe("CompressSpecificFilesInDir", func() {
+		It("only adds the given files", func() {
+			path, err := compressor.CompressSpecificFilesInDir(srcDir, []string{"bin"})
+			Expect(err).ToNot(HaveOccurred())
+			defer compressor.CleanUp(path)
+
+			file, err := os.Open(path)
+			Expect(err).ToNot(HaveOccurred())
+			defer file.Close()
+
+			gzipReader, err := gzip.NewReader(file)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tarEntries(gzipReader)).To(ConsistOf("bin/", "bin/run", "bin/start"))
+		})
+	})
+
+	Describe("CompressFilesInDirAs", func() {
+		for _, format := range []archive.Format{archive.FormatGzip, archive.FormatZstd, archive.FormatTar} {
+			format := format
+
+			It(fmt.Sprintf("compresses as %s so that the archive can be decompressed again", format), func() {
+				path, _, err := compressor.CompressFilesInDirAs(srcDir, format, nil)
+				Expect(err).ToNot(HaveOccurred())
+				defer compressor.CleanUp(path)
+
+				Expect(compressor.DecompressFileToDir(path, dstDir, boshcmd.CompressorOptions{})).To(Succeed())
+				expectExtracted()
+			})
+		}
+
+		It("returns the digest of the archive", func() {
+			path, digest, err := compressor.CompressFilesInDirAs(srcDir, archive.FormatZstd, boshcrypto.DigestAlgorithmSHA256)
+			Expect(err).ToNot(HaveOccurred())
+			defer compressor.CleanUp(path)
+
+			contents, err := fs.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(digest).To(Equal(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA256, fmt.Sprintf("%x", sha256.Sum256(contents)))))
+		})
+
+		It("returns error when the digest cannot be calculated", func() {
+			_, _, err := compressor.CompressFilesInDirAs(srcDir, archive.FormatGzip, boshcrypto.NewUnknownAlgorithm("fake-algorithm"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-algorithm"))
+		})
+
+		It("returns error for formats that cannot be created", func() {
+			_, _, err := compressor.CompressFilesInDirAs(srcDir, archive.FormatXz, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Compressing archives as 'xz' is not supported"))
+		})
+
+		It("returns error when the dir does not exist", func() {
+			_, _, err := compressor.CompressFilesInDirAs(filepath.Join(tmpDir, "missing"), archive.FormatGzip, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("DecompressFileToDir", func() {
+		It("decompresses xz archives", func() {
+			var buf bytes.Buffer
+
+			xzWriter, err := xz.NewWriter(&buf)
+			Expect(err).ToNot(HaveOccurred())
+			writeTar(xzWriter, tarEntry{name: "./data", contents: "fake-data"})
+			Expect(xzWriter.Close()).To(Succeed())
+
+			path := filepath.Join(tmpDir, "archive.txz")
+			Expect(fs.WriteFile(path, buf.Bytes())).To(Succeed())
+
+			Expect(compressor.DecompressFileToDir(path, dstDir, boshcmd.CompressorOptions{})).To(Succeed())
+			Expect(fs.ReadFileString(filepath.Join(dstDir, "data"))).To(Equal("fake-data"))
+		})
+
+		It("replaces existing files", func() {
+			Expect(fs.WriteFileString(filepath.Join(dstDir, "bin", "run"), "old-run")).To(Succeed())
+
+			path, err := compressor.CompressFilesInDir(srcDir)
+			Expect(err).ToNot(HaveOccurred())
+			defer compressor.CleanUp(path)
+
+			Expect(compressor.DecompressFileToDir(path, dstDir, boshcmd.CompressorOptions{})).To(Succeed())
+			expectExtracted()
+		})
+
+		It("returns error for entries outside of the dir", func() {
+			var buf bytes.Buffer
+			writeTar(&buf, tarEntry{name: "../outside", contents: "fake-data"})
+
+			path := filepath.Join(tmpDir, "archive.tar")
+			Expect(fs.WriteFile(path, buf.Bytes())).To(Succeed())
+
+			err := compressor.DecompressFileToDir(path, dstDir, boshcmd.CompressorOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("outside of the target directory"))
+			Expect(fs.FileExists(filepath.Join(tmpDir, "outside"))).To(BeFalse())
+		})
+
+		It("returns error for entries below extracted symlinks", func() {
+			var buf bytes.Buffer
+			writeTar(&buf,
+				tarEntry{name: "link", target: tmpDir},
+				tarEntry{name: "link/outside", contents: "fake-data"},
+			)
+
+			path := filepath.Join(tmpDir, "archive.tar")
+			Expect(fs.WriteFile(path, buf.Bytes())).To(Succeed())
+
+			err := compressor.DecompressFileToDir(path, dstDir, boshcmd.CompressorOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("below a symlink"))
+			Expect(fs.FileExists(filepath.Join(tmpDir, "outside"))).To(BeFalse())
+		})
+
+		It("returns error when the archive is corrupt", func() {
+			path := filepath.Join(tmpDir, "archive.tgz")
+			Expect(fs.WriteFile(path, []byte{0x1f, 0x8b, 0x00})).To(Succeed())
+
+			err := compressor.DecompressFileToDir(path, dstDir, boshcmd.CompressorOptions{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,53 @@
+package archive
+
+import (
+	"io"
+
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+)
+
+// streamingDigest computes the digest of everything written to it. Algorithms
+// only digest readers, so the written bytes are piped to a goroutine.
+type streamingDigest struct {
+	writer *io.PipeWriter
+	result chan digestResult
+}
+
+type digestResult struct {
+	digest boshcrypto.Digest
+	err    error
+}
+
+func newStreamingDigest(algorithm boshcrypto.Algorithm) *streamingDigest {
+	reader, writer := io.Pipe()
+
+	d := &streamingDigest{
+		writer: writer,
+		result: make(chan digestResult, 1),
+	}
+
+	go func() {
+		digest, err := algorithm.CreateDigest(reader)
+
+		// Unblock writes in case the algorithm stopped reading early
+		reader.CloseWithError(err)
+
+		d.result <- digestResult{digest: digest, err: err}
+	}()
+
+	return d
+}
+
+func (d *streamingDigest) Write(p []byte) (int, error) {
+	return d.writer.Write(p)
+}
+
+// Finish returns the digest of the written bytes; passing an error aborts
+// the digest instead
+func (d *streamingDigest) Finish(err error) (boshcrypto.Digest, error) {
+	d.writer.CloseWithError(err)
+
+	result := <-d.result
+
+	return result.digest, result.err
+}
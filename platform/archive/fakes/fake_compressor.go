@@ -0,0 +1,31 @@
+package fakes
+
+import (
+	"github.com/cloudfoundry/bosh-agent/platform/archive"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	fakecmd "github.com/cloudfoundry/bosh-utils/fileutil/fakes"
+)
+
+type FakeCompressor struct {
+	*fakecmd.FakeCompressor
+
+	CompressFilesInDirFormat    archive.Format
+	CompressFilesInDirAlgorithm boshcrypto.Algorithm
+	CompressFilesInDirDigest    boshcrypto.Digest
+}
+
+func NewFakeCompressor() *FakeCompressor {
+	return &FakeCompressor{FakeCompressor: fakecmd.NewFakeCompressor()}
+}
+
+func (fc *FakeCompressor) CompressFilesInDirAs(dir string, format archive.Format, algorithm boshcrypto.Algorithm) (string, boshcrypto.Digest, error) {
+	fc.CompressFilesInDirFormat = format
+	fc.CompressFilesInDirAlgorithm = algorithm
+
+	path, err := fc.CompressFilesInDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return path, fc.CompressFilesInDirDigest, nil
+}
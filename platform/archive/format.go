@@ -0,0 +1,45 @@
+package archive
+
+import "bytes"
+
+type Format string
+
+const (
+	FormatGzip Format = "gzip"
+	FormatZstd Format = "zstd"
+
+	// FormatXz archives can be decompressed but not created
+	FormatXz Format = "xz"
+
+	// FormatTar is an uncompressed tar archive
+	FormatTar Format = "tar"
+)
+
+type Options struct {
+	// CompiledPackagesFormat and LogsFormat choose how compiled packages and
+	// fetched logs are compressed. Both default to gzip since that is what
+	// older agents, the director and the CLI are able to read.
+	CompiledPackagesFormat Format
+	LogsFormat             Format
+}
+
+var formatMagics = []struct {
+	format Format
+	magic  []byte
+}{
+	{FormatGzip, []byte{0x1f, 0x8b}},
+	{FormatZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{FormatXz, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+}
+
+// detectFormat recognizes compressed archives by the magic bytes they start
+// with and assumes everything else is a plain tar archive
+func detectFormat(header []byte) Format {
+	for _, m := range formatMagics {
+		if bytes.HasPrefix(header, m.magic) {
+			return m.format
+		}
+	}
+
+	return FormatTar
+}
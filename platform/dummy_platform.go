@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
@@ -41,7 +43,7 @@ type dummyPlatform struct {
 	collector          boshstats.Collector
 	fs                 boshsys.FileSystem
 	cmdRunner          boshsys.CmdRunner
-	compressor         boshcmd.Compressor
+	compressor         bosharchive.Compressor
 	copier             boshcmd.Copier
 	dirProvider        boshdirs.Provider
 	vitalsService      boshvitals.Service
@@ -64,11 +66,11 @@ func NewDummyPlatform(
 		fs:                 fs,
 		cmdRunner:          cmdRunner,
 		collector:          collector,
-		compressor:         boshcmd.NewTarballCompressor(cmdRunner, fs),
+		compressor:         bosharchive.NewCompressor(fs),
 		copier:             boshcmd.NewGenericCpCopier(fs, logger),
 		dirProvider:        dirProvider,
 		devicePathResolver: devicePathResolver,
-		vitalsService:      boshvitals.NewService(collector, dirProvider),
+		vitalsService:      boshvitals.NewService(collector, dirProvider, boshcgroup.NewManager(fs, boshcgroup.DefaultRoot, logger)),
 		certManager:        boshcert.NewDummyCertManager(fs, cmdRunner, 0, logger),
 		logger:             logger,
 		auditLogger:        auditLogger,
@@ -87,7 +89,7 @@ func (p dummyPlatform) GetRunner() (runner boshsys.CmdRunner) {
 	return p.cmdRunner
 }
 
-func (p dummyPlatform) GetCompressor() (compressor boshcmd.Compressor) {
+func (p dummyPlatform) GetCompressor() (compressor bosharchive.Compressor) {
 	return p.compressor
 }
 
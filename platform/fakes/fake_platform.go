@@ -6,6 +6,8 @@ import (
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
 	"github.com/cloudfoundry/bosh-agent/platform"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
+	fakearchive "github.com/cloudfoundry/bosh-agent/platform/archive/fakes"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	fakecert "github.com/cloudfoundry/bosh-agent/platform/cert/fakes"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
@@ -22,7 +24,7 @@ import (
 type FakePlatform struct {
 	Fs                *fakesys.FakeFileSystem
 	Runner            *fakesys.FakeCmdRunner
-	FakeCompressor    *fakecmd.FakeCompressor
+	FakeCompressor    *fakearchive.FakeCompressor
 	FakeCopier        *fakecmd.FakeCopier
 	FakeVitalsService *fakevitals.FakeService
 	fsType            string
@@ -142,6 +144,7 @@ type FakePlatform struct {
 	SetupMonitUserSetup         bool
 	GetMonitCredentialsUsername string
 	GetMonitCredentialsPassword string
+	GetMonitCredentialsErr      error
 
 	PrepareForNetworkingChangeCalled bool
 	PrepareForNetworkingChangeErr    error
@@ -171,7 +174,7 @@ func NewFakePlatform() (platform *FakePlatform) {
 	platform = new(FakePlatform)
 	platform.Fs = fakesys.NewFakeFileSystem()
 	platform.Runner = fakesys.NewFakeCmdRunner()
-	platform.FakeCompressor = fakecmd.NewFakeCompressor()
+	platform.FakeCompressor = fakearchive.NewFakeCompressor()
 	platform.FakeCopier = fakecmd.NewFakeCopier()
 	platform.FakeVitalsService = fakevitals.NewFakeService()
 	platform.DevicePathResolver = fakedpresolv.NewFakeDevicePathResolver()
@@ -207,7 +210,7 @@ func (p *FakePlatform) GetRunner() (runner boshsys.CmdRunner) {
 	return p.Runner
 }
 
-func (p *FakePlatform) GetCompressor() (compressor boshcmd.Compressor) {
+func (p *FakePlatform) GetCompressor() (compressor bosharchive.Compressor) {
 	return p.FakeCompressor
 }
 
@@ -446,6 +449,7 @@ func (p *FakePlatform) SetupMonitUser() (err error) {
 func (p *FakePlatform) GetMonitCredentials() (username, password string, err error) {
 	username = p.GetMonitCredentialsUsername
 	password = p.GetMonitCredentialsPassword
+	err = p.GetMonitCredentialsErr
 	return
 }
 
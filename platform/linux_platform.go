@@ -12,6 +12,7 @@ import (
 	"text/template"
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	boshdevutil "github.com/cloudfoundry/bosh-agent/platform/deviceutil"
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
@@ -86,7 +87,7 @@ type linux struct {
 	fs                     boshsys.FileSystem
 	cmdRunner              boshsys.CmdRunner
 	collector              boshstats.Collector
-	compressor             boshcmd.Compressor
+	compressor             bosharchive.Compressor
 	copier                 boshcmd.Copier
 	dirProvider            boshdirs.Provider
 	vitalsService          boshvitals.Service
@@ -108,7 +109,7 @@ func NewLinuxPlatform(
 	fs boshsys.FileSystem,
 	cmdRunner boshsys.CmdRunner,
 	collector boshstats.Collector,
-	compressor boshcmd.Compressor,
+	compressor bosharchive.Compressor,
 	copier boshcmd.Copier,
 	dirProvider boshdirs.Provider,
 	vitalsService boshvitals.Service,
@@ -175,7 +176,7 @@ func (p linux) GetRunner() (runner boshsys.CmdRunner) {
 	return p.cmdRunner
 }
 
-func (p linux) GetCompressor() (runner boshcmd.Compressor) {
+func (p linux) GetCompressor() (runner bosharchive.Compressor) {
 	return p.compressor
 }
 
@@ -15,6 +15,7 @@ import (
 	. "github.com/cloudfoundry/bosh-agent/platform"
 
 	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
+	fakecgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup/fakes"
 	fakecert "github.com/cloudfoundry/bosh-agent/platform/cert/fakes"
 	fakedevutil "github.com/cloudfoundry/bosh-agent/platform/deviceutil/fakes"
 	fakedisk "github.com/cloudfoundry/bosh-agent/platform/disk/fakes"
@@ -25,6 +26,7 @@ import (
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	fakeuuidgen "github.com/cloudfoundry/bosh-utils/uuid/fakes"
 
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -45,7 +47,7 @@ func describeLinuxPlatform() {
 		devicePathResolver         *fakedpresolv.FakeDevicePathResolver
 		platform                   Platform
 		cdutil                     *fakedevutil.FakeDeviceUtil
-		compressor                 boshcmd.Compressor
+		compressor                 bosharchive.Compressor
 		copier                     boshcmd.Copier
 		vitalsService              boshvitals.Service
 		netManager                 *fakenet.FakeManager
@@ -72,9 +74,9 @@ func describeLinuxPlatform() {
 		diskManager = fakedisk.NewFakeDiskManager()
 		dirProvider = boshdirs.NewProvider("/fake-dir")
 		cdutil = fakedevutil.NewFakeDeviceUtil()
-		compressor = boshcmd.NewTarballCompressor(cmdRunner, fs)
+		compressor = bosharchive.NewCompressor(fs)
 		copier = boshcmd.NewGenericCpCopier(fs, logger)
-		vitalsService = boshvitals.NewService(collector, dirProvider)
+		vitalsService = boshvitals.NewService(collector, dirProvider, fakecgroup.NewFakeManager())
 		netManager = &fakenet.FakeManager{}
 		certManager = new(fakecert.FakeManager)
 		monitRetryStrategy = fakeretry.NewFakeRetryStrategy()
@@ -6,6 +6,7 @@ import (
 	"log"
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -27,7 +28,7 @@ type AuditLoggerProvider interface {
 type Platform interface {
 	GetFs() boshsys.FileSystem
 	GetRunner() boshsys.CmdRunner
-	GetCompressor() boshcmd.Compressor
+	GetCompressor() bosharchive.Compressor
 	GetCopier() boshcmd.Copier
 	GetDirProvider() boshdir.Provider
 	GetVitalsService() boshvitals.Service
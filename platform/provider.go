@@ -6,6 +6,8 @@ import (
 	"github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	"github.com/pivotal-golang/clock"
 
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshcdrom "github.com/cloudfoundry/bosh-agent/platform/cdrom"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
@@ -61,13 +63,13 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 	linuxCdrom := boshcdrom.NewLinuxCdrom("/dev/sr0", udev, runner)
 	linuxCdutil := boshcdrom.NewCdUtil(dirProvider.SettingsDir(), fs, linuxCdrom, logger)
 
-	compressor := boshcmd.NewTarballCompressor(runner, fs)
+	compressor := bosharchive.NewCompressor(fs)
 	copier := boshcmd.NewGenericCpCopier(fs, logger)
 
 	// Kick of stats collection as soon as possible
 	statsCollector.StartCollecting(SigarStatsCollectionInterval, nil)
 
-	vitalsService := boshvitals.NewService(statsCollector, dirProvider)
+	vitalsService := boshvitals.NewService(statsCollector, dirProvider, boshcgroup.NewManager(fs, boshcgroup.DefaultRoot, logger))
 
 	ipResolver := boship.NewResolver(boship.NetworkInterfaceToAddrsFunc)
 
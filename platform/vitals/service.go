@@ -5,6 +5,7 @@ import (
 
 	"github.com/cloudfoundry/gosigar"
 
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -17,12 +18,14 @@ type Service interface {
 type concreteService struct {
 	statsCollector boshstats.Collector
 	dirProvider    boshdirs.Provider
+	cgroups        boshcgroup.Manager
 }
 
-func NewService(statsCollector boshstats.Collector, dirProvider boshdirs.Provider) Service {
+func NewService(statsCollector boshstats.Collector, dirProvider boshdirs.Provider, cgroups boshcgroup.Manager) Service {
 	return concreteService{
 		statsCollector: statsCollector,
 		dirProvider:    dirProvider,
+		cgroups:        cgroups,
 	}
 }
 
@@ -33,6 +36,7 @@ func (s concreteService) Get() (vitals Vitals, err error) {
 		memStats  boshstats.Usage
 		swapStats boshstats.Usage
 		diskStats DiskVitals
+		cgroups   map[string]CGroupVitals
 	)
 
 	loadStats, err = s.statsCollector.GetCPULoad()
@@ -65,6 +69,12 @@ func (s concreteService) Get() (vitals Vitals, err error) {
 		return
 	}
 
+	cgroups, err = s.getCGroupStats()
+	if err != nil {
+		err = bosherr.WrapError(err, "Getting CGroup Stats")
+		return
+	}
+
 	vitals = Vitals{
 		Load: createLoadVitals(loadStats),
 		CPU: CPUVitals{
@@ -75,6 +85,8 @@ func (s concreteService) Get() (vitals Vitals, err error) {
 		Mem:  createMemVitals(memStats),
 		Swap: createMemVitals(swapStats),
 		Disk: diskStats,
+
+		CGroups: cgroups,
 	}
 	return
 }
@@ -115,6 +127,49 @@ func (s concreteService) addDiskStats(diskStats DiskVitals, path, name string) (
 	return
 }
 
+func (s concreteService) getCGroupStats() (map[string]CGroupVitals, error) {
+	if !s.cgroups.Supported() {
+		return nil, nil
+	}
+
+	jobs, err := s.cgroups.Jobs()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	cgroups := make(map[string]CGroupVitals, len(jobs))
+
+	for _, job := range jobs {
+		// Cgroups of jobs being removed disappear while they are read
+		usage, err := s.cgroups.Usage(job)
+		if err != nil {
+			continue
+		}
+
+		cgroups[job] = CGroupVitals{
+			MemoryKb:      fmt.Sprintf("%d", usage.MemoryBytes/1024),
+			MemoryLimitKb: formatLimit(usage.MemoryMaxBytes / 1024),
+			CPUSecs:       fmt.Sprintf("%.2f", float64(usage.CPUUsageUsec)/1e6),
+			Pids:          fmt.Sprintf("%d", usage.Pids),
+			PidsLimit:     formatLimit(int64(usage.PidsMax)),
+		}
+	}
+
+	return cgroups, nil
+}
+
+func formatLimit(limit int64) string {
+	if limit == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%d", limit)
+}
+
 func createMemVitals(memUsage boshstats.Usage) MemoryVitals {
 	return MemoryVitals{
 		Percent: memUsage.Percent().FormatFractionOf100(0),
@@ -1,12 +1,15 @@
 package vitals_test
 
 import (
+	"errors"
 	"runtime"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	fakecgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup/fakes"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
 	. "github.com/cloudfoundry/bosh-agent/platform/vitals"
@@ -16,7 +19,7 @@ import (
 
 const Windows = runtime.GOOS == "windows"
 
-func buildVitalsService() (statsCollector *fakestats.FakeCollector, service Service) {
+func buildVitalsService(cgroups boshcgroup.Manager) (statsCollector *fakestats.FakeCollector, service Service) {
 	dirProvider := boshdirs.NewProvider("/fake/base/dir")
 	statsCollector = &fakestats.FakeCollector{
 		CPULoad: boshstats.CPULoad{
@@ -54,14 +57,14 @@ func buildVitalsService() (statsCollector *fakestats.FakeCollector, service Serv
 		},
 	}
 
-	service = NewService(statsCollector, dirProvider)
+	service = NewService(statsCollector, dirProvider, cgroups)
 	statsCollector.StartCollecting(1*time.Millisecond, nil)
 	return
 }
 
 var _ = Describe("Vitals service", func() {
 	It("vitals construction", func() {
-		_, service := buildVitalsService()
+		_, service := buildVitalsService(fakecgroup.NewFakeManager())
 		vitals, err := service.Get()
 
 		expectedVitals := map[string]interface{}{
@@ -106,7 +109,7 @@ var _ = Describe("Vitals service", func() {
 
 	It("getting vitals when missing disks", func() {
 
-		statsCollector, service := buildVitalsService()
+		statsCollector, service := buildVitalsService(fakecgroup.NewFakeManager())
 		statsCollector.DiskStats = map[string]boshstats.DiskStats{
 			"/": boshstats.DiskStats{
 				DiskUsage:  boshstats.Usage{Used: 100, Total: 200},
@@ -122,10 +125,55 @@ var _ = Describe("Vitals service", func() {
 	})
 	It("get getting vitals on system disk error", func() {
 
-		statsCollector, service := buildVitalsService()
+		statsCollector, service := buildVitalsService(fakecgroup.NewFakeManager())
 		statsCollector.DiskStats = map[string]boshstats.DiskStats{}
 
 		_, err := service.Get()
 		Expect(err).To(HaveOccurred())
 	})
+
+	Describe("cgroups", func() {
+		var cgroups *fakecgroup.FakeManager
+
+		BeforeEach(func() {
+			cgroups = fakecgroup.NewFakeManager()
+			cgroups.Usages["redis"] = boshcgroup.Usage{
+				MemoryBytes:    64 * 1024 * 1024,
+				MemoryMaxBytes: 256 * 1024 * 1024,
+				CPUUsageUsec:   1234567,
+				Pids:           3,
+				PidsMax:        64,
+			}
+			cgroups.Usages["nginx"] = boshcgroup.Usage{MemoryBytes: 2048, Pids: 1}
+		})
+
+		It("includes the usage of the cgroups of jobs", func() {
+			_, service := buildVitalsService(cgroups)
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.CGroups).To(Equal(map[string]CGroupVitals{
+				"redis": {MemoryKb: "65536", MemoryLimitKb: "262144", CPUSecs: "1.23", Pids: "3", PidsLimit: "64"},
+				"nginx": {MemoryKb: "2", CPUSecs: "0.00", Pids: "1"},
+			}))
+		})
+
+		It("omits cgroups when cgroup v2 is not available", func() {
+			cgroups.IsSupported = false
+			_, service := buildVitalsService(cgroups)
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			boshassert.LacksJSONKey(GinkgoT(), vitals, "cgroups")
+		})
+
+		It("returns an error when the cgroups of jobs cannot be listed", func() {
+			cgroups.JobsErr = errors.New("fake-jobs-error")
+			_, service := buildVitalsService(cgroups)
+
+			_, err := service.Get()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-jobs-error"))
+		})
+	})
 })
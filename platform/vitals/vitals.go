@@ -6,6 +6,8 @@ type Vitals struct {
 	Load []string     `json:"load,omitempty"`
 	Mem  MemoryVitals `json:"mem"`
 	Swap MemoryVitals `json:"swap"`
+
+	CGroups map[string]CGroupVitals `json:"cgroups,omitempty"`
 }
 
 type CPUVitals struct {
@@ -25,3 +27,12 @@ type MemoryVitals struct {
 	Kb      string `json:"kb,omitempty"`
 	Percent string `json:"percent,omitempty"`
 }
+
+// CGroupVitals is the usage of the cgroup of a job with resource limits
+type CGroupVitals struct {
+	MemoryKb      string `json:"mem_kb"`
+	MemoryLimitKb string `json:"mem_limit_kb,omitempty"`
+	CPUSecs       string `json:"cpu_secs"`
+	Pids          string `json:"pids"`
+	PidsLimit     string `json:"pids_limit,omitempty"`
+}
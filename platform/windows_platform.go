@@ -11,6 +11,8 @@ import (
 	"strings"
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/jobsupervisor/cgroup"
+	bosharchive "github.com/cloudfoundry/bosh-agent/platform/archive"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
@@ -29,7 +31,7 @@ type WindowsPlatform struct {
 	collector              boshstats.Collector
 	fs                     boshsys.FileSystem
 	cmdRunner              boshsys.CmdRunner
-	compressor             boshcmd.Compressor
+	compressor             bosharchive.Compressor
 	copier                 boshcmd.Copier
 	dirProvider            boshdirs.Provider
 	vitalsService          boshvitals.Service
@@ -58,12 +60,12 @@ func NewWindowsPlatform(
 		fs:                     fs,
 		cmdRunner:              cmdRunner,
 		collector:              collector,
-		compressor:             boshcmd.NewTarballCompressor(cmdRunner, fs),
+		compressor:             bosharchive.NewCompressor(fs),
 		copier:                 boshcmd.NewGenericCpCopier(fs, logger),
 		dirProvider:            dirProvider,
 		netManager:             netManager,
 		devicePathResolver:     devicePathResolver,
-		vitalsService:          boshvitals.NewService(collector, dirProvider),
+		vitalsService:          boshvitals.NewService(collector, dirProvider, boshcgroup.NewManager(fs, boshcgroup.DefaultRoot, logger)),
 		certManager:            certManager,
 		defaultNetworkResolver: defaultNetworkResolver,
 		auditLogger:            auditLogger,
@@ -83,7 +85,7 @@ func (p WindowsPlatform) GetRunner() (runner boshsys.CmdRunner) {
 	return p.cmdRunner
 }
 
-func (p WindowsPlatform) GetCompressor() (compressor boshcmd.Compressor) {
+func (p WindowsPlatform) GetCompressor() (compressor bosharchive.Compressor) {
 	return p.compressor
 }
 
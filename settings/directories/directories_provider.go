@@ -97,3 +97,7 @@ func (p Provider) InstanceDNSDir() string {
 func (p Provider) BlobsDir() string {
 	return filepath.Join(p.DataDir(), "blobs")
 }
+
+func (p Provider) BlobsCacheDir() string {
+	return filepath.Join(p.DataDir(), "blobs_cache")
+}
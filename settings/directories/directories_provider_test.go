@@ -37,6 +37,7 @@ var _ = Describe("Provider", func() {
 		Entry("InstanceDir()", p.InstanceDir(), "/some/dir/instance"),
 		Entry("DisksDir()", p.DisksDir(), "/some/dir/instance/disks"),
 		Entry("BlobsDir()", p.BlobsDir(), "/some/dir/data/blobs"),
+		Entry("BlobsCacheDir()", p.BlobsCacheDir(), "/some/dir/data/blobs_cache"),
 		Entry("InstanceDNSDir()", p.InstanceDNSDir(), "/some/dir/instance/dns"),
 	)
 
@@ -204,6 +204,8 @@ type BoshEnv struct {
 	} `json:"mbus"`
 
 	IPv6 IPv6 `json:"ipv6"`
+
+	Syslog Syslog `json:"syslog"`
 }
 
 type CertKeyPair struct {
@@ -215,6 +217,23 @@ type IPv6 struct {
 	Enable bool `json:"enable"`
 }
 
+// Syslog configures forwarding of agent and job logs to a remote collector
+type Syslog struct {
+	// e.g. "logs.example.com:6514"
+	Address string `json:"address"`
+
+	// "tcp" (default) or "tls"
+	Transport string `json:"transport"`
+
+	CACert string `json:"ca_cert"`
+
+	BufferSizeInMB uint64 `json:"buffer_size"`
+}
+
+func (s Syslog) Enabled() bool {
+	return s.Address != ""
+}
+
 type DNSRecords struct {
 	Version uint64      `json:"Version"`
 	Records [][2]string `json:"records"`
@@ -703,6 +703,29 @@ var _ = Describe("Settings", func() {
 			Expect(env.Bosh.IPv6).To(Equal(IPv6{Enable: true}))
 		})
 
+		It("can configure syslog forwarding", func() {
+			env := Env{}
+			err := json.Unmarshal([]byte(`{"bosh": {} }`), &env)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(env.Bosh.Syslog.Enabled()).To(BeFalse())
+
+			env = Env{}
+			err = json.Unmarshal([]byte(`{"bosh": {"syslog": {
+				"address": "fake-host:6514",
+				"transport": "tls",
+				"ca_cert": "fake-ca-cert",
+				"buffer_size": 50
+			} } }`), &env)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(env.Bosh.Syslog.Enabled()).To(BeTrue())
+			Expect(env.Bosh.Syslog).To(Equal(Syslog{
+				Address:        "fake-host:6514",
+				Transport:      "tls",
+				CACert:         "fake-ca-cert",
+				BufferSizeInMB: 50,
+			}))
+		})
+
 		Context("when swap_size is not specified in the json", func() {
 			It("unmarshalls correctly", func() {
 				var env Env
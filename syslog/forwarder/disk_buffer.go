@@ -0,0 +1,302 @@
+package forwarder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+var ErrBufferFull = errors.New("Buffer is full")
+
+const (
+	segmentExt       = ".seg"
+	cursorFileName   = "cursor"
+	recordHeaderSize = 4
+)
+
+// diskBuffer is a FIFO queue of frames persisted in a directory as a
+// sequence of append-only segment files. Frames are removed only after
+// they were acknowledged so that undelivered frames survive agent restarts.
+// The read position is saved on Close; after a crash frames of the oldest
+// segment may be delivered again.
+type diskBuffer struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	lock     sync.Mutex
+	segments []uint64
+	size     int64
+
+	writer     *os.File
+	writerSeq  uint64
+	writerSize int64
+
+	reader       *os.File
+	readOffset   int64
+	resumeOffset int64
+	peekedLength int64
+
+	notifyCh chan struct{}
+}
+
+func newDiskBuffer(dir string, maxSize, segmentSize int64) (*diskBuffer, error) {
+	err := os.MkdirAll(dir, os.FileMode(0700))
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Creating buffer dir '%s'", dir)
+	}
+
+	b := &diskBuffer{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: segmentSize,
+		notifyCh:    make(chan struct{}, 1),
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing buffer segments")
+	}
+
+	for _, path := range paths {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Checking buffer segment '%s'", path)
+		}
+
+		b.segments = append(b.segments, seq)
+		b.size += info.Size()
+
+		if seq > b.writerSeq {
+			b.writerSeq = seq
+		}
+	}
+
+	sort.Sort(uint64Slice(b.segments))
+
+	cursor, err := ioutil.ReadFile(b.cursorPath())
+	if err == nil && len(b.segments) > 0 {
+		var seq uint64
+		var offset int64
+
+		_, err = fmt.Sscanf(string(cursor), "%d %d", &seq, &offset)
+		if err == nil && seq == b.segments[0] {
+			b.resumeOffset = offset
+		}
+	}
+
+	return b, nil
+}
+
+// Push appends a frame or returns ErrBufferFull when there is no space left
+func (b *diskBuffer) Push(frame []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	recordSize := int64(recordHeaderSize + len(frame))
+
+	if b.size+recordSize > b.maxSize {
+		return ErrBufferFull
+	}
+
+	if b.writer == nil || b.writerSize >= b.segmentSize {
+		err := b.openNextSegment()
+		if err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, recordSize)
+	binary.BigEndian.PutUint32(record, uint32(len(frame)))
+	copy(record[recordHeaderSize:], frame)
+
+	_, err := b.writer.Write(record)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing buffer record")
+	}
+
+	b.writerSize += recordSize
+	b.size += recordSize
+
+	select {
+	case b.notifyCh <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Peek returns the oldest unacknowledged frame without removing it
+func (b *diskBuffer) Peek() ([]byte, bool, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for len(b.segments) > 0 {
+		if b.reader == nil {
+			reader, err := os.Open(b.segmentPath(b.segments[0]))
+			if err != nil {
+				return nil, false, bosherr.WrapError(err, "Opening buffer segment")
+			}
+			b.reader = reader
+			b.readOffset = b.resumeOffset
+			b.resumeOffset = 0
+		}
+
+		frame, err := b.readRecord()
+		if err == nil {
+			return frame, true, nil
+		}
+
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, false, bosherr.WrapError(err, "Reading buffer record")
+		}
+
+		// Drained (or truncated by a crash); remove it and move on
+		err = b.removeOldestSegment()
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return nil, false, nil
+}
+
+// Ack removes the frame last returned by Peek
+func (b *diskBuffer) Ack() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.readOffset += b.peekedLength
+	b.peekedLength = 0
+}
+
+// Notify receives a value after frames were pushed
+func (b *diskBuffer) Notify() <-chan struct{} {
+	return b.notifyCh
+}
+
+func (b *diskBuffer) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	cursor := ""
+	if b.reader != nil {
+		cursor = fmt.Sprintf("%d %d", b.segments[0], b.readOffset)
+
+		_ = b.reader.Close()
+		b.reader = nil
+	}
+
+	err := ioutil.WriteFile(b.cursorPath(), []byte(cursor), os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapError(err, "Saving buffer cursor")
+	}
+
+	if b.writer != nil {
+		err := b.writer.Close()
+		b.writer = nil
+		return err
+	}
+
+	return nil
+}
+
+func (b *diskBuffer) readRecord() ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+
+	_, err := b.reader.ReadAt(header, b.readOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, binary.BigEndian.Uint32(header))
+
+	_, err = b.reader.ReadAt(frame, b.readOffset+recordHeaderSize)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+
+	b.peekedLength = int64(recordHeaderSize + len(frame))
+
+	return frame, nil
+}
+
+func (b *diskBuffer) removeOldestSegment() error {
+	seq := b.segments[0]
+	path := b.segmentPath(seq)
+
+	_ = b.reader.Close()
+	b.reader = nil
+
+	if b.writer != nil && seq == b.writerSeq {
+		_ = b.writer.Close()
+		b.writer = nil
+	}
+
+	info, err := os.Stat(path)
+	if err == nil {
+		b.size -= info.Size()
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing buffer segment '%s'", path)
+	}
+
+	b.segments = b.segments[1:]
+
+	return nil
+}
+
+func (b *diskBuffer) openNextSegment() error {
+	if b.writer != nil {
+		err := b.writer.Close()
+		if err != nil {
+			return bosherr.WrapError(err, "Closing buffer segment")
+		}
+	}
+
+	b.writerSeq++
+
+	writer, err := os.OpenFile(b.segmentPath(b.writerSeq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapError(err, "Creating buffer segment")
+	}
+
+	b.writer = writer
+	b.writerSize = 0
+	b.segments = append(b.segments, b.writerSeq)
+
+	return nil
+}
+
+func (b *diskBuffer) cursorPath() string {
+	return filepath.Join(b.dir, cursorFileName)
+}
+
+func (b *diskBuffer) segmentPath(seq uint64) string {
+	return filepath.Join(b.dir, strconv.FormatUint(seq, 10)+segmentExt)
+}
+
+type uint64Slice []uint64
+
+func (s uint64Slice) Len() int           { return len(s) }
+func (s uint64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
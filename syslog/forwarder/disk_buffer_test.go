@@ -0,0 +1,135 @@
+package forwarder_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/syslog/forwarder"
+)
+
+var _ = Describe("DiskBuffer", func() {
+	var (
+		dir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "disk-buffer")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	peek := func(buffer DiskBuffer) string {
+		frame, found, err := buffer.Peek()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		return string(frame)
+	}
+
+	It("returns frames in the order they were pushed", func() {
+		buffer, err := NewDiskBuffer(dir, 1024, 16)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, frame := range []string{"frame-1", "frame-2", "frame-3"} {
+			Expect(buffer.Push([]byte(frame))).To(Succeed())
+		}
+
+		Expect(peek(buffer)).To(Equal("frame-1"))
+		Expect(peek(buffer)).To(Equal("frame-1"))
+		buffer.Ack()
+		Expect(peek(buffer)).To(Equal("frame-2"))
+		buffer.Ack()
+		Expect(peek(buffer)).To(Equal("frame-3"))
+		buffer.Ack()
+
+		_, found, err := buffer.Peek()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+
+		segments, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(segments).To(BeEmpty())
+	})
+
+	It("notifies when frames are pushed", func() {
+		buffer, err := NewDiskBuffer(dir, 1024, 16)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buffer.Push([]byte("frame-1"))).To(Succeed())
+		Eventually(buffer.Notify()).Should(Receive())
+	})
+
+	It("returns ErrBufferFull when the maximum size would be exceeded", func() {
+		buffer, err := NewDiskBuffer(dir, 22, 1024)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buffer.Push([]byte("frame-1"))).To(Succeed())
+		Expect(buffer.Push([]byte("frame-2"))).To(Succeed())
+		Expect(buffer.Push([]byte("frame-3"))).To(Equal(ErrBufferFull))
+
+		peek(buffer)
+		buffer.Ack()
+		peek(buffer)
+		buffer.Ack()
+		_, _, err = buffer.Peek()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buffer.Push([]byte("frame-3"))).To(Succeed())
+		Expect(peek(buffer)).To(Equal("frame-3"))
+	})
+
+	It("keeps unacknowledged frames across restarts", func() {
+		buffer, err := NewDiskBuffer(dir, 1024, 16)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buffer.Push([]byte("frame-1"))).To(Succeed())
+		Expect(buffer.Push([]byte("frame-2"))).To(Succeed())
+		Expect(buffer.Push([]byte("frame-3"))).To(Succeed())
+
+		Expect(peek(buffer)).To(Equal("frame-1"))
+		buffer.Ack()
+		Expect(peek(buffer)).To(Equal("frame-2"))
+		buffer.Ack()
+		Expect(buffer.Close()).To(Succeed())
+
+		buffer, err = NewDiskBuffer(dir, 1024, 16)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buffer.Push([]byte("frame-4"))).To(Succeed())
+
+		Expect(peek(buffer)).To(Equal("frame-3"))
+		buffer.Ack()
+		Expect(peek(buffer)).To(Equal("frame-4"))
+	})
+
+	It("skips records truncated by a crash", func() {
+		buffer, err := NewDiskBuffer(dir, 1024, 1024)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buffer.Push([]byte("frame-1"))).To(Succeed())
+		Expect(buffer.Close()).To(Succeed())
+
+		segment := filepath.Join(dir, "1.seg")
+		f, err := os.OpenFile(segment, os.O_APPEND|os.O_WRONLY, 0600)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.Write([]byte{0, 0, 0, 9, 'f', 'r'})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		buffer, err = NewDiskBuffer(dir, 1024, 1024)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buffer.Push([]byte("frame-2"))).To(Succeed())
+
+		Expect(peek(buffer)).To(Equal("frame-1"))
+		buffer.Ack()
+		Expect(peek(buffer)).To(Equal("frame-2"))
+	})
+})
@@ -0,0 +1,47 @@
+package forwarder
+
+/*
+Exports internals so that they can be tested from the forwarder_test
+package. Because this is a *_test file it is not included in the build.
+*/
+
+import (
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type DiskBuffer struct{ *diskBuffer }
+
+func NewDiskBuffer(dir string, maxSize, segmentSize int64) (DiskBuffer, error) {
+	b, err := newDiskBuffer(dir, maxSize, segmentSize)
+	return DiskBuffer{b}, err
+}
+
+type Tailer struct{ *tailer }
+
+func NewTailer(sources []string, positionsPath string, logger boshlog.Logger) Tailer {
+	return Tailer{newTailer(sources, positionsPath, logger)}
+}
+
+func (t Tailer) Poll(handler func(path string, line []byte) error) error {
+	return t.tailer.Poll(handler)
+}
+
+func NewForwarderWithInterval(
+	settings boshsettings.Syslog,
+	hostname string,
+	dirProvider boshdirs.Provider,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+	interval time.Duration,
+) Forwarder {
+	f := NewForwarder(settings, hostname, dirProvider, timeService, logger).(*concreteForwarder)
+	f.pollInterval = interval
+	f.minRetryInterval = interval
+	return f
+}
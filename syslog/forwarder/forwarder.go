@@ -0,0 +1,223 @@
+package forwarder
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	forwarderLogTag = "syslogForwarder"
+
+	agentAppName = "bosh-agent"
+
+	defaultBufferSizeInMB = 100
+	segmentSize           = 1024 * 1024
+
+	defaultPollInterval = 1 * time.Second
+	minRetryInterval    = 1 * time.Second
+	maxRetryInterval    = 1 * time.Minute
+)
+
+type Forwarder interface {
+	// Start blocks forwarding logs until Stop is called
+	Start() error
+	Stop() error
+}
+
+// concreteForwarder tails job logs and the agent log and ships every line
+// to a remote syslog collector. Lines are first written to a bounded disk
+// buffer; when the collector is unreachable and the buffer fills up the
+// tailer stops advancing (backpressure) instead of dropping lines.
+type concreteForwarder struct {
+	settings    boshsettings.Syslog
+	hostname    string
+	logsDir     string
+	agentLog    string
+	bufferDir   string
+	timeService clock.Clock
+	logger      boshlog.Logger
+
+	pollInterval     time.Duration
+	minRetryInterval time.Duration
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewForwarder(
+	settings boshsettings.Syslog,
+	hostname string,
+	dirProvider boshdirs.Provider,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Forwarder {
+	return &concreteForwarder{
+		settings:         settings,
+		hostname:         hostname,
+		logsDir:          dirProvider.LogsDir(),
+		agentLog:         filepath.Join(dirProvider.BoshDir(), "log", "current"),
+		bufferDir:        filepath.Join(dirProvider.DataDir(), "syslog_forwarder"),
+		timeService:      timeService,
+		logger:           logger,
+		pollInterval:     defaultPollInterval,
+		minRetryInterval: minRetryInterval,
+		stopCh:           make(chan struct{}),
+	}
+}
+
+func (f *concreteForwarder) Start() error {
+	sender, err := newSender(f.settings.Address, f.settings.Transport, f.settings.CACert)
+	if err != nil {
+		return bosherr.WrapError(err, "Building syslog sender")
+	}
+
+	bufferSizeInMB := f.settings.BufferSizeInMB
+	if bufferSizeInMB == 0 {
+		bufferSizeInMB = defaultBufferSizeInMB
+	}
+
+	buffer, err := newDiskBuffer(
+		filepath.Join(f.bufferDir, "buffer"),
+		int64(bufferSizeInMB)*1024*1024,
+		segmentSize,
+	)
+	if err != nil {
+		return bosherr.WrapError(err, "Opening syslog buffer")
+	}
+
+	tailer := newTailer(
+		[]string{f.logsDir, f.agentLog},
+		filepath.Join(f.bufferDir, "positions.json"),
+		f.logger,
+	)
+
+	f.logger.Info(forwarderLogTag, "Forwarding logs to '%s'", f.settings.Address)
+
+	f.wg.Add(1)
+	go f.send(sender, buffer)
+
+	f.tail(tailer, buffer)
+
+	f.wg.Wait()
+
+	return buffer.Close()
+}
+
+func (f *concreteForwarder) Stop() error {
+	f.stopOnce.Do(func() { close(f.stopCh) })
+	return nil
+}
+
+func (f *concreteForwarder) tail(tailer *tailer, buffer *diskBuffer) {
+	full := false
+
+	for {
+		err := tailer.Poll(func(path string, line []byte) error {
+			msg := message{
+				Timestamp: f.timeService.Now(),
+				Hostname:  f.hostname,
+				AppName:   f.appName(path),
+				Severity:  severity(path),
+				Path:      path,
+				Content:   string(line),
+			}
+			return buffer.Push(msg.Frame())
+		})
+
+		switch {
+		case err == ErrBufferFull:
+			if !full {
+				f.logger.Warn(forwarderLogTag, "Buffer is full, pausing log tailing")
+			}
+			full = true
+		case err != nil:
+			f.logger.Error(forwarderLogTag, "Tailing logs: %s", err.Error())
+		default:
+			full = false
+		}
+
+		select {
+		case <-f.stopCh:
+			return
+		case <-f.timeService.NewTimer(f.pollInterval).C():
+		}
+	}
+}
+
+func (f *concreteForwarder) send(sender *sender, buffer *diskBuffer) {
+	defer f.wg.Done()
+	defer func() { _ = sender.Close() }()
+
+	retryInterval := f.minRetryInterval
+
+	for {
+		frame, found, err := buffer.Peek()
+		if err != nil {
+			f.logger.Error(forwarderLogTag, "Reading buffer: %s", err.Error())
+		}
+
+		if !found {
+			select {
+			case <-f.stopCh:
+				return
+			case <-buffer.Notify():
+			case <-f.timeService.NewTimer(f.pollInterval).C():
+			}
+			continue
+		}
+
+		err = sender.Send(frame)
+		if err != nil {
+			f.logger.Warn(forwarderLogTag, "Retrying in %s: %s", retryInterval, err.Error())
+
+			select {
+			case <-f.stopCh:
+				return
+			case <-f.timeService.NewTimer(retryInterval).C():
+			}
+
+			retryInterval *= 2
+			if retryInterval > maxRetryInterval {
+				retryInterval = maxRetryInterval
+			}
+			continue
+		}
+
+		buffer.Ack()
+		retryInterval = f.minRetryInterval
+
+		select {
+		case <-f.stopCh:
+			return
+		default:
+		}
+	}
+}
+
+// appName returns the job name for files in the logs dir,
+// e.g. "/var/vcap/sys/log/redis/redis.stdout.log" is logged as "redis"
+func (f *concreteForwarder) appName(path string) string {
+	relPath, err := filepath.Rel(f.logsDir, path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return agentAppName
+	}
+
+	return strings.SplitN(filepath.ToSlash(relPath), "/", 2)[0]
+}
+
+func severity(path string) int {
+	if strings.Contains(filepath.Base(path), "stderr") {
+		return severityWarning
+	}
+	return severityInfo
+}
@@ -0,0 +1,197 @@
+package forwarder_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
+	. "github.com/cloudfoundry/bosh-agent/syslog/forwarder"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("Forwarder", func() {
+	var (
+		baseDir     string
+		dirProvider boshdirs.Provider
+		port        uint16
+		logger      boshlog.Logger
+		server      boshsyslog.Server
+		forwarder   Forwarder
+		msgs        chan boshsyslog.Msg
+		startErrCh  chan error
+	)
+
+	grabEphemeralPort := func() uint16 {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		defer l.Close()
+
+		_, portStr, err := net.SplitHostPort(l.Addr().String())
+		Expect(err).ToNot(HaveOccurred())
+
+		port, err := strconv.Atoi(portStr)
+		Expect(err).ToNot(HaveOccurred())
+
+		return uint16(port)
+	}
+
+	appendTo := func(path, content string) {
+		err := os.MkdirAll(filepath.Dir(path), 0700)
+		Expect(err).ToNot(HaveOccurred())
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.WriteString(content)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+	}
+
+	startServer := func() {
+		server = boshsyslog.NewServer(port, net.Listen, nil, logger)
+		go func() {
+			defer GinkgoRecover()
+			_ = server.Start(func(msg boshsyslog.Msg) { msgs <- msg })
+		}()
+	}
+
+	startForwarder := func(settings boshsettings.Syslog) {
+		forwarder = NewForwarderWithInterval(
+			settings,
+			"fake-agent-id",
+			dirProvider,
+			clock.NewClock(),
+			logger,
+			10*time.Millisecond,
+		)
+		go func() {
+			startErrCh <- forwarder.Start()
+		}()
+	}
+
+	BeforeEach(func() {
+		var err error
+		baseDir, err = ioutil.TempDir("", "forwarder")
+		Expect(err).ToNot(HaveOccurred())
+
+		dirProvider = boshdirs.NewProvider(baseDir)
+		port = grabEphemeralPort()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		msgs = make(chan boshsyslog.Msg, 10)
+		startErrCh = make(chan error, 1)
+		server = nil
+		forwarder = nil
+	})
+
+	AfterEach(func() {
+		if forwarder != nil {
+			Expect(forwarder.Stop()).To(Succeed())
+			Eventually(startErrCh).Should(Receive(BeNil()))
+		}
+		if server != nil {
+			_ = server.Stop()
+		}
+		_ = os.RemoveAll(baseDir)
+	})
+
+	It("forwards job and agent log lines as RFC 5424 messages", func() {
+		jobLog := filepath.Join(dirProvider.LogsDir(), "redis", "redis.stderr.log")
+		agentLog := filepath.Join(dirProvider.BoshDir(), "log", "current")
+		appendTo(jobLog, "old line\n")
+		appendTo(agentLog, "")
+
+		startServer()
+		startForwarder(boshsettings.Syslog{Address: "127.0.0.1:" + strconv.Itoa(int(port))})
+
+		// Wait for initial scan which skips existing content
+		Eventually(func() bool {
+			_, err := os.Stat(filepath.Join(dirProvider.DataDir(), "syslog_forwarder", "positions.json"))
+			return err == nil
+		}).Should(BeTrue())
+
+		appendTo(jobLog, "job line\n")
+
+		var msg boshsyslog.Msg
+		Eventually(msgs, 5*time.Second).Should(Receive(&msg))
+		Expect(msg.Format).To(Equal(boshsyslog.FormatRFC5424))
+		Expect(msg.Content).To(Equal("job line"))
+		Expect(msg.Hostname).To(Equal("fake-agent-id"))
+		Expect(msg.AppName).To(Equal("redis"))
+		Expect(msg.Severity).To(Equal(4))
+		Expect(msg.StructuredData).To(Equal(boshsyslog.StructuredData{
+			"file@47450": {"path": jobLog},
+		}))
+
+		appendTo(agentLog, "agent line\n")
+
+		Eventually(msgs, 5*time.Second).Should(Receive(&msg))
+		Expect(msg.Content).To(Equal("agent line"))
+		Expect(msg.AppName).To(Equal("bosh-agent"))
+		Expect(msg.Severity).To(Equal(6))
+	})
+
+	It("buffers lines while the collector is unreachable", func() {
+		jobLog := filepath.Join(dirProvider.LogsDir(), "redis", "redis.stdout.log")
+		appendTo(jobLog, "")
+
+		startForwarder(boshsettings.Syslog{Address: "127.0.0.1:" + strconv.Itoa(int(port))})
+
+		Eventually(func() bool {
+			_, err := os.Stat(filepath.Join(dirProvider.DataDir(), "syslog_forwarder", "positions.json"))
+			return err == nil
+		}).Should(BeTrue())
+
+		appendTo(jobLog, "line 1\nline 2\n")
+
+		Eventually(func() ([]string, error) {
+			return filepath.Glob(filepath.Join(dirProvider.DataDir(), "syslog_forwarder", "buffer", "*.seg"))
+		}).ShouldNot(BeEmpty())
+
+		startServer()
+
+		var msg boshsyslog.Msg
+		Eventually(msgs, 5*time.Second).Should(Receive(&msg))
+		Expect(msg.Content).To(Equal("line 1"))
+		Eventually(msgs, 5*time.Second).Should(Receive(&msg))
+		Expect(msg.Content).To(Equal("line 2"))
+	})
+
+	It("returns an error for unknown transports", func() {
+		forwarder := NewForwarder(
+			boshsettings.Syslog{Address: "127.0.0.1:514", Transport: "fake-transport"},
+			"fake-agent-id",
+			dirProvider,
+			clock.NewClock(),
+			logger,
+		)
+
+		err := forwarder.Start()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unknown syslog transport 'fake-transport'"))
+	})
+
+	It("returns an error for invalid CA certificates", func() {
+		forwarder := NewForwarder(
+			boshsettings.Syslog{Address: "127.0.0.1:514", Transport: "tls", CACert: "fake-ca-cert"},
+			"fake-agent-id",
+			dirProvider,
+			clock.NewClock(),
+			logger,
+		)
+
+		err := forwarder.Start()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing syslog CA certificate"))
+	})
+})
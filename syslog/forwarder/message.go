@@ -0,0 +1,77 @@
+package forwarder
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Facility and severities used when forwarding log lines,
+// see https://tools.ietf.org/html/rfc5424#section-6.2.1
+const (
+	facilityUser    = 1
+	severityWarning = 4
+	severityInfo    = 6
+)
+
+// Private enterprise number registered to the Cloud Foundry Foundation
+const enterpriseNumber = "47450"
+
+const nilValue = "-"
+
+// RFC 5424 allows at most six digits of fractional seconds
+const timestampFormat = "2006-01-02T15:04:05.999999Z07:00"
+
+type message struct {
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	Severity  int
+	Path      string
+	Content   string
+}
+
+// Frame formats the message as an octet-counted RFC 5424 frame
+// (https://tools.ietf.org/html/rfc6587#section-3.4.1)
+func (m message) Frame() []byte {
+	msg := m.format()
+	return []byte(strconv.Itoa(len(msg)) + " " + msg)
+}
+
+func (m message) format() string {
+	priority := facilityUser*8 + m.Severity
+
+	return "<" + strconv.Itoa(priority) + ">1 " +
+		m.Timestamp.UTC().Format(timestampFormat) + " " +
+		headerField(m.Hostname, 255) + " " +
+		headerField(m.AppName, 48) + " " +
+		nilValue + " " +
+		nilValue + " " +
+		"[file@" + enterpriseNumber + " path=\"" + escapeParamValue(m.Path) + "\"] " +
+		m.Content
+}
+
+// headerField replaces characters not allowed in RFC 5424 header fields
+// (PRINTUSASCII) and truncates the value to the maximum field length
+func headerField(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+
+	if value == "" {
+		return nilValue
+	}
+
+	if len(value) > maxLen {
+		return value[:maxLen]
+	}
+
+	return value
+}
+
+func escapeParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
@@ -0,0 +1,100 @@
+package forwarder
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	dialTimeout  = 10 * time.Second
+	writeTimeout = 30 * time.Second
+)
+
+type sender struct {
+	address   string
+	tlsConfig *tls.Config
+
+	conn net.Conn
+}
+
+func newSender(address, transport, caCert string) (*sender, error) {
+	s := &sender{address: address}
+
+	switch transport {
+	case "", "tcp":
+		return s, nil
+
+	case "tls":
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing syslog address '%s'", address)
+		}
+
+		s.tlsConfig = &tls.Config{
+			ServerName: host,
+			MinVersion: tls.VersionTLS12,
+		}
+
+		if caCert != "" {
+			certPool := x509.NewCertPool()
+			if !certPool.AppendCertsFromPEM([]byte(caCert)) {
+				return nil, bosherr.Error("Parsing syslog CA certificate")
+			}
+			s.tlsConfig.RootCAs = certPool
+		}
+
+		return s, nil
+
+	default:
+		return nil, bosherr.Errorf("Unknown syslog transport '%s'", transport)
+	}
+}
+
+// Send writes a frame, connecting first if necessary. The connection is
+// dropped on failure so that the next Send reconnects.
+func (s *sender) Send(frame []byte) error {
+	if s.conn == nil {
+		conn, err := s.dial()
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Connecting to '%s'", s.address)
+		}
+		s.conn = conn
+	}
+
+	err := s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err == nil {
+		_, err = s.conn.Write(frame)
+	}
+
+	if err != nil {
+		_ = s.Close()
+		return bosherr.WrapErrorf(err, "Writing to '%s'", s.address)
+	}
+
+	return nil
+}
+
+func (s *sender) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
+
+func (s *sender) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	if s.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, "tcp", s.address, s.tlsConfig)
+	}
+
+	return dialer.Dial("tcp", s.address)
+}
@@ -0,0 +1,216 @@
+package forwarder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const tailerLogTag = "logTailer"
+
+// maxLineLength bounds lines so that a file without newlines cannot
+// grow a single message indefinitely
+const maxLineLength = 64 * 1024
+
+// readChunkSize bounds how much of a single file is read per poll
+const readChunkSize = 1024 * 1024
+
+type tailedFile struct {
+	Offset int64 `json:"offset"`
+
+	info os.FileInfo
+	seen bool
+}
+
+type lineHandler func(path string, line []byte) error
+
+// tailer follows log files, handing complete lines to a lineHandler.
+// Each source is either a file or a directory whose *.log files are tailed.
+// Offsets are persisted so that lines are neither lost nor repeated
+// across agent restarts.
+type tailer struct {
+	sources       []string
+	positionsPath string
+	logger        boshlog.Logger
+
+	files       map[string]*tailedFile
+	initialScan bool
+}
+
+func newTailer(sources []string, positionsPath string, logger boshlog.Logger) *tailer {
+	t := &tailer{
+		sources:       sources,
+		positionsPath: positionsPath,
+		logger:        logger,
+		files:         map[string]*tailedFile{},
+		initialScan:   true,
+	}
+
+	contents, err := ioutil.ReadFile(positionsPath)
+	if err == nil {
+		err = json.Unmarshal(contents, &t.files)
+		if err != nil {
+			logger.Warn(tailerLogTag, "Ignoring invalid positions file: %s", err.Error())
+			t.files = map[string]*tailedFile{}
+		}
+		t.initialScan = false
+	}
+
+	return t
+}
+
+// Poll reads new lines from all sources. When the handler fails
+// (e.g. because the buffer is full) the file offset is not advanced
+// past the failing line, so it is retried on the next poll.
+func (t *tailer) Poll(handler lineHandler) error {
+	for _, src := range t.sources {
+		for _, path := range t.expand(src) {
+			err := t.pollFile(path, handler)
+			if err != nil {
+				_ = t.savePositions()
+				return err
+			}
+		}
+	}
+
+	for path, file := range t.files {
+		if !file.seen {
+			delete(t.files, path)
+		}
+		file.seen = false
+	}
+
+	t.initialScan = false
+
+	return t.savePositions()
+}
+
+func (t *tailer) expand(src string) []string {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil
+	}
+
+	if !info.IsDir() {
+		return []string{src}
+	}
+
+	var paths []string
+
+	_ = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.HasSuffix(path, ".log") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+
+	return paths
+}
+
+func (t *tailer) pollFile(path string, handler lineHandler) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	file, found := t.files[path]
+
+	switch {
+	case !found && t.initialScan:
+		// Do not ship history that was written before forwarding was enabled
+		file = &tailedFile{Offset: info.Size()}
+	case !found:
+		file = &tailedFile{}
+	case file.info != nil && !os.SameFile(file.info, info):
+		t.logger.Debug(tailerLogTag, "File '%s' was rotated", path)
+		file.Offset = 0
+	case info.Size() < file.Offset:
+		t.logger.Debug(tailerLogTag, "File '%s' was truncated", path)
+		file.Offset = 0
+	}
+
+	file.info = info
+	file.seen = true
+	t.files[path] = file
+
+	if info.Size() == file.Offset {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	_, err = f.Seek(file.Offset, 0)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Seeking in '%s'", path)
+	}
+
+	remaining := info.Size() - file.Offset
+	if remaining > readChunkSize {
+		remaining = readChunkSize
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(f, remaining))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading '%s'", path)
+	}
+
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		advance := end + 1
+
+		if end == -1 {
+			if len(data) < maxLineLength {
+				// Wait for the rest of the line
+				return nil
+			}
+			end = maxLineLength
+			advance = maxLineLength
+		} else if end > maxLineLength {
+			end = maxLineLength
+			advance = maxLineLength
+		}
+
+		line := bytes.TrimRight(data[:end], "\r")
+		if len(line) > 0 {
+			err = handler(path, line)
+			if err != nil {
+				return err
+			}
+		}
+
+		file.Offset += int64(advance)
+		data = data[advance:]
+	}
+
+	return nil
+}
+
+func (t *tailer) savePositions() error {
+	contents, err := json.Marshal(t.files)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling positions")
+	}
+
+	tmpPath := t.positionsPath + ".tmp"
+
+	err = ioutil.WriteFile(tmpPath, contents, os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapError(err, "Writing positions")
+	}
+
+	return os.Rename(tmpPath, t.positionsPath)
+}
@@ -0,0 +1,164 @@
+package forwarder_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/syslog/forwarder"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("Tailer", func() {
+	var (
+		dir           string
+		logsDir       string
+		agentLog      string
+		positionsPath string
+		logger        boshlog.Logger
+		lines         []string
+	)
+
+	collect := func(path string, line []byte) error {
+		rel, err := filepath.Rel(dir, path)
+		Expect(err).ToNot(HaveOccurred())
+		lines = append(lines, rel+": "+string(line))
+		return nil
+	}
+
+	appendTo := func(path, content string) {
+		err := os.MkdirAll(filepath.Dir(path), 0700)
+		Expect(err).ToNot(HaveOccurred())
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.WriteString(content)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "tailer")
+		Expect(err).ToNot(HaveOccurred())
+
+		logsDir = filepath.Join(dir, "sys", "log")
+		agentLog = filepath.Join(dir, "bosh", "log", "current")
+		positionsPath = filepath.Join(dir, "positions.json")
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		lines = nil
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("only ships lines written after the first poll", func() {
+		appendTo(filepath.Join(logsDir, "redis", "redis.stdout.log"), "old line\n")
+		appendTo(agentLog, "old agent line\n")
+
+		tailer := NewTailer([]string{logsDir, agentLog}, positionsPath, logger)
+		Expect(tailer.Poll(collect)).To(Succeed())
+		Expect(lines).To(BeEmpty())
+
+		appendTo(filepath.Join(logsDir, "redis", "redis.stdout.log"), "line 1\nline 2\n")
+		appendTo(agentLog, "agent line\n")
+		appendTo(filepath.Join(logsDir, "nginx", "access.log"), "new file line\n")
+		appendTo(filepath.Join(logsDir, "nginx", "ignored.txt"), "ignored\n")
+
+		Expect(tailer.Poll(collect)).To(Succeed())
+		Expect(lines).To(ConsistOf(
+			"sys/log/redis/redis.stdout.log: line 1",
+			"sys/log/redis/redis.stdout.log: line 2",
+			"sys/log/nginx/access.log: new file line",
+			"bosh/log/current: agent line",
+		))
+	})
+
+	It("waits for lines to be completed", func() {
+		path := filepath.Join(logsDir, "redis", "redis.stdout.log")
+		appendTo(path, "")
+
+		tailer := NewTailer([]string{logsDir}, positionsPath, logger)
+		Expect(tailer.Poll(collect)).To(Succeed())
+
+		appendTo(path, "partial")
+		Expect(tailer.Poll(collect)).To(Succeed())
+		Expect(lines).To(BeEmpty())
+
+		appendTo(path, " line\n")
+		Expect(tailer.Poll(collect)).To(Succeed())
+		Expect(lines).To(Equal([]string{"sys/log/redis/redis.stdout.log: partial line"}))
+	})
+
+	It("starts from the beginning of truncated or rotated files", func() {
+		path := filepath.Join(logsDir, "redis", "redis.stdout.log")
+		appendTo(path, "")
+
+		tailer := NewTailer([]string{logsDir}, positionsPath, logger)
+		Expect(tailer.Poll(collect)).To(Succeed())
+
+		appendTo(path, "line 1 before truncation\n")
+		Expect(tailer.Poll(collect)).To(Succeed())
+
+		Expect(os.Truncate(path, 0)).To(Succeed())
+		appendTo(path, "line 2\n")
+		Expect(tailer.Poll(collect)).To(Succeed())
+
+		Expect(os.Rename(path, path+".1")).To(Succeed())
+		appendTo(path, "line 3 after rotation\n")
+		Expect(tailer.Poll(collect)).To(Succeed())
+
+		Expect(lines).To(Equal([]string{
+			"sys/log/redis/redis.stdout.log: line 1 before truncation",
+			"sys/log/redis/redis.stdout.log: line 2",
+			"sys/log/redis/redis.stdout.log: line 3 after rotation",
+		}))
+	})
+
+	It("retries lines that could not be handled", func() {
+		path := filepath.Join(logsDir, "redis", "redis.stdout.log")
+		appendTo(path, "")
+
+		tailer := NewTailer([]string{logsDir}, positionsPath, logger)
+		Expect(tailer.Poll(collect)).To(Succeed())
+
+		appendTo(path, "line 1\nline 2\n")
+
+		handlerErr := errors.New("fake-buffer-full")
+		err := tailer.Poll(func(path string, line []byte) error {
+			if string(line) == "line 2" {
+				return handlerErr
+			}
+			return collect(path, line)
+		})
+		Expect(err).To(Equal(handlerErr))
+
+		Expect(tailer.Poll(collect)).To(Succeed())
+		Expect(lines).To(Equal([]string{
+			"sys/log/redis/redis.stdout.log: line 1",
+			"sys/log/redis/redis.stdout.log: line 2",
+		}))
+	})
+
+	It("resumes from persisted positions", func() {
+		path := filepath.Join(logsDir, "redis", "redis.stdout.log")
+		appendTo(path, "old line\n")
+
+		tailer := NewTailer([]string{logsDir}, positionsPath, logger)
+		Expect(tailer.Poll(collect)).To(Succeed())
+
+		appendTo(path, "written while agent was down\n")
+
+		tailer = NewTailer([]string{logsDir}, positionsPath, logger)
+		Expect(tailer.Poll(collect)).To(Succeed())
+
+		Expect(lines).To(Equal([]string{
+			"sys/log/redis/redis.stdout.log: written while agent was down",
+		}))
+	})
+})
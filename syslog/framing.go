@@ -0,0 +1,55 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const maxFrameLenDigits = 9
+
+// scanFrames is a bufio.SplitFunc that understands both TCP framing methods
+// described in https://tools.ietf.org/html/rfc6587#section-3.4: frames
+// starting with a digit are octet-counted ("MSG-LEN SP SYSLOG-MSG"),
+// all others are terminated by a newline.
+func scanFrames(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if data[0] >= '0' && data[0] <= '9' {
+		return scanOctetCountedFrame(data, atEOF)
+	}
+
+	return bufio.ScanLines(data, atEOF)
+}
+
+func scanOctetCountedFrame(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	space := bytes.IndexByte(data, ' ')
+	if space == -1 {
+		if len(data) > maxFrameLenDigits {
+			return 0, nil, bosherr.Error("Octet count too long")
+		}
+		if atEOF {
+			return 0, nil, bosherr.Error("Truncated octet count")
+		}
+		return 0, nil, nil
+	}
+
+	length, err := strconv.Atoi(string(data[:space]))
+	if err != nil {
+		return 0, nil, bosherr.WrapError(err, "Parsing octet count")
+	}
+
+	end := space + 1 + length
+	if end > len(data) {
+		if atEOF {
+			return 0, nil, bosherr.Errorf("Truncated frame, expected %d octets", length)
+		}
+		return 0, nil, nil
+	}
+
+	return end, bytes.TrimRight(data[space+1:end], "\n"), nil
+}
@@ -0,0 +1,195 @@
+package syslog
+
+import (
+	"bytes"
+	"regexp"
+	"time"
+
+	"github.com/jeromer/syslogparser"
+	"github.com/jeromer/syslogparser/rfc3164"
+	"github.com/jeromer/syslogparser/rfc5424"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// RFC 5424 messages carry a version number right after the priority
+// (e.g. "<34>1 2003-10-11T22:14:15.003Z ..."); RFC 3164 messages do not.
+var rfc5424Header = regexp.MustCompile(`^<\d{1,3}>\d{1,2} `)
+
+// The vendored parsers index past the end of messages that end within their
+// header, so only messages with a complete header are passed to them. An RFC
+// 5424 header is followed by at least the start of its structured data and an
+// RFC 3164 header by the end of its tag.
+var (
+	rfc5424CompleteHeader = regexp.MustCompile(
+		`^<\d{1,3}>\d{1,2} (-|\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d{1,6})?(Z|[+-]\d\d:\d\d)) [^ ]* [^ ]{0,47} [^ ]{0,127} [^ ]{0,31} .`,
+	)
+	rfc3164CompleteHeader = regexp.MustCompile(
+		`^<\d{1,3}>[A-Za-z]{3} ( \d|\d\d) \d\d:\d\d:\d\d( [^ ]*|[^ ]+) [^ :]*[ :]`,
+	)
+)
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+func parseMessage(buff []byte) (Msg, error) {
+	if rfc5424Header.Match(buff) {
+		if !rfc5424CompleteHeader.Match(buff) {
+			return Msg{}, bosherr.Error("Message does not have a complete RFC 5424 header")
+		}
+
+		return parseRFC5424(buff)
+	}
+
+	if !rfc3164CompleteHeader.Match(buff) {
+		return Msg{}, bosherr.Error("Message does not have a complete RFC 3164 header")
+	}
+
+	return parseRFC3164(buff)
+}
+
+func parseRFC3164(buff []byte) (Msg, error) {
+	p := rfc3164.NewParser(buff)
+
+	err := p.Parse()
+	if err != nil {
+		return Msg{}, err
+	}
+
+	parts := p.Dump()
+
+	content, ok := parts["content"].(string)
+	if !ok {
+		return Msg{}, bosherr.Error("Retrieving syslog message string content")
+	}
+
+	return Msg{
+		Format:    FormatRFC3164,
+		Content:   content,
+		Hostname:  stringPart(parts, "hostname"),
+		AppName:   stringPart(parts, "tag"),
+		Timestamp: timePart(parts, "timestamp"),
+		Severity:  intPart(parts, "severity"),
+		Facility:  intPart(parts, "facility"),
+	}, nil
+}
+
+func parseRFC5424(buff []byte) (Msg, error) {
+	p := rfc5424.NewParser(buff)
+
+	err := p.Parse()
+	if err != nil {
+		return Msg{}, err
+	}
+
+	parts := p.Dump()
+
+	content, ok := parts["message"].(string)
+	if !ok {
+		return Msg{}, bosherr.Error("Retrieving syslog message string content")
+	}
+
+	structuredData, err := parseStructuredData(stringPart(parts, "structured_data"))
+	if err != nil {
+		return Msg{}, bosherr.WrapError(err, "Parsing structured data")
+	}
+
+	return Msg{
+		Format:         FormatRFC5424,
+		Content:        string(bytes.TrimPrefix([]byte(content), utf8BOM)),
+		Hostname:       nilValue(stringPart(parts, "hostname")),
+		AppName:        nilValue(stringPart(parts, "app_name")),
+		ProcID:         nilValue(stringPart(parts, "proc_id")),
+		MsgID:          nilValue(stringPart(parts, "msg_id")),
+		Timestamp:      timePart(parts, "timestamp"),
+		Severity:       intPart(parts, "severity"),
+		Facility:       intPart(parts, "facility"),
+		StructuredData: structuredData,
+	}, nil
+}
+
+// parseStructuredData parses SD-ELEMENTs as defined in
+// https://tools.ietf.org/html/rfc5424#section-6.3
+func parseStructuredData(sd string) (StructuredData, error) {
+	if sd == "" || sd == "-" {
+		return nil, nil
+	}
+
+	data := StructuredData{}
+	i := 0
+
+	for i < len(sd) {
+		if sd[i] != '[' {
+			return nil, bosherr.Errorf("Expected '[' at position %d", i)
+		}
+		i++
+
+		start := i
+		for i < len(sd) && sd[i] != ' ' && sd[i] != ']' {
+			i++
+		}
+		if i == start {
+			return nil, bosherr.Errorf("Missing SD-ID at position %d", start)
+		}
+
+		params := map[string]string{}
+		data[sd[start:i]] = params
+
+		for i < len(sd) && sd[i] == ' ' {
+			i++
+
+			start = i
+			for i < len(sd) && sd[i] != '=' {
+				i++
+			}
+			if i+1 >= len(sd) || sd[i+1] != '"' {
+				return nil, bosherr.Errorf("Malformed SD-PARAM at position %d", start)
+			}
+			name := sd[start:i]
+			i += 2
+
+			var value bytes.Buffer
+			for i < len(sd) && sd[i] != '"' {
+				if sd[i] == '\\' && i+1 < len(sd) && (sd[i+1] == '"' || sd[i+1] == '\\' || sd[i+1] == ']') {
+					i++
+				}
+				value.WriteByte(sd[i])
+				i++
+			}
+			if i >= len(sd) {
+				return nil, bosherr.Errorf("Unterminated value for SD-PARAM '%s'", name)
+			}
+			i++
+
+			params[name] = value.String()
+		}
+
+		if i >= len(sd) || sd[i] != ']' {
+			return nil, bosherr.Errorf("Expected ']' at position %d", i)
+		}
+		i++
+	}
+
+	return data, nil
+}
+
+func stringPart(parts syslogparser.LogParts, key string) string {
+	value, _ := parts[key].(string)
+	return value
+}
+
+func intPart(parts syslogparser.LogParts, key string) int {
+	value, _ := parts[key].(int)
+	return value
+}
+
+func timePart(parts syslogparser.LogParts, key string) time.Time {
+	value, _ := parts[key].(time.Time)
+	return value
+}
+
+func nilValue(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
+}
@@ -2,42 +2,71 @@ package syslog
 
 import (
 	"bufio"
+	"bytes"
 	"net"
 	"strconv"
 	"sync"
 
-	"github.com/jeromer/syslogparser/rfc3164"
-
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
 const concreteServerLogTag = "concreteServer"
 
+// maxDatagramSize is the largest payload a UDP datagram can carry
+const maxDatagramSize = 65535
+
 type concreteServer struct {
 	port   uint16
 	logger boshlog.Logger
 
-	listener         net.Listener
-	lock             sync.Mutex
-	listenerProvider func(protocol, address string) (net.Listener, error)
+	listener               net.Listener
+	packetConn             net.PacketConn
+	lock                   sync.Mutex
+	listenerProvider       func(protocol, address string) (net.Listener, error)
+	packetListenerProvider func(protocol, address string) (net.PacketConn, error)
 }
 
-func NewServer(port uint16, listenerProvider func(protocol, address string) (net.Listener, error), logger boshlog.Logger) Server {
-	return &concreteServer{port: port, logger: logger, listenerProvider: listenerProvider}
+// NewServer returns a server accepting RFC 3164 and RFC 5424 messages over
+// TCP and, when packetListenerProvider is not nil, over UDP on the same port.
+func NewServer(
+	port uint16,
+	listenerProvider func(protocol, address string) (net.Listener, error),
+	packetListenerProvider func(protocol, address string) (net.PacketConn, error),
+	logger boshlog.Logger,
+) Server {
+	return &concreteServer{
+		port:                   port,
+		logger:                 logger,
+		listenerProvider:       listenerProvider,
+		packetListenerProvider: packetListenerProvider,
+	}
 }
 
 func (s *concreteServer) Start(callback CallbackFunc) error {
 	var err error
 
+	address := "127.0.0.1:" + strconv.Itoa(int(s.port))
+
 	s.lock.Lock()
 
-	s.listener, err = s.listenerProvider("tcp", "127.0.0.1:"+strconv.Itoa(int(s.port)))
+	s.listener, err = s.listenerProvider("tcp", address)
 	if err != nil {
 		s.lock.Unlock()
 		return bosherr.WrapErrorf(err, "Listening on port %d", s.port)
 	}
 
+	if s.packetListenerProvider != nil {
+		s.packetConn, err = s.packetListenerProvider("udp", address)
+		if err != nil {
+			_ = s.listener.Close()
+			s.lock.Unlock()
+			return bosherr.WrapErrorf(err, "Listening on UDP port %d", s.port)
+		}
+
+		go s.handlePackets(s.packetConn, callback)
+	}
+
 	// Should not defer unlock since there is a long-running loop
 	s.lock.Unlock()
 
@@ -55,6 +84,12 @@ func (s *concreteServer) Stop() error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	if s.packetConn != nil {
+		if err := s.packetConn.Close(); err != nil {
+			s.logger.Error(concreteServerLogTag, "Failed to close UDP listener: %s", err.Error())
+		}
+	}
+
 	if s.listener != nil {
 		return s.listener.Close()
 	}
@@ -70,43 +105,48 @@ func (s *concreteServer) handleConnection(conn net.Conn, callback CallbackFunc)
 	}()
 
 	scanner := bufio.NewScanner(conn)
+	scanner.Split(scanFrames)
 
 	for scanner.Scan() {
-		bytes := scanner.Bytes()
+		s.handleMessage(scanner.Bytes(), callback)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		s.logger.Error(
+			concreteServerLogTag,
+			"Scanner error while parsing syslog message: %s",
+			err.Error(),
+		)
+	}
+}
 
-		p := rfc3164.NewParser(bytes)
+// handlePackets treats each datagram as a single message as described in
+// https://tools.ietf.org/html/rfc5426#section-3.1
+func (s *concreteServer) handlePackets(conn net.PacketConn, callback CallbackFunc) {
+	buf := make([]byte, maxDatagramSize)
 
-		err := p.Parse()
+	for {
+		n, _, err := conn.ReadFrom(buf)
 		if err != nil {
-			s.logger.Error(
-				concreteServerLogTag,
-				"Failed to parse syslog message: %s error: %s",
-				string(bytes), err.Error(),
-			)
-			continue
+			s.logger.Debug(concreteServerLogTag, "Stopped reading UDP messages: %s", err.Error())
+			return
 		}
 
-		content, ok := p.Dump()["content"].(string)
-		if !ok {
-			s.logger.Error(
-				concreteServerLogTag,
-				"Failed to retrieve syslog message string content: %s",
-				string(bytes),
-			)
-			continue
-		}
-
-		message := Msg{Content: content}
-
-		callback(message)
+		s.handleMessage(bytes.TrimRight(buf[:n], "\r\n"), callback)
 	}
+}
 
-	err := scanner.Err()
+func (s *concreteServer) handleMessage(buff []byte, callback CallbackFunc) {
+	message, err := parseMessage(buff)
 	if err != nil {
 		s.logger.Error(
 			concreteServerLogTag,
-			"Scanner error while parsing syslog message: %s",
-			err.Error(),
+			"Failed to parse syslog message: %s error: %s",
+			string(buff), err.Error(),
 		)
+		return
 	}
+
+	callback(message)
 }
@@ -1,7 +1,32 @@
 package syslog
 
+import (
+	"time"
+)
+
+type Format int
+
+const (
+	FormatRFC3164 Format = iota
+	FormatRFC5424
+)
+
+// StructuredData maps RFC 5424 SD-IDs to their SD-PARAMs
+type StructuredData map[string]map[string]string
+
 type Msg struct {
 	Content string
+
+	Format    Format
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	Timestamp time.Time
+	Severity  int
+	Facility  int
+
+	StructuredData StructuredData
 }
 
 type CallbackFunc func(Msg)
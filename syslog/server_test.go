@@ -25,6 +25,8 @@ var _ = Describe("Server", func() {
 		server           Server
 		msgs             *msgCollector
 		listenerProvider func(protocol, address string) (net.Listener, error)
+
+		packetListenerProvider func(protocol, address string) (net.PacketConn, error)
 	)
 
 	grabEphemeralPort := func() uint16 {
@@ -68,7 +70,10 @@ var _ = Describe("Server", func() {
 		listenerProvider = func(protocol, Iaddr string) (net.Listener, error) {
 			return net.Listen(protocol, Iaddr)
 		}
-		server = NewServer(serverPort, listenerProvider, logger)
+		packetListenerProvider = func(protocol, Iaddr string) (net.PacketConn, error) {
+			return net.ListenPacket(protocol, Iaddr)
+		}
+		server = NewServer(serverPort, listenerProvider, packetListenerProvider, logger)
 		msgs = &msgCollector{}
 	})
 
@@ -142,11 +147,116 @@ var _ = Describe("Server", func() {
 		Expect(contents).To(ContainElement("msg4"))
 	})
 
+	It("parses RFC 5424 messages including structured data", func() {
+		doneCh := make(chan struct{})
+		go server.Start(captureNMsgs(msgs, doneCh, 2))
+
+		conn, err := waitToDial()
+		Expect(err).ToNot(HaveOccurred())
+
+		fmt.Fprintf(conn, "<165>1 2003-10-11T22:14:15.003Z mymachine evntslog 123 ID47 [exampleSDID@32473 iut=\"3\" eventSource=\"App\\\"lication\"][origin ip=\"10.0.0.1\"] msg1\n")
+		fmt.Fprintf(conn, "<38>1 - - sshd - - - msg2\n")
+
+		<-doneCh
+
+		err = server.Stop()
+		Expect(err).ToNot(HaveOccurred())
+
+		messages := msgs.Msgs()
+		Expect(len(messages)).To(Equal(2))
+
+		Expect(messages[0].Format).To(Equal(FormatRFC5424))
+		Expect(messages[0].Content).To(Equal("msg1"))
+		Expect(messages[0].Hostname).To(Equal("mymachine"))
+		Expect(messages[0].AppName).To(Equal("evntslog"))
+		Expect(messages[0].ProcID).To(Equal("123"))
+		Expect(messages[0].MsgID).To(Equal("ID47"))
+		Expect(messages[0].Severity).To(Equal(5))
+		Expect(messages[0].Facility).To(Equal(20))
+		Expect(messages[0].Timestamp).To(Equal(time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)))
+		Expect(messages[0].StructuredData).To(Equal(StructuredData{
+			"exampleSDID@32473": {"iut": "3", "eventSource": "App\"lication"},
+			"origin":            {"ip": "10.0.0.1"},
+		}))
+
+		Expect(messages[1].Format).To(Equal(FormatRFC5424))
+		Expect(messages[1].Content).To(Equal("msg2"))
+		Expect(messages[1].Hostname).To(Equal(""))
+		Expect(messages[1].AppName).To(Equal("sshd"))
+		Expect(messages[1].StructuredData).To(BeNil())
+	})
+
+	It("accepts octet-counted framing mixed with newline framing", func() {
+		doneCh := make(chan struct{})
+		go server.Start(captureNMsgs(msgs, doneCh, 3))
+
+		conn, err := waitToDial()
+		Expect(err).ToNot(HaveOccurred())
+
+		msg1 := "<38>1 2016-01-01T00:00:00Z localhost sshd 1 - - msg1\nwith newline"
+		msg3 := "<38>Jan  1 00:00:00 localhost sshd[22636]: msg3"
+		fmt.Fprintf(conn, "%d %s", len(msg1), msg1)
+		fmt.Fprintf(conn, "<38>Jan  1 00:00:00 localhost sshd[22636]: msg2\n")
+		fmt.Fprintf(conn, "%d %s", len(msg3), msg3)
+
+		<-doneCh
+
+		err = server.Stop()
+		Expect(err).ToNot(HaveOccurred())
+
+		messages := msgs.Msgs()
+		Expect(len(messages)).To(Equal(3))
+		Expect(messages[0].Content).To(Equal("msg1\nwith newline"))
+		Expect(messages[1].Content).To(Equal("msg2"))
+		Expect(messages[2].Content).To(Equal("msg3"))
+	})
+
+	It("accepts messages over UDP", func() {
+		doneCh := make(chan struct{})
+		go server.Start(captureNMsgs(msgs, doneCh, 2))
+
+		_, err := waitToDial()
+		Expect(err).ToNot(HaveOccurred())
+
+		conn, err := net.Dial("udp", "127.0.0.1:"+strconv.Itoa(int(serverPort)))
+		Expect(err).ToNot(HaveOccurred())
+
+		fmt.Fprintf(conn, "<38>Jan  1 00:00:00 localhost sshd[22636]: msg1\n")
+		fmt.Fprintf(conn, "<38>1 2016-01-01T00:00:00Z localhost sshd 1 - [meta seq=\"1\"] msg2")
+
+		<-doneCh
+
+		err = server.Stop()
+		Expect(err).ToNot(HaveOccurred())
+
+		messages := msgs.Msgs()
+		Expect(len(messages)).To(Equal(2))
+		Expect(messages[0].Format).To(Equal(FormatRFC3164))
+		Expect(messages[0].Content).To(Equal("msg1"))
+		Expect(messages[1].Format).To(Equal(FormatRFC5424))
+		Expect(messages[1].Content).To(Equal("msg2"))
+		Expect(messages[1].StructuredData).To(Equal(StructuredData{"meta": {"seq": "1"}}))
+	})
+
+	It("returns error if server fails to listen on UDP", func() {
+		packetListenerProvider = func(protocol, Iaddr string) (net.PacketConn, error) {
+			return nil, errors.New("Fail!")
+		}
+		server := NewServer(serverPort, listenerProvider, packetListenerProvider, logger)
+		err := server.Start(nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Listening on UDP port"))
+
+		// Make sure TCP listener was released
+		_, err = net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(int(serverPort)))
+		Expect(err).To(HaveOccurred())
+	})
+
 	It("returns error if server fails to listen", func() {
 		listenerProvider = func(protocol, Iaddr string) (net.Listener, error) {
 			return nil, errors.New("Fail!")
 		}
-		server := NewServer(10, listenerProvider, logger)
+		server := NewServer(10, listenerProvider, nil, logger)
 		err := server.Start(nil)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("Fail!"))
@@ -156,7 +266,7 @@ var _ = Describe("Server", func() {
 		outBuf := bytes.NewBufferString("")
 		errBuf := newLockedWriter(bytes.NewBufferString(""))
 		logger := boshlog.NewWriterLogger(boshlog.LevelDebug, outBuf, errBuf)
-		server = NewServer(serverPort, listenerProvider, logger)
+		server = NewServer(serverPort, listenerProvider, packetListenerProvider, logger)
 
 		doneCh := make(chan struct{})
 		go server.Start(captureNMsgs(msgs, doneCh, 2))
@@ -166,6 +276,10 @@ var _ = Describe("Server", func() {
 
 		fmt.Fprintf(conn, "<38>Jan  1 00:00:00 localhost sshd[22636]: msg1\n")
 		fmt.Fprintf(conn, "invalid-syslog-format\n")
+		fmt.Fprintf(conn, "<38>1 \n")
+		fmt.Fprintf(conn, "<38>1 - - - - - [unterminated msg\n")
+		fmt.Fprintf(conn, "<38>1 2016-01-01T00:00:00Z localhost sshd 1\n")
+		fmt.Fprintf(conn, "<38>Jan  1 00:00:00 localhost sshd[22636]\n")
 		fmt.Fprintf(conn, "<38>Jan  1 00:00:00 localhost sshd[22636]: msg2\n")
 
 		<-doneCh
@@ -175,6 +289,10 @@ var _ = Describe("Server", func() {
 
 		Expect(string(errBuf.Bytes())).To(
 			ContainSubstring("Failed to parse syslog message"))
+		Expect(string(errBuf.Bytes())).To(
+			ContainSubstring("Message does not have a complete RFC 5424 header"))
+		Expect(string(errBuf.Bytes())).To(
+			ContainSubstring("Message does not have a complete RFC 3164 header"))
 
 		// Make sure that subsequent messages are still interpreted
 		messages := msgs.Msgs()
@@ -189,7 +307,7 @@ var _ = Describe("Server", func() {
 		outBuf := bytes.NewBufferString("")
 		errBuf := newNotifyingWriter(newLockedWriter(bytes.NewBufferString("")), writeCh)
 		logger := boshlog.NewWriterLogger(boshlog.LevelDebug, outBuf, errBuf)
-		server = NewServer(serverPort, listenerProvider, logger)
+		server = NewServer(serverPort, listenerProvider, packetListenerProvider, logger)
 
 		go server.Start(nil)
 